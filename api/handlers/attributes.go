@@ -0,0 +1,245 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/geoo115/Ecommerce/db"
+	"github.com/geoo115/Ecommerce/models"
+	"github.com/geoo115/Ecommerce/services"
+	"github.com/geoo115/Ecommerce/utils"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// ListAttributes returns every product attribute with its enum options
+func ListAttributes(c *gin.Context) {
+	var attributes []models.Attribute
+	if err := db.DB.Scopes(preloadAttributeOptions).Order("name, id").Find(&attributes).Error; err != nil {
+		utils.SendInternalError(c, "Failed to fetch attributes")
+		return
+	}
+
+	utils.SendSuccess(c, http.StatusOK, "Attributes retrieved successfully", attributes)
+}
+
+// CreateAttribute defines a new product attribute (admin only)
+func CreateAttribute(c *gin.Context) {
+	var input struct {
+		Name       string   `json:"name" binding:"required,max=100"`
+		Code       string   `json:"code" binding:"max=64"`
+		Type       string   `json:"type" binding:"required"`
+		Unit       string   `json:"unit" binding:"max=20"`
+		Filterable *bool    `json:"filterable"`
+		Options    []string `json:"options"`
+	}
+	if err := Base.BindJSON(c, &input); err != nil {
+		return
+	}
+
+	attribute := models.Attribute{
+		Name:       utils.SanitizeString(input.Name),
+		Code:       input.Code,
+		Type:       input.Type,
+		Unit:       utils.SanitizeString(input.Unit),
+		Filterable: input.Filterable == nil || *input.Filterable,
+	}
+	if err := services.NewAttributeService().CreateAttribute(&attribute, input.Options); err != nil {
+		sendAttributeError(c, err)
+		return
+	}
+
+	utils.SendSuccess(c, http.StatusCreated, "Attribute created successfully", attribute)
+}
+
+// UpdateAttribute renames an attribute, changes its unit or filterability, or adds enum options (admin only)
+func UpdateAttribute(c *gin.Context) {
+	attribute, ok := loadAttribute(c)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		Name       *string  `json:"name" binding:"omitempty,min=1,max=100"`
+		Unit       *string  `json:"unit" binding:"omitempty,max=20"`
+		Filterable *bool    `json:"filterable"`
+		AddOptions []string `json:"add_options"`
+	}
+	if err := Base.BindJSON(c, &input); err != nil {
+		return
+	}
+
+	update := services.AttributeUpdate{
+		Name:       input.Name,
+		Unit:       input.Unit,
+		Filterable: input.Filterable,
+		AddOptions: input.AddOptions,
+	}
+	if err := services.NewAttributeService().UpdateAttribute(attribute, update); err != nil {
+		sendAttributeError(c, err)
+		return
+	}
+	invalidateProductCache(0)
+
+	utils.SendSuccess(c, http.StatusOK, "Attribute updated successfully", attribute)
+}
+
+// DeleteAttribute removes an attribute and every product's value for it (admin only)
+func DeleteAttribute(c *gin.Context) {
+	attribute, ok := loadAttribute(c)
+	if !ok {
+		return
+	}
+
+	if err := services.NewAttributeService().DeleteAttribute(attribute); err != nil {
+		utils.SendInternalError(c, "Failed to delete attribute")
+		return
+	}
+	invalidateProductCache(0)
+
+	utils.SendSuccess(c, http.StatusOK, "Attribute deleted successfully", nil)
+}
+
+// ListCategoryAttributes returns the attributes that apply to a category, including those
+// inherited from its parent categories
+func ListCategoryAttributes(c *gin.Context) {
+	categoryID, err := Base.ValidateIDParam(c, "id")
+	if err != nil {
+		return
+	}
+
+	attributes, err := services.NewAttributeService().CategoryAttributes(categoryID)
+	if err != nil {
+		sendAttributeError(c, err)
+		return
+	}
+
+	utils.SendSuccess(c, http.StatusOK, "Category attributes retrieved successfully", attributes)
+}
+
+// AssignCategoryAttribute makes an attribute available to a category and its subcategories (admin only)
+func AssignCategoryAttribute(c *gin.Context) {
+	categoryID, err := Base.ValidateIDParam(c, "id")
+	if err != nil {
+		return
+	}
+
+	var input struct {
+		AttributeID uint `json:"attribute_id" binding:"required"`
+		Position    int  `json:"position" binding:"min=0"`
+	}
+	if err := Base.BindJSON(c, &input); err != nil {
+		return
+	}
+
+	assignment, err := services.NewAttributeService().AssignToCategory(categoryID, input.AttributeID, input.Position)
+	if err != nil {
+		sendAttributeError(c, err)
+		return
+	}
+
+	utils.SendSuccess(c, http.StatusCreated, "Attribute assigned successfully", assignment)
+}
+
+// UnassignCategoryAttribute removes an attribute from a category (admin only)
+func UnassignCategoryAttribute(c *gin.Context) {
+	categoryID, err := Base.ValidateIDParam(c, "id")
+	if err != nil {
+		return
+	}
+	attributeID, err := Base.ValidateIDParam(c, "attribute_id")
+	if err != nil {
+		return
+	}
+
+	if err := services.NewAttributeService().UnassignFromCategory(categoryID, attributeID); err != nil {
+		sendAttributeError(c, err)
+		return
+	}
+	invalidateProductCache(0)
+
+	utils.SendSuccess(c, http.StatusOK, "Attribute unassigned successfully", nil)
+}
+
+// SetProductAttributes sets a product's attribute values keyed by attribute code (admin only).
+// A null value removes the product's value for that attribute.
+func SetProductAttributes(c *gin.Context) {
+	productID, err := Base.ValidateIDParam(c, "id")
+	if err != nil {
+		return
+	}
+
+	var input struct {
+		Values map[string]interface{} `json:"values" binding:"required"`
+	}
+	if err := Base.BindJSON(c, &input); err != nil {
+		return
+	}
+
+	var product models.Product
+	if err := db.DB.First(&product, productID).Error; err != nil {
+		Base.HandleDBError(c, err, "Product not found", "Failed to fetch product")
+		return
+	}
+
+	if err := services.NewAttributeService().SetProductValues(&product, input.Values); err != nil {
+		sendAttributeError(c, err)
+		return
+	}
+	invalidateProductCache(product.ID)
+
+	var values []models.ProductAttributeValue
+	if err := db.DB.Preload("Attribute").Where("product_id = ?", product.ID).Order("attribute_id").Find(&values).Error; err != nil {
+		utils.SendInternalError(c, "Failed to fetch product attributes")
+		return
+	}
+
+	utils.SendSuccess(c, http.StatusOK, "Product attributes updated successfully", values)
+}
+
+// loadAttribute fetches the attribute in the :id param with its enum options
+func loadAttribute(c *gin.Context) (*models.Attribute, bool) {
+	attributeID, err := Base.ValidateIDParam(c, "id")
+	if err != nil {
+		return nil, false
+	}
+
+	var attribute models.Attribute
+	if err := db.DB.Scopes(preloadAttributeOptions).First(&attribute, attributeID).Error; err != nil {
+		Base.HandleDBError(c, err, "Attribute not found", "Failed to fetch attribute")
+		return nil, false
+	}
+	return &attribute, true
+}
+
+// preloadAttributeOptions loads enum options in display order
+func preloadAttributeOptions(query *gorm.DB) *gorm.DB {
+	return query.Preload("Options", func(options *gorm.DB) *gorm.DB {
+		return options.Order("position, id")
+	})
+}
+
+// sendAttributeError maps attribute errors to responses
+func sendAttributeError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrAttributeNotFound):
+		utils.SendNotFound(c, "Attribute not found")
+	case errors.Is(err, services.ErrCategoryNotFound):
+		utils.SendNotFound(c, "Category not found")
+	case errors.Is(err, services.ErrDuplicateAttribute):
+		utils.SendConflict(c, "Attribute code is already in use")
+	case errors.Is(err, services.ErrInvalidAttributeType):
+		utils.SendValidationError(c, "Type must be string, number, boolean or enum")
+	case errors.Is(err, services.ErrInvalidAttributeCode):
+		utils.SendValidationError(c, "Code may only contain lowercase letters, digits and hyphens")
+	case errors.Is(err, services.ErrAttributeOptions):
+		utils.SendValidationError(c, "Options can only be set on enum attributes, which need at least one")
+	case errors.Is(err, services.ErrNoChanges):
+		utils.SendValidationError(c, "Update contains no changes")
+	case errors.Is(err, services.ErrAttributeNotAssigned), errors.Is(err, services.ErrInvalidAttributeValue):
+		// These carry the offending attribute code
+		utils.SendValidationError(c, err.Error())
+	default:
+		utils.SendInternalError(c, "Failed to save attribute")
+	}
+}
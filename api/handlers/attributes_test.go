@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/geoo115/Ecommerce/db"
+	"github.com/geoo115/Ecommerce/models"
+	"github.com/geoo115/Ecommerce/services"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAttributeEndpoints(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	SetupTestDB(t)
+
+	category := models.Category{Name: "Shoes"}
+	db.DB.Create(&category)
+	boot := models.Product{Name: "Hiking Boot", Price: 120, CategoryID: category.ID}
+	db.DB.Create(&boot)
+	sandal := models.Product{Name: "Sandal", Price: 40, CategoryID: category.ID}
+	db.DB.Create(&sandal)
+
+	router := gin.New()
+	router.POST("/attributes", CreateAttribute)
+	router.PUT("/attributes/:id", UpdateAttribute)
+	router.GET("/categories/:id/attributes", ListCategoryAttributes)
+	router.POST("/categories/:id/attributes", AssignCategoryAttribute)
+	router.PUT("/product/:id/attributes", SetProductAttributes)
+	router.GET("/product/:id", GetProduct)
+	router.GET("/products", ListProducts)
+
+	send := func(method, path, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, path, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := send("POST", "/attributes", `{"name":"Material","type":"enum","options":["Leather","Canvas"]}`)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	var created struct {
+		Data models.Attribute `json:"data"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &created)
+	assert.Equal(t, "material", created.Data.Code)
+	assert.True(t, created.Data.Filterable)
+
+	w = send("POST", "/attributes", `{"name":"Waterproof","type":"boolean"}`)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	var waterproof struct {
+		Data models.Attribute `json:"data"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &waterproof)
+
+	w = send("POST", "/attributes", `{"name":"Material","type":"string"}`)
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	w = send("PUT", fmt.Sprintf("/attributes/%d", created.Data.ID), `{"add_options":["Rubber"]}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	for _, id := range []uint{created.Data.ID, waterproof.Data.ID} {
+		w = send("POST", fmt.Sprintf("/categories/%d/attributes", category.ID), fmt.Sprintf(`{"attribute_id":%d}`, id))
+		assert.Equal(t, http.StatusCreated, w.Code)
+	}
+	w = send("GET", fmt.Sprintf("/categories/%d/attributes", category.ID), "")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = send("PUT", fmt.Sprintf("/product/%d/attributes", boot.ID), `{"values":{"material":"leather","waterproof":true}}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = send("PUT", fmt.Sprintf("/product/%d/attributes", sandal.ID), `{"values":{"material":"Rubber","waterproof":false}}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = send("PUT", fmt.Sprintf("/product/%d/attributes", sandal.ID), `{"values":{"waterproof":"yes"}}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = send("GET", fmt.Sprintf("/product/%d", boot.ID), "")
+	var fetched struct {
+		Data models.Product `json:"data"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &fetched)
+	assert.Len(t, fetched.Data.Attributes, 2)
+
+	// Filtering narrows the products and facets are counted over the filtered set
+	w = send("GET", "/products?attr.waterproof=true&facets=true", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	var listed struct {
+		Data struct {
+			Products []models.Product `json:"products"`
+			Facets   []services.Facet `json:"facets"`
+		} `json:"data"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &listed)
+	if assert.Len(t, listed.Data.Products, 1) {
+		assert.Equal(t, boot.ID, listed.Data.Products[0].ID)
+	}
+	if assert.Len(t, listed.Data.Facets, 2) {
+		assert.Equal(t, "material", listed.Data.Facets[0].Code)
+		assert.Equal(t, []services.FacetValue{{Value: "Leather", Count: 1}}, listed.Data.Facets[0].Values)
+	}
+
+	w = send("GET", "/products?attr.colour=red", "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
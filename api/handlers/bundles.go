@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/geoo115/Ecommerce/db"
+	"github.com/geoo115/Ecommerce/models"
+	"github.com/geoo115/Ecommerce/services"
+	"github.com/geoo115/Ecommerce/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// SetProductBundle makes a product a bundle of other products, replacing its components (admin only).
+// The bundle keeps its own price; its stock is what its components' stock makes up.
+func SetProductBundle(c *gin.Context) {
+	productID, err := Base.ValidateIDParam(c, "id")
+	if err != nil {
+		return
+	}
+
+	var input struct {
+		Components []services.BundleComponent `json:"components" binding:"required,min=1"`
+	}
+	if err := Base.BindJSON(c, &input); err != nil {
+		return
+	}
+
+	var product models.Product
+	if err := db.DB.First(&product, productID).Error; err != nil {
+		Base.HandleDBError(c, err, "Product not found", "Failed to fetch product")
+		return
+	}
+
+	if err := services.NewBundleService().SetComponents(&product, input.Components); err != nil {
+		sendBundleError(c, err)
+		return
+	}
+	invalidateProductCache(product.ID)
+
+	var bundle models.Product
+	if err := db.DB.Preload("Inventory").Preload("BundleItems.Component").First(&bundle, product.ID).Error; err != nil {
+		utils.SendInternalError(c, "Failed to load bundle")
+		return
+	}
+	products := []models.Product{bundle}
+	attachComputedStock(db.DB, products)
+
+	utils.SendSuccess(c, http.StatusOK, "Bundle updated successfully", products[0])
+}
+
+// sendBundleError maps bundle errors to responses
+func sendBundleError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrInvalidBundle),
+		errors.Is(err, services.ErrBundleComponent),
+		errors.Is(err, services.ErrBundleVariants),
+		errors.Is(err, services.ErrDigitalConflict):
+		utils.SendValidationError(c, err.Error())
+	default:
+		utils.SendInternalError(c, "Failed to save bundle")
+	}
+}
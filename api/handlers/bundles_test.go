@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/geoo115/Ecommerce/db"
+	"github.com/geoo115/Ecommerce/models"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProductBundleEndpoints(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	SetupTestDB(t)
+
+	user := models.User{Username: "kitbuyer", Email: "kitbuyer@example.com"}
+	db.DB.Create(&user)
+	camera := models.Product{Name: "Camera", Price: 600}
+	lens := models.Product{Name: "Lens", Price: 250}
+	kit := models.Product{Name: "Camera Kit", Price: 800}
+	for _, product := range []*models.Product{&camera, &lens, &kit} {
+		db.DB.Create(product)
+	}
+	db.DB.Create(&models.Inventory{ProductID: camera.ID, Stock: 3})
+	db.DB.Create(&models.Inventory{ProductID: lens.ID, Stock: 4})
+	db.DB.Create(&models.Inventory{ProductID: kit.ID, Stock: 0})
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) { c.Set("userID", user.ID) })
+	router.GET("/product/:id", GetProduct)
+	router.PUT("/product/:id/bundle", SetProductBundle)
+	router.POST("/cart", AddToCart)
+
+	send := func(method, path, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, path, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+		return w
+	}
+	base := fmt.Sprintf("/product/%d", kit.ID)
+
+	assert.Equal(t, http.StatusBadRequest, send("PUT", base+"/bundle", `{"components":[]}`).Code)
+	assert.Equal(t, http.StatusBadRequest, send("PUT", base+"/bundle", fmt.Sprintf(`{"components":[{"product_id":%d,"quantity":1}]}`, kit.ID)).Code)
+	assert.Equal(t, http.StatusNotFound, send("PUT", "/product/9999/bundle", fmt.Sprintf(`{"components":[{"product_id":%d,"quantity":1}]}`, camera.ID)).Code)
+
+	w := send("PUT", base+"/bundle", fmt.Sprintf(`{"components":[{"product_id":%d,"quantity":1},{"product_id":%d,"quantity":2}]}`, camera.ID, lens.ID))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// The bundle's stock is what its components make up: camera 3, lens 4/2
+	var response struct {
+		Data models.Product `json:"data"`
+	}
+	w = send("GET", base, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	json.Unmarshal(w.Body.Bytes(), &response)
+	assert.Equal(t, models.ProductTypeBundle, response.Data.Type)
+	assert.Equal(t, 2, response.Data.Inventory.Stock)
+	if assert.Len(t, response.Data.BundleItems, 2) {
+		assert.Equal(t, "Lens", response.Data.BundleItems[1].Component.Name)
+	}
+
+	assert.Equal(t, http.StatusBadRequest, send("POST", "/cart", fmt.Sprintf(`{"product_id":%d,"quantity":3}`, kit.ID)).Code)
+	assert.Equal(t, http.StatusCreated, send("POST", "/cart", fmt.Sprintf(`{"product_id":%d,"quantity":2}`, kit.ID)).Code)
+}
@@ -7,14 +7,16 @@ import (
 
 	"github.com/geoo115/Ecommerce/db"
 	"github.com/geoo115/Ecommerce/models"
+	"github.com/geoo115/Ecommerce/services"
 	"github.com/geoo115/Ecommerce/utils"
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 )
 
 type AddToCartInput struct {
-	ProductID uint `json:"product_id" binding:"required"`
-	Quantity  int  `json:"quantity" binding:"required,min=1"`
+	ProductID uint  `json:"product_id" binding:"required"`
+	VariantID *uint `json:"variant_id"` // Required for products with variants
+	Quantity  int   `json:"quantity" binding:"required,min=1"`
 }
 
 func AddToCart(c *gin.Context) {
@@ -35,26 +37,19 @@ func AddToCart(c *gin.Context) {
 	}
 
 	// Check stock availability
-	hasStock, err := CheckStock(input.ProductID, input.Quantity)
-	if err != nil || !hasStock {
-		// Tests expect specific message
-		if err != nil && err.Error() == "Insufficient stock for product" {
-			utils.SendValidationError(c, "Insufficient stock for product")
-		} else if err != nil && err.Error() == "product not found" {
-			utils.SendNotFound(c, "product not found")
-		} else {
-			utils.SendValidationError(c, "Insufficient stock for product")
-		}
+	if _, err := CheckStock(input.ProductID, input.VariantID, input.Quantity); err != nil {
+		sendStockCheckError(c, err)
 		return
 	}
 
 	// If an item exists, increment quantity instead of creating duplicate
 	var existing models.Cart
-	if err := db.DB.Where("user_id = ? AND product_id = ?", userID, input.ProductID).First(&existing).Error; err == nil {
+	if err := db.DB.Scopes(models.WhereVariant(input.VariantID)).
+		Where("user_id = ? AND product_id = ?", userID, input.ProductID).First(&existing).Error; err == nil {
 		newQty := existing.Quantity + input.Quantity
 		// Stock check already done for input qty; ensure combined qty still within stock
-		if ok, _ := CheckStock(input.ProductID, newQty); !ok {
-			utils.SendValidationError(c, "Insufficient stock for product")
+		if _, err := CheckStock(input.ProductID, input.VariantID, newQty); err != nil {
+			sendStockCheckError(c, err)
 			return
 		}
 		existing.Quantity = newQty
@@ -70,6 +65,7 @@ func AddToCart(c *gin.Context) {
 	cartItem := models.Cart{
 		UserID:    userID,
 		ProductID: input.ProductID,
+		VariantID: input.VariantID,
 		Quantity:  input.Quantity,
 	}
 
@@ -81,20 +77,33 @@ func AddToCart(c *gin.Context) {
 	utils.SendSuccess(c, http.StatusCreated, "Item added to cart", cartItem)
 }
 
-func CheckStock(productID uint, quantity int) (bool, error) {
-	var inventory models.Inventory
-	if err := db.DB.Where("product_id = ?", productID).First(&inventory).Error; err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return false, errors.New("product not found")
-		}
+// CheckStock verifies a product, or the chosen variant of a product with variants, has enough stock.
+// Drafts are reported as missing products.
+func CheckStock(productID uint, variantID *uint, quantity int) (bool, error) {
+	stock, err := services.AvailableStock(db.DB, productID, variantID)
+	if err != nil {
 		return false, err
 	}
-	if inventory.Stock < quantity {
+	if stock < quantity {
 		return false, errors.New("Insufficient stock for product")
 	}
 	return true, nil
 }
 
+// sendStockCheckError maps a failed stock check to a response
+func sendStockCheckError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrProductNotFound):
+		utils.SendNotFound(c, "Product not found")
+	case errors.Is(err, services.ErrVariantNotFound):
+		utils.SendNotFound(c, "Variant not found")
+	case errors.Is(err, services.ErrVariantRequired):
+		utils.SendValidationError(c, "A variant must be selected for this product")
+	default:
+		utils.SendValidationError(c, "Insufficient stock for product")
+	}
+}
+
 func ListCart(c *gin.Context) {
 	var cartItems []models.Cart
 	userID, exists := c.Get("userID")
@@ -106,6 +115,7 @@ func ListCart(c *gin.Context) {
 	if err := db.DB.Where("user_id = ?", userID).
 		Preload("Product.Category").
 		Preload("Product.Inventory").
+		Preload("Variant").
 		Preload("User").
 		Find(&cartItems).Error; err != nil {
 		utils.SendInternalError(c, "Failed to list cart items")
@@ -115,7 +125,7 @@ func ListCart(c *gin.Context) {
 	// Calculate total amount
 	var totalAmount float64
 	for _, item := range cartItems {
-		totalAmount += float64(item.Quantity) * item.Product.Price
+		totalAmount += float64(item.Quantity) * item.UnitPrice()
 	}
 
 	utils.SendSuccess(c, http.StatusOK, "Cart items retrieved successfully", gin.H{
@@ -184,9 +194,8 @@ func UpdateCartItem(c *gin.Context) {
 	}
 
 	// Check stock availability
-	hasStock, err := CheckStock(cartItem.ProductID, input.Quantity)
-	if err != nil || !hasStock {
-		utils.SendValidationError(c, "Insufficient stock for product")
+	if _, err := CheckStock(cartItem.ProductID, cartItem.VariantID, input.Quantity); err != nil {
+		sendStockCheckError(c, err)
 		return
 	}
 
@@ -236,7 +236,7 @@ func TestAddToCart_ProductNotFound(t *testing.T) {
 	router.ServeHTTP(w, req)
 
 	assert.Equal(t, http.StatusNotFound, w.Code)
-	assert.Contains(t, w.Body.String(), "product not found")
+	assert.Contains(t, w.Body.String(), "Product not found")
 }
 
 func TestListCart_Success(t *testing.T) {
@@ -565,7 +565,7 @@ func TestCheckStock_Sufficient(t *testing.T) {
 	inv := models.Inventory{ProductID: prod.ID, Stock: 10}
 	db.DB.Create(&inv)
 
-	hasStock, err := CheckStock(prod.ID, 5)
+	hasStock, err := CheckStock(prod.ID, nil, 5)
 	assert.NoError(t, err)
 	assert.True(t, hasStock)
 }
@@ -583,7 +583,7 @@ func TestCheckStock_Insufficient(t *testing.T) {
 	inv := models.Inventory{ProductID: prod.ID, Stock: 3}
 	db.DB.Create(&inv)
 
-	hasStock, err := CheckStock(prod.ID, 5)
+	hasStock, err := CheckStock(prod.ID, nil, 5)
 	assert.Error(t, err)
 	assert.False(t, hasStock)
 	assert.Contains(t, err.Error(), "Insufficient stock for product")
@@ -592,7 +592,7 @@ func TestCheckStock_Insufficient(t *testing.T) {
 func TestCheckStock_ProductNotFound(t *testing.T) {
 	SetupTestDB(t)
 
-	hasStock, err := CheckStock(999, 1)
+	hasStock, err := CheckStock(999, nil, 1)
 	assert.Error(t, err)
 	assert.False(t, hasStock)
 	assert.Contains(t, err.Error(), "product not found")
@@ -601,7 +601,7 @@ func TestCheckStock_ProductNotFound(t *testing.T) {
 func BenchmarkAddToCart(b *testing.B) {
 	gin.SetMode(gin.TestMode)
 	testDB, _ := gorm.Open(sqlite.Open("file::memory:?cache=shared"), &gorm.Config{})
-	testDB.AutoMigrate(&models.User{}, &models.Category{}, &models.Product{}, &models.Inventory{}, &models.Cart{})
+	testDB.AutoMigrate(&models.User{}, &models.Category{}, &models.Product{}, &models.Inventory{}, &models.ProductVariant{}, &models.Cart{})
 	db.DB = testDB
 
 	// Setup test data
@@ -641,7 +641,7 @@ func BenchmarkAddToCart(b *testing.B) {
 func BenchmarkListCart(b *testing.B) {
 	gin.SetMode(gin.TestMode)
 	testDB, _ := gorm.Open(sqlite.Open("file::memory:?cache=shared"), &gorm.Config{})
-	testDB.AutoMigrate(&models.User{}, &models.Category{}, &models.Product{}, &models.Inventory{}, &models.Cart{})
+	testDB.AutoMigrate(&models.User{}, &models.Category{}, &models.Product{}, &models.Inventory{}, &models.ProductVariant{}, &models.Cart{})
 	db.DB = testDB
 
 	// Setup test data
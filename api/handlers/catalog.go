@@ -0,0 +1,118 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strings"
+
+	"github.com/geoo115/Ecommerce/cache"
+	"github.com/geoo115/Ecommerce/config"
+	"github.com/geoo115/Ecommerce/services"
+	"github.com/geoo115/Ecommerce/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// ImportCatalog upserts products by SKU from a CSV or NDJSON file (admin only). The file is
+// the request body, or a multipart "file" field. The format comes from the format query
+// parameter, the file extension or the content type. With dry_run=true the file is only validated.
+func ImportCatalog(c *gin.Context) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, config.GetCatalogConfig().MaxImportSize)
+
+	var source io.Reader = c.Request.Body
+	formatHint := c.ContentType()
+	if strings.HasPrefix(formatHint, "multipart/") {
+		fileHeader, err := c.FormFile("file")
+		if err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				sendCatalogError(c, err)
+				return
+			}
+			utils.SendValidationError(c, "A catalog file is required")
+			return
+		}
+		file, err := fileHeader.Open()
+		if err != nil {
+			utils.SendInternalError(c, "Failed to read upload")
+			return
+		}
+		defer file.Close()
+		source = file
+		formatHint = strings.TrimPrefix(filepath.Ext(fileHeader.Filename), ".")
+	}
+	if value := c.Query("format"); value != "" {
+		formatHint = value
+	}
+	format, err := services.ParseCatalogFormat(formatHint)
+	if err != nil {
+		sendCatalogError(c, err)
+		return
+	}
+
+	report, err := services.NewCatalogService().Import(source, format, c.Query("dry_run") == "true", c.GetUint("userID"))
+	if err != nil {
+		sendCatalogError(c, err)
+		return
+	}
+	if len(report.Errors) > 0 {
+		c.JSON(http.StatusUnprocessableEntity, utils.APIResponse{
+			Success: false,
+			Error:   "Import has invalid rows; nothing was saved",
+			Data:    report,
+			Code:    http.StatusUnprocessableEntity,
+		})
+		return
+	}
+	if !report.Committed {
+		utils.SendSuccess(c, http.StatusOK, "Catalog is valid; nothing was saved", report)
+		return
+	}
+
+	// An import can touch any product, so every cached listing and product is dropped
+	services.InvalidateSearchIndex()
+	if cch := cache.GetCache(); cch != nil {
+		if err := cch.Clear(); err != nil {
+			utils.Warn("Failed to clear cache after catalog import: %v", err)
+		}
+	}
+	utils.SendSuccess(c, http.StatusOK, "Catalog imported successfully", report)
+}
+
+// ExportCatalog streams every product as CSV or NDJSON, in the columns ImportCatalog accepts (admin only)
+func ExportCatalog(c *gin.Context) {
+	format, err := services.ParseCatalogFormat(c.DefaultQuery("format", services.CatalogFormatCSV))
+	if err != nil {
+		sendCatalogError(c, err)
+		return
+	}
+
+	contentType := "text/csv; charset=utf-8"
+	if format == services.CatalogFormatNDJSON {
+		contentType = "application/x-ndjson"
+	}
+	c.Header("Content-Type", contentType)
+	c.Header("Content-Disposition", `attachment; filename="catalog.`+format+`"`)
+	c.Status(http.StatusOK)
+
+	// Headers are already sent, so a failure part-way can only be logged
+	if err := services.NewCatalogService().Export(c.Writer, format); err != nil {
+		utils.Error("Catalog export failed: %v", err)
+	}
+}
+
+// sendCatalogError maps catalog import errors to HTTP responses
+func sendCatalogError(c *gin.Context, err error) {
+	var tooLarge *http.MaxBytesError
+	switch {
+	case errors.As(err, &tooLarge):
+		utils.SendError(c, http.StatusRequestEntityTooLarge, "Import file exceeds the maximum size")
+	case errors.Is(err, services.ErrCatalogTooLarge):
+		utils.SendError(c, http.StatusRequestEntityTooLarge, err.Error())
+	case errors.Is(err, services.ErrCatalogFormat), errors.Is(err, services.ErrCatalogFile):
+		utils.SendValidationError(c, err.Error())
+	default:
+		utils.SendInternalError(c, "Failed to import catalog")
+	}
+}
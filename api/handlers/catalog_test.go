@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/geoo115/Ecommerce/db"
+	"github.com/geoo115/Ecommerce/models"
+	"github.com/geoo115/Ecommerce/services"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCatalogImportExport(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	SetupTestDB(t)
+
+	router := gin.New()
+	router.POST("/product/import", ImportCatalog)
+	router.GET("/product/export", ExportCatalog)
+
+	send := func(path, contentType string, body *bytes.Buffer) (*httptest.ResponseRecorder, services.ImportReport) {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", path, body)
+		req.Header.Set("Content-Type", contentType)
+		router.ServeHTTP(w, req)
+		var response struct {
+			Data services.ImportReport `json:"data"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &response)
+		return w, response.Data
+	}
+	csvFile := "sku,name,price,stock,category\nLM-1,Desk Lamp,25,5,Lighting\nLM-2,Floor Lamp,80,2,Lighting\n"
+
+	t.Run("DryRun", func(t *testing.T) {
+		w, report := send("/product/import?dry_run=true", "text/csv", bytes.NewBufferString(csvFile))
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.True(t, report.DryRun)
+		assert.Equal(t, 2, report.Created)
+
+		var count int64
+		db.DB.Model(&models.Product{}).Count(&count)
+		assert.Zero(t, count)
+	})
+
+	t.Run("InvalidRows", func(t *testing.T) {
+		body := `{"sku":"LM-3","name":"L","price":10,"category":"Lighting"}` + "\n" + `{"sku":"LM-4","colour":"red"}`
+		w, report := send("/product/import?format=ndjson", "application/octet-stream", bytes.NewBufferString(body))
+		assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+		if assert.Len(t, report.Errors, 2) {
+			assert.Equal(t, "name", report.Errors[0].Field)
+			assert.Equal(t, 1, report.Errors[0].Row)
+			assert.Equal(t, 2, report.Errors[1].Row)
+		}
+	})
+
+	t.Run("MultipartUpload", func(t *testing.T) {
+		var body bytes.Buffer
+		form := multipart.NewWriter(&body)
+		part, _ := form.CreateFormFile("file", "catalog.csv")
+		part.Write([]byte(csvFile))
+		form.Close()
+
+		w, report := send("/product/import", form.FormDataContentType(), &body)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.True(t, report.Committed)
+		assert.Equal(t, []string{"Lighting"}, report.CategoriesCreated)
+
+		var lamp models.Product
+		db.DB.Preload("Inventory").Where("sku = ?", "LM-2").First(&lamp)
+		assert.Equal(t, 80.0, lamp.Price)
+		assert.Equal(t, 2, lamp.Inventory.Stock)
+	})
+
+	t.Run("UnknownFormat", func(t *testing.T) {
+		w, _ := send("/product/import", "application/xml", bytes.NewBufferString("<catalog/>"))
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("Export", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/product/export?format=ndjson", nil)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "application/x-ndjson", w.Header().Get("Content-Type"))
+		lines := strings.Split(strings.TrimSpace(w.Body.String()), "\n")
+		if assert.Len(t, lines, 2) {
+			assert.JSONEq(t, `{"sku":"LM-1","name":"Desk Lamp","description":"","price":25,"stock":5,"category":"Lighting"}`, lines[0])
+		}
+
+		w = httptest.NewRecorder()
+		req, _ = http.NewRequest("GET", "/product/export", nil)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, "sku,name,description,price,stock,category\nLM-1,Desk Lamp,,25,5,Lighting\nLM-2,Floor Lamp,,80,2,Lighting\n", w.Body.String())
+	})
+}
@@ -1,22 +1,35 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 
 	"github.com/geoo115/Ecommerce/db"
 	"github.com/geoo115/Ecommerce/models"
+	"github.com/geoo115/Ecommerce/services"
 	"github.com/geoo115/Ecommerce/utils"
 	"github.com/gin-gonic/gin"
 )
 
 func ListCategories(c *gin.Context) {
 	var categories []models.Category
-	if err := db.DB.Find(&categories).Error; err != nil {
+	pagination, ok := Base.FetchPage(c, db.DB.Model(&models.Category{}), services.OrderByID("categories"), &categories, "Failed to fetch categories")
+	if !ok {
+		return
+	}
+
+	utils.SendPage(c, "Categories retrieved successfully", categories, pagination)
+}
+
+// CategoryTree returns all categories nested under their parents
+func CategoryTree(c *gin.Context) {
+	tree, err := services.NewCategoryService().Tree()
+	if err != nil {
 		utils.SendInternalError(c, "Failed to fetch categories")
 		return
 	}
 
-	utils.SendSuccess(c, http.StatusOK, "Categories retrieved successfully", categories)
+	utils.SendSuccess(c, http.StatusOK, "Category tree retrieved successfully", tree)
 }
 
 func AddCategory(c *gin.Context) {
@@ -39,15 +52,94 @@ func AddCategory(c *gin.Context) {
 		utils.SendValidationError(c, "Invalid category name")
 		return
 	}
+	category.Description = utils.SanitizeString(category.Description)
+	category.Children, category.Products = nil, nil
 
-	if err := db.DB.Create(&category).Error; err != nil {
-		utils.SendInternalError(c, "Failed to create category")
+	if err := services.NewCategoryService().Create(&category); err != nil {
+		sendCategoryError(c, err, "Failed to create category")
 		return
 	}
+	invalidateProductCache(0)
 
 	utils.SendSuccess(c, http.StatusCreated, "Category added successfully", category)
 }
 
+// GetCategoryBySlug retrieves a category by its slug. Former slugs redirect permanently to the current one.
+func GetCategoryBySlug(c *gin.Context) {
+	id, current, err := services.NewSlugService().Resolve(models.SlugResourceCategory, c.Param("slug"))
+	if err != nil {
+		sendSlugLookupError(c, err, "Category not found")
+		return
+	}
+	if current != c.Param("slug") {
+		redirectToSlug(c, current)
+		return
+	}
+
+	var category models.Category
+	if err := db.DB.First(&category, id).Error; err != nil {
+		Base.HandleDBError(c, err, "Category not found", "Failed to fetch category")
+		return
+	}
+	utils.SendSuccess(c, http.StatusOK, "Category retrieved successfully", category)
+}
+
+// UpdateCategory renames a category, changes its slug, description or sort order, or moves
+// it under another parent. "parent_id": 0 moves it to the top level.
+func UpdateCategory(c *gin.Context) {
+	// Only admin can update categories
+	role, _ := c.Get("userRole")
+	if role != "admin" {
+		utils.SendForbidden(c, "")
+		return
+	}
+
+	idUint, err := Base.ValidateIDParam(c, "id")
+	if err != nil {
+		return
+	}
+
+	var input struct {
+		Name        *string `json:"name"`
+		Slug        *string `json:"slug"`
+		Description *string `json:"description" binding:"omitempty,max=1000"`
+		ParentID    *uint   `json:"parent_id"`
+		SortOrder   *int    `json:"sort_order"`
+	}
+	if err := Base.BindJSON(c, &input); err != nil {
+		return
+	}
+	if input.Name != nil && !utils.ValidateCategoryName(*input.Name) {
+		utils.SendValidationError(c, "Invalid category name")
+		return
+	}
+	if input.Description != nil {
+		description := utils.SanitizeString(*input.Description)
+		input.Description = &description
+	}
+
+	var category models.Category
+	if err := db.DB.First(&category, idUint).Error; err != nil {
+		Base.HandleDBError(c, err, "Category not found", "Failed to fetch category")
+		return
+	}
+
+	update := services.CategoryUpdate{
+		Name:        input.Name,
+		Slug:        input.Slug,
+		Description: input.Description,
+		ParentID:    input.ParentID,
+		SortOrder:   input.SortOrder,
+	}
+	if err := services.NewCategoryService().Update(&category, update); err != nil {
+		sendCategoryError(c, err, "Failed to update category")
+		return
+	}
+	invalidateProductCache(0)
+
+	utils.SendSuccess(c, http.StatusOK, "Category updated successfully", category)
+}
+
 func DeleteCategory(c *gin.Context) {
 	// Only admin can delete categories
 	role, _ := c.Get("userRole")
@@ -63,15 +155,36 @@ func DeleteCategory(c *gin.Context) {
 		return
 	}
 
-	tx := db.DB.Delete(&models.Category{}, idUint)
-	if tx.Error != nil {
-		utils.SendInternalError(c, "Failed to delete category")
+	var category models.Category
+	if err := db.DB.First(&category, idUint).Error; err != nil {
+		Base.HandleDBError(c, err, "Category not found", "Failed to delete category")
 		return
 	}
-	if tx.RowsAffected == 0 {
-		utils.SendNotFound(c, "Category not found")
+
+	// Child categories move up to the deleted category's parent
+	if err := services.NewCategoryService().Delete(&category); err != nil {
+		utils.SendInternalError(c, "Failed to delete category")
 		return
 	}
+	invalidateProductCache(0)
 
 	utils.SendSuccess(c, http.StatusOK, "Category deleted successfully", nil)
 }
+
+// sendCategoryError maps category tree errors to responses
+func sendCategoryError(c *gin.Context, err error, internalMsg string) {
+	switch {
+	case errors.Is(err, services.ErrParentNotFound):
+		utils.SendValidationError(c, "Parent category not found")
+	case errors.Is(err, services.ErrCategoryCycle):
+		utils.SendValidationError(c, "Category cannot be moved under itself or one of its descendants")
+	case errors.Is(err, services.ErrInvalidSlug):
+		utils.SendValidationError(c, "Slug may only contain lowercase letters, digits and hyphens")
+	case errors.Is(err, services.ErrNoChanges):
+		utils.SendValidationError(c, "Update contains no changes")
+	case errors.Is(err, services.ErrDuplicateSlug):
+		utils.SendConflict(c, "Slug is already in use")
+	default:
+		utils.SendInternalError(c, internalMsg)
+	}
+}
@@ -64,6 +64,56 @@ func TestListCategories_NoCategoriesFound(t *testing.T) {
 	assert.Empty(t, data)
 }
 
+func TestListCategories_Pagination(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	SetupTestDB(t)
+
+	for i := 1; i <= 5; i++ {
+		db.DB.Create(&models.Category{Name: fmt.Sprintf("Category %d", i)})
+	}
+
+	router := gin.New()
+	router.GET("/categories", ListCategories)
+	type page struct {
+		Data       []models.Category `json:"data"`
+		Pagination utils.Pagination  `json:"pagination"`
+	}
+	get := func(path string) (*httptest.ResponseRecorder, page) {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", path, nil)
+		router.ServeHTTP(w, req)
+		var response page
+		json.Unmarshal(w.Body.Bytes(), &response)
+		return w, response
+	}
+
+	w, first := get("/categories?limit=2&include_total=true")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Len(t, first.Data, 2)
+	assert.Equal(t, 2, first.Pagination.Limit)
+	if assert.NotNil(t, first.Pagination.Total) {
+		assert.Equal(t, int64(5), *first.Pagination.Total)
+	}
+	assert.Empty(t, first.Pagination.PrevCursor)
+	link := w.Header().Get("Link")
+	assert.Contains(t, link, `</categories?include_total=true&limit=2>; rel="first"`)
+	assert.Contains(t, link, `</categories?cursor=`+first.Pagination.NextCursor+`&include_total=true&limit=2>; rel="next"`)
+	assert.NotContains(t, link, `rel="prev"`)
+
+	w, second := get("/categories?limit=2&cursor=" + first.Pagination.NextCursor)
+	if assert.Len(t, second.Data, 2) {
+		assert.Equal(t, "Category 3", second.Data[0].Name)
+	}
+	assert.Nil(t, second.Pagination.Total)
+	assert.Contains(t, w.Header().Get("Link"), `rel="prev"`)
+
+	_, back := get("/categories?limit=2&cursor=" + second.Pagination.PrevCursor)
+	assert.Equal(t, first.Data, back.Data)
+
+	w, _ = get("/categories?cursor=bogus")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
 func TestListCategories_DatabaseError(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 	// Setup a test DB, but then close its underlying connection to simulate an error
@@ -401,3 +451,78 @@ func TestAddCategory_SpecialCharacters(t *testing.T) {
 
 	assert.Equal(t, http.StatusCreated, w.Code)
 }
+
+func TestCategoryTreeEndpoints(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	SetupTestDB(t)
+
+	parent := models.Category{Name: "Furniture"}
+	db.DB.Create(&parent)
+	child := models.Category{Name: "Chairs", ParentID: &parent.ID}
+	db.DB.Create(&child)
+	other := models.Category{Name: "Garden"}
+	db.DB.Create(&other)
+	product := models.Product{Name: "Office Chair", Price: 90, CategoryID: child.ID}
+	db.DB.Create(&product)
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set("userRole", "admin")
+		c.Next()
+	})
+	router.GET("/categories/tree", CategoryTree)
+	router.PUT("/categories/:id", UpdateCategory)
+	router.GET("/products", ListProducts)
+	router.GET("/product/:id", GetProduct)
+
+	send := func(method, path, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, path, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := send("GET", "/categories/tree", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	var tree struct {
+		Data []models.Category `json:"data"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &tree)
+	if assert.Len(t, tree.Data, 2) {
+		assert.Equal(t, "Furniture", tree.Data[0].Name)
+		assert.Len(t, tree.Data[0].Children, 1)
+	}
+
+	// Listing by the parent only includes subcategory products when asked
+	var listed struct {
+		Data []models.Product `json:"data"`
+	}
+	w = send("GET", fmt.Sprintf("/products?category_id=%d", parent.ID), "")
+	json.Unmarshal(w.Body.Bytes(), &listed)
+	assert.Empty(t, listed.Data)
+	w = send("GET", fmt.Sprintf("/products?category_id=%d&include_descendants=true", parent.ID), "")
+	json.Unmarshal(w.Body.Bytes(), &listed)
+	assert.Len(t, listed.Data, 1)
+
+	w = send("GET", fmt.Sprintf("/product/%d", product.ID), "")
+	var fetched struct {
+		Data models.Product `json:"data"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &fetched)
+	if assert.Len(t, fetched.Data.Breadcrumbs, 2) {
+		assert.Equal(t, "furniture", fetched.Data.Breadcrumbs[0].Slug)
+		assert.Equal(t, "Chairs", fetched.Data.Breadcrumbs[1].Name)
+	}
+
+	// Moving a parent under its own child is rejected
+	w = send("PUT", fmt.Sprintf("/categories/%d", parent.ID), fmt.Sprintf(`{"parent_id":%d}`, child.ID))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = send("PUT", fmt.Sprintf("/categories/%d", child.ID), fmt.Sprintf(`{"parent_id":%d,"slug":"seating","sort_order":3}`, other.ID))
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = send("PUT", fmt.Sprintf("/categories/%d", other.ID), `{"slug":"seating"}`)
+	assert.Equal(t, http.StatusConflict, w.Code)
+	w = send("PUT", "/categories/9999", `{"name":"Missing"}`)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
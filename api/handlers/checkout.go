@@ -3,10 +3,10 @@ package handlers
 import (
 	"errors"
 	"net/http"
-	"strconv"
 
 	"github.com/geoo115/Ecommerce/db"
 	"github.com/geoo115/Ecommerce/models"
+	"github.com/geoo115/Ecommerce/services"
 	"github.com/geoo115/Ecommerce/utils"
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
@@ -15,7 +15,8 @@ import (
 // ProcessPayment handles payment processing
 func ProcessPayment(c *gin.Context) {
 	var paymentRequest struct {
-		OrderID       uint    `json:"order_id" binding:"required"`
+		OrderID       uint    `json:"order_id" binding:"required_without=OrderNumber"`
+		OrderNumber   string  `json:"order_number"`
 		PaymentMethod string  `json:"payment_method" binding:"required"`
 		Amount        float64 `json:"amount" binding:"required"`
 	}
@@ -25,9 +26,13 @@ func ProcessPayment(c *gin.Context) {
 		return
 	}
 
-	// Check if the order exists
+	// Check if the order exists, by ID or order number
+	query := db.DB.Where("id = ?", paymentRequest.OrderID)
+	if paymentRequest.OrderID == 0 {
+		query = db.DB.Where("order_number = ?", paymentRequest.OrderNumber)
+	}
 	var order models.Order
-	if err := db.DB.First(&order, paymentRequest.OrderID).Error; err != nil {
+	if err := query.First(&order).Error; err != nil {
 		// DB closed or other DB error => 500, record not found => 404
 		if !errors.Is(err, gorm.ErrRecordNotFound) {
 			utils.SendInternalError(c, "Internal server error")
@@ -43,25 +48,26 @@ func ProcessPayment(c *gin.Context) {
 		return
 	}
 
-	// Record the payment
-	payment := models.Payment{
-		OrderID:     paymentRequest.OrderID,
-		PaymentMode: paymentRequest.PaymentMethod,
-		Amount:      paymentRequest.Amount,
-		Status:      "Success",
-	}
-
-	if err := db.DB.Create(&payment).Error; err != nil {
+	// Charge and record the payment, marking the order paid
+	charged, err := services.NewPaymentService().ChargeOrder(&order, paymentRequest.PaymentMethod)
+	if err != nil {
+		if errors.Is(err, services.ErrPaymentDeclined) {
+			utils.SendError(c, http.StatusPaymentRequired, "Payment declined")
+			return
+		}
+		if errors.Is(err, services.ErrOrderNotPayable) {
+			utils.SendConflict(c, "Order cannot be paid in its current status")
+			return
+		}
+		if errors.Is(err, services.ErrInsufficientStock) {
+			// A digital product's licence keys ran out before the order was paid
+			utils.SendValidationError(c, "Insufficient stock for product")
+			return
+		}
 		utils.SendInternalError(c, "Failed to record payment")
 		return
 	}
-
-	// Update the order status
-	order.Status = "Paid"
-	if err := db.DB.Save(&order).Error; err != nil {
-		utils.SendInternalError(c, "Failed to update order status")
-		return
-	}
+	payment := *charged
 
 	// Fetch the payment with related order and user details
 	if err := db.DB.Preload("Order.User").First(&payment, payment.ID).Error; err != nil {
@@ -71,17 +77,22 @@ func ProcessPayment(c *gin.Context) {
 	utils.SendSuccess(c, http.StatusOK, "Payment processed successfully", gin.H{"payment": payment})
 }
 
-// GetPaymentStatus retrieves the payment status of a specific order
+// GetPaymentStatus retrieves the payment status of a specific order.
+// The order may be referenced by ID or order number.
 func GetPaymentStatus(c *gin.Context) {
-	orderIDStr := c.Param("order_id")
-	orderIDUint64, convErr := strconv.ParseUint(orderIDStr, 10, 64)
-	if convErr != nil {
-		utils.SendNotFound(c, "Payment not found for the given order ID")
+	var order models.Order
+	if err := whereOrderRef(db.DB, c.Param("order_id")).Select("id").First(&order).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.SendNotFound(c, "Payment not found for the given order ID")
+			return
+		}
+		utils.SendInternalError(c, "Internal server error")
 		return
 	}
 
 	var payment models.Payment
-	if err := db.DB.Preload("Order.User").Where("order_id = ?", uint(orderIDUint64)).First(&payment).Error; err != nil {
+	// Report the most recent payment attempt for the order
+	if err := db.DB.Preload("Order.User").Where("order_id = ?", order.ID).Last(&payment).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			utils.SendNotFound(c, "Payment not found for the given order ID")
 			return
@@ -93,7 +104,8 @@ func GetPaymentStatus(c *gin.Context) {
 	utils.SendSuccess(c, http.StatusOK, "Payment status retrieved", gin.H{"payment": payment})
 }
 
-// Checkout processes the checkout by clearing the cart and creating an order
+// Checkout processes the checkout by clearing the cart and creating an order.
+// An optional JSON body {"redeem_points": n} redeems loyalty points as a discount.
 func Checkout(c *gin.Context) {
 	var cartItems []models.Cart
 	uid, err := Base.GetUserID(c)
@@ -101,8 +113,17 @@ func Checkout(c *gin.Context) {
 		return
 	}
 
+	var input struct {
+		RedeemPoints int `json:"redeem_points" binding:"gte=0"`
+	}
+	if c.Request.Body != nil && c.Request.ContentLength != 0 {
+		if err := Base.BindJSON(c, &input); err != nil {
+			return
+		}
+	}
+
 	// Fetch all cart items for the user
-	if err := db.DB.Where("user_id = ?", uid).Preload("Product").Find(&cartItems).Error; err != nil {
+	if err := db.DB.Where("user_id = ?", uid).Preload("Product").Preload("Variant").Find(&cartItems).Error; err != nil {
 		utils.SendInternalError(c, "Failed to fetch cart items")
 		return
 	}
@@ -110,36 +131,89 @@ func Checkout(c *gin.Context) {
 	// Calculate total amount
 	var totalAmount float64
 	for _, item := range cartItems {
-		totalAmount += float64(item.Quantity) * item.Product.Price
+		totalAmount += float64(item.Quantity) * item.UnitPrice()
+	}
+
+	// Apply loyalty points, never discounting more than the order is worth
+	loyalty := services.NewLoyaltyService()
+	pointsRedeemed := 0
+	var discount float64
+	if input.RedeemPoints > 0 {
+		balance, err := loyalty.GetBalance(uid)
+		if err != nil {
+			utils.SendInternalError(c, "Failed to fetch loyalty balance")
+			return
+		}
+		if balance < input.RedeemPoints {
+			utils.SendValidationError(c, "Insufficient loyalty points")
+			return
+		}
+		discount = loyalty.DiscountForPoints(input.RedeemPoints)
+		pointsRedeemed = input.RedeemPoints
+		if discount > totalAmount {
+			discount = totalAmount
+			pointsRedeemed = loyalty.PointsForDiscount(discount)
+		}
 	}
 
 	// Create a new order
 	order := models.Order{
-		UserID:      uid,
-		TotalAmount: totalAmount,
-		Status:      "Pending",
-		Items:       []models.OrderItem{},
+		UserID:         uid,
+		TotalAmount:    totalAmount - discount,
+		Status:         "Pending",
+		Items:          []models.OrderItem{},
+		PointsRedeemed: pointsRedeemed,
+		DiscountAmount: discount,
 	}
 
 	for _, item := range cartItems {
-		order.Items = append(order.Items, models.OrderItem{
+		orderItem := models.OrderItem{
 			ProductID: item.ProductID,
+			VariantID: item.VariantID,
 			Quantity:  item.Quantity,
-			Price:     item.Product.Price,
-		})
+			Price:     item.UnitPrice(),
+		}
+		if item.Variant != nil {
+			orderItem.SKU = item.Variant.SKU
+		}
+		if orderItem.Components, err = services.BundleOrderComponents(db.DB, item.ProductID); err != nil {
+			utils.SendInternalError(c, "Failed to load bundle components")
+			return
+		}
+		order.Items = append(order.Items, orderItem)
 	}
 
-	if err := db.DB.Create(&order).Error; err != nil {
-		utils.SendInternalError(c, "Failed to create order")
+	// Drafts cannot be ordered, even if they were carted before being unpublished
+	err = db.DB.Transaction(func(tx *gorm.DB) error {
+		for _, item := range cartItems {
+			if _, _, err := services.ResolveVariant(tx, item.ProductID, item.VariantID); err != nil {
+				return err
+			}
+		}
+		if err := models.CreateOrder(tx, &order); err != nil {
+			return err
+		}
+		return services.NewLoyaltyServiceWithDB(tx).RedeemPoints(uid, order.ID, pointsRedeemed)
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInsufficientPoints):
+			utils.SendValidationError(c, "Insufficient loyalty points")
+		case errors.Is(err, services.ErrProductNotFound):
+			utils.SendNotFound(c, "Product not found")
+		case errors.Is(err, services.ErrVariantNotFound), errors.Is(err, services.ErrVariantRequired):
+			utils.SendValidationError(c, "A variant must be selected for this product")
+		default:
+			utils.SendInternalError(c, "Failed to create order")
+		}
 		return
 	}
 
 	// Decrement inventory for purchased items (best-effort)
+	orders := services.NewOrderService()
 	for _, item := range cartItems {
-		var inv models.Inventory
-		if err := db.DB.Where("product_id = ?", item.ProductID).First(&inv).Error; err == nil {
-			inv.Stock = inv.Stock - item.Quantity
-			db.DB.Save(&inv)
+		if err := orders.ReserveStock(item.ProductID, item.VariantID, item.Quantity); err != nil {
+			utils.Warn("Failed to decrement stock for product %d on order %d: %v", item.ProductID, order.ID, err)
 		}
 	}
 
@@ -0,0 +1,184 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/geoo115/Ecommerce/db"
+	"github.com/geoo115/Ecommerce/models"
+	"github.com/geoo115/Ecommerce/services"
+	"github.com/geoo115/Ecommerce/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// ListDeliveryMethods returns the shipping options offered at checkout
+func ListDeliveryMethods(c *gin.Context) {
+	utils.SendSuccess(c, http.StatusOK, "Delivery methods retrieved successfully", services.DeliveryMethods())
+}
+
+// StartCheckoutSession snapshots the authenticated user's cart into a new checkout session
+func StartCheckoutSession(c *gin.Context) {
+	uid, err := Base.GetUserID(c)
+	if err != nil {
+		return
+	}
+
+	session, err := services.NewCheckoutService().StartSession(uid)
+	if err != nil {
+		sendCheckoutError(c, err)
+		return
+	}
+
+	utils.SendSuccess(c, http.StatusCreated, "Checkout session started", session)
+}
+
+// GetCheckoutSession returns one of the authenticated user's checkout sessions
+func GetCheckoutSession(c *gin.Context) {
+	session, ok := loadCheckoutSession(c)
+	if !ok {
+		return
+	}
+	utils.SendSuccess(c, http.StatusOK, "Checkout session retrieved successfully", session)
+}
+
+// SetCheckoutAddress chooses the shipping address for a checkout session
+func SetCheckoutAddress(c *gin.Context) {
+	var input struct {
+		AddressID uint `json:"address_id" binding:"required"`
+	}
+	if err := Base.BindJSON(c, &input); err != nil {
+		return
+	}
+
+	session, ok := loadCheckoutSession(c)
+	if !ok {
+		return
+	}
+	if err := services.NewCheckoutService().SetAddress(session, input.AddressID); err != nil {
+		sendCheckoutError(c, err)
+		return
+	}
+
+	utils.SendSuccess(c, http.StatusOK, "Shipping address selected", session)
+}
+
+// SetCheckoutDelivery chooses the delivery method for a checkout session
+func SetCheckoutDelivery(c *gin.Context) {
+	var input struct {
+		DeliveryMethod string `json:"delivery_method" binding:"required"`
+	}
+	if err := Base.BindJSON(c, &input); err != nil {
+		return
+	}
+
+	session, ok := loadCheckoutSession(c)
+	if !ok {
+		return
+	}
+	if err := services.NewCheckoutService().SetDeliveryMethod(session, input.DeliveryMethod); err != nil {
+		sendCheckoutError(c, err)
+		return
+	}
+
+	utils.SendSuccess(c, http.StatusOK, "Delivery method selected", session)
+}
+
+// ReviewCheckoutSession confirms the session totals, optionally redeeming loyalty points
+func ReviewCheckoutSession(c *gin.Context) {
+	var input struct {
+		RedeemPoints int `json:"redeem_points" binding:"gte=0"`
+	}
+	if c.Request.Body != nil && c.Request.ContentLength != 0 {
+		if err := Base.BindJSON(c, &input); err != nil {
+			return
+		}
+	}
+
+	session, ok := loadCheckoutSession(c)
+	if !ok {
+		return
+	}
+	if err := services.NewCheckoutService().Review(session, input.RedeemPoints); err != nil {
+		sendCheckoutError(c, err)
+		return
+	}
+
+	utils.SendSuccess(c, http.StatusOK, "Checkout reviewed", session)
+}
+
+// PayCheckoutSession pays for a reviewed session, creating the order and its payment
+func PayCheckoutSession(c *gin.Context) {
+	var input struct {
+		PaymentMethod string `json:"payment_method" binding:"required"`
+	}
+	if err := Base.BindJSON(c, &input); err != nil {
+		return
+	}
+
+	session, ok := loadCheckoutSession(c)
+	if !ok {
+		return
+	}
+	order, payment, err := services.NewCheckoutService().Complete(session, input.PaymentMethod)
+	if err != nil {
+		sendCheckoutError(c, err)
+		return
+	}
+
+	utils.SendSuccess(c, http.StatusOK, "Checkout completed successfully", gin.H{
+		"order":   order,
+		"payment": payment,
+	})
+}
+
+// loadCheckoutSession fetches the session in the :id param, scoped to the authenticated user
+func loadCheckoutSession(c *gin.Context) (*models.CheckoutSession, bool) {
+	uid, err := Base.GetUserID(c)
+	if err != nil {
+		return nil, false
+	}
+	id, err := Base.ValidateIDParam(c, "id")
+	if err != nil {
+		return nil, false
+	}
+
+	var session models.CheckoutSession
+	if err := db.DB.Preload("Items.Product").Preload("Address").
+		Where("id = ? AND user_id = ?", id, uid).First(&session).Error; err != nil {
+		Base.HandleDBError(c, err, "Checkout session not found", "Failed to fetch checkout session")
+		return nil, false
+	}
+	return &session, true
+}
+
+// sendCheckoutError maps checkout service errors to API responses
+func sendCheckoutError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrCartEmpty):
+		utils.SendValidationError(c, "Cart is empty")
+	case errors.Is(err, services.ErrSessionExpired):
+		utils.SendError(c, http.StatusGone, "Checkout session has expired")
+	case errors.Is(err, services.ErrSessionClosed):
+		utils.SendConflict(c, "Checkout session is already completed")
+	case errors.Is(err, services.ErrCheckoutStep):
+		utils.SendConflict(c, "Complete the previous checkout steps first")
+	case errors.Is(err, services.ErrDeliveryMethodRequired):
+		utils.SendConflict(c, "Choose a delivery method first")
+	case errors.Is(err, services.ErrAddressNotFound):
+		utils.SendNotFound(c, "Address not found")
+	case errors.Is(err, services.ErrUnknownDeliveryMethod):
+		utils.SendValidationError(c, "Unknown delivery method")
+	case errors.Is(err, services.ErrShippingNotRequired):
+		utils.SendValidationError(c, "Digital-only orders are not shipped")
+	case errors.Is(err, services.ErrInsufficientPoints):
+		utils.SendValidationError(c, "Insufficient loyalty points")
+	case errors.Is(err, services.ErrInsufficientStock):
+		utils.SendValidationError(c, "Insufficient stock for product")
+	case errors.Is(err, services.ErrProductNotFound), errors.Is(err, services.ErrVariantNotFound), errors.Is(err, services.ErrVariantRequired):
+		utils.SendConflict(c, "An item in the checkout is no longer available")
+	case errors.Is(err, services.ErrPaymentDeclined):
+		utils.SendError(c, http.StatusPaymentRequired, "Payment declined")
+	default:
+		utils.SendInternalError(c, "Failed to process checkout")
+	}
+}
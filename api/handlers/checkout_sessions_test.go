@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/geoo115/Ecommerce/db"
+	"github.com/geoo115/Ecommerce/models"
+	"github.com/geoo115/Ecommerce/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func setupCheckoutSessionRouter(userID uint) *gin.Engine {
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set("userID", userID)
+		c.Next()
+	})
+	router.GET("/checkout/delivery-methods", ListDeliveryMethods)
+	router.POST("/checkout/sessions", StartCheckoutSession)
+	router.GET("/checkout/sessions/:id", GetCheckoutSession)
+	router.PUT("/checkout/sessions/:id/address", SetCheckoutAddress)
+	router.PUT("/checkout/sessions/:id/delivery", SetCheckoutDelivery)
+	router.PUT("/checkout/sessions/:id/review", ReviewCheckoutSession)
+	router.POST("/checkout/sessions/:id/pay", PayCheckoutSession)
+	return router
+}
+
+func TestCheckoutSessionFlow(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	SetupTestDB(t)
+	utils.AppLogger = utils.NewLogger(utils.INFO)
+
+	user := CreateTestUser(t, db.DB, "session")
+	other := CreateTestUser(t, db.DB, "intruder")
+	address := models.Address{UserID: user.ID, Address: "1 Main St", City: "Town", ZipCode: "12345"}
+	db.DB.Create(&address)
+	product := models.Product{Name: "Kettle", Price: 20}
+	db.DB.Create(&product)
+	db.DB.Create(&models.Inventory{ProductID: product.ID, Stock: 10})
+	db.DB.Create(&models.Cart{UserID: user.ID, ProductID: product.ID, Quantity: 1})
+
+	router := setupCheckoutSessionRouter(user.ID)
+	do := func(r *gin.Engine, method, path, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, path, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := do(router, "POST", "/checkout/sessions", "")
+	assert.Equal(t, http.StatusCreated, w.Code)
+	var started struct {
+		Data models.CheckoutSession `json:"data"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &started)
+	base := fmt.Sprintf("/checkout/sessions/%d", started.Data.ID)
+
+	// Sessions are private to their owner
+	assert.Equal(t, http.StatusNotFound, do(setupCheckoutSessionRouter(other.ID), "GET", base, "").Code)
+
+	assert.Equal(t, http.StatusConflict, do(router, "PUT", base+"/review", "").Code)
+	assert.Equal(t, http.StatusNotFound, do(router, "PUT", base+"/address", `{"address_id": 9999}`).Code)
+	assert.Equal(t, http.StatusOK, do(router, "PUT", base+"/address", fmt.Sprintf(`{"address_id": %d}`, address.ID)).Code)
+	assert.Equal(t, http.StatusBadRequest, do(router, "PUT", base+"/delivery", `{"delivery_method": "drone"}`).Code)
+	assert.Equal(t, http.StatusOK, do(router, "PUT", base+"/delivery", `{"delivery_method": "standard"}`).Code)
+	assert.Equal(t, http.StatusConflict, do(router, "POST", base+"/pay", `{"payment_method": "card"}`).Code)
+	assert.Equal(t, http.StatusOK, do(router, "PUT", base+"/review", "").Code)
+
+	w = do(router, "POST", base+"/pay", `{"payment_method": "card"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var paid struct {
+		Data struct {
+			Order   models.Order   `json:"order"`
+			Payment models.Payment `json:"payment"`
+		} `json:"data"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &paid)
+	assert.Equal(t, 24.99, paid.Data.Order.TotalAmount)
+	assert.Equal(t, "Paid", paid.Data.Order.Status)
+	assert.Equal(t, paid.Data.Order.ID, paid.Data.Payment.OrderID)
+
+	assert.Equal(t, http.StatusConflict, do(router, "POST", base+"/pay", `{"payment_method": "card"}`).Code)
+
+	// The cart was consumed by the session
+	assert.Equal(t, http.StatusBadRequest, do(router, "POST", "/checkout/sessions", "").Code)
+	assert.Equal(t, http.StatusOK, do(router, "GET", "/checkout/delivery-methods", "").Code)
+}
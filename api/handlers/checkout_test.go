@@ -10,6 +10,7 @@ import (
 
 	"github.com/geoo115/Ecommerce/db"
 	"github.com/geoo115/Ecommerce/models"
+	"github.com/geoo115/Ecommerce/services"
 	"github.com/geoo115/Ecommerce/utils"
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
@@ -263,16 +264,11 @@ func TestProcessPayment_OrderAlreadyPaid(t *testing.T) {
 	req.Header.Set("Content-Type", "application/json")
 	router.ServeHTTP(w, req)
 
-	// Check the actual response - it might be a validation error
-	var response map[string]interface{}
-	json.Unmarshal(w.Body.Bytes(), &response)
-	if w.Code == http.StatusBadRequest {
-		// If it's a bad request, that's also acceptable for this test
-		assert.Equal(t, http.StatusBadRequest, w.Code)
-	} else {
-		// If it succeeds, that's also fine
-		assert.Equal(t, http.StatusOK, w.Code)
-	}
+	// A paid order cannot be charged again
+	assert.Equal(t, http.StatusConflict, w.Code)
+	var paymentCount int64
+	db.DB.Model(&models.Payment{}).Where("order_id = ?", order.ID).Count(&paymentCount)
+	assert.Equal(t, int64(0), paymentCount)
 }
 
 func TestGetPaymentStatus_InvalidOrderID(t *testing.T) {
@@ -616,6 +612,99 @@ func TestCheckout_MultipleProducts(t *testing.T) {
 	assert.Equal(t, 125.0, order.TotalAmount)
 }
 
+func TestCheckout_RedeemLoyaltyPoints(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	SetupTestDB(t)
+
+	utils.AppLogger = utils.NewLogger(utils.INFO)
+
+	user := models.User{Username: "testuser", Email: "test@example.com", Phone: "+15550000001"}
+	db.DB.Create(&user)
+
+	cat := models.Category{Name: "testcat"}
+	db.DB.Create(&cat)
+
+	prod := models.Product{Name: "testprod", Price: 10.0, CategoryID: cat.ID}
+	db.DB.Create(&prod)
+	db.DB.Create(&models.Inventory{ProductID: prod.ID, Stock: 10})
+	db.DB.Create(&models.Cart{UserID: user.ID, ProductID: prod.ID, Quantity: 2})
+
+	loyalty := services.NewLoyaltyService()
+	_, err := loyalty.AdjustPoints(user.ID, 5000, "Bonus")
+	assert.NoError(t, err)
+
+	router := gin.New()
+	router.POST("/checkout", func(c *gin.Context) {
+		c.Set("userID", user.ID)
+		Checkout(c)
+	})
+
+	// Redeeming more than the balance is rejected
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/checkout", bytes.NewBufferString(`{"redeem_points": 6000}`))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// 5000 points are worth 50.00, so only the 2000 needed to cover the 20.00 order are used
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", "/checkout", bytes.NewBufferString(`{"redeem_points": 5000}`))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var order models.Order
+	db.DB.Where("user_id = ?", user.ID).First(&order)
+	assert.Equal(t, 0.0, order.TotalAmount)
+	assert.Equal(t, 20.0, order.DiscountAmount)
+	assert.Equal(t, 2000, order.PointsRedeemed)
+
+	balance, _ := loyalty.GetBalance(user.ID)
+	assert.Equal(t, 3000, balance)
+}
+
+func TestProcessPayment_AwardsLoyaltyPoints(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	SetupTestDB(t)
+
+	utils.AppLogger = utils.NewLogger(utils.INFO)
+
+	user := models.User{Username: "testuser", Email: "test@example.com", Phone: "+15550000001"}
+	db.DB.Create(&user)
+
+	cat := models.Category{Name: "testcat"}
+	db.DB.Create(&cat)
+
+	prod := models.Product{Name: "testprod", Price: 25.0, CategoryID: cat.ID}
+	db.DB.Create(&prod)
+
+	order := models.Order{
+		UserID:      user.ID,
+		TotalAmount: 50.0,
+		Status:      "Pending",
+		Items:       []models.OrderItem{{ProductID: prod.ID, Quantity: 2, Price: 25.0}},
+	}
+	db.DB.Create(&order)
+
+	router := gin.New()
+	router.POST("/payments", ProcessPayment)
+
+	jsonData, _ := json.Marshal(map[string]interface{}{
+		"order_id":       order.ID,
+		"payment_method": "credit_card",
+		"amount":         50.0,
+	})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/payments", bytes.NewBuffer(jsonData))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	balance, _ := services.NewLoyaltyService().GetBalance(user.ID)
+	assert.Equal(t, 50, balance)
+}
+
 // Benchmark tests
 func BenchmarkProcessPayment(b *testing.B) {
 	gin.SetMode(gin.TestMode)
@@ -0,0 +1,225 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/geoo115/Ecommerce/db"
+	"github.com/geoo115/Ecommerce/models"
+	"github.com/geoo115/Ecommerce/services"
+	"github.com/geoo115/Ecommerce/utils"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// collectionInput is the request body for creating and replacing collections
+type collectionInput struct {
+	Name        string                  `json:"name" binding:"required,max=100"`
+	Slug        string                  `json:"slug" binding:"max=128"`
+	Description string                  `json:"description" binding:"max=1000"`
+	Type        string                  `json:"type" binding:"required"`
+	Sort        string                  `json:"sort"`
+	Rules       []models.CollectionRule `json:"rules"`
+	ProductIDs  []uint                  `json:"product_ids"`
+}
+
+// definition converts the request body to the service's input
+func (input collectionInput) definition() services.CollectionInput {
+	return services.CollectionInput{
+		Name:        utils.SanitizeString(input.Name),
+		Slug:        input.Slug,
+		Description: utils.SanitizeString(input.Description),
+		Type:        input.Type,
+		Sort:        input.Sort,
+		Rules:       input.Rules,
+		ProductIDs:  input.ProductIDs,
+	}
+}
+
+// ListCollections returns a page of collections
+func ListCollections(c *gin.Context) {
+	var collections []models.Collection
+	pagination, ok := Base.FetchPage(c, db.DB.Model(&models.Collection{}), services.OrderByID("collections"), &collections, "Failed to fetch collections")
+	if !ok {
+		return
+	}
+	if collections == nil {
+		collections = []models.Collection{}
+	}
+
+	utils.SendPage(c, "Collections retrieved successfully", collections, pagination)
+}
+
+// GetCollection returns a collection by slug, with a smart collection's rules
+func GetCollection(c *gin.Context) {
+	var collection models.Collection
+	if err := db.DB.Preload("Rules").Where("slug = ?", c.Param("slug")).First(&collection).Error; err != nil {
+		Base.HandleDBError(c, err, "Collection not found", "Failed to fetch collection")
+		return
+	}
+
+	utils.SendSuccess(c, http.StatusOK, "Collection retrieved successfully", collection)
+}
+
+// ListCollectionProducts returns a page of a collection's published products. Manual collections
+// keep their own order unless a sort is requested; smart collections match their rules at the
+// time of the request. Accepts the same sorting and filtering parameters as ListProducts.
+func ListCollectionProducts(c *gin.Context) {
+	var collection models.Collection
+	if err := db.DB.Where("slug = ?", c.Param("slug")).First(&collection).Error; err != nil {
+		Base.HandleDBError(c, err, "Collection not found", "Failed to fetch collection")
+		return
+	}
+
+	collectionScope, err := services.NewCollectionService().ProductScope(&collection)
+	if err != nil {
+		utils.SendInternalError(c, "Failed to fetch collection products")
+		return
+	}
+
+	// Cached under the products prefix so product changes re-evaluate smart collections
+	sendProductListing(c, db.DB, productListing{
+		cachePrefix: fmt.Sprintf("products:collections:%d", collection.ID),
+		scope: func(query *gorm.DB) *gorm.DB {
+			return query.Scopes(services.PublishedProducts, collectionScope)
+		},
+		order: func(options services.ProductListOptions) services.PageOrder {
+			return services.CollectionPageOrder(&collection, options.Sort)
+		},
+		message: "Collection products retrieved successfully",
+	})
+}
+
+// GetAdminCollection returns a collection with its rules or ordered products (admin only)
+func GetAdminCollection(c *gin.Context) {
+	collection, ok := loadCollection(c)
+	if !ok {
+		return
+	}
+
+	utils.SendSuccess(c, http.StatusOK, "Collection retrieved successfully", collection)
+}
+
+// CreateCollection creates a manual collection from an ordered list of product IDs, or a smart
+// collection from rules that products must all meet (admin only)
+func CreateCollection(c *gin.Context) {
+	var input collectionInput
+	if err := Base.BindJSON(c, &input); err != nil {
+		return
+	}
+
+	collection, err := services.NewCollectionService().Create(input.definition())
+	if err != nil {
+		sendCollectionError(c, err)
+		return
+	}
+	invalidateProductCache(0)
+
+	utils.SendSuccess(c, http.StatusCreated, "Collection created successfully", collection)
+}
+
+// UpdateCollection replaces a collection's definition, including its rules or products (admin only)
+func UpdateCollection(c *gin.Context) {
+	collection, ok := loadCollection(c)
+	if !ok {
+		return
+	}
+
+	var input collectionInput
+	if err := Base.BindJSON(c, &input); err != nil {
+		return
+	}
+
+	if err := services.NewCollectionService().Update(collection, input.definition()); err != nil {
+		sendCollectionError(c, err)
+		return
+	}
+	invalidateProductCache(0)
+
+	utils.SendSuccess(c, http.StatusOK, "Collection updated successfully", collection)
+}
+
+// DeleteCollection removes a collection (admin only)
+func DeleteCollection(c *gin.Context) {
+	collection, ok := loadCollection(c)
+	if !ok {
+		return
+	}
+
+	if err := services.NewCollectionService().Delete(collection); err != nil {
+		utils.SendInternalError(c, "Failed to delete collection")
+		return
+	}
+	invalidateProductCache(0)
+
+	utils.SendSuccess(c, http.StatusOK, "Collection deleted successfully", nil)
+}
+
+// SetProductTags replaces a product's tags (admin only). Tags are stored lowercased.
+func SetProductTags(c *gin.Context) {
+	productID, err := Base.ValidateIDParam(c, "id")
+	if err != nil {
+		return
+	}
+
+	var input struct {
+		Tags []string `json:"tags" binding:"required"`
+	}
+	if err := Base.BindJSON(c, &input); err != nil {
+		return
+	}
+
+	var product models.Product
+	if err := db.DB.First(&product, productID).Error; err != nil {
+		Base.HandleDBError(c, err, "Product not found", "Failed to fetch product")
+		return
+	}
+
+	if err := services.NewTagService().SetTags(&product, input.Tags); err != nil {
+		if errors.Is(err, services.ErrInvalidTag) {
+			utils.SendValidationError(c, "Tags must be 1-64 characters without commas, and a product may have at most 50")
+		} else {
+			utils.SendInternalError(c, "Failed to save tags")
+		}
+		return
+	}
+	invalidateProductCache(product.ID)
+
+	utils.SendSuccess(c, http.StatusOK, "Product tags updated successfully", product.Tags)
+}
+
+// loadCollection fetches the collection in the :id param with its rules and ordered products
+func loadCollection(c *gin.Context) (*models.Collection, bool) {
+	collectionID, err := Base.ValidateIDParam(c, "id")
+	if err != nil {
+		return nil, false
+	}
+
+	var collection models.Collection
+	err = db.DB.Preload("Rules").Preload("Items", func(items *gorm.DB) *gorm.DB {
+		return items.Order("position")
+	}).First(&collection, collectionID).Error
+	if err != nil {
+		Base.HandleDBError(c, err, "Collection not found", "Failed to fetch collection")
+		return nil, false
+	}
+	return &collection, true
+}
+
+// sendCollectionError maps collection errors to responses
+func sendCollectionError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrInvalidCollection), errors.Is(err, services.ErrInvalidCollectionRule):
+		// These carry what is wrong with the definition
+		utils.SendValidationError(c, err.Error())
+	case errors.Is(err, services.ErrProductNotFound):
+		utils.SendNotFound(c, "Product not found")
+	case errors.Is(err, services.ErrInvalidSlug):
+		utils.SendValidationError(c, "Slug may only contain lowercase letters, digits and hyphens")
+	case errors.Is(err, services.ErrDuplicateSlug):
+		utils.SendConflict(c, "Slug is already in use")
+	default:
+		utils.SendInternalError(c, "Failed to save collection")
+	}
+}
@@ -0,0 +1,131 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/geoo115/Ecommerce/cache"
+	"github.com/geoo115/Ecommerce/db"
+	"github.com/geoo115/Ecommerce/models"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCollectionEndpoints(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	SetupTestDB(t)
+	originalCache := cache.GlobalCache
+	cache.GlobalCache = cache.NewInMemoryCache()
+	defer func() { cache.GlobalCache = originalCache }()
+
+	category := models.Category{Name: "Summer"}
+	db.DB.Create(&category)
+	products := map[string]models.Product{}
+	for _, p := range []struct {
+		name  string
+		price float64
+	}{{"Sun Hat", 20}, {"Sandals", 45}, {"Parasol", 90}, {"Cooler", 30}} {
+		product := models.Product{Name: p.name, Price: p.price, CategoryID: category.ID}
+		db.DB.Create(&product)
+		db.DB.Create(&models.Inventory{ProductID: product.ID, Stock: 5})
+		products[p.name] = product
+	}
+
+	router := gin.New()
+	router.PUT("/product/:id/tags", SetProductTags)
+	router.PUT("/product/:id/publishing", SetProductPublishing)
+	router.GET("/product/:id", GetProduct)
+	router.GET("/collections", ListCollections)
+	router.GET("/collections/:slug", GetCollection)
+	router.GET("/collections/:slug/products", ListCollectionProducts)
+	router.POST("/admin/collections", CreateCollection)
+	router.GET("/admin/collections/:id", GetAdminCollection)
+	router.PUT("/admin/collections/:id", UpdateCollection)
+	router.DELETE("/admin/collections/:id", DeleteCollection)
+
+	send := func(method, path, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, path, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+		return w
+	}
+	listed := func(path string) []string {
+		w := send("GET", path, "")
+		assert.Equal(t, http.StatusOK, w.Code)
+		var response struct {
+			Data []models.Product `json:"data"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &response)
+		names := make([]string, len(response.Data))
+		for i, product := range response.Data {
+			names[i] = product.Name
+		}
+		return names
+	}
+	create := func(body string) models.Collection {
+		w := send("POST", "/admin/collections", body)
+		assert.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+		var response struct {
+			Data models.Collection `json:"data"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &response)
+		return response.Data
+	}
+
+	for name, tags := range map[string]string{"Sun Hat": `["Summer"]`, "Sandals": `["summer","beach"]`, "Parasol": `["summer"]`} {
+		w := send("PUT", fmt.Sprintf("/product/%d/tags", products[name].ID), `{"tags":`+tags+`}`)
+		assert.Equal(t, http.StatusOK, w.Code)
+	}
+	assert.Equal(t, http.StatusBadRequest, send("PUT", fmt.Sprintf("/product/%d/tags", products["Cooler"].ID), `{"tags":["a,b"]}`).Code)
+	assert.Equal(t, http.StatusNotFound, send("PUT", "/product/9999/tags", `{"tags":["summer"]}`).Code)
+	assert.Contains(t, send("GET", fmt.Sprintf("/product/%d", products["Sandals"].ID), "").Body.String(), `"name":"beach"`)
+
+	t.Run("ManualCollections", func(t *testing.T) {
+		picks := create(fmt.Sprintf(`{"name":"Staff Picks","type":"manual","product_ids":[%d,%d,%d]}`,
+			products["Cooler"].ID, products["Sun Hat"].ID, products["Parasol"].ID))
+		assert.Equal(t, "staff-picks", picks.Slug)
+
+		assert.Equal(t, []string{"Cooler", "Sun Hat", "Parasol"}, listed("/collections/staff-picks/products"))
+		assert.Equal(t, []string{"Sun Hat", "Cooler", "Parasol"}, listed("/collections/staff-picks/products?sort=price_asc"))
+		assert.Equal(t, []string{"Cooler", "Sun Hat"}, listed("/collections/staff-picks/products?limit=2"))
+
+		w := send("GET", fmt.Sprintf("/admin/collections/%d", picks.ID), "")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), fmt.Sprintf(`"product_id":%d`, products["Cooler"].ID))
+
+		path := fmt.Sprintf("/admin/collections/%d", picks.ID)
+		w = send("PUT", path, fmt.Sprintf(`{"name":"Staff Picks","type":"manual","product_ids":[%d]}`, products["Sandals"].ID))
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, []string{"Sandals"}, listed("/collections/staff-picks/products"))
+
+		assert.Equal(t, http.StatusNotFound, send("PUT", path, `{"name":"Staff Picks","type":"manual","product_ids":[9999]}`).Code)
+		assert.Equal(t, http.StatusConflict, send("POST", "/admin/collections", `{"name":"Other","slug":"staff-picks","type":"manual"}`).Code)
+
+		assert.Equal(t, http.StatusOK, send("DELETE", path, "").Code)
+		assert.Equal(t, http.StatusNotFound, send("GET", "/collections/staff-picks/products", "").Code)
+	})
+
+	t.Run("SmartCollections", func(t *testing.T) {
+		assert.Equal(t, http.StatusBadRequest, send("POST", "/admin/collections", `{"name":"Bad","type":"smart","rules":[{"field":"colour","operator":"eq","value":"red"}]}`).Code)
+		create(`{"name":"Summer Under 50","type":"smart","rules":[{"field":"tag","operator":"eq","value":"summer"},{"field":"price","operator":"lt","value":"50"}]}`)
+
+		w := send("GET", "/collections/summer-under-50", "")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), `"field":"tag"`)
+		assert.Contains(t, send("GET", "/collections", "").Body.String(), "summer-under-50")
+
+		// Cached pages are re-evaluated as products change
+		assert.Equal(t, []string{"Sun Hat", "Sandals"}, listed("/collections/summer-under-50/products"))
+		send("PUT", fmt.Sprintf("/product/%d/tags", products["Cooler"].ID), `{"tags":["summer"]}`)
+		assert.Equal(t, []string{"Sun Hat", "Sandals", "Cooler"}, listed("/collections/summer-under-50/products"))
+
+		// Drafts are left out
+		assert.Equal(t, http.StatusOK, send("PUT", fmt.Sprintf("/product/%d/publishing", products["Sandals"].ID), `{"status":"draft"}`).Code)
+		assert.Equal(t, []string{"Sun Hat", "Cooler"}, listed("/collections/summer-under-50/products"))
+	})
+}
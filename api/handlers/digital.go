@@ -0,0 +1,248 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/geoo115/Ecommerce/db"
+	"github.com/geoo115/Ecommerce/models"
+	"github.com/geoo115/Ecommerce/services"
+	"github.com/geoo115/Ecommerce/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// SetProductType makes a product digital, or simple again (admin only). Digital products
+// are downloaded rather than shipped and hold no stock.
+func SetProductType(c *gin.Context) {
+	product, ok := loadProductForDigital(c)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		Type string `json:"type" binding:"required"`
+	}
+	if err := Base.BindJSON(c, &input); err != nil {
+		return
+	}
+
+	if err := services.NewDigitalService().SetType(product, input.Type); err != nil {
+		sendDigitalError(c, err)
+		return
+	}
+	invalidateProductCache(product.ID)
+
+	utils.SendSuccess(c, http.StatusOK, "Product type updated successfully", product)
+}
+
+// UploadDigitalFile attaches a downloadable file to a digital product (admin only).
+// Expects a multipart form with the file in the "file" field.
+func UploadDigitalFile(c *gin.Context) {
+	product, ok := loadProductForDigital(c)
+	if !ok {
+		return
+	}
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		utils.SendValidationError(c, "A file is required")
+		return
+	}
+	file, err := fileHeader.Open()
+	if err != nil {
+		utils.SendInternalError(c, "Failed to read upload")
+		return
+	}
+	defer file.Close()
+
+	digitalFile, err := services.NewDigitalService().AddFile(product, services.DigitalFileUpload{
+		Name:        fileHeader.Filename,
+		ContentType: fileHeader.Header.Get("Content-Type"),
+		Data:        file,
+	})
+	if err != nil {
+		sendDigitalError(c, err)
+		return
+	}
+
+	utils.SendSuccess(c, http.StatusCreated, "File uploaded successfully", digitalFile)
+}
+
+// ListDigitalFiles returns a digital product's files (admin only)
+func ListDigitalFiles(c *gin.Context) {
+	productID, err := Base.ValidateIDParam(c, "id")
+	if err != nil {
+		return
+	}
+
+	var files []models.DigitalFile
+	if err := db.DB.Where("product_id = ?", productID).Order("id").Find(&files).Error; err != nil {
+		utils.SendInternalError(c, "Failed to fetch files")
+		return
+	}
+
+	utils.SendSuccess(c, http.StatusOK, "Files retrieved successfully", files)
+}
+
+// DeleteDigitalFile removes a file from a digital product (admin only)
+func DeleteDigitalFile(c *gin.Context) {
+	productID, err := Base.ValidateIDParam(c, "id")
+	if err != nil {
+		return
+	}
+	fileID, err := Base.ValidateIDParam(c, "file_id")
+	if err != nil {
+		return
+	}
+
+	var file models.DigitalFile
+	if err := db.DB.Where("id = ? AND product_id = ?", fileID, productID).First(&file).Error; err != nil {
+		Base.HandleDBError(c, err, "File not found", "Failed to fetch file")
+		return
+	}
+
+	if err := services.NewDigitalService().DeleteFile(&file); err != nil {
+		utils.SendInternalError(c, "Failed to delete file")
+		return
+	}
+
+	utils.SendSuccess(c, http.StatusOK, "File deleted successfully", nil)
+}
+
+// AddLicenceKeys loads licence keys into a digital product's pool (admin only). Keys already
+// in the pool are skipped.
+func AddLicenceKeys(c *gin.Context) {
+	product, ok := loadProductForDigital(c)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		Keys []string `json:"keys" binding:"required,min=1"`
+	}
+	if err := Base.BindJSON(c, &input); err != nil {
+		return
+	}
+
+	digital := services.NewDigitalService()
+	added, err := digital.AddLicenceKeys(product, input.Keys)
+	if err != nil {
+		sendDigitalError(c, err)
+		return
+	}
+	pool, err := digital.LicencePool(product.ID)
+	if err != nil {
+		utils.SendInternalError(c, "Failed to count licence keys")
+		return
+	}
+	invalidateProductCache(product.ID)
+
+	utils.SendSuccess(c, http.StatusCreated, "Licence keys added successfully", gin.H{"added": added, "pool": pool})
+}
+
+// GetLicencePool counts a digital product's licence keys and those still available (admin only)
+func GetLicencePool(c *gin.Context) {
+	productID, err := Base.ValidateIDParam(c, "id")
+	if err != nil {
+		return
+	}
+
+	pool, err := services.NewDigitalService().LicencePool(productID)
+	if err != nil {
+		utils.SendInternalError(c, "Failed to count licence keys")
+		return
+	}
+
+	utils.SendSuccess(c, http.StatusOK, "Licence keys counted successfully", pool)
+}
+
+// ListOrderDownloads returns the licence keys and fresh signed download links of the
+// authenticated user's paid order. The order may be referenced by ID or order number.
+func ListOrderDownloads(c *gin.Context) {
+	userID, err := Base.GetUserID(c)
+	if err != nil {
+		return
+	}
+
+	var order models.Order
+	if err := whereOrderRef(db.DB, c.Param("id")).Where("user_id = ?", userID).First(&order).Error; err != nil {
+		Base.HandleDBError(c, err, "Order not found", "Failed to fetch order")
+		return
+	}
+
+	items, err := services.NewDigitalService().OrderDownloads(&order, time.Now())
+	if err != nil {
+		sendDigitalError(c, err)
+		return
+	}
+
+	utils.SendSuccess(c, http.StatusOK, "Downloads retrieved successfully", items)
+}
+
+// DownloadDigitalFile streams a file through a signed download link. The link itself is the
+// credential, so no login is needed; each download counts against the item's limit.
+func DownloadDigitalFile(c *gin.Context) {
+	orderItemID, err := Base.ValidateIDParam(c, "item_id")
+	if err != nil {
+		return
+	}
+	fileID, err := Base.ValidateIDParam(c, "file_id")
+	if err != nil {
+		return
+	}
+	expires, err := strconv.ParseInt(c.Query("expires"), 10, 64)
+	if err != nil {
+		sendDigitalError(c, services.ErrInvalidDownloadLink)
+		return
+	}
+
+	file, contents, err := services.NewDigitalService().Download(orderItemID, fileID, expires, c.Query("signature"), time.Now())
+	if err != nil {
+		sendDigitalError(c, err)
+		return
+	}
+	defer contents.Close()
+
+	c.Header("Cache-Control", "private, no-store")
+	c.DataFromReader(http.StatusOK, file.Size, file.ContentType, contents, map[string]string{
+		"Content-Disposition": fmt.Sprintf("attachment; filename=%q", file.Name),
+	})
+}
+
+// loadProductForDigital fetches the product in the :id param
+func loadProductForDigital(c *gin.Context) (*models.Product, bool) {
+	productID, err := Base.ValidateIDParam(c, "id")
+	if err != nil {
+		return nil, false
+	}
+
+	var product models.Product
+	if err := db.DB.First(&product, productID).Error; err != nil {
+		Base.HandleDBError(c, err, "Product not found", "Failed to fetch product")
+		return nil, false
+	}
+	return &product, true
+}
+
+// sendDigitalError maps digital product errors to responses
+func sendDigitalError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrInvalidProductType),
+		errors.Is(err, services.ErrDigitalConflict),
+		errors.Is(err, services.ErrNotDigital),
+		errors.Is(err, services.ErrInvalidDigitalFile),
+		errors.Is(err, services.ErrInvalidLicenceKey):
+		utils.SendValidationError(c, err.Error())
+	case errors.Is(err, services.ErrDownloadUnavailable):
+		utils.SendConflict(c, "Downloads are available once the order is paid")
+	case errors.Is(err, services.ErrInvalidDownloadLink):
+		utils.SendForbidden(c, "Download link is invalid or has expired")
+	case errors.Is(err, services.ErrDownloadLimitReached):
+		utils.SendError(c, http.StatusGone, "Download limit reached")
+	default:
+		utils.SendInternalError(c, "Failed to process digital product")
+	}
+}
@@ -0,0 +1,133 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/geoo115/Ecommerce/cache"
+	"github.com/geoo115/Ecommerce/db"
+	"github.com/geoo115/Ecommerce/models"
+	"github.com/geoo115/Ecommerce/services"
+	"github.com/geoo115/Ecommerce/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDigitalProductEndpoints(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	SetupTestDB(t)
+	utils.AppLogger = utils.NewLogger(utils.INFO)
+	originalCache := cache.GlobalCache
+	cache.GlobalCache = cache.NewInMemoryCache()
+	originalFiles := services.DigitalFiles
+	services.DigitalFiles = services.NewLocalBlobStore(t.TempDir(), "")
+	os.Setenv("DOWNLOAD_SIGNING_KEY", "test-signing-key")
+	defer func() {
+		cache.GlobalCache = originalCache
+		services.DigitalFiles = originalFiles
+		os.Unsetenv("DOWNLOAD_SIGNING_KEY")
+	}()
+
+	user := models.User{Username: "reader", Email: "reader@example.com"}
+	db.DB.Create(&user)
+	product := models.Product{Name: "Field Guide", Price: 12}
+	db.DB.Create(&product)
+	db.DB.Create(&models.Inventory{ProductID: product.ID})
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set("userID", user.ID)
+		c.Next()
+	})
+	router.PUT("/product/:id/type", SetProductType)
+	router.POST("/product/:id/files", UploadDigitalFile)
+	router.GET("/product/:id/files", ListDigitalFiles)
+	router.DELETE("/product/:id/files/:file_id", DeleteDigitalFile)
+	router.POST("/product/:id/licence-keys", AddLicenceKeys)
+	router.GET("/product/:id/licence-keys", GetLicencePool)
+	router.GET("/orders/:id/downloads", ListOrderDownloads)
+	router.GET("/downloads/:item_id/:file_id", DownloadDigitalFile)
+
+	send := func(method, path, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, path, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+		return w
+	}
+	upload := func(name, contents string) *httptest.ResponseRecorder {
+		var body bytes.Buffer
+		form := multipart.NewWriter(&body)
+		part, _ := form.CreateFormFile("file", name)
+		part.Write([]byte(contents))
+		form.Close()
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", fmt.Sprintf("/product/%d/files", product.ID), &body)
+		req.Header.Set("Content-Type", form.FormDataContentType())
+		router.ServeHTTP(w, req)
+		return w
+	}
+	productPath := fmt.Sprintf("/product/%d", product.ID)
+
+	// Files and keys only attach to digital products
+	assert.Equal(t, http.StatusBadRequest, upload("guide.pdf", "pages").Code)
+	assert.Equal(t, http.StatusBadRequest, send("PUT", productPath+"/type", `{"type":"bundle"}`).Code)
+	assert.Equal(t, http.StatusOK, send("PUT", productPath+"/type", `{"type":"digital"}`).Code)
+
+	w := upload("guide.pdf", "pages")
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.NotContains(t, w.Body.String(), "products/")
+	assert.Equal(t, http.StatusCreated, upload("extra.pdf", "more pages").Code)
+	var files struct {
+		Data []models.DigitalFile `json:"data"`
+	}
+	json.Unmarshal(send("GET", productPath+"/files", "").Body.Bytes(), &files)
+	if !assert.Len(t, files.Data, 2) {
+		return
+	}
+	assert.Equal(t, http.StatusOK, send("DELETE", fmt.Sprintf("%s/files/%d", productPath, files.Data[1].ID), "").Code)
+	assert.Equal(t, http.StatusNotFound, send("DELETE", fmt.Sprintf("%s/files/%d", productPath, files.Data[1].ID), "").Code)
+
+	w = send("POST", productPath+"/licence-keys", `{"keys":["KEY-1","KEY-2","KEY-1"]}`)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Contains(t, w.Body.String(), `"added":2`)
+	assert.Contains(t, send("GET", productPath+"/licence-keys", "").Body.String(), `"available":2`)
+
+	order := models.Order{UserID: user.ID}
+	assert.NoError(t, services.NewOrderService().CreateOrder(&order, []services.OrderLine{{ProductID: product.ID, Quantity: 1}}))
+	downloadsPath := fmt.Sprintf("/orders/%d/downloads", order.ID)
+	assert.Equal(t, http.StatusConflict, send("GET", downloadsPath, "").Code)
+	_, err := services.NewPaymentService().ChargeOrder(&order, "card")
+	assert.NoError(t, err)
+
+	w = send("GET", downloadsPath, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	var downloads struct {
+		Data []services.DigitalItem `json:"data"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &downloads)
+	if !assert.Len(t, downloads.Data, 1) || !assert.Len(t, downloads.Data[0].Downloads, 1) {
+		return
+	}
+	assert.Equal(t, []string{"KEY-1"}, downloads.Data[0].LicenceKeys)
+	link := downloads.Data[0].Downloads[0].URL
+
+	w = send("GET", link, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "pages", w.Body.String())
+	assert.Contains(t, w.Header().Get("Content-Disposition"), `filename="guide.pdf"`)
+
+	assert.Equal(t, http.StatusForbidden, send("GET", link+"0", "").Code)
+	assert.Equal(t, http.StatusForbidden, send("GET", fmt.Sprintf("/downloads/%d/%d", downloads.Data[0].OrderItemID, files.Data[0].ID), "").Code)
+
+	// Other customers' orders are not found
+	other := models.Order{UserID: user.ID + 1, Status: "Paid"}
+	db.DB.Create(&other)
+	assert.Equal(t, http.StatusNotFound, send("GET", fmt.Sprintf("/orders/%d/downloads", other.ID), "").Code)
+}
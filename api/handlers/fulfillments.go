@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/geoo115/Ecommerce/db"
+	"github.com/geoo115/Ecommerce/models"
+	"github.com/geoo115/Ecommerce/services"
+	"github.com/geoo115/Ecommerce/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// CreateFulfillment ships some or all of an order's items as one parcel (admin only)
+func CreateFulfillment(c *gin.Context) {
+	orderID, err := Base.ValidateIDParam(c, "id")
+	if err != nil {
+		return
+	}
+
+	var input struct {
+		Carrier        string                     `json:"carrier" binding:"required"`
+		TrackingNumber string                     `json:"tracking_number" binding:"required"`
+		ShippedAt      *time.Time                 `json:"shipped_at"`
+		Items          []services.FulfillmentLine `json:"items" binding:"required,min=1"`
+	}
+	if err := Base.BindJSON(c, &input); err != nil {
+		return
+	}
+
+	var order models.Order
+	if err := db.DB.First(&order, orderID).Error; err != nil {
+		Base.HandleDBError(c, err, "Order not found", "Failed to fetch order")
+		return
+	}
+
+	fulfillment := models.Fulfillment{
+		Carrier:        input.Carrier,
+		TrackingNumber: input.TrackingNumber,
+	}
+	if input.ShippedAt != nil {
+		fulfillment.ShippedAt = *input.ShippedAt
+	}
+
+	if err := services.NewFulfillmentService().CreateFulfillment(&order, &fulfillment, input.Items); err != nil {
+		switch {
+		case errors.Is(err, services.ErrOrderNotFulfillable):
+			utils.SendConflict(c, "Order cannot be shipped in status "+order.Status)
+		case errors.Is(err, services.ErrInvalidFulfillment):
+			utils.SendValidationError(c, "Fulfillment quantities exceed unshipped items")
+		default:
+			utils.SendInternalError(c, "Failed to create fulfillment")
+		}
+		return
+	}
+
+	utils.SendSuccess(c, http.StatusCreated, "Fulfillment created successfully", gin.H{
+		"fulfillment":  fulfillment,
+		"order_status": order.Status,
+	})
+}
+
+// UpdateFulfillmentStatus updates a parcel's delivery status (admin only)
+func UpdateFulfillmentStatus(c *gin.Context) {
+	fulfillmentID, err := Base.ValidateIDParam(c, "id")
+	if err != nil {
+		return
+	}
+
+	var input struct {
+		Status string `json:"status" binding:"required"`
+	}
+	if err := Base.BindJSON(c, &input); err != nil {
+		return
+	}
+
+	var fulfillment models.Fulfillment
+	if err := db.DB.Preload("Items").First(&fulfillment, fulfillmentID).Error; err != nil {
+		Base.HandleDBError(c, err, "Fulfillment not found", "Failed to fetch fulfillment")
+		return
+	}
+
+	if err := services.NewFulfillmentService().UpdateStatus(&fulfillment, input.Status); err != nil {
+		if errors.Is(err, services.ErrInvalidFulfillmentStatus) {
+			utils.SendValidationError(c, "Status must be one of: Shipped, In Transit, Delivered")
+			return
+		}
+		utils.SendInternalError(c, "Failed to update fulfillment")
+		return
+	}
+
+	utils.SendSuccess(c, http.StatusOK, "Fulfillment updated successfully", fulfillment)
+}
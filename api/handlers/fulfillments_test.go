@@ -0,0 +1,118 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/geoo115/Ecommerce/db"
+	"github.com/geoo115/Ecommerce/models"
+	"github.com/geoo115/Ecommerce/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreateFulfillment(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	SetupTestDB(t)
+	utils.AppLogger = utils.NewLogger(utils.INFO)
+
+	user := CreateTestUser(t, db.DB, "shipper")
+	order := models.Order{UserID: user.ID, Status: "Paid", Items: []models.OrderItem{{ProductID: 1, Quantity: 3, Price: 2}}}
+	db.DB.Create(&order)
+	itemID := order.Items[0].ID
+
+	router := gin.New()
+	router.POST("/admin/orders/:id/fulfillments", CreateFulfillment)
+	router.GET("/orders/:id", func(c *gin.Context) {
+		c.Set("userID", user.ID)
+		GetOrder(c)
+	})
+
+	tests := []struct {
+		name       string
+		orderID    string
+		body       string
+		wantStatus int
+	}{
+		{"first parcel", fmt.Sprint(order.ID), fmt.Sprintf(`{"carrier":"UPS","tracking_number":"1Z1","items":[{"order_item_id":%d,"quantity":2}]}`, itemID), http.StatusCreated},
+		{"too many units", fmt.Sprint(order.ID), fmt.Sprintf(`{"carrier":"UPS","tracking_number":"1Z2","items":[{"order_item_id":%d,"quantity":2}]}`, itemID), http.StatusBadRequest},
+		{"missing tracking", fmt.Sprint(order.ID), fmt.Sprintf(`{"carrier":"UPS","items":[{"order_item_id":%d,"quantity":1}]}`, itemID), http.StatusBadRequest},
+		{"unknown order", "9999", fmt.Sprintf(`{"carrier":"UPS","tracking_number":"1Z3","items":[{"order_item_id":%d,"quantity":1}]}`, itemID), http.StatusNotFound},
+		{"last parcel", fmt.Sprint(order.ID), fmt.Sprintf(`{"carrier":"DHL","tracking_number":"JD4","items":[{"order_item_id":%d,"quantity":1}]}`, itemID), http.StatusCreated},
+		{"already shipped", fmt.Sprint(order.ID), fmt.Sprintf(`{"carrier":"DHL","tracking_number":"JD5","items":[{"order_item_id":%d,"quantity":1}]}`, itemID), http.StatusConflict},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("POST", "/admin/orders/"+tt.orderID+"/fulfillments", bytes.NewBufferString(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			router.ServeHTTP(w, req)
+			assert.Equal(t, tt.wantStatus, w.Code)
+		})
+	}
+
+	// Customers see each parcel on the order
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", fmt.Sprintf("/orders/%d", order.ID), nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response struct {
+		Data models.Order `json:"data"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &response)
+	assert.Equal(t, "Shipped", response.Data.Status)
+	if assert.Len(t, response.Data.Fulfillments, 2) {
+		assert.Equal(t, "1Z1", response.Data.Fulfillments[0].TrackingNumber)
+		assert.Equal(t, 2, response.Data.Fulfillments[0].Items[0].Quantity)
+	}
+}
+
+func TestUpdateFulfillmentStatus(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	SetupTestDB(t)
+	utils.AppLogger = utils.NewLogger(utils.INFO)
+
+	order := models.Order{UserID: 1, Status: "Shipped", Items: []models.OrderItem{{ProductID: 1, Quantity: 1}}}
+	db.DB.Create(&order)
+	fulfillment := models.Fulfillment{
+		OrderID: order.ID,
+		Carrier: "UPS",
+		Status:  "Shipped",
+		Items:   []models.FulfillmentItem{{OrderItemID: order.Items[0].ID, Quantity: 1}},
+	}
+	db.DB.Create(&fulfillment)
+
+	router := gin.New()
+	router.PUT("/admin/fulfillments/:id/status", UpdateFulfillmentStatus)
+
+	tests := []struct {
+		name       string
+		id         string
+		body       string
+		wantStatus int
+	}{
+		{"invalid status", fmt.Sprint(fulfillment.ID), `{"status":"Lost"}`, http.StatusBadRequest},
+		{"unknown fulfillment", "9999", `{"status":"Delivered"}`, http.StatusNotFound},
+		{"delivered", fmt.Sprint(fulfillment.ID), `{"status":"Delivered"}`, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("PUT", "/admin/fulfillments/"+tt.id+"/status", bytes.NewBufferString(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			router.ServeHTTP(w, req)
+			assert.Equal(t, tt.wantStatus, w.Code)
+		})
+	}
+
+	var stored models.Order
+	db.DB.First(&stored, order.ID)
+	assert.Equal(t, "Delivered", stored.Status)
+}
@@ -1,10 +1,13 @@
 package handlers
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
 
 	"github.com/geoo115/Ecommerce/db"
+	"github.com/geoo115/Ecommerce/services"
 	"github.com/geoo115/Ecommerce/utils"
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
@@ -94,8 +97,10 @@ func (h *HandlerBase) HandleDBError(c *gin.Context, err error, notFoundMsg, inte
 
 // PaginationParams holds pagination parameters
 type PaginationParams struct {
-	Page  int
-	Limit int
+	Page         int
+	Limit        int
+	Cursor       string // Opaque cursor from a previous page; takes precedence over Page
+	IncludeTotal bool
 }
 
 // GetPaginationParams extracts pagination parameters from query string
@@ -111,7 +116,17 @@ func (h *HandlerBase) GetPaginationParams(c *gin.Context) PaginationParams {
 		limit = l
 	}
 
-	return PaginationParams{Page: page, Limit: limit}
+	return PaginationParams{
+		Page:         page,
+		Limit:        limit,
+		Cursor:       c.Query("cursor"),
+		IncludeTotal: c.Query("include_total") == "true",
+	}
+}
+
+// Key renders the parameters for cache keys
+func (p PaginationParams) Key() string {
+	return fmt.Sprintf("page:%d:limit:%d:cursor:%s:total:%t", p.Page, p.Limit, p.Cursor, p.IncludeTotal)
 }
 
 // ApplyPagination applies pagination to a GORM query
@@ -120,6 +135,29 @@ func (h *HandlerBase) ApplyPagination(query *gorm.DB, params PaginationParams) *
 	return query.Offset(offset).Limit(params.Limit)
 }
 
+// FetchPage loads the requested page of query into dest using cursor pagination. Scopes such
+// as preloads apply to the rows but not to the total count. It responds and returns false on
+// an invalid cursor or a database error.
+func (h *HandlerBase) FetchPage(c *gin.Context, query *gorm.DB, order services.PageOrder, dest interface{}, internalMsg string, scopes ...func(*gorm.DB) *gorm.DB) (*utils.Pagination, bool) {
+	params := h.GetPaginationParams(c)
+	pagination, err := services.FetchPage(query, order, services.PageRequest{
+		Limit:        params.Limit,
+		Cursor:       params.Cursor,
+		Offset:       (params.Page - 1) * params.Limit,
+		IncludeTotal: params.IncludeTotal,
+	}, dest, scopes...)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidCursor) {
+			utils.SendValidationError(c, "Invalid cursor")
+		} else {
+			utils.SendInternalError(c, internalMsg)
+			utils.Error("Database error: %v", err)
+		}
+		return nil, false
+	}
+	return pagination, true
+}
+
 // TransactionWrapper wraps operations in a database transaction
 func (h *HandlerBase) TransactionWrapper(c *gin.Context, fn func(*gorm.DB) error) error {
 	tx := db.DB.Begin()
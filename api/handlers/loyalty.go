@@ -0,0 +1,150 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/geoo115/Ecommerce/db"
+	"github.com/geoo115/Ecommerce/models"
+	"github.com/geoo115/Ecommerce/services"
+	"github.com/geoo115/Ecommerce/utils"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// GetLoyaltySummary returns the authenticated user's points balance and tier
+func GetLoyaltySummary(c *gin.Context) {
+	uid, err := Base.GetUserID(c)
+	if err != nil {
+		return
+	}
+
+	summary, err := services.NewLoyaltyService().GetSummary(uid)
+	if err != nil {
+		utils.SendInternalError(c, "Failed to fetch loyalty balance")
+		return
+	}
+
+	utils.SendSuccess(c, http.StatusOK, "Loyalty balance retrieved successfully", summary)
+}
+
+// ListLoyaltyHistory returns the authenticated user's points ledger
+func ListLoyaltyHistory(c *gin.Context) {
+	uid, err := Base.GetUserID(c)
+	if err != nil {
+		return
+	}
+
+	var entries []models.LoyaltyTransaction
+	pagination, ok := Base.FetchPage(c, db.DB.Model(&models.LoyaltyTransaction{}).Where("user_id = ?", uid), services.NewestFirst("loyalty_transactions"), &entries, "Failed to fetch loyalty history")
+	if !ok {
+		return
+	}
+
+	utils.SendPage(c, "Loyalty history retrieved successfully", entries, pagination)
+}
+
+// GetUserLoyalty returns any user's points balance and tier (admin only)
+func GetUserLoyalty(c *gin.Context) {
+	userID, err := Base.ValidateIDParam(c, "id")
+	if err != nil {
+		return
+	}
+
+	var user models.User
+	if err := db.DB.First(&user, userID).Error; err != nil {
+		Base.HandleDBError(c, err, "User not found", "Failed to fetch user")
+		return
+	}
+
+	summary, err := services.NewLoyaltyService().GetSummary(userID)
+	if err != nil {
+		utils.SendInternalError(c, "Failed to fetch loyalty balance")
+		return
+	}
+
+	utils.SendSuccess(c, http.StatusOK, "Loyalty balance retrieved successfully", summary)
+}
+
+// AdjustLoyaltyPoints manually credits or debits a user's points (admin only)
+func AdjustLoyaltyPoints(c *gin.Context) {
+	userID, err := Base.ValidateIDParam(c, "id")
+	if err != nil {
+		return
+	}
+
+	var input struct {
+		Points int    `json:"points" binding:"required"`
+		Reason string `json:"reason" binding:"required"`
+	}
+	if err := Base.BindJSON(c, &input); err != nil {
+		return
+	}
+
+	var user models.User
+	if err := db.DB.First(&user, userID).Error; err != nil {
+		Base.HandleDBError(c, err, "User not found", "Failed to fetch user")
+		return
+	}
+
+	entry, err := services.NewLoyaltyService().AdjustPoints(userID, input.Points, utils.SanitizeString(input.Reason))
+	if err != nil {
+		if errors.Is(err, services.ErrInsufficientPoints) {
+			utils.SendValidationError(c, "Insufficient loyalty points")
+			return
+		}
+		utils.SendInternalError(c, "Failed to adjust loyalty points")
+		return
+	}
+
+	utils.SendSuccess(c, http.StatusOK, "Loyalty points adjusted successfully", entry)
+}
+
+// ListLoyaltyEarnRates lists the per-category earn rate overrides (admin only)
+func ListLoyaltyEarnRates(c *gin.Context) {
+	var rates []models.LoyaltyEarnRate
+	if err := db.DB.Preload("Category").Find(&rates).Error; err != nil {
+		utils.SendInternalError(c, "Failed to fetch earn rates")
+		return
+	}
+
+	utils.SendSuccess(c, http.StatusOK, "Earn rates retrieved successfully", rates)
+}
+
+// SetLoyaltyEarnRate creates or updates the earn rate for a category (admin only)
+func SetLoyaltyEarnRate(c *gin.Context) {
+	categoryID, err := Base.ValidateIDParam(c, "category_id")
+	if err != nil {
+		return
+	}
+
+	var input struct {
+		PointsPerUnit float64 `json:"points_per_unit" binding:"gte=0"`
+	}
+	if err := Base.BindJSON(c, &input); err != nil {
+		return
+	}
+
+	var category models.Category
+	if err := db.DB.First(&category, categoryID).Error; err != nil {
+		Base.HandleDBError(c, err, "Category not found", "Failed to fetch category")
+		return
+	}
+
+	var rate models.LoyaltyEarnRate
+	err = db.DB.Where("category_id = ?", categoryID).First(&rate).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		utils.SendInternalError(c, "Failed to fetch earn rate")
+		return
+	}
+
+	rate.CategoryID = categoryID
+	rate.PointsPerUnit = input.PointsPerUnit
+	if err := db.DB.Save(&rate).Error; err != nil {
+		utils.SendInternalError(c, "Failed to save earn rate")
+		return
+	}
+
+	rate.Category = category
+	utils.SendSuccess(c, http.StatusOK, "Earn rate saved successfully", rate)
+}
@@ -0,0 +1,180 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/geoo115/Ecommerce/db"
+	"github.com/geoo115/Ecommerce/models"
+	"github.com/geoo115/Ecommerce/services"
+	"github.com/geoo115/Ecommerce/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetLoyaltySummary_Success(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	SetupTestDB(t)
+	utils.AppLogger = utils.NewLogger(utils.INFO)
+
+	user := CreateTestUser(t, db.DB, "loyalty")
+	_, err := services.NewLoyaltyService().AdjustPoints(user.ID, 250, "Welcome bonus")
+	assert.NoError(t, err)
+
+	router := gin.New()
+	router.GET("/loyalty", func(c *gin.Context) {
+		c.Set("userID", user.ID)
+		GetLoyaltySummary(c)
+	})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/loyalty", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var response struct {
+		Data services.LoyaltySummary `json:"data"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &response)
+	assert.Equal(t, 250, response.Data.Balance)
+	assert.Equal(t, 2.5, response.Data.BalanceValue)
+	assert.Equal(t, "Bronze", response.Data.Tier.Name)
+}
+
+func TestGetLoyaltySummary_Unauthorized(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	SetupTestDB(t)
+
+	router := gin.New()
+	router.GET("/loyalty", GetLoyaltySummary)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/loyalty", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func TestListLoyaltyHistory_Success(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	SetupTestDB(t)
+	utils.AppLogger = utils.NewLogger(utils.INFO)
+
+	user := CreateTestUser(t, db.DB, "history")
+	service := services.NewLoyaltyService()
+	service.AdjustPoints(user.ID, 100, "First")
+	service.AdjustPoints(user.ID, -30, "Second")
+
+	router := gin.New()
+	router.GET("/loyalty/history", func(c *gin.Context) {
+		c.Set("userID", user.ID)
+		ListLoyaltyHistory(c)
+	})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/loyalty/history?limit=1&include_total=true", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var response struct {
+		Data       []models.LoyaltyTransaction `json:"data"`
+		Pagination utils.Pagination            `json:"pagination"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &response)
+	if assert.NotNil(t, response.Pagination.Total) {
+		assert.Equal(t, int64(2), *response.Pagination.Total)
+	}
+	if assert.Len(t, response.Data, 1) {
+		assert.Equal(t, -30, response.Data[0].Points)
+	}
+
+	// The next page holds the older entry
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/loyalty/history?limit=1&cursor="+response.Pagination.NextCursor, nil)
+	router.ServeHTTP(w, req)
+	response.Data, response.Pagination = nil, utils.Pagination{}
+	json.Unmarshal(w.Body.Bytes(), &response)
+	if assert.Len(t, response.Data, 1) {
+		assert.Equal(t, 100, response.Data[0].Points)
+	}
+	assert.Empty(t, response.Pagination.NextCursor)
+}
+
+func TestAdjustLoyaltyPoints(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	SetupTestDB(t)
+	utils.AppLogger = utils.NewLogger(utils.INFO)
+
+	user := CreateTestUser(t, db.DB, "adjust")
+
+	router := gin.New()
+	router.POST("/admin/loyalty/users/:id/adjust", AdjustLoyaltyPoints)
+
+	tests := []struct {
+		name       string
+		userID     string
+		body       string
+		wantStatus int
+	}{
+		{"credit", fmt.Sprint(user.ID), `{"points": 75, "reason": "Apology"}`, http.StatusOK},
+		{"debit beyond balance", fmt.Sprint(user.ID), `{"points": -100, "reason": "Correction"}`, http.StatusBadRequest},
+		{"missing reason", fmt.Sprint(user.ID), `{"points": 5}`, http.StatusBadRequest},
+		{"unknown user", "9999", `{"points": 5, "reason": "Nope"}`, http.StatusNotFound},
+		{"invalid id", "abc", `{"points": 5, "reason": "Nope"}`, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("POST", "/admin/loyalty/users/"+tt.userID+"/adjust", bytes.NewBufferString(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			router.ServeHTTP(w, req)
+			assert.Equal(t, tt.wantStatus, w.Code)
+		})
+	}
+
+	balance, _ := services.NewLoyaltyService().GetBalance(user.ID)
+	assert.Equal(t, 75, balance)
+}
+
+func TestSetLoyaltyEarnRate(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	SetupTestDB(t)
+	utils.AppLogger = utils.NewLogger(utils.INFO)
+
+	category := models.Category{Name: "Rated"}
+	db.DB.Create(&category)
+
+	router := gin.New()
+	router.PUT("/admin/loyalty/earn-rates/:category_id", SetLoyaltyEarnRate)
+	router.GET("/admin/loyalty/earn-rates", ListLoyaltyEarnRates)
+
+	for _, rate := range []string{"2", "4.5"} {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("PUT", fmt.Sprintf("/admin/loyalty/earn-rates/%d", category.ID), bytes.NewBufferString(`{"points_per_unit": `+rate+`}`))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+	}
+
+	var rates []models.LoyaltyEarnRate
+	db.DB.Find(&rates)
+	if assert.Len(t, rates, 1) {
+		assert.Equal(t, 4.5, rates[0].PointsPerUnit)
+	}
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("PUT", "/admin/loyalty/earn-rates/9999", bytes.NewBufferString(`{"points_per_unit": 1}`))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/admin/loyalty/earn-rates", nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+}
@@ -4,12 +4,12 @@ import (
 	"fmt"
 	"net/http"
 	"strconv"
-	"strings"
 	"time"
 
 	"github.com/geoo115/Ecommerce/cache"
 	"github.com/geoo115/Ecommerce/db"
 	"github.com/geoo115/Ecommerce/models"
+	"github.com/geoo115/Ecommerce/services"
 	"github.com/geoo115/Ecommerce/utils"
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
@@ -21,46 +21,40 @@ func OptimizedListProducts(c *gin.Context) {
 		Base.HandleDBError(c, fmt.Errorf("database connection is nil"), "Database error", "Database error")
 		return
 	}
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
 	categoryID, _ := strconv.ParseUint(c.DefaultQuery("category_id", "0"), 10, 32)
+	pagination := Base.GetPaginationParams(c)
 
 	// Try cache first
 	cache := cache.GetCache()
+	cacheKey := fmt.Sprintf("products:optimized:category:%d:%s", categoryID, pagination.Key())
+	var results productResults
 	if cache != nil {
-		if categoryID > 0 {
-			if products, err := cache.GetProductsByCategory(uint(categoryID), page, limit); err == nil {
-				c.JSON(http.StatusOK, gin.H{
-					"products": products,
-					"cached":   true,
-				})
-				return
-			}
+		if err := cache.Get(cacheKey, &results); err == nil {
+			sendOptimizedPage(c, gin.H{"products": results.Products, "cached": true}, results.Pagination)
+			return
 		}
 	}
 
 	// Cache miss - query database
-	var products []models.Product
-	query := db.DB.Preload("Category").Offset((page - 1) * limit).Limit(limit)
-
+	query := db.DB.Model(&models.Product{}).Scopes(services.PublishedProducts)
 	if categoryID > 0 {
 		query = query.Where("category_id = ?", categoryID)
 	}
 
-	if err := query.Find(&products).Error; err != nil {
-		utils.SendError(c, http.StatusInternalServerError, "Failed to fetch products")
+	var ok bool
+	results.Pagination, ok = Base.FetchPage(c, query, services.OrderByID("products"), &results.Products, "Failed to fetch products", func(page *gorm.DB) *gorm.DB {
+		return page.Preload("Category")
+	})
+	if !ok {
 		return
 	}
 
 	// Cache the result
-	if cache != nil && categoryID > 0 {
-		cache.SetProductsByCategory(uint(categoryID), page, limit, products)
+	if cache != nil {
+		cache.Set(cacheKey, results)
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"products": products,
-		"cached":   false,
-	})
+	sendOptimizedPage(c, gin.H{"products": results.Products, "cached": false}, results.Pagination)
 }
 
 // OptimizedGetProduct provides cached product retrieval
@@ -89,7 +83,7 @@ func OptimizedGetProduct(c *gin.Context) {
 
 	// Cache miss - query database with optimized preloading
 	var product models.Product
-	if err := db.DB.Preload("Category").Preload("Inventory").First(&product, id).Error; err != nil {
+	if err := db.DB.Scopes(services.PublishedProducts).Preload("Category").Preload("Inventory").First(&product, id).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			utils.SendNotFound(c, "Product not found")
 			return
@@ -246,7 +240,7 @@ func OptimizedHealthCheck(c *gin.Context) {
 	})
 }
 
-// OptimizedProductSearch provides full-text search with caching
+// OptimizedProductSearch provides relevance-ordered full-text search with caching
 func OptimizedProductSearch(c *gin.Context) {
 	query := c.Query("q")
 	if query == "" {
@@ -259,47 +253,39 @@ func OptimizedProductSearch(c *gin.Context) {
 		return
 	}
 
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	pagination := Base.GetPaginationParams(c)
 
 	// Try cache first
 	cache := cache.GetCache()
-	cacheKey := fmt.Sprintf("search:%s:page:%d:limit:%d", query, page, limit)
+	cacheKey := fmt.Sprintf("search:%s:%s", query, pagination.Key())
+	var results productResults
 	if cache != nil {
-		var products []models.Product
-		if err := cache.Get(cacheKey, &products); err == nil {
-			c.JSON(http.StatusOK, gin.H{
-				"products": products,
-				"query":    query,
-				"cached":   true,
-			})
+		if err := cache.Get(cacheKey, &results); err == nil {
+			sendOptimizedPage(c, gin.H{"products": results.Products, "query": query, "cached": true}, results.Pagination)
 			return
 		}
 	}
 
-	// Cache miss - perform search
-	var products []models.Product
-	searchQuery := "%" + strings.ToUpper(query) + "%"
-
-	if err := db.DB.Where("UPPER(name) LIKE ? OR UPPER(description) LIKE ?", searchQuery, searchQuery).
-		Preload("Category").
-		Offset((page - 1) * limit).
-		Limit(limit).
-		Find(&products).Error; err != nil {
-		utils.SendError(c, http.StatusInternalServerError, "Search failed")
+	// Cache miss - perform search; results are ranked by relevance, so cursors are positional
+	search, err := services.NewSearchService().Expand(services.ParseSearchQuery(query))
+	if err != nil {
+		utils.Warn("Failed to expand search synonyms: %v", err)
+	}
+	var ok bool
+	matches := services.NewSearchBackend(db.DB).Search(db.DB.Model(&models.Product{}).Scopes(services.PublishedProducts), search)
+	results.Pagination, ok = Base.FetchPage(c, matches, services.PageOrder{}, &results.Products, "Search failed", func(page *gorm.DB) *gorm.DB {
+		return page.Preload("Category")
+	})
+	if !ok {
 		return
 	}
 
 	// Cache the result
 	if cache != nil {
-		cache.Set(cacheKey, products)
+		cache.Set(cacheKey, results)
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"products": products,
-		"query":    query,
-		"cached":   false,
-	})
+	sendOptimizedPage(c, gin.H{"products": results.Products, "query": query, "cached": false}, results.Pagination)
 }
 
 // OptimizedOrderHistory provides paginated order history with caching
@@ -315,42 +301,46 @@ func OptimizedOrderHistory(c *gin.Context) {
 		return
 	}
 
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	pagination := Base.GetPaginationParams(c)
 
 	// Try cache first
 	cache := cache.GetCache()
-	cacheKey := fmt.Sprintf("orders:user:%d:page:%d:limit:%d", userID.(uint), page, limit)
+	cacheKey := fmt.Sprintf("orders:user:%d:%s", userID.(uint), pagination.Key())
+	var results orderPage
 	if cache != nil {
-		var orders []models.Order
-		if err := cache.Get(cacheKey, &orders); err == nil {
-			c.JSON(http.StatusOK, gin.H{
-				"orders": orders,
-				"cached": true,
-			})
+		if err := cache.Get(cacheKey, &results); err == nil {
+			sendOptimizedPage(c, gin.H{"orders": results.Orders, "cached": true}, results.Pagination)
 			return
 		}
 	}
 
 	// Cache miss - query database with optimized joins
-	var orders []models.Order
-	if err := db.DB.Where("user_id = ?", userID).
-		Preload("Items.Product").
-		Order("created_at DESC").
-		Offset((page - 1) * limit).
-		Limit(limit).
-		Find(&orders).Error; err != nil {
-		utils.SendError(c, http.StatusInternalServerError, "Failed to fetch orders")
+	var ok bool
+	results.Pagination, ok = Base.FetchPage(c, db.DB.Model(&models.Order{}).Where("user_id = ?", userID), services.NewestFirst("orders"), &results.Orders, "Failed to fetch orders", func(page *gorm.DB) *gorm.DB {
+		return page.Preload("Items.Product").Preload("Items.Components")
+	})
+	if !ok {
 		return
 	}
 
 	// Cache the result
 	if cache != nil {
-		cache.Set(cacheKey, orders)
+		cache.Set(cacheKey, results)
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"orders": orders,
-		"cached": false,
-	})
+	sendOptimizedPage(c, gin.H{"orders": results.Orders, "cached": false}, results.Pagination)
+}
+
+// orderPage is a page of order history, cached as a unit
+type orderPage struct {
+	Orders     []models.Order    `json:"orders"`
+	Pagination *utils.Pagination `json:"pagination"`
+}
+
+// sendOptimizedPage responds with a page in the optimized handlers' flat shape plus its
+// pagination details and Link headers
+func sendOptimizedPage(c *gin.Context, body gin.H, pagination *utils.Pagination) {
+	utils.SetLinkHeader(c, pagination)
+	body["pagination"] = pagination
+	c.JSON(http.StatusOK, body)
 }
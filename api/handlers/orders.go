@@ -1,12 +1,17 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
+	"slices"
+	"strconv"
 
 	"github.com/geoo115/Ecommerce/db"
 	"github.com/geoo115/Ecommerce/models"
+	"github.com/geoo115/Ecommerce/services"
 	"github.com/geoo115/Ecommerce/utils"
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 // PlaceOrder creates a new order for the authenticated user
@@ -19,8 +24,9 @@ func PlaceOrder(c *gin.Context) {
 
 	var orderRequest struct {
 		Items []struct {
-			ProductID uint `json:"product_id" binding:"required"`
-			Quantity  int  `json:"quantity" binding:"required,min=1"`
+			ProductID uint  `json:"product_id" binding:"required"`
+			VariantID *uint `json:"variant_id"`
+			Quantity  int   `json:"quantity" binding:"required,min=1"`
 		} `json:"items" binding:"required,min=1"`
 	}
 
@@ -35,56 +41,36 @@ func PlaceOrder(c *gin.Context) {
 		return
 	}
 
-	var totalAmount float64
-	var orderItems []models.OrderItem
-
+	var lines []services.OrderLine
 	for _, item := range orderRequest.Items {
 		// Validate quantity
 		if !utils.ValidateQuantity(item.Quantity) {
 			utils.SendValidationError(c, "Quantity must be between 1 and 1000")
 			return
 		}
+		lines = append(lines, services.OrderLine{ProductID: item.ProductID, VariantID: item.VariantID, Quantity: item.Quantity})
+	}
 
-		var product models.Product
-		if err := db.DB.First(&product, item.ProductID).Error; err != nil {
+	// Create the order, reserving stock for every line atomically
+	order := models.Order{UserID: userID.(uint)}
+	if err := services.NewOrderService().CreateOrder(&order, lines); err != nil {
+		switch {
+		case errors.Is(err, services.ErrProductNotFound):
 			utils.SendNotFound(c, "Product not found")
-			return
-		}
-
-		// Check inventory
-		var inventory models.Inventory
-		if err := db.DB.Where("product_id = ?", item.ProductID).First(&inventory).Error; err != nil || inventory.Stock < item.Quantity {
+		case errors.Is(err, services.ErrVariantNotFound):
+			utils.SendNotFound(c, "Variant not found")
+		case errors.Is(err, services.ErrVariantRequired):
+			utils.SendValidationError(c, "A variant must be selected for this product")
+		case errors.Is(err, services.ErrInsufficientStock):
 			utils.SendValidationError(c, "Insufficient stock for product")
-			return
+		default:
+			utils.SendInternalError(c, "Failed to create order")
 		}
-
-		// Deduct stock
-		inventory.Stock -= item.Quantity
-		db.DB.Save(&inventory)
-
-		totalAmount += product.Price * float64(item.Quantity)
-		orderItems = append(orderItems, models.OrderItem{
-			ProductID: item.ProductID,
-			Quantity:  item.Quantity,
-			Price:     product.Price,
-		})
-	}
-
-	// Create the order
-	order := models.Order{
-		UserID:      userID.(uint),
-		TotalAmount: totalAmount,
-		Status:      "Pending",
-		Items:       orderItems,
-	}
-
-	if err := db.DB.Create(&order).Error; err != nil {
-		utils.SendInternalError(c, "Failed to create order")
 		return
 	}
 
 	// Load complete order with relationships
-	if err := db.DB.Preload("Items.Product").Preload("Items.Product.Category").First(&order, order.ID).Error; err != nil {
+	if err := db.DB.Preload("Items.Product").Preload("Items.Product.Category").Preload("Items.Components").First(&order, order.ID).Error; err != nil {
 		utils.SendInternalError(c, "Failed to load order details")
 		return
 	}
@@ -92,7 +78,7 @@ func PlaceOrder(c *gin.Context) {
 	utils.SendSuccess(c, http.StatusCreated, "Order placed successfully", order)
 }
 
-// ListOrders retrieves all orders for the authenticated user
+// ListOrders retrieves the authenticated user's orders, newest first
 func ListOrders(c *gin.Context) {
 	userID, exists := c.Get("userID")
 	if !exists {
@@ -100,16 +86,33 @@ func ListOrders(c *gin.Context) {
 		return
 	}
 
+	query := db.DB.Where("user_id = ?", userID)
+	if number := c.Query("order_number"); number != "" {
+		query = query.Where("order_number LIKE ?", number+"%")
+	}
+
 	var orders []models.Order
-	if err := db.DB.Where("user_id = ?", userID).Preload("Items.Product").Find(&orders).Error; err != nil {
-		utils.SendInternalError(c, "Failed to fetch orders")
+	pagination, ok := Base.FetchPage(c, query.Model(&models.Order{}), services.NewestFirst("orders"), &orders, "Failed to fetch orders", func(page *gorm.DB) *gorm.DB {
+		return page.Preload("Items.Product").Preload("Items.Components")
+	})
+	if !ok {
 		return
 	}
 
-	utils.SendSuccess(c, http.StatusOK, "Orders retrieved successfully", orders)
+	utils.SendPage(c, "Orders retrieved successfully", orders, pagination)
 }
 
-// GetOrder retrieves details of a specific order for the authenticated user
+// whereOrderRef matches an order by its numeric ID or its order number. Order numbers always
+// contain a non-digit, as the configuration rejects formats that would produce only digits.
+func whereOrderRef(query *gorm.DB, ref string) *gorm.DB {
+	if id, err := strconv.ParseUint(ref, 10, 64); err == nil {
+		return query.Where("id = ?", id)
+	}
+	return query.Where("order_number = ?", ref)
+}
+
+// GetOrder retrieves details of a specific order for the authenticated user.
+// The order may be referenced by ID or order number.
 func GetOrder(c *gin.Context) {
 	userID, exists := c.Get("userID")
 	if !exists {
@@ -125,10 +128,13 @@ func GetOrder(c *gin.Context) {
 
 	var order models.Order
 
-	if err := db.DB.Where("id = ? AND user_id = ?", orderID, userID).
+	if err := whereOrderRef(db.DB, orderID).Where("user_id = ?", userID).
 		Preload("Items.Product").
 		Preload("Items.Product.Category").
 		Preload("Items.Product.Inventory").
+		Preload("Items.Components").
+		Preload("Fulfillments.Items").
+		Preload("Cancellations").
 		Preload("User").First(&order).Error; err != nil {
 		utils.SendNotFound(c, "Order not found")
 		return
@@ -137,7 +143,8 @@ func GetOrder(c *gin.Context) {
 	utils.SendSuccess(c, http.StatusOK, "Order retrieved successfully", order)
 }
 
-// CancelOrder cancels an existing order if it is still pending
+// CancelOrder cancels an existing order if it is still pending.
+// The order may be referenced by ID or order number.
 func CancelOrder(c *gin.Context) {
 	userID, exists := c.Get("userID")
 	if !exists {
@@ -153,7 +160,7 @@ func CancelOrder(c *gin.Context) {
 
 	var order models.Order
 
-	if err := db.DB.Where("id = ? AND user_id = ?", orderID, userID).Preload("Items").First(&order).Error; err != nil {
+	if err := whereOrderRef(db.DB, orderID).Where("user_id = ?", userID).Preload("Items").First(&order).Error; err != nil {
 		utils.SendNotFound(c, "Order not found")
 		return
 	}
@@ -171,13 +178,284 @@ func CancelOrder(c *gin.Context) {
 	}
 
 	// Restock inventory
+	orders := services.NewOrderService()
 	for _, item := range order.Items {
-		var inventory models.Inventory
-		if err := db.DB.Where("product_id = ?", item.ProductID).First(&inventory).Error; err == nil {
-			inventory.Stock += item.Quantity
-			db.DB.Save(&inventory)
+		if err := orders.RestockItem(&item, item.Quantity); err != nil {
+			utils.Warn("Failed to restock product %d for order %d: %v", item.ProductID, order.ID, err)
 		}
 	}
 
+	// Restore any points redeemed on the order
+	if err := services.NewLoyaltyService().ReverseOrderPoints(order.ID); err != nil {
+		utils.Warn("Failed to reverse loyalty points for order %d: %v", order.ID, err)
+	}
+
 	utils.SendSuccess(c, http.StatusOK, "Order cancelled successfully", nil)
 }
+
+// refundableOrderStatuses are the order statuses a full refund may be issued from
+var refundableOrderStatuses = []string{"Paid", "Partially Shipped", "Shipped", "Delivered"}
+
+// errOrderNotRefundable is returned when another request changed the order before the refund claimed it
+var errOrderNotRefundable = errors.New("order is no longer refundable")
+
+// RefundOrder refunds a paid order in full and reverses its loyalty points (admin only)
+func RefundOrder(c *gin.Context) {
+	orderID, err := Base.ValidateIDParam(c, "id")
+	if err != nil {
+		return
+	}
+
+	var order models.Order
+	if err := db.DB.First(&order, orderID).Error; err != nil {
+		Base.HandleDBError(c, err, "Order not found", "Failed to fetch order")
+		return
+	}
+
+	if !slices.Contains(refundableOrderStatuses, order.Status) {
+		utils.SendValidationError(c, "Only paid orders can be refunded")
+		return
+	}
+
+	var refund *models.Payment
+	err = db.DB.Transaction(func(tx *gorm.DB) error {
+		// Claim the order first so a concurrent refund cannot refund it again
+		result := tx.Model(&models.Order{}).
+			Where("id = ? AND status IN ?", order.ID, refundableOrderStatuses).
+			Update("status", "Refunded")
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errOrderNotRefundable
+		}
+
+		var err error
+		refund, err = services.NewPaymentServiceWithDB(tx).RefundPartial(&order, order.TotalAmount)
+		if err != nil {
+			return err
+		}
+		order.Status = "Refunded"
+		return nil
+	})
+	if errors.Is(err, errOrderNotRefundable) {
+		utils.SendConflict(c, "Order has already been refunded or changed")
+		return
+	}
+	if errors.Is(err, services.ErrNoPayment) {
+		utils.SendNotFound(c, "Payment not found")
+		return
+	}
+	if err != nil {
+		utils.SendInternalError(c, "Failed to refund order")
+		return
+	}
+
+	if err := services.NewLoyaltyService().ReverseOrderPoints(order.ID); err != nil {
+		utils.Warn("Failed to reverse loyalty points for order %d: %v", order.ID, err)
+	}
+
+	utils.SendSuccess(c, http.StatusOK, "Order refunded successfully", gin.H{"order": order, "refund": refund})
+}
+
+// LookupOrder finds any order by ID or order number for support staff (admin only)
+func LookupOrder(c *gin.Context) {
+	var order models.Order
+	if err := whereOrderRef(db.DB, c.Param("ref")).
+		Preload("Items.Product").
+		Preload("Items.Components").
+		Preload("Fulfillments.Items").
+		Preload("User").First(&order).Error; err != nil {
+		Base.HandleDBError(c, err, "Order not found", "Failed to fetch order")
+		return
+	}
+
+	utils.SendSuccess(c, http.StatusOK, "Order retrieved successfully", order)
+}
+
+// AmendOrder changes the address or item quantities of an order that has not shipped yet.
+// A quantity of 0 removes the item; new products or variants are added at the current price.
+func AmendOrder(c *gin.Context) {
+	uid, err := Base.GetUserID(c)
+	if err != nil {
+		return
+	}
+
+	var input struct {
+		AddressID *uint `json:"address_id"`
+		Items     []struct {
+			ProductID uint  `json:"product_id" binding:"required"`
+			VariantID *uint `json:"variant_id"`
+			Quantity  int   `json:"quantity" binding:"gte=0,lte=10000"`
+		} `json:"items" binding:"dive"`
+	}
+	if err := Base.BindJSON(c, &input); err != nil {
+		return
+	}
+
+	var order models.Order
+	if err := whereOrderRef(db.DB, c.Param("id")).Where("user_id = ?", uid).First(&order).Error; err != nil {
+		Base.HandleDBError(c, err, "Order not found", "Failed to fetch order")
+		return
+	}
+
+	amendment := services.OrderAmendment{AddressID: input.AddressID}
+	for _, item := range input.Items {
+		amendment.Items = append(amendment.Items, services.OrderLine{ProductID: item.ProductID, VariantID: item.VariantID, Quantity: item.Quantity})
+	}
+
+	revision, err := services.NewOrderService().AmendOrder(&order, amendment, uid)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrOrderNotAmendable):
+			utils.SendConflict(c, "Order can no longer be amended")
+		case errors.Is(err, services.ErrNoChanges):
+			utils.SendValidationError(c, "Amendment contains no changes")
+		case errors.Is(err, services.ErrEmptyOrder):
+			utils.SendValidationError(c, "Order must keep at least one item; cancel it instead")
+		case errors.Is(err, services.ErrAddressNotFound):
+			utils.SendNotFound(c, "Address not found")
+		case errors.Is(err, services.ErrProductNotFound):
+			utils.SendNotFound(c, "Product not found")
+		case errors.Is(err, services.ErrVariantNotFound):
+			utils.SendNotFound(c, "Variant not found")
+		case errors.Is(err, services.ErrVariantRequired):
+			utils.SendValidationError(c, "A variant must be selected for this product")
+		case errors.Is(err, services.ErrInsufficientStock):
+			utils.SendValidationError(c, "Insufficient stock for product")
+		case errors.Is(err, services.ErrPaymentDeclined):
+			utils.SendError(c, http.StatusPaymentRequired, "Payment declined")
+		case errors.Is(err, services.ErrNoPayment):
+			utils.SendConflict(c, "Order has no payment to adjust")
+		default:
+			utils.SendInternalError(c, "Failed to amend order")
+		}
+		return
+	}
+
+	if err := db.DB.Preload("Items.Product").Preload("Items.Components").Preload("Address").First(&order, order.ID).Error; err != nil {
+		utils.SendInternalError(c, "Failed to load order details")
+		return
+	}
+
+	utils.SendSuccess(c, http.StatusOK, "Order amended successfully", gin.H{"order": order, "revision": revision})
+}
+
+// ListOrderRevisions returns the amendment history of one of the authenticated user's orders
+func ListOrderRevisions(c *gin.Context) {
+	uid, err := Base.GetUserID(c)
+	if err != nil {
+		return
+	}
+
+	var order models.Order
+	if err := whereOrderRef(db.DB, c.Param("id")).Where("user_id = ?", uid).First(&order).Error; err != nil {
+		Base.HandleDBError(c, err, "Order not found", "Failed to fetch order")
+		return
+	}
+
+	var revisions []models.OrderRevision
+	if err := db.DB.Where("order_id = ?", order.ID).Order("revision").Find(&revisions).Error; err != nil {
+		utils.SendInternalError(c, "Failed to fetch order revisions")
+		return
+	}
+
+	utils.SendSuccess(c, http.StatusOK, "Order revisions retrieved successfully", revisions)
+}
+
+// CancelOrderItems cancels quantities of individual lines on the authenticated user's pending order
+func CancelOrderItems(c *gin.Context) {
+	uid, err := Base.GetUserID(c)
+	if err != nil {
+		return
+	}
+
+	var order models.Order
+	if err := whereOrderRef(db.DB, c.Param("id")).Where("user_id = ?", uid).First(&order).Error; err != nil {
+		Base.HandleDBError(c, err, "Order not found", "Failed to fetch order")
+		return
+	}
+	if order.Status != "Pending" {
+		utils.SendConflict(c, "Only pending orders can be changed; contact support")
+		return
+	}
+
+	cancelOrderItems(c, &order, uid)
+}
+
+// AdminCancelOrderItems cancels quantities of individual lines on any order not yet shipped (admin only)
+func AdminCancelOrderItems(c *gin.Context) {
+	orderID, err := Base.ValidateIDParam(c, "id")
+	if err != nil {
+		return
+	}
+	adminID, _ := c.Get("userID")
+	uid, _ := adminID.(uint)
+
+	var order models.Order
+	if err := db.DB.First(&order, orderID).Error; err != nil {
+		Base.HandleDBError(c, err, "Order not found", "Failed to fetch order")
+		return
+	}
+
+	cancelOrderItems(c, &order, uid)
+}
+
+// cancelOrderItems binds the lines to cancel and applies them to the order
+func cancelOrderItems(c *gin.Context, order *models.Order, cancelledBy uint) {
+	var input struct {
+		Items  []services.CancelLine `json:"items" binding:"required,min=1"`
+		Reason string                `json:"reason" binding:"required"`
+	}
+	if err := Base.BindJSON(c, &input); err != nil {
+		return
+	}
+
+	cancellations, err := services.NewOrderService().CancelItems(order, input.Items, input.Reason, cancelledBy)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrOrderNotAmendable):
+			utils.SendConflict(c, "Order has already shipped")
+		case errors.Is(err, services.ErrInvalidCancel):
+			utils.SendValidationError(c, "Cancellation quantity exceeds the item's remaining quantity")
+		case errors.Is(err, services.ErrNoPayment):
+			utils.SendConflict(c, "Order has no payment to refund")
+		default:
+			utils.SendInternalError(c, "Failed to cancel order items")
+		}
+		return
+	}
+
+	if err := db.DB.Preload("Items.Product").Preload("Items.Components").First(order, order.ID).Error; err != nil {
+		utils.SendInternalError(c, "Failed to load order details")
+		return
+	}
+
+	utils.SendSuccess(c, http.StatusOK, "Order items cancelled successfully", gin.H{"order": order, "cancellations": cancellations})
+}
+
+// ReorderOrder adds the items of one of the authenticated user's past orders to their cart
+func ReorderOrder(c *gin.Context) {
+	uid, err := Base.GetUserID(c)
+	if err != nil {
+		return
+	}
+
+	var order models.Order
+	if err := whereOrderRef(db.DB, c.Param("id")).Where("user_id = ?", uid).Preload("Items").First(&order).Error; err != nil {
+		Base.HandleDBError(c, err, "Order not found", "Failed to fetch order")
+		return
+	}
+
+	result, err := services.NewCartService().Reorder(uid, order.Items)
+	if err != nil {
+		utils.SendInternalError(c, "Failed to add items to cart")
+		return
+	}
+
+	message := "Items added to cart"
+	if len(result.Added) == 0 && len(result.Adjusted) == 0 {
+		message = "No items from this order are currently available"
+	}
+	utils.SendSuccess(c, http.StatusOK, message, result)
+}
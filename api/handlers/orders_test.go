@@ -3,6 +3,7 @@ package handlers
 import (
 	"bytes"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"os"
@@ -11,6 +12,7 @@ import (
 
 	"github.com/geoo115/Ecommerce/db"
 	"github.com/geoo115/Ecommerce/models"
+	"github.com/geoo115/Ecommerce/services"
 	"github.com/geoo115/Ecommerce/utils"
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
@@ -312,6 +314,66 @@ func TestGetOrder_Success(t *testing.T) {
 	assert.Equal(t, "Order retrieved successfully", response["message"])
 }
 
+func TestGetOrder_ByOrderNumber(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	SetupTestDB(t)
+
+	user := CreateTestUser(t, db.DB, "numbered")
+	other := CreateTestUser(t, db.DB, "stranger")
+	order := models.Order{UserID: user.ID, TotalAmount: 15, Status: "Pending"}
+	db.DB.Create(&order)
+	db.DB.Create(&models.Payment{OrderID: order.ID, Amount: 15, Status: "Success"})
+
+	routerFor := func(userID uint) *gin.Engine {
+		router := gin.New()
+		router.Use(func(c *gin.Context) {
+			c.Set("userID", userID)
+			c.Next()
+		})
+		router.GET("/orders", ListOrders)
+		router.GET("/orders/:id", GetOrder)
+		router.GET("/payments/:order_id", GetPaymentStatus)
+		router.GET("/admin/orders/lookup/:ref", LookupOrder)
+		return router
+	}
+	router := routerFor(user.ID)
+
+	tests := []struct {
+		name       string
+		router     *gin.Engine
+		path       string
+		wantStatus int
+	}{
+		{"own order by number", router, "/orders/" + order.OrderNumber, http.StatusOK},
+		{"other user's order by number", routerFor(other.ID), "/orders/" + order.OrderNumber, http.StatusNotFound},
+		{"unknown number", router, "/orders/ORD-0000-000000", http.StatusNotFound},
+		{"payment status by number", router, "/payments/" + order.OrderNumber, http.StatusOK},
+		{"admin lookup by number", router, "/admin/orders/lookup/" + order.OrderNumber, http.StatusOK},
+		{"admin lookup unknown", router, "/admin/orders/lookup/NOPE", http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", tt.path, nil)
+			tt.router.ServeHTTP(w, req)
+			assert.Equal(t, tt.wantStatus, w.Code)
+		})
+	}
+
+	// Orders can be searched by number prefix
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/orders?order_number="+order.OrderNumber[:len(order.OrderNumber)-2], nil)
+	router.ServeHTTP(w, req)
+	var response struct {
+		Data []models.Order `json:"data"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &response)
+	if assert.Len(t, response.Data, 1) {
+		assert.Equal(t, order.OrderNumber, response.Data[0].OrderNumber)
+	}
+}
+
 func TestGetOrder_NotFound(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 	SetupTestDB(t)
@@ -410,6 +472,99 @@ func TestCancelOrder_AlreadyShipped(t *testing.T) {
 	assert.Equal(t, http.StatusBadRequest, w.Code)
 }
 
+func TestRefundOrder(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	SetupTestDB(t)
+	utils.AppLogger = utils.NewLogger(utils.INFO)
+
+	user := models.User{Username: "testuser", Phone: "+15550000001", Password: "pw"}
+	db.DB.Create(&user)
+
+	cat := models.Category{Name: "Test Category"}
+	db.DB.Create(&cat)
+
+	prod := models.Product{Name: "Test Product", Price: 40, CategoryID: cat.ID}
+	db.DB.Create(&prod)
+
+	paid := models.Order{
+		UserID:      user.ID,
+		TotalAmount: 40,
+		Status:      "Paid",
+		Items:       []models.OrderItem{{ProductID: prod.ID, Quantity: 1, Price: 40}},
+	}
+	db.DB.Create(&paid)
+	db.DB.Create(&models.Payment{OrderID: paid.ID, PaymentMode: "credit_card", Amount: 40, Status: "Success"})
+	_, err := services.NewLoyaltyService().AwardOrderPoints(paid.ID)
+	assert.NoError(t, err)
+
+	pending := models.Order{UserID: user.ID, TotalAmount: 10, Status: "Pending"}
+	db.DB.Create(&pending)
+
+	router := gin.New()
+	router.POST("/admin/orders/:id/refund", RefundOrder)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/admin/orders/"+strconv.Itoa(int(paid.ID))+"/refund", nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var refunded models.Order
+	db.DB.First(&refunded, paid.ID)
+	assert.Equal(t, "Refunded", refunded.Status)
+
+	var refund models.Payment
+	assert.NoError(t, db.DB.Where("order_id = ? AND status = ?", paid.ID, "Refunded").First(&refund).Error)
+	assert.Equal(t, "credit_card", refund.PaymentMode)
+
+	balance, _ := services.NewLoyaltyService().GetBalance(user.ID)
+	assert.Equal(t, 0, balance)
+
+	// Refunding twice or refunding an unpaid order is rejected
+	for _, id := range []uint{paid.ID, pending.ID} {
+		w = httptest.NewRecorder()
+		req, _ = http.NewRequest("POST", "/admin/orders/"+strconv.Itoa(int(id))+"/refund", nil)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", "/admin/orders/9999/refund", nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	// A paid order without a recorded payment is not refunded
+	unrecorded := models.Order{UserID: user.ID, TotalAmount: 15, Status: "Paid"}
+	db.DB.Create(&unrecorded)
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", "/admin/orders/"+strconv.Itoa(int(unrecorded.ID))+"/refund", nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	db.DB.First(&unrecorded, unrecorded.ID)
+	assert.Equal(t, "Paid", unrecorded.Status)
+	var refunds int64
+	db.DB.Model(&models.Payment{}).Where("order_id = ?", unrecorded.ID).Count(&refunds)
+	assert.Zero(t, refunds)
+
+	// A refund that loses the race to a concurrent one is rejected without refunding again
+	raced := models.Order{UserID: user.ID, TotalAmount: 20, Status: "Paid"}
+	db.DB.Create(&raced)
+	db.DB.Create(&models.Payment{OrderID: raced.ID, PaymentMode: "credit_card", Amount: 20, Status: "Success"})
+	fired := false
+	db.DB.Callback().Query().After("gorm:query").Register("test:concurrent_refund", func(tx *gorm.DB) {
+		if !fired && tx.Statement.Table == "orders" {
+			fired = true
+			tx.Session(&gorm.Session{NewDB: true}).Model(&models.Order{}).Where("id = ?", raced.ID).Update("status", "Refunded")
+		}
+	})
+	defer db.DB.Callback().Query().Remove("test:concurrent_refund")
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", "/admin/orders/"+strconv.Itoa(int(raced.ID))+"/refund", nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusConflict, w.Code)
+	db.DB.Model(&models.Payment{}).Where("order_id = ? AND status = ?", raced.ID, "Refunded").Count(&refunds)
+	assert.Zero(t, refunds)
+}
+
 func BenchmarkPlaceOrder(b *testing.B) {
 	gin.SetMode(gin.TestMode)
 	testDB, _ := gorm.Open(sqlite.Open("file::memory:?cache=shared"), &gorm.Config{})
@@ -496,3 +651,175 @@ func BenchmarkListOrders(b *testing.B) {
 		router.ServeHTTP(w, req)
 	}
 }
+
+func TestAmendOrder(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	SetupTestDB(t)
+	utils.AppLogger = utils.NewLogger(utils.INFO)
+
+	user := CreateTestUser(t, db.DB, "amend")
+	product := models.Product{Name: "Candle", Price: 6}
+	db.DB.Create(&product)
+	db.DB.Create(&models.Inventory{ProductID: product.ID, Stock: 10})
+	order := models.Order{UserID: user.ID, Status: "Pending", TotalAmount: 6, Items: []models.OrderItem{{ProductID: product.ID, Quantity: 1, Price: 6}}}
+	db.DB.Create(&order)
+	shipped := models.Order{UserID: user.ID, Status: "Shipped", TotalAmount: 6, Items: []models.OrderItem{{ProductID: product.ID, Quantity: 1, Price: 6}}}
+	db.DB.Create(&shipped)
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set("userID", user.ID)
+		c.Next()
+	})
+	router.PUT("/orders/:id/amend", AmendOrder)
+	router.GET("/orders/:id/revisions", ListOrderRevisions)
+
+	tests := []struct {
+		name       string
+		ref        string
+		body       string
+		wantStatus int
+	}{
+		{"bump quantity by number", order.OrderNumber, fmt.Sprintf(`{"items":[{"product_id":%d,"quantity":3}]}`, product.ID), http.StatusOK},
+		{"no changes", fmt.Sprint(order.ID), fmt.Sprintf(`{"items":[{"product_id":%d,"quantity":3}]}`, product.ID), http.StatusBadRequest},
+		{"remove everything", fmt.Sprint(order.ID), fmt.Sprintf(`{"items":[{"product_id":%d,"quantity":0}]}`, product.ID), http.StatusBadRequest},
+		{"foreign address", fmt.Sprint(order.ID), `{"address_id": 9999}`, http.StatusNotFound},
+		{"already shipped", fmt.Sprint(shipped.ID), fmt.Sprintf(`{"items":[{"product_id":%d,"quantity":2}]}`, product.ID), http.StatusConflict},
+		{"unknown order", "9999", `{}`, http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("PUT", "/orders/"+tt.ref+"/amend", bytes.NewBufferString(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			router.ServeHTTP(w, req)
+			assert.Equal(t, tt.wantStatus, w.Code)
+		})
+	}
+
+	var stored models.Order
+	db.DB.First(&stored, order.ID)
+	assert.Equal(t, 18.0, stored.TotalAmount)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", fmt.Sprintf("/orders/%d/revisions", order.ID), nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var response struct {
+		Data []models.OrderRevision `json:"data"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &response)
+	if assert.Len(t, response.Data, 1) {
+		assert.Equal(t, 6.0, response.Data[0].PreviousTotal)
+		assert.Equal(t, 18.0, response.Data[0].NewTotal)
+	}
+}
+
+func TestCancelOrderItems(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	SetupTestDB(t)
+	utils.AppLogger = utils.NewLogger(utils.INFO)
+
+	user := CreateTestUser(t, db.DB, "lines")
+	product := models.Product{Name: "Socks", Price: 3}
+	db.DB.Create(&product)
+	db.DB.Create(&models.Inventory{ProductID: product.ID, Stock: 5})
+	pending := models.Order{UserID: user.ID, Status: "Pending", TotalAmount: 9, Items: []models.OrderItem{{ProductID: product.ID, Quantity: 3, Price: 3}}}
+	db.DB.Create(&pending)
+	paid := models.Order{UserID: user.ID, Status: "Paid", TotalAmount: 6, Items: []models.OrderItem{{ProductID: product.ID, Quantity: 2, Price: 3}}}
+	db.DB.Create(&paid)
+	db.DB.Create(&models.Payment{OrderID: paid.ID, PaymentMode: "card", Amount: 6, Status: "Success"})
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set("userID", user.ID)
+		c.Next()
+	})
+	router.PUT("/orders/:id/items/cancel", CancelOrderItems)
+	router.POST("/admin/orders/:id/items/cancel", AdminCancelOrderItems)
+
+	body := func(itemID uint, qty int, reason string) string {
+		return fmt.Sprintf(`{"items":[{"order_item_id":%d,"quantity":%d}],"reason":%q}`, itemID, qty, reason)
+	}
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		body       string
+		wantStatus int
+	}{
+		{"customer cancels one line", "PUT", fmt.Sprintf("/orders/%d/items/cancel", pending.ID), body(pending.Items[0].ID, 1, "Too many"), http.StatusOK},
+		{"missing reason", "PUT", fmt.Sprintf("/orders/%d/items/cancel", pending.ID), body(pending.Items[0].ID, 1, ""), http.StatusBadRequest},
+		{"too many units", "PUT", fmt.Sprintf("/orders/%d/items/cancel", pending.ID), body(pending.Items[0].ID, 5, "Oops"), http.StatusBadRequest},
+		{"customer cannot change paid order", "PUT", fmt.Sprintf("/orders/%d/items/cancel", paid.ID), body(paid.Items[0].ID, 1, "Oops"), http.StatusConflict},
+		{"admin cancels paid order line", "POST", fmt.Sprintf("/admin/orders/%d/items/cancel", paid.ID), body(paid.Items[0].ID, 2, "Out of stock"), http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest(tt.method, tt.path, bytes.NewBufferString(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			router.ServeHTTP(w, req)
+			assert.Equal(t, tt.wantStatus, w.Code)
+		})
+	}
+
+	var stored models.Order
+	db.DB.First(&stored, pending.ID)
+	assert.Equal(t, 6.0, stored.TotalAmount)
+	assert.Equal(t, "Pending", stored.Status)
+
+	var cancelled models.Order
+	db.DB.First(&cancelled, paid.ID)
+	assert.Equal(t, "Cancelled", cancelled.Status)
+
+	var inv models.Inventory
+	db.DB.Where("product_id = ?", product.ID).First(&inv)
+	assert.Equal(t, 8, inv.Stock)
+}
+
+func TestReorderOrder(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	SetupTestDB(t)
+	utils.AppLogger = utils.NewLogger(utils.INFO)
+
+	user := CreateTestUser(t, db.DB, "reorder")
+	other := CreateTestUser(t, db.DB, "notmine")
+	product := models.Product{Name: "Tea", Price: 4}
+	db.DB.Create(&product)
+	db.DB.Create(&models.Inventory{ProductID: product.ID, Stock: 10})
+	order := models.Order{UserID: user.ID, Status: "Delivered", Items: []models.OrderItem{{ProductID: product.ID, Quantity: 2, Price: 4}}}
+	db.DB.Create(&order)
+	foreign := models.Order{UserID: other.ID, Status: "Delivered", Items: []models.OrderItem{{ProductID: product.ID, Quantity: 1, Price: 4}}}
+	db.DB.Create(&foreign)
+
+	router := gin.New()
+	router.POST("/orders/:id/reorder", func(c *gin.Context) {
+		c.Set("userID", user.ID)
+		ReorderOrder(c)
+	})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", fmt.Sprintf("/orders/%d/reorder", order.ID), nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response struct {
+		Data services.ReorderResult `json:"data"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &response)
+	if assert.Len(t, response.Data.Added, 1) {
+		assert.Equal(t, 2, response.Data.Added[0].Quantity)
+	}
+
+	var cart models.Cart
+	assert.NoError(t, db.DB.Where("user_id = ? AND product_id = ?", user.ID, product.ID).First(&cart).Error)
+	assert.Equal(t, 2, cart.Quantity)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", fmt.Sprintf("/orders/%d/reorder", foreign.ID), nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
@@ -0,0 +1,134 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/geoo115/Ecommerce/db"
+	"github.com/geoo115/Ecommerce/models"
+	"github.com/geoo115/Ecommerce/services"
+	"github.com/geoo115/Ecommerce/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// GetPriceTimeline returns a product's current prices, price history and schedules (admin only)
+func GetPriceTimeline(c *gin.Context) {
+	productID, err := Base.ValidateIDParam(c, "id")
+	if err != nil {
+		return
+	}
+
+	timeline, err := services.NewPriceService().Timeline(productID)
+	if err != nil {
+		sendPriceError(c, err, "Failed to fetch price timeline")
+		return
+	}
+	utils.SendSuccess(c, http.StatusOK, "Price timeline retrieved successfully", timeline)
+}
+
+// CreatePriceSchedule schedules a price change or a sale for a product (admin only).
+// starts_at defaults to now, in which case the change is applied straight away.
+func CreatePriceSchedule(c *gin.Context) {
+	productID, err := Base.ValidateIDParam(c, "id")
+	if err != nil {
+		return
+	}
+
+	var input struct {
+		Kind     string     `json:"kind" binding:"required,oneof=price_change sale"`
+		Price    float64    `json:"price"`
+		StartsAt *time.Time `json:"starts_at"`
+		EndsAt   *time.Time `json:"ends_at"`
+		Reason   string     `json:"reason" binding:"max=255"`
+	}
+	if err := Base.BindJSON(c, &input); err != nil {
+		return
+	}
+
+	var product models.Product
+	if err := db.DB.First(&product, productID).Error; err != nil {
+		Base.HandleDBError(c, err, "Product not found", "Failed to fetch product")
+		return
+	}
+
+	now := time.Now()
+	request := services.PriceScheduleInput{
+		Kind:      input.Kind,
+		Price:     input.Price,
+		StartsAt:  now,
+		EndsAt:    input.EndsAt,
+		Reason:    utils.SanitizeString(input.Reason),
+		CreatedBy: c.GetUint("userID"),
+	}
+	if input.StartsAt != nil {
+		request.StartsAt = *input.StartsAt
+	}
+
+	pricing := services.NewPriceService()
+	schedule, err := pricing.Schedule(&product, request)
+	if err != nil {
+		sendPriceError(c, err, "Failed to schedule price")
+		return
+	}
+	if !schedule.StartsAt.After(now) {
+		changed, err := pricing.ApplyDueSchedules(now)
+		for _, id := range changed {
+			invalidateProductCache(id)
+		}
+		if err != nil {
+			utils.SendInternalError(c, "Failed to apply price")
+			return
+		}
+		db.DB.First(schedule, schedule.ID)
+	}
+
+	utils.SendSuccess(c, http.StatusCreated, "Price scheduled successfully", schedule)
+}
+
+// CancelPriceSchedule withdraws a scheduled price change or sale; a running sale ends now (admin only)
+func CancelPriceSchedule(c *gin.Context) {
+	productID, err := Base.ValidateIDParam(c, "id")
+	if err != nil {
+		return
+	}
+	scheduleID, err := Base.ValidateIDParam(c, "schedule_id")
+	if err != nil {
+		return
+	}
+
+	var schedule models.PriceSchedule
+	if err := db.DB.Where("product_id = ?", productID).First(&schedule, scheduleID).Error; err != nil {
+		Base.HandleDBError(c, err, "Price schedule not found", "Failed to fetch price schedule")
+		return
+	}
+
+	wasActive := schedule.Status == models.PriceScheduleActive
+	if err := services.NewPriceService().Cancel(&schedule, c.GetUint("userID")); err != nil {
+		sendPriceError(c, err, "Failed to cancel price schedule")
+		return
+	}
+	if wasActive {
+		invalidateProductCache(productID)
+	}
+
+	utils.SendSuccess(c, http.StatusOK, "Price schedule cancelled successfully", schedule)
+}
+
+// sendPriceError maps pricing errors to responses
+func sendPriceError(c *gin.Context, err error, internalMsg string) {
+	switch {
+	case errors.Is(err, services.ErrProductNotFound):
+		utils.SendNotFound(c, "Product not found")
+	case errors.Is(err, services.ErrInvalidPrice):
+		utils.SendValidationError(c, "Price must be greater than 0 and less than 999999.99")
+	case errors.Is(err, services.ErrInvalidPriceSchedule):
+		utils.SendValidationError(c, "Price changes take a start time; sales take a start time and an optional later end time")
+	case errors.Is(err, services.ErrSaleOverlap):
+		utils.SendConflict(c, "Sale overlaps another sale of the product")
+	case errors.Is(err, services.ErrPriceScheduleClosed):
+		utils.SendConflict(c, "Price schedule has already completed or been cancelled")
+	default:
+		utils.SendInternalError(c, internalMsg)
+	}
+}
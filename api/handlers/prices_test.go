@@ -0,0 +1,132 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/geoo115/Ecommerce/db"
+	"github.com/geoo115/Ecommerce/models"
+	"github.com/geoo115/Ecommerce/services"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestPriceSchedulesAndTimeline(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	SetupTestDB(t)
+
+	product := models.Product{Name: "Desk Lamp", Price: 25}
+	db.DB.Create(&product)
+	db.DB.Create(&models.Inventory{ProductID: product.ID, Stock: 5})
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) { c.Set("userID", uint(42)) })
+	router.PUT("/product/:id", EditProduct)
+	router.GET("/product/:id/prices", GetPriceTimeline)
+	router.POST("/product/:id/price-schedules", CreatePriceSchedule)
+	router.DELETE("/product/:id/price-schedules/:schedule_id", CancelPriceSchedule)
+
+	send := func(method, path, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, path, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+		return w
+	}
+	base := fmt.Sprintf("/product/%d", product.ID)
+	timeline := func() services.PriceTimeline {
+		t.Helper()
+		var response struct {
+			Data services.PriceTimeline `json:"data"`
+		}
+		w := send("GET", base+"/prices", "")
+		assert.Equal(t, http.StatusOK, w.Code)
+		json.Unmarshal(w.Body.Bytes(), &response)
+		return response.Data
+	}
+
+	w := send("PUT", base, `{"price": 28, "price_reason": "New supplier"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// A sale without a start time begins straight away
+	w = send("POST", base+"/price-schedules", `{"kind": "sale", "price": 20, "reason": "Clearance"}`)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	var created struct {
+		Data models.PriceSchedule `json:"data"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &created)
+	assert.Equal(t, models.PriceScheduleActive, created.Data.Status)
+
+	current := timeline()
+	assert.Equal(t, 20.0, current.Price)
+	if assert.NotNil(t, current.CompareAtPrice) {
+		assert.Equal(t, 28.0, *current.CompareAtPrice)
+	}
+	if assert.Len(t, current.History, 2) {
+		assert.Equal(t, models.PriceSourceManual, current.History[1].Source)
+		assert.Equal(t, "New supplier", current.History[1].Reason)
+		assert.Equal(t, uint(42), current.History[1].ChangedBy)
+	}
+
+	future := time.Now().Add(24 * time.Hour).UTC().Format(time.RFC3339)
+	w = send("POST", base+"/price-schedules", fmt.Sprintf(`{"kind": "sale", "price": 15, "starts_at": %q}`, future))
+	assert.Equal(t, http.StatusConflict, w.Code)
+	w = send("POST", base+"/price-schedules", fmt.Sprintf(`{"kind": "price_change", "price": 30, "starts_at": %q, "ends_at": %q}`, future, future))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// Cancelling the running sale restores the regular price
+	w = send("DELETE", fmt.Sprintf("%s/price-schedules/%d", base, created.Data.ID), "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	current = timeline()
+	assert.Equal(t, 28.0, current.Price)
+	assert.Nil(t, current.CompareAtPrice)
+	assert.Equal(t, http.StatusConflict, send("DELETE", fmt.Sprintf("%s/price-schedules/%d", base, created.Data.ID), "").Code)
+
+	assert.Equal(t, http.StatusNotFound, send("GET", "/product/9999/prices", "").Code)
+}
+
+func TestEditProduct_KeepsScheduledChanges(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	SetupTestDB(t)
+
+	publishAt := time.Now().Add(-time.Minute)
+	product := models.Product{Name: "Desk Lamp", Price: 25, Status: models.ProductStatusDraft, PublishAt: &publishAt}
+	db.DB.Create(&product)
+
+	router := gin.New()
+	router.PUT("/product/:id", EditProduct)
+
+	// The schedulers publish the product and start a sale after the edit has loaded it
+	fired := false
+	db.DB.Callback().Query().After("gorm:query").Register("test:scheduled_change", func(tx *gorm.DB) {
+		if !fired && tx.Statement.Table == "products" {
+			fired = true
+			tx.Session(&gorm.Session{NewDB: true}).Model(&models.Product{}).Where("id = ?", product.ID).Updates(map[string]interface{}{
+				"status": models.ProductStatusPublished, "publish_at": nil, "price": 20, "compare_at_price": 25,
+			})
+		}
+	})
+	defer db.DB.Callback().Query().Remove("test:scheduled_change")
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("PUT", fmt.Sprintf("/product/%d", product.ID), bytes.NewBufferString(`{"name": "Reading Lamp"}`))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var stored models.Product
+	db.DB.First(&stored, product.ID)
+	assert.Equal(t, "Reading Lamp", stored.Name)
+	assert.Equal(t, models.ProductStatusPublished, stored.Status)
+	assert.Nil(t, stored.PublishAt)
+	assert.Equal(t, 20.0, stored.Price)
+	if assert.NotNil(t, stored.CompareAtPrice) {
+		assert.Equal(t, 25.0, *stored.CompareAtPrice)
+	}
+}
@@ -1,14 +1,18 @@
 package handlers
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/geoo115/Ecommerce/api/middlewares"
 	"github.com/geoo115/Ecommerce/cache"
 	"github.com/geoo115/Ecommerce/db"
 	"github.com/geoo115/Ecommerce/models"
+	"github.com/geoo115/Ecommerce/services"
 	"github.com/geoo115/Ecommerce/utils"
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
@@ -57,15 +61,23 @@ func AddProduct(c *gin.Context) {
 		return
 	}
 
-	tx := db.DB.Begin()
-
-	// Create the product
+	// Create the product, as a draft if requested so it can be prepared before launch
 	product := models.Product{
 		Name:        utils.SanitizeString(input.Name),
 		Price:       input.Price,
 		CategoryID:  input.CategoryID,
 		Description: utils.SanitizeString(input.Description),
 	}
+	publishing := services.PublishingInput{Status: input.Status, PublishAt: input.PublishAt, UnpublishAt: input.UnpublishAt}
+	if publishing.Status == "" {
+		publishing.Status = models.ProductStatusPublished
+	}
+	if err := services.ApplyPublishing(&product, publishing, time.Now()); err != nil {
+		utils.SendValidationError(c, err.Error())
+		return
+	}
+
+	tx := db.DB.Begin()
 
 	if err := tx.Create(&product).Error; err != nil {
 		tx.Rollback()
@@ -80,6 +92,14 @@ func AddProduct(c *gin.Context) {
 		return
 	}
 
+	// The opening price starts the product's price history
+	if err := tx.Create(&models.PriceHistory{ProductID: product.ID, NewPrice: product.Price,
+		Source: models.PriceSourceManual, Reason: "Product created", ChangedBy: c.GetUint("userID")}).Error; err != nil {
+		tx.Rollback()
+		utils.SendInternalError(c, "Failed to record price")
+		return
+	}
+
 	// Create inventory record
 	inventory := models.Inventory{
 		ProductID: product.ID,
@@ -105,12 +125,7 @@ func AddProduct(c *gin.Context) {
 		return
 	}
 
-	// Invalidate product cache if cache is initialized
-	if cch := cache.GetCache(); cch != nil {
-		if err := cch.InvalidateProductCache(completeProduct.ID); err != nil {
-			utils.Warn("Failed to invalidate product cache: %v", err)
-		}
-	}
+	invalidateProductCache(completeProduct.ID)
 
 	utils.SendSuccess(c, http.StatusCreated, "Product created successfully", completeProduct)
 }
@@ -125,48 +140,89 @@ func ListProductsWithDB(c *gin.Context, dbInstance *gorm.DB) {
 		Base.HandleDBError(c, fmt.Errorf("database connection is nil"), "Database error", "Database error")
 		return
 	}
+	sendProductListing(c, dbInstance, productListing{
+		cachePrefix: "products:list",
+		scope:       services.PublishedProducts,
+		order:       services.ProductListOptions.PageOrder,
+		message:     "Products retrieved successfully",
+	})
+}
+
+// productListing describes a public listing of products: which products it covers, the
+// prefix its pages are cached under and how it is ordered for the requested options
+type productListing struct {
+	cachePrefix string
+	scope       func(*gorm.DB) *gorm.DB
+	order       func(services.ProductListOptions) services.PageOrder
+	message     string
+}
+
+// sendProductListing responds with a page of a listing's products, sorted and filtered by the
+// query parameters ListProducts accepts
+func sendProductListing(c *gin.Context, dbInstance *gorm.DB, listing productListing) {
 	// Check cache first (avoid shadowing package name and handle nil cache)
 	cch := cache.GetCache()
-	page := 1
-	limit := 10
+	pagination := Base.GetPaginationParams(c)
 
-	if p, err := strconv.Atoi(c.Query("page")); err == nil && p > 0 {
-		page = p
+	// Sorting plus category, price and availability filters; include_descendants=true also
+	// matches products in subcategories
+	options, err := services.ParseProductListOptions(c.Request.URL.Query())
+	if err != nil {
+		utils.SendValidationError(c, err.Error())
+		return
 	}
 
-	if l, err := strconv.Atoi(c.Query("limit")); err == nil && l > 0 {
-		limit = l
+	attributeScope, filterKey, ok := attributeFilters(c, dbInstance)
+	if !ok {
+		return
 	}
+	withFacets := c.Query("facets") == "true"
 
-	cacheKey := fmt.Sprintf("products:list:page:%d:limit:%d", page, limit)
+	cacheKey := fmt.Sprintf("%s:%s:options:%s:attributes:%s:facets:%t",
+		listing.cachePrefix, pagination.Key(), options.Key(), filterKey, withFacets)
 
 	// Try to get from cache
-	var products []models.Product
+	var results productResults
 	if cch != nil {
-		if err := cch.Get(cacheKey, &products); err == nil {
+		if err := cch.Get(cacheKey, &results); err == nil {
 			utils.Info("Products retrieved from cache")
-			utils.SendSuccess(c, http.StatusOK, "Products retrieved successfully", products)
+			sendProductResults(c, listing.message, results, withFacets)
 			return
 		}
 	}
 
 	// Cache miss - fetch from database
-	query := dbInstance.Preload("Category").Preload("Inventory")
-	query = paginate(c, query)
+	filterScope, err := options.FilterScope(dbInstance)
+	if err != nil {
+		utils.SendInternalError(c, "Failed to fetch categories")
+		return
+	}
+	filtered := dbInstance.Model(&models.Product{}).Scopes(listing.scope, attributeScope, filterScope).Session(&gorm.Session{})
 
-	if err := query.Find(&products).Error; err != nil {
-		utils.SendInternalError(c, "Failed to fetch products")
+	results.Pagination, ok = Base.FetchPage(c, filtered, listing.order(options), &results.Products, "Failed to fetch products", preloadProductDetails)
+	if !ok {
 		return
 	}
+	attachBreadcrumbs(dbInstance, results.Products)
+	attachComputedStock(dbInstance, results.Products)
+
+	if withFacets {
+		facets, err := services.NewAttributeServiceWithDB(dbInstance).Facets(filtered.Select("products.id"))
+		if err != nil {
+			utils.SendInternalError(c, "Failed to count product facets")
+			return
+		}
+		results.Facets = facets
+	}
 
 	// Cache the result if cache is available
 	if cch != nil {
-		if err := cch.Set(cacheKey, products); err != nil {
+		if err := cch.Set(cacheKey, results); err != nil {
 			utils.Warn("Failed to cache products: %v", err)
 		}
 	}
 
-	utils.SendSuccess(c, http.StatusOK, "Products retrieved successfully", products)
+	sendProductResults(c, listing.message, results, withFacets)
 }
 
 // ListProductsHandlerWrapper wraps the ListProducts handler to inject the database instance
@@ -186,7 +242,6 @@ func GetProductWithDB(c *gin.Context, dbInstance *gorm.DB) {
 		Base.HandleDBError(c, fmt.Errorf("database connection is nil"), "Database error", "Database error")
 		return
 	}
-	var product models.Product
 	id := c.Param("id")
 
 	// Validate ID parameter
@@ -201,10 +256,47 @@ func GetProductWithDB(c *gin.Context, dbInstance *gorm.DB) {
 		return
 	}
 
-	if err := dbInstance.Preload("Category").Preload("Inventory").First(&product, id).Error; err != nil {
+	sendProductDetails(c, dbInstance, id)
+}
+
+// GetProductBySlug retrieves a product by its slug. Former slugs redirect permanently to the current one.
+func GetProductBySlug(c *gin.Context) {
+	id, current, err := services.NewSlugService().Resolve(models.SlugResourceProduct, c.Param("slug"))
+	if err != nil {
+		sendSlugLookupError(c, err, "Product not found")
+		return
+	}
+	if current != c.Param("slug") {
+		redirectToSlug(c, current)
+		return
+	}
+	sendProductDetails(c, db.DB, id)
+}
+
+// sendProductDetails responds with a product and everything shown on its page. Drafts are
+// treated as missing except for admins.
+func sendProductDetails(c *gin.Context, dbInstance *gorm.DB, id interface{}) {
+	var product models.Product
+	if err := dbInstance.Preload("Category").Preload("Inventory").Scopes(preloadImages).
+		Preload("Options.Values").Preload("Variants.OptionValues").Preload("Attributes.Attribute").
+		Preload("BundleItems.Component").Preload("Tags").First(&product, id).Error; err != nil {
 		utils.SendNotFound(c, "Product not found")
 		return
 	}
+	// Drafts are only shown to admins previewing them
+	if !product.IsPublished() && c.GetString("userRole") != "admin" {
+		utils.SendNotFound(c, "Product not found")
+		return
+	}
+	if crumbs, err := services.NewCategoryServiceWithDB(dbInstance).Breadcrumbs(product.CategoryID); err == nil {
+		product.Breadcrumbs = crumbs
+	}
+	products := []models.Product{product}
+	attachComputedStock(dbInstance, products)
+	product = products[0]
+	if product.IsPublished() {
+		recordProductView(c, product.ID)
+	}
 
 	utils.SendSuccess(c, http.StatusOK, "Product retrieved successfully", product)
 }
@@ -254,6 +346,8 @@ func EditProductWithDB(c *gin.Context, dbInstance *gorm.DB) {
 		Price       float64 `json:"price"`
 		Description string  `json:"description"`
 		Stock       int     `json:"stock"`
+		Slug        *string `json:"slug"`         // The old slug keeps working as a redirect
+		PriceReason string  `json:"price_reason"` // Recorded in the price history
 	}
 
 	if err := c.ShouldBindJSON(&updateData); err != nil {
@@ -282,19 +376,43 @@ func EditProductWithDB(c *gin.Context, dbInstance *gorm.DB) {
 		return
 	}
 
-	// Update fields if provided
+	// Update fields if provided, writing only what changed so concurrent price schedule or
+	// publishing updates to the row are kept
+	updates := map[string]interface{}{}
 	if updateData.Name != "" {
 		product.Name = utils.SanitizeString(updateData.Name)
-	}
-	if updateData.Price > 0 {
-		product.Price = updateData.Price
+		updates["name"] = product.Name
 	}
 	if updateData.Description != "" {
 		product.Description = utils.SanitizeString(updateData.Description)
+		updates["description"] = product.Description
 	}
 
-	// Update the product
-	if err := dbInstance.Save(&product).Error; err != nil {
+	err := dbInstance.Transaction(func(tx *gorm.DB) error {
+		if updateData.Slug != nil {
+			if err := services.NewSlugServiceWithDB(tx).ChangeProductSlug(&product, *updateData.Slug); err != nil {
+				return err
+			}
+		}
+		if len(updates) > 0 {
+			if err := tx.Model(&product).Updates(updates).Error; err != nil {
+				return err
+			}
+		}
+		// Price changes are recorded, and during a sale change the price the sale ends on
+		if updateData.Price > 0 {
+			return services.NewPriceServiceWithDB(tx).SetPrice(&product, updateData.Price, c.GetUint("userID"), updateData.PriceReason)
+		}
+		return nil
+	})
+	switch {
+	case errors.Is(err, services.ErrInvalidSlug):
+		utils.SendValidationError(c, "Slug may only contain lowercase letters, digits and hyphens")
+		return
+	case errors.Is(err, services.ErrDuplicateSlug):
+		utils.SendConflict(c, "Slug is already in use")
+		return
+	case err != nil:
 		utils.SendInternalError(c, "Failed to update product")
 		return
 	}
@@ -314,12 +432,7 @@ func EditProductWithDB(c *gin.Context, dbInstance *gorm.DB) {
 		return
 	}
 
-	// Invalidate product cache if cache is initialized
-	if cch := cache.GetCache(); cch != nil {
-		if err := cch.InvalidateProductCache(product.ID); err != nil {
-			utils.Warn("Failed to invalidate product cache: %v", err)
-		}
-	}
+	invalidateProductCache(product.ID)
 
 	utils.SendSuccess(c, http.StatusOK, "Product updated successfully", product)
 }
@@ -369,12 +482,7 @@ func DeleteProductWithDB(c *gin.Context, dbInstance *gorm.DB) {
 		return
 	}
 
-	// Invalidate product cache if cache is initialized
-	if cch := cache.GetCache(); cch != nil {
-		if err := cch.InvalidateProductCache(productID); err != nil {
-			utils.Warn("Failed to invalidate product cache: %v", err)
-		}
-	}
+	invalidateProductCache(productID)
 
 	utils.SendSuccess(c, http.StatusOK, "Product deleted successfully", nil)
 }
@@ -398,6 +506,7 @@ func SearchProductsWithDB(c *gin.Context, dbInstance *gorm.DB) {
 	}
 	query := c.Query("q")
 	category := c.Query("category")
+	includeDescendants := c.Query("include_descendants") == "true"
 
 	// Sanitize search query (empty query is allowed to return all products)
 	query = utils.SanitizeString(query)
@@ -409,46 +518,107 @@ func SearchProductsWithDB(c *gin.Context, dbInstance *gorm.DB) {
 		return
 	}
 
+	attributeScope, filterKey, ok := attributeFilters(c, dbInstance)
+	if !ok {
+		return
+	}
+	withFacets := c.Query("facets") == "true"
+
 	// Create cache key
-	cacheKey := fmt.Sprintf("products:search:q:%s:category:%s", query, category)
+	pagination := Base.GetPaginationParams(c)
+	cacheKey := fmt.Sprintf("products:search:q:%s:category:%s:descendants:%t:attributes:%s:facets:%t:%s",
+		query, category, includeDescendants, filterKey, withFacets, pagination.Key())
 
 	// Check cache first
 	cch := cache.GetCache()
-	var products []models.Product
+	var results productResults
 	if cch != nil {
-		if err := cch.Get(cacheKey, &products); err == nil {
+		if err := cch.Get(cacheKey, &results); err == nil {
 			utils.Info("Search results retrieved from cache")
-			utils.SendSuccess(c, http.StatusOK, "Search completed successfully", products)
+			sendProductResults(c, "Search completed successfully", results, withFacets)
 			return
 		}
 	}
 
 	// Cache miss - perform search
-	dbQuery := dbInstance.Model(&models.Product{})
-
-	// Apply search filter (case-insensitive)
-	if query != "" {
-		dbQuery = dbQuery.Where("LOWER(products.name) LIKE ?", "%"+query+"%")
-	}
+	dbQuery := dbInstance.Model(&models.Product{}).Scopes(services.PublishedProducts, attributeScope)
 
 	// Apply category filter if provided
-	if category != "" {
+	if category != "" && includeDescendants {
+		var matched models.Category
+		if err := dbInstance.Where("LOWER(name) = ?", category).First(&matched).Error; err == nil {
+			ids, err := services.NewCategoryServiceWithDB(dbInstance).DescendantIDs(matched.ID)
+			if err != nil {
+				utils.SendInternalError(c, "Failed to fetch categories")
+				return
+			}
+			dbQuery = dbQuery.Where("products.category_id IN ?", ids)
+		} else {
+			dbQuery = dbQuery.Where("1 = 0")
+		}
+	} else if category != "" {
 		dbQuery = dbQuery.Joins("JOIN categories ON products.category_id = categories.id").Where("LOWER(categories.name) = ?", category)
 	}
+	dbQuery = dbQuery.Session(&gorm.Session{})
+
+	// Full-text match on name, category and description, most relevant first, with each
+	// synonym of a phrase in the query searched as well
+	backend := services.NewSearchBackend(dbInstance)
+	searcher := services.NewSearchServiceWithDB(dbInstance)
+	// Results are ranked by relevance, so their cursors are positional
+	find := func(search services.SearchQuery) (*gorm.DB, bool) {
+		if expanded, err := searcher.Expand(search); err == nil {
+			search = expanded
+		} else {
+			utils.Warn("Failed to expand search synonyms: %v", err)
+		}
+		matches := backend.Search(dbQuery, search).Session(&gorm.Session{})
+		results.Products = nil
+		page, ok := Base.FetchPage(c, matches, services.PageOrder{}, &results.Products, "Failed to fetch products", preloadProductDetails)
+		results.Pagination = page
+		return matches, ok
+	}
+
+	search := services.ParseSearchQuery(query)
+	matches, ok := find(search)
+	if !ok {
+		return
+	}
+
+	// Nothing matched, so retry with misspelt words replaced by the closest catalogue words
+	if len(results.Products) == 0 && pagination.Page == 1 && pagination.Cursor == "" {
+		corrected, changed, err := searcher.Correct(search)
+		if err != nil {
+			utils.Warn("Failed to correct search query: %v", err)
+		} else if changed {
+			if matches, ok = find(corrected); !ok {
+				return
+			}
+			if len(results.Products) > 0 {
+				results.CorrectedQuery = corrected.Text()
+			}
+		}
+	}
+	attachBreadcrumbs(dbInstance, results.Products)
+	attachComputedStock(dbInstance, results.Products)
 
-	if err := dbQuery.Preload("Category").Preload("Inventory").Find(&products).Error; err != nil {
-		utils.SendInternalError(c, "Failed to fetch products")
-		return
+	if withFacets {
+		facets, err := services.NewAttributeServiceWithDB(dbInstance).Facets(matches.Select("products.id"))
+		if err != nil {
+			utils.SendInternalError(c, "Failed to count product facets")
+			return
+		}
+		results.Facets = facets
 	}
 
 	// Cache the search results
 	if cch != nil {
-		if err := cch.Set(cacheKey, products); err != nil {
+		if err := cch.Set(cacheKey, results); err != nil {
 			utils.Warn("Failed to cache search results: %v", err)
 		}
 	}
 
-	utils.SendSuccess(c, http.StatusOK, "Search completed successfully", products)
+	sendProductResults(c, "Search completed successfully", results, withFacets)
 }
 
 // SearchProductsHandlerWrapper wraps the SearchProducts handler to inject the database instance
@@ -458,13 +628,108 @@ func SearchProductsHandlerWrapper(dbInstance *gorm.DB) gin.HandlerFunc {
 	}
 }
 
-func paginate(c *gin.Context, query *gorm.DB) *gorm.DB {
-	limit := 10
-	page := c.Query("page")
-	if p, err := strconv.Atoi(page); err == nil && p > 0 {
-		query = query.Offset((p - 1) * limit).Limit(limit)
+// preloadProductDetails loads what product listings show alongside each product
+func preloadProductDetails(query *gorm.DB) *gorm.DB {
+	return query.Preload("Category").Preload("Inventory").Preload("Tags").Scopes(preloadImages)
+}
+
+// preloadImages loads product images in display order with their thumbnails
+func preloadImages(query *gorm.DB) *gorm.DB {
+	return query.Preload("Images", func(images *gorm.DB) *gorm.DB {
+		return images.Order("position, id")
+	}).Preload("Images.Thumbnails")
+}
+
+// productResults is a page of products with optional facet counts, cached as a unit
+type productResults struct {
+	Products       []models.Product  `json:"products"`
+	Facets         []services.Facet  `json:"facets,omitempty"`
+	CorrectedQuery string            `json:"corrected_query,omitempty"`
+	Pagination     *utils.Pagination `json:"pagination,omitempty"`
+}
+
+// sendProductResults responds with a page of products alone, or with their facets when requested.
+// A search answered with a corrected spelling reports it in X-Search-Corrected-Query.
+func sendProductResults(c *gin.Context, message string, results productResults, withFacets bool) {
+	if results.CorrectedQuery != "" {
+		c.Header("X-Search-Corrected-Query", results.CorrectedQuery)
+	}
+	if results.Products == nil {
+		results.Products = []models.Product{}
+	}
+	if !withFacets {
+		utils.SendPage(c, message, results.Products, results.Pagination)
+		return
+	}
+	if results.Facets == nil {
+		results.Facets = []services.Facet{}
+	}
+	utils.SendPage(c, message, gin.H{"products": results.Products, "facets": results.Facets}, results.Pagination)
+}
+
+// attributeFilters builds the product scope for attr.* query parameters along with a stable key
+// for caching. It responds and returns false when the filters are invalid.
+func attributeFilters(c *gin.Context, dbInstance *gorm.DB) (func(*gorm.DB) *gorm.DB, string, bool) {
+	filters, err := services.ParseAttributeFilters(c.Request.URL.Query())
+	if err == nil {
+		var scope func(*gorm.DB) *gorm.DB
+		if scope, err = services.NewAttributeServiceWithDB(dbInstance).FilterScope(filters); err == nil {
+			keys := make([]string, len(filters))
+			for i, filter := range filters {
+				keys[i] = filter.String()
+			}
+			return scope, strings.Join(keys, ";"), true
+		}
+	}
+
+	if errors.Is(err, services.ErrInvalidAttributeFilter) {
+		utils.SendValidationError(c, err.Error())
 	} else {
-		query = query.Limit(limit)
+		utils.SendInternalError(c, "Failed to apply attribute filters")
+	}
+	return nil, "", false
+}
+
+// attachBreadcrumbs fills in each product's category path; failures only drop the breadcrumbs
+func attachBreadcrumbs(dbInstance *gorm.DB, products []models.Product) {
+	if err := services.NewCategoryServiceWithDB(dbInstance).AttachBreadcrumbs(products); err != nil {
+		utils.Warn("Failed to load category breadcrumbs: %v", err)
+	}
+}
+
+// attachComputedStock shows each bundle's stock as the number of bundles its components make up
+// and each digital product's as its available licence keys; failures leave the stored stock
+func attachComputedStock(dbInstance *gorm.DB, products []models.Product) {
+	if err := services.AttachComputedStock(dbInstance, products); err != nil {
+		utils.Warn("Failed to load computed stock: %v", err)
+	}
+}
+
+// redirectToSlug permanently redirects a request for a former slug to the same path with the current slug
+func redirectToSlug(c *gin.Context, slug string) {
+	location := strings.TrimSuffix(c.Request.URL.Path, c.Param("slug")) + slug
+	if c.Request.URL.RawQuery != "" {
+		location += "?" + c.Request.URL.RawQuery
+	}
+	c.Redirect(http.StatusMovedPermanently, location)
+}
+
+// sendSlugLookupError maps slug lookup failures to responses
+func sendSlugLookupError(c *gin.Context, err error, notFoundMsg string) {
+	if errors.Is(err, services.ErrSlugNotFound) {
+		utils.SendNotFound(c, notFoundMsg)
+		return
+	}
+	utils.SendInternalError(c, "Failed to look up slug")
+}
+
+// invalidateProductCache drops cached copies of a product and of product listings, and
+// rebuilds the search index used for suggestions and typo correction on next use
+func invalidateProductCache(productID uint) {
+	services.InvalidateSearchIndex()
+	if cch := cache.GetCache(); cch != nil {
+		if err := cch.InvalidateProductCache(productID); err != nil {
+			utils.Warn("Failed to invalidate product cache: %v", err)
+		}
 	}
-	return query
 }
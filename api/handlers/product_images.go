@@ -0,0 +1,141 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/geoo115/Ecommerce/db"
+	"github.com/geoo115/Ecommerce/models"
+	"github.com/geoo115/Ecommerce/services"
+	"github.com/geoo115/Ecommerce/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// UploadProductImage stores a new image for a product and generates its thumbnails (admin only).
+// Expects a multipart form with an "image" file and optional "alt_text", "position" and "is_primary".
+func UploadProductImage(c *gin.Context) {
+	productID, err := Base.ValidateIDParam(c, "id")
+	if err != nil {
+		return
+	}
+
+	var product models.Product
+	if err := db.DB.First(&product, productID).Error; err != nil {
+		Base.HandleDBError(c, err, "Product not found", "Failed to fetch product")
+		return
+	}
+
+	fileHeader, err := c.FormFile("image")
+	if err != nil {
+		utils.SendValidationError(c, "An image file is required")
+		return
+	}
+
+	upload := services.ImageUpload{
+		AltText:   c.PostForm("alt_text"),
+		IsPrimary: c.PostForm("is_primary") == "true",
+	}
+	if value := c.PostForm("position"); value != "" {
+		position, err := strconv.Atoi(value)
+		if err != nil || position < 0 {
+			utils.SendValidationError(c, "Position must be a non-negative integer")
+			return
+		}
+		upload.Position = &position
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		utils.SendInternalError(c, "Failed to read upload")
+		return
+	}
+	defer file.Close()
+	upload.Data = file
+
+	img, err := services.NewMediaService().AddImage(&product, upload)
+	if err != nil {
+		sendMediaError(c, err)
+		return
+	}
+	invalidateProductCache(product.ID)
+
+	utils.SendSuccess(c, http.StatusCreated, "Image uploaded successfully", img)
+}
+
+// UpdateProductImage changes an image's alt text, position or primary flag (admin only)
+func UpdateProductImage(c *gin.Context) {
+	img, ok := loadProductImage(c)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		AltText   *string `json:"alt_text" binding:"omitempty,max=255"`
+		Position  *int    `json:"position" binding:"omitempty,min=0"`
+		IsPrimary *bool   `json:"is_primary"`
+	}
+	if err := Base.BindJSON(c, &input); err != nil {
+		return
+	}
+
+	update := services.ImageUpdate{AltText: input.AltText, Position: input.Position, IsPrimary: input.IsPrimary}
+	if err := services.NewMediaService().UpdateImage(img, update); err != nil {
+		sendMediaError(c, err)
+		return
+	}
+	invalidateProductCache(img.ProductID)
+
+	utils.SendSuccess(c, http.StatusOK, "Image updated successfully", img)
+}
+
+// DeleteProductImage removes an image and its thumbnails (admin only)
+func DeleteProductImage(c *gin.Context) {
+	img, ok := loadProductImage(c)
+	if !ok {
+		return
+	}
+
+	if err := services.NewMediaService().DeleteImage(img); err != nil {
+		utils.SendInternalError(c, "Failed to delete image")
+		return
+	}
+	invalidateProductCache(img.ProductID)
+
+	utils.SendSuccess(c, http.StatusOK, "Image deleted successfully", nil)
+}
+
+// loadProductImage fetches the image in the :image_id param, scoped to the product in :id
+func loadProductImage(c *gin.Context) (*models.ProductImage, bool) {
+	productID, err := Base.ValidateIDParam(c, "id")
+	if err != nil {
+		return nil, false
+	}
+	imageID, err := Base.ValidateIDParam(c, "image_id")
+	if err != nil {
+		return nil, false
+	}
+
+	var img models.ProductImage
+	if err := db.DB.Preload("Thumbnails").Where("id = ? AND product_id = ?", imageID, productID).First(&img).Error; err != nil {
+		Base.HandleDBError(c, err, "Image not found", "Failed to fetch image")
+		return nil, false
+	}
+	return &img, true
+}
+
+// sendMediaError maps image upload errors to responses
+func sendMediaError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrImageTooLarge):
+		utils.SendError(c, http.StatusRequestEntityTooLarge, "Image exceeds the maximum upload size")
+	case errors.Is(err, services.ErrImageDimensions):
+		utils.SendError(c, http.StatusRequestEntityTooLarge, "Image exceeds the maximum dimensions")
+	case errors.Is(err, services.ErrUnsupportedImage):
+		utils.SendValidationError(c, "Image must be a JPEG, PNG or GIF file")
+	case errors.Is(err, services.ErrNoChanges):
+		utils.SendValidationError(c, "Update contains no changes")
+	default:
+		utils.SendInternalError(c, "Failed to save image")
+	}
+}
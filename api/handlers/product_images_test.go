@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/png"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/geoo115/Ecommerce/db"
+	"github.com/geoo115/Ecommerce/models"
+	"github.com/geoo115/Ecommerce/services"
+	"github.com/geoo115/Ecommerce/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProductImageEndpoints(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	SetupTestDB(t)
+	utils.AppLogger = utils.NewLogger(utils.INFO)
+
+	originalBlobs := services.Blobs
+	services.Blobs = services.NewLocalBlobStore(t.TempDir(), "/media")
+	defer func() { services.Blobs = originalBlobs }()
+
+	product := models.Product{Name: "Desk", Price: 120}
+	db.DB.Create(&product)
+	db.DB.Create(&models.Inventory{ProductID: product.ID, Stock: 2})
+
+	router := gin.New()
+	router.GET("/product/:id", GetProduct)
+	router.POST("/product/:id/images", UploadProductImage)
+	router.PUT("/product/:id/images/:image_id", UpdateProductImage)
+	router.DELETE("/product/:id/images/:image_id", DeleteProductImage)
+
+	var file bytes.Buffer
+	png.Encode(&file, image.NewNRGBA(image.Rect(0, 0, 40, 20)))
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	part, _ := form.CreateFormFile("image", "desk.png")
+	part.Write(file.Bytes())
+	form.WriteField("alt_text", "Oak desk")
+	form.Close()
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", fmt.Sprintf("/product/%d/images", product.ID), &body)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	var created struct {
+		Data models.ProductImage `json:"data"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &created)
+	assert.True(t, created.Data.IsPrimary)
+	assert.Contains(t, created.Data.URL, "/media/products/")
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("PUT", fmt.Sprintf("/product/%d/images/%d", product.ID, created.Data.ID), bytes.NewBufferString(`{"alt_text":"Oak desk, front"}`))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", fmt.Sprintf("/product/%d", product.ID), nil)
+	router.ServeHTTP(w, req)
+	var fetched struct {
+		Data models.Product `json:"data"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &fetched)
+	if assert.Len(t, fetched.Data.Images, 1) {
+		assert.Equal(t, "Oak desk, front", fetched.Data.Images[0].AltText)
+		assert.NotEmpty(t, fetched.Data.Images[0].Thumbnails)
+	}
+
+	// Uploads without an image file are rejected
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", fmt.Sprintf("/product/%d/images", product.ID), nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("DELETE", fmt.Sprintf("/product/%d/images/%d", product.ID, created.Data.ID), nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+}
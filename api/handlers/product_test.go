@@ -855,3 +855,102 @@ func TestAddProduct_DatabaseError(t *testing.T) {
 	assert.Equal(t, http.StatusInternalServerError, w.Code)
 	assert.Contains(t, w.Body.String(), "Database error")
 }
+
+func TestListProducts_SortingAndFilters(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	SetupTestDB(t)
+
+	category := models.Category{Name: "Stationery"}
+	db.DB.Create(&category)
+	other := models.Category{Name: "Garden"}
+	db.DB.Create(&other)
+	for i, price := range []float64{8, 2, 5, 12} {
+		product := models.Product{Name: "Pen " + strconv.Itoa(i), Price: price, CategoryID: category.ID}
+		db.DB.Create(&product)
+		db.DB.Create(&models.Inventory{ProductID: product.ID, Stock: i})
+	}
+	db.DB.Create(&models.Product{Name: "Trowel", Price: 9, CategoryID: other.ID})
+
+	router := gin.New()
+	router.GET("/products", ListProducts)
+	list := func(query string) (int, []float64) {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/products?"+query, nil)
+		router.ServeHTTP(w, req)
+		var response struct {
+			Data []models.Product `json:"data"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &response)
+		prices := make([]float64, len(response.Data))
+		for i, product := range response.Data {
+			prices[i] = product.Price
+		}
+		return w.Code, prices
+	}
+
+	code, prices := list("sort=price_asc&limit=3")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, []float64{2, 5, 8}, prices)
+	_, prices = list("sort=price_asc&limit=3&page=2")
+	assert.Equal(t, []float64{9, 12}, prices)
+
+	_, prices = list("sort=price_desc&min_price=5&max_price=9&category_id=" + strconv.Itoa(int(category.ID)))
+	assert.Equal(t, []float64{8, 5}, prices)
+	_, prices = list("sort=price_asc&in_stock=true")
+	assert.Equal(t, []float64{2, 5, 12}, prices)
+	_, prices = list("sort=price_asc&category_id=" + strconv.Itoa(int(category.ID)) + "," + strconv.Itoa(int(other.ID)))
+	assert.Len(t, prices, 5)
+
+	for _, query := range []string{"sort=cheapest", "min_price=abc", "min_price=10&max_price=1", "category_id=x"} {
+		code, _ := list(query)
+		assert.Equal(t, http.StatusBadRequest, code, query)
+	}
+}
+
+func TestProductSlugs(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	SetupTestDB(t)
+
+	category := models.Category{Name: "Kitchen"}
+	db.DB.Create(&category)
+	product := models.Product{Name: "Steel Kettle", Price: 30, CategoryID: category.ID}
+	db.DB.Create(&product)
+	db.DB.Create(&models.Inventory{ProductID: product.ID, Stock: 3})
+
+	router := gin.New()
+	router.GET("/products/slug/:slug", GetProductBySlug)
+	router.GET("/categories/slug/:slug", GetCategoryBySlug)
+	router.PUT("/product/:id", EditProduct)
+
+	send := func(method, path, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, path, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := send("GET", "/products/slug/steel-kettle", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	var response struct {
+		Data models.Product `json:"data"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &response)
+	assert.Equal(t, product.ID, response.Data.ID)
+	assert.Equal(t, "/products/steel-kettle", response.Data.CanonicalURL)
+	assert.Equal(t, "/categories/kitchen", response.Data.Category.CanonicalURL)
+
+	assert.Equal(t, http.StatusBadRequest, send("PUT", "/product/"+strconv.Itoa(int(product.ID)), `{"slug":"Kettle!"}`).Code)
+	w = send("PUT", "/product/"+strconv.Itoa(int(product.ID)), `{"slug":"kettle"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	json.Unmarshal(w.Body.Bytes(), &response)
+	assert.Equal(t, "/products/kettle", response.Data.CanonicalURL)
+
+	// The old slug redirects permanently, keeping the query string
+	w = send("GET", "/products/slug/steel-kettle?ref=mail", "")
+	assert.Equal(t, http.StatusMovedPermanently, w.Code)
+	assert.Equal(t, "/products/slug/kettle?ref=mail", w.Header().Get("Location"))
+
+	assert.Equal(t, http.StatusNotFound, send("GET", "/products/slug/toaster", "").Code)
+	assert.Equal(t, http.StatusOK, send("GET", "/categories/slug/kitchen", "").Code)
+}
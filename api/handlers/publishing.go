@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/geoo115/Ecommerce/db"
+	"github.com/geoo115/Ecommerce/models"
+	"github.com/geoo115/Ecommerce/services"
+	"github.com/geoo115/Ecommerce/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// SetProductPublishing publishes a product, turns it back into a draft or schedules when it is
+// published and unpublished (admin only). Each request replaces the product's schedule.
+func SetProductPublishing(c *gin.Context) {
+	productID, err := Base.ValidateIDParam(c, "id")
+	if err != nil {
+		return
+	}
+
+	var input services.PublishingInput
+	if err := Base.BindJSON(c, &input); err != nil {
+		return
+	}
+
+	var product models.Product
+	if err := db.DB.First(&product, productID).Error; err != nil {
+		Base.HandleDBError(c, err, "Product not found", "Failed to fetch product")
+		return
+	}
+
+	if err := services.NewPublishingService().Set(&product, input, time.Now()); err != nil {
+		if errors.Is(err, services.ErrInvalidPublishing) {
+			utils.SendValidationError(c, err.Error())
+		} else {
+			utils.SendInternalError(c, "Failed to update product status")
+		}
+		return
+	}
+	invalidateProductCache(product.ID)
+
+	utils.SendSuccess(c, http.StatusOK, "Product status updated successfully", product)
+}
+
+// ListAdminProducts lists products including drafts, optionally only those with a given
+// status, so merchandisers can review drafts before they launch (admin only). Admins preview
+// a draft's page through GET /product/:id.
+func ListAdminProducts(c *gin.Context) {
+	query := db.DB.Model(&models.Product{})
+	switch status := c.Query("status"); status {
+	case "":
+	case models.ProductStatusDraft, models.ProductStatusPublished:
+		query = query.Where("products.status = ?", status)
+	default:
+		utils.SendValidationError(c, "status must be draft or published")
+		return
+	}
+	if c.Query("scheduled") == "true" {
+		query = query.Where("(products.publish_at IS NOT NULL OR products.unpublish_at IS NOT NULL)")
+	}
+
+	var products []models.Product
+	pagination, ok := Base.FetchPage(c, query, services.OrderByID("products"), &products, "Failed to fetch products", preloadProductDetails)
+	if !ok {
+		return
+	}
+	attachComputedStock(db.DB, products)
+	if products == nil {
+		products = []models.Product{}
+	}
+
+	utils.SendPage(c, "Products retrieved successfully", products, pagination)
+}
@@ -0,0 +1,139 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/geoo115/Ecommerce/api/middlewares"
+	"github.com/geoo115/Ecommerce/cache"
+	"github.com/geoo115/Ecommerce/db"
+	"github.com/geoo115/Ecommerce/models"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProductPublishingEndpoints(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	SetupTestDB(t)
+	originalCache := cache.GlobalCache
+	cache.GlobalCache = cache.NewInMemoryCache()
+	defer func() { cache.GlobalCache = originalCache }()
+
+	user := models.User{Username: "merchandiser", Email: "merchandiser@example.com"}
+	db.DB.Create(&user)
+	category := models.Category{Name: "Lighting"}
+	db.DB.Create(&category)
+	lamp := models.Product{Name: "Desk Lamp", Price: 35, CategoryID: category.ID}
+	db.DB.Create(&lamp)
+	db.DB.Create(&models.Inventory{ProductID: lamp.ID, Stock: 5})
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set("userID", user.ID)
+		c.Set("userRole", c.GetHeader("X-Test-Role"))
+	})
+	router.POST("/product", middlewares.ValidateProduct(), AddProduct)
+	router.PUT("/product/:id/publishing", SetProductPublishing)
+	router.GET("/admin/products", ListAdminProducts)
+	router.GET("/products", ListProducts)
+	router.GET("/product/:id", GetProduct)
+	router.GET("/products/search", SearchProducts)
+	router.GET("/products/suggest", SuggestProducts)
+	router.POST("/cart", AddToCart)
+	router.POST("/checkout", Checkout)
+
+	send := func(method, path, body, role string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, path, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Test-Role", role)
+		router.ServeHTTP(w, req)
+		return w
+	}
+	listed := func(path string) []string {
+		w := send("GET", path, "", "")
+		assert.Equal(t, http.StatusOK, w.Code)
+		var response struct {
+			Data []models.Product `json:"data"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &response)
+		names := make([]string, len(response.Data))
+		for i, product := range response.Data {
+			names[i] = product.Name
+		}
+		return names
+	}
+
+	// Listings are cached before the draft exists
+	assert.Equal(t, []string{"Desk Lamp"}, listed("/products"))
+
+	w := send("POST", "/product", fmt.Sprintf(`{"name":"Floor Lamp","price":120,"category_id":%d,"description":"Launching soon","stock":5,"status":"draft"}`, category.ID), "admin")
+	assert.Equal(t, http.StatusCreated, w.Code)
+	var created struct {
+		Data models.Product `json:"data"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &created)
+	draft := created.Data
+	assert.Equal(t, models.ProductStatusDraft, draft.Status)
+	assert.Equal(t, http.StatusBadRequest, send("POST", "/product", fmt.Sprintf(`{"name":"Wall Lamp","price":60,"category_id":%d,"description":"Wall","stock":5,"status":"hidden"}`, category.ID), "admin").Code)
+
+	t.Run("DraftsAreHiddenFromShoppers", func(t *testing.T) {
+		assert.Equal(t, []string{"Desk Lamp"}, listed("/products"))
+		assert.Equal(t, []string{"Desk Lamp"}, listed("/products/search?q=lamp"))
+		assert.Equal(t, http.StatusNotFound, send("GET", fmt.Sprintf("/product/%d", draft.ID), "", "").Code)
+		assert.NotContains(t, send("GET", "/products/suggest?q=floor", "", "").Body.String(), "Floor Lamp")
+
+		w := send("POST", "/cart", fmt.Sprintf(`{"product_id":%d,"quantity":1}`, draft.ID), "")
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("AdminsPreviewDrafts", func(t *testing.T) {
+		assert.Equal(t, http.StatusOK, send("GET", fmt.Sprintf("/product/%d", draft.ID), "", "admin").Code)
+
+		w := send("GET", "/admin/products?status=draft", "", "admin")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), "Floor Lamp")
+		assert.NotContains(t, w.Body.String(), "Desk Lamp")
+		assert.Equal(t, http.StatusBadRequest, send("GET", "/admin/products?status=hidden", "", "admin").Code)
+	})
+
+	t.Run("SchedulingAndPublishing", func(t *testing.T) {
+		path := fmt.Sprintf("/product/%d/publishing", draft.ID)
+		publishAt := time.Now().Add(24 * time.Hour).UTC().Format(time.RFC3339)
+		assert.Equal(t, http.StatusBadRequest, send("PUT", path, `{"status":"published","publish_at":"`+publishAt+`"}`, "admin").Code)
+		assert.Equal(t, http.StatusNotFound, send("PUT", "/product/9999/publishing", `{"status":"published"}`, "admin").Code)
+
+		w := send("PUT", path, `{"status":"draft","publish_at":"`+publishAt+`"}`, "admin")
+		assert.Equal(t, http.StatusOK, w.Code)
+		w = send("GET", "/admin/products?scheduled=true", "", "admin")
+		assert.Contains(t, w.Body.String(), "Floor Lamp")
+
+		w = send("PUT", path, `{"status":"published"}`, "admin")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, []string{"Desk Lamp", "Floor Lamp"}, listed("/products"))
+		assert.Contains(t, send("GET", "/products/suggest?q=floor", "", "").Body.String(), "Floor Lamp")
+		assert.Equal(t, http.StatusCreated, send("POST", "/cart", fmt.Sprintf(`{"product_id":%d,"quantity":1}`, draft.ID), "").Code)
+
+		// Unpublishing drops the product from cached listings again
+		assert.Equal(t, http.StatusOK, send("PUT", fmt.Sprintf("/product/%d/publishing", lamp.ID), `{"status":"draft"}`, "admin").Code)
+		assert.Equal(t, []string{"Floor Lamp"}, listed("/products"))
+	})
+
+	t.Run("CartedDrafts", func(t *testing.T) {
+		// Floor Lamp is in the cart from publishing; adding more once it is a draft again fails
+		assert.Equal(t, http.StatusOK, send("PUT", fmt.Sprintf("/product/%d/publishing", draft.ID), `{"status":"draft"}`, "admin").Code)
+		w := send("POST", "/cart", fmt.Sprintf(`{"product_id":%d,"quantity":1}`, draft.ID), "")
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		assert.Contains(t, w.Body.String(), "Product not found")
+
+		assert.Equal(t, http.StatusNotFound, send("POST", "/checkout", "", "").Code)
+		var orders int64
+		db.DB.Model(&models.Order{}).Where("user_id = ?", user.ID).Count(&orders)
+		assert.Equal(t, int64(0), orders)
+	})
+}
@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+
+	"github.com/geoo115/Ecommerce/cache"
+	"github.com/geoo115/Ecommerce/config"
+	"github.com/geoo115/Ecommerce/db"
+	"github.com/geoo115/Ecommerce/models"
+	"github.com/geoo115/Ecommerce/services"
+	"github.com/geoo115/Ecommerce/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// guestTokenPattern matches the X-Guest-Token a storefront generates and keeps for a visitor
+// who is not signed in, such as a UUID
+var guestTokenPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{16,128}$`)
+
+// viewerKey identifies whose browsing history a request belongs to: the signed-in user, or
+// else the guest in X-Guest-Token. It is empty for anonymous requests without a guest token.
+func viewerKey(c *gin.Context) string {
+	if userID := c.GetUint("userID"); userID != 0 {
+		return fmt.Sprintf("account:%d", userID)
+	}
+	if token := c.GetHeader("X-Guest-Token"); guestTokenPattern.MatchString(token) {
+		return "guest:" + token
+	}
+	return ""
+}
+
+// recordProductView adds a product to the viewer's recently viewed products; failures only lose the view
+func recordProductView(c *gin.Context, productID uint) {
+	owner := viewerKey(c)
+	cch := cache.GetCache()
+	if owner == "" || cch == nil {
+		return
+	}
+	cfg := config.GetRecentlyViewedConfig()
+	if err := cch.AddRecentlyViewed(owner, productID, cfg.Limit, cfg.TTL); err != nil {
+		utils.Warn("Failed to record product view: %v", err)
+	}
+}
+
+// GetRecentlyViewed returns the products the signed-in user or guest viewed most recently, with
+// their current prices and stock. Deleted and unpublished products are left out.
+func GetRecentlyViewed(c *gin.Context) {
+	owner, ok := requireViewer(c)
+	if !ok {
+		return
+	}
+
+	products := []models.Product{}
+	var productIDs []uint
+	if cch := cache.GetCache(); cch != nil {
+		var err error
+		if productIDs, err = cch.GetRecentlyViewed(owner); err != nil {
+			utils.SendInternalError(c, "Failed to fetch recently viewed products")
+			return
+		}
+	}
+	if len(productIDs) > 0 {
+		var found []models.Product
+		if err := db.DB.Scopes(services.PublishedProducts, preloadProductDetails).Where("id IN ?", productIDs).Find(&found).Error; err != nil {
+			utils.SendInternalError(c, "Failed to fetch recently viewed products")
+			return
+		}
+		attachComputedStock(db.DB, found)
+
+		byID := make(map[uint]models.Product, len(found))
+		for _, product := range found {
+			byID[product.ID] = product
+		}
+		for _, id := range productIDs {
+			if product, ok := byID[id]; ok {
+				products = append(products, product)
+			}
+		}
+	}
+
+	utils.SendSuccess(c, http.StatusOK, "Recently viewed products retrieved successfully", products)
+}
+
+// ClearRecentlyViewed forgets the signed-in user's or guest's recently viewed products
+func ClearRecentlyViewed(c *gin.Context) {
+	owner, ok := requireViewer(c)
+	if !ok {
+		return
+	}
+
+	if cch := cache.GetCache(); cch != nil {
+		if err := cch.ClearRecentlyViewed(owner); err != nil {
+			utils.SendInternalError(c, "Failed to clear recently viewed products")
+			return
+		}
+	}
+	utils.SendSuccess(c, http.StatusOK, "Recently viewed products cleared successfully", nil)
+}
+
+// requireViewer returns the viewer key, responding when the request has neither a valid token nor a guest token
+func requireViewer(c *gin.Context) (string, bool) {
+	owner := viewerKey(c)
+	if owner == "" {
+		utils.SendUnauthorized(c, "Sign in or send an X-Guest-Token to see recently viewed products")
+		return "", false
+	}
+	return owner, true
+}
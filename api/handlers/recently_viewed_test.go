@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/geoo115/Ecommerce/cache"
+	"github.com/geoo115/Ecommerce/db"
+	"github.com/geoo115/Ecommerce/models"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecentlyViewedEndpoints(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	SetupTestDB(t)
+	originalCache := cache.GlobalCache
+	cache.GlobalCache = cache.NewInMemoryCache()
+	defer func() { cache.GlobalCache = originalCache }()
+	os.Setenv("RECENTLY_VIEWED_LIMIT", "3")
+	defer os.Unsetenv("RECENTLY_VIEWED_LIMIT")
+
+	var products []models.Product
+	for i, name := range []string{"Lamp", "Rug", "Vase", "Clock"} {
+		product := models.Product{Name: name, Price: float64(10 * (i + 1))}
+		db.DB.Create(&product)
+		db.DB.Create(&models.Inventory{ProductID: product.ID, Stock: 5})
+		products = append(products, product)
+	}
+	lamp, rug, vase, clock := products[0], products[1], products[2], products[3]
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		if user := c.GetHeader("X-Test-User"); user != "" {
+			var id uint
+			fmt.Sscan(user, &id)
+			c.Set("userID", id)
+		}
+	})
+	router.GET("/product/:id", GetProduct)
+	router.GET("/recently-viewed", GetRecentlyViewed)
+	router.DELETE("/recently-viewed", ClearRecentlyViewed)
+
+	send := func(method, path string, headers map[string]string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, path, nil)
+		for key, value := range headers {
+			req.Header.Set(key, value)
+		}
+		router.ServeHTTP(w, req)
+		return w
+	}
+	view := func(product models.Product, headers map[string]string) {
+		w := send("GET", fmt.Sprintf("/product/%d", product.ID), headers)
+		assert.Equal(t, http.StatusOK, w.Code)
+	}
+	history := func(headers map[string]string) []models.Product {
+		w := send("GET", "/recently-viewed", headers)
+		assert.Equal(t, http.StatusOK, w.Code)
+		var response struct {
+			Data []models.Product `json:"data"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &response)
+		return response.Data
+	}
+	names := func(products []models.Product) []string {
+		result := make([]string, len(products))
+		for i, product := range products {
+			result[i] = product.Name
+		}
+		return result
+	}
+
+	user := map[string]string{"X-Test-User": "7"}
+	guest := map[string]string{"X-Guest-Token": "3f6c1a9e-5b2d-4c8e-9a7f-1d2e3c4b5a69"}
+
+	t.Run("RecordsDistinctViewsMostRecentFirst", func(t *testing.T) {
+		view(lamp, user)
+		view(rug, user)
+		view(lamp, user)
+		view(vase, user)
+		view(clock, user)
+		// Only the last three distinct products are kept, and the lamp's second view moved it up
+		assert.Equal(t, []string{"Clock", "Vase", "Lamp"}, names(history(user)))
+
+		// Current prices and stock are returned rather than those at the time of viewing
+		db.DB.Model(&models.Product{}).Where("id = ?", clock.ID).Update("price", 99)
+		viewed := history(user)
+		if assert.NotEmpty(t, viewed) {
+			assert.Equal(t, 99.0, viewed[0].Price)
+			assert.Equal(t, 5, viewed[0].Inventory.Stock)
+		}
+	})
+
+	t.Run("GuestsHaveTheirOwnHistory", func(t *testing.T) {
+		view(rug, guest)
+		assert.Equal(t, []string{"Rug"}, names(history(guest)))
+
+		// Anonymous views without a valid guest token are not recorded
+		view(vase, nil)
+		view(vase, map[string]string{"X-Guest-Token": "short"})
+		assert.Equal(t, []string{"Rug"}, names(history(guest)))
+		assert.Equal(t, http.StatusUnauthorized, send("GET", "/recently-viewed", nil).Code)
+		assert.Equal(t, http.StatusUnauthorized, send("DELETE", "/recently-viewed", map[string]string{"X-Guest-Token": "short"}).Code)
+	})
+
+	t.Run("DeletedProductsAreExcluded", func(t *testing.T) {
+		db.DB.Delete(&vase)
+		assert.Equal(t, []string{"Clock", "Lamp"}, names(history(user)))
+	})
+
+	t.Run("Clear", func(t *testing.T) {
+		w := send("DELETE", "/recently-viewed", user)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Empty(t, history(user))
+		assert.Equal(t, []string{"Rug"}, names(history(guest)))
+	})
+}
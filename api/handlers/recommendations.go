@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/geoo115/Ecommerce/cache"
+	"github.com/geoo115/Ecommerce/config"
+	"github.com/geoo115/Ecommerce/db"
+	"github.com/geoo115/Ecommerce/models"
+	"github.com/geoo115/Ecommerce/services"
+	"github.com/geoo115/Ecommerce/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// GetRelatedProducts returns products frequently bought with a product, falling back to
+// products from the same category and of a similar price
+func GetRelatedProducts(c *gin.Context) {
+	productID, err := Base.ValidateIDParam(c, "id")
+	if err != nil {
+		return
+	}
+	limit, ok := recommendationLimit(c)
+	if !ok {
+		return
+	}
+
+	cacheKey := fmt.Sprintf("%srelated:%d:%d", cache.RecommendationKeyPrefix, productID, limit)
+	sendRecommendations(c, cacheKey, "Related products retrieved successfully", func() ([]services.Recommendation, error) {
+		return services.NewRecommendationService().Related(productID, limit, preloadProductDetails)
+	})
+}
+
+// GetCartSuggestions suggests products to add to the authenticated user's cart based on what it holds
+func GetCartSuggestions(c *gin.Context) {
+	userID, err := Base.GetUserID(c)
+	if err != nil {
+		return
+	}
+	limit, ok := recommendationLimit(c)
+	if !ok {
+		return
+	}
+
+	var productIDs []uint
+	if err := db.DB.Model(&models.Cart{}).Where("user_id = ?", userID).Distinct().Pluck("product_id", &productIDs).Error; err != nil {
+		utils.SendInternalError(c, "Failed to fetch cart")
+		return
+	}
+
+	// Carts holding the same products share suggestions
+	sort.Slice(productIDs, func(i, j int) bool { return productIDs[i] < productIDs[j] })
+	ids := make([]string, len(productIDs))
+	for i, id := range productIDs {
+		ids[i] = strconv.FormatUint(uint64(id), 10)
+	}
+	cacheKey := fmt.Sprintf("%scart:%s:%d", cache.RecommendationKeyPrefix, strings.Join(ids, ","), limit)
+	sendRecommendations(c, cacheKey, "Cart suggestions retrieved successfully", func() ([]services.Recommendation, error) {
+		return services.NewRecommendationService().ForProducts(productIDs, limit, preloadProductDetails)
+	})
+}
+
+// recommendationLimit reads the optional limit query parameter, responding when it is invalid
+func recommendationLimit(c *gin.Context) (int, bool) {
+	maxLimit := config.GetRecommendationConfig().PerProduct
+	value := c.Query("limit")
+	if value == "" {
+		return maxLimit, true
+	}
+	limit, err := strconv.Atoi(value)
+	if err != nil || limit < 1 || limit > maxLimit {
+		utils.SendValidationError(c, fmt.Sprintf("limit must be between 1 and %d", maxLimit))
+		return 0, false
+	}
+	return limit, true
+}
+
+// sendRecommendations responds with cached recommendations, or computes and caches them
+func sendRecommendations(c *gin.Context, cacheKey, message string, compute func() ([]services.Recommendation, error)) {
+	cch := cache.GetCache()
+	var recommendations []services.Recommendation
+	if cch != nil {
+		if err := cch.Get(cacheKey, &recommendations); err == nil {
+			utils.SendSuccess(c, http.StatusOK, message, recommendations)
+			return
+		}
+	}
+
+	recommendations, err := compute()
+	if errors.Is(err, services.ErrProductNotFound) {
+		utils.SendNotFound(c, "Product not found")
+		return
+	}
+	if err != nil {
+		utils.SendInternalError(c, "Failed to fetch recommendations")
+		return
+	}
+	if cch != nil {
+		if err := cch.Set(cacheKey, recommendations); err != nil {
+			utils.Warn("Failed to cache recommendations: %v", err)
+		}
+	}
+	utils.SendSuccess(c, http.StatusOK, message, recommendations)
+}
@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/geoo115/Ecommerce/cache"
+	"github.com/geoo115/Ecommerce/db"
+	"github.com/geoo115/Ecommerce/models"
+	"github.com/geoo115/Ecommerce/services"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecommendationEndpoints(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	SetupTestDB(t)
+	originalCache := cache.GlobalCache
+	cache.GlobalCache = cache.NewInMemoryCache()
+	defer func() { cache.GlobalCache = originalCache }()
+
+	user := models.User{Username: "browser", Email: "browser@example.com"}
+	db.DB.Create(&user)
+	kettle := models.Product{Name: "Kettle", Price: 40}
+	toaster := models.Product{Name: "Toaster", Price: 45}
+	db.DB.Create(&kettle)
+	db.DB.Create(&toaster)
+	db.DB.Create(&models.Order{UserID: user.ID, Status: "Paid", Items: []models.OrderItem{
+		{ProductID: kettle.ID, Quantity: 1, Price: 40}, {ProductID: toaster.ID, Quantity: 1, Price: 45},
+	}})
+	db.DB.Create(&models.Cart{UserID: user.ID, ProductID: kettle.ID, Quantity: 1})
+	_, err := services.NewRecommendationService().Rebuild(time.Now())
+	assert.NoError(t, err)
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) { c.Set("userID", user.ID) })
+	router.GET("/product/:id/related", GetRelatedProducts)
+	router.GET("/cart/suggestions", GetCartSuggestions)
+
+	get := func(path string) ([]services.Recommendation, int) {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", path, nil)
+		router.ServeHTTP(w, req)
+		var response struct {
+			Data []services.Recommendation `json:"data"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &response)
+		return response.Data, w.Code
+	}
+
+	related, code := get(fmt.Sprintf("/product/%d/related", kettle.ID))
+	assert.Equal(t, http.StatusOK, code)
+	if assert.Len(t, related, 1) {
+		assert.Equal(t, "Toaster", related[0].Product.Name)
+		assert.Equal(t, models.RecommendationBoughtTogether, related[0].Reason)
+	}
+	_, code = get(fmt.Sprintf("/product/%d/related?limit=0", kettle.ID))
+	assert.Equal(t, http.StatusBadRequest, code)
+	_, code = get("/product/9999/related")
+	assert.Equal(t, http.StatusNotFound, code)
+
+	suggested, code := get("/cart/suggestions")
+	assert.Equal(t, http.StatusOK, code)
+	if assert.Len(t, suggested, 1) {
+		assert.Equal(t, toaster.ID, suggested[0].Product.ID)
+	}
+
+	// Results are served from the cache until recommendations are recomputed
+	db.DB.Unscoped().Where("1 = 1").Delete(&models.ProductRecommendation{})
+	related, _ = get(fmt.Sprintf("/product/%d/related", kettle.ID))
+	assert.Len(t, related, 1)
+	assert.NoError(t, cache.GetCache().InvalidateRecommendationCache())
+	related, _ = get(fmt.Sprintf("/product/%d/related", kettle.ID))
+	assert.Empty(t, related)
+}
@@ -133,12 +133,16 @@ func InventoryReport(c *gin.Context) {
 		end = end.Add(24*time.Hour - time.Second)
 	}
 
+	// Products with variants report one row per variant; simple products use their inventory row
 	var report []struct {
-		ProductName  string    `json:"product_name"`
-		CurrentStock int       `json:"current_stock"`
-		StockValue   float64   `json:"stock_value"`
-		LastUpdated  time.Time `json:"last_updated"`
-		Category     string    `json:"category"`
+		ProductName      string     `json:"product_name"`
+		SKU              string     `json:"sku,omitempty"`
+		Variant          string     `json:"variant,omitempty"`
+		CurrentStock     int        `json:"current_stock"`
+		StockValue       float64    `json:"stock_value"`
+		LastUpdated      time.Time  `json:"last_updated"`
+		VariantUpdatedAt *time.Time `json:"-"`
+		Category         string     `json:"category"`
 	}
 
 	if db.DB == nil {
@@ -149,20 +153,24 @@ func InventoryReport(c *gin.Context) {
 	query := db.DB.Model(&models.Product{}).
 		Select(`
 			products.name AS product_name,
-			inventories.stock AS current_stock,
-			inventories.stock * products.price AS stock_value,
+			product_variants.sku AS sku,
+			product_variants.title AS variant,
+			COALESCE(product_variants.stock, inventories.stock) AS current_stock,
+			COALESCE(product_variants.stock, inventories.stock) * COALESCE(product_variants.price_override, products.price) AS stock_value,
 			inventories.updated_at AS last_updated,
+			product_variants.updated_at AS variant_updated_at,
 			categories.name AS category
 		`).
+		Joins("LEFT JOIN product_variants ON product_variants.product_id = products.id AND product_variants.deleted_at IS NULL").
 		Joins("LEFT JOIN inventories ON inventories.product_id = products.id").
 		Joins("LEFT JOIN categories ON categories.id = products.category_id")
 
 	// Add date filters if provided
 	if !start.IsZero() {
-		query = query.Where("inventories.updated_at >= ?", start)
+		query = query.Where("(product_variants.id IS NULL AND inventories.updated_at >= ?) OR product_variants.updated_at >= ?", start, start)
 	}
 	if !end.IsZero() {
-		query = query.Where("inventories.updated_at <= ?", end)
+		query = query.Where("(product_variants.id IS NULL AND inventories.updated_at <= ?) OR product_variants.updated_at <= ?", end, end)
 	}
 
 	// Execute the query
@@ -174,7 +182,10 @@ func InventoryReport(c *gin.Context) {
 	// Calculate summary statistics
 	var totalItems int
 	var totalValue float64
-	for _, item := range report {
+	for i, item := range report {
+		if item.VariantUpdatedAt != nil {
+			report[i].LastUpdated = *item.VariantUpdatedAt
+		}
 		totalItems += item.CurrentStock
 		totalValue += item.StockValue
 	}
@@ -20,7 +20,7 @@ func setupReportsTestDB(t *testing.T) *gorm.DB {
 	assert.NoError(t, err)
 
 	err = testDB.AutoMigrate(&models.User{}, &models.Category{}, &models.Product{},
-		&models.Inventory{}, &models.Order{}, &models.OrderItem{})
+		&models.Inventory{}, &models.ProductVariant{}, &models.Order{}, &models.OrderItem{})
 	assert.NoError(t, err)
 
 	db.DB = testDB
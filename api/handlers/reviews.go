@@ -6,8 +6,10 @@ import (
 
 	"github.com/geoo115/Ecommerce/db"
 	"github.com/geoo115/Ecommerce/models"
+	"github.com/geoo115/Ecommerce/services"
 	"github.com/geoo115/Ecommerce/utils"
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 // AddReview adds a new review for a product
@@ -65,7 +67,7 @@ func AddReview(c *gin.Context) {
 	utils.SendSuccess(c, http.StatusCreated, "Review added successfully", gin.H{"review": review})
 }
 
-// ListReviews lists all reviews for a specific product
+// ListReviews lists a product's reviews, newest first
 func ListReviews(c *gin.Context) {
 	pidStr := c.Param("id")
 	pid, err := strconv.ParseUint(pidStr, 10, 64)
@@ -82,15 +84,17 @@ func ListReviews(c *gin.Context) {
 	}
 
 	var reviews []models.Review
-	if err := db.DB.Where("product_id = ?", uint(pid)).Preload("User").Find(&reviews).Error; err != nil {
-		utils.SendInternalError(c, "Failed to fetch reviews")
+	pagination, ok := Base.FetchPage(c, db.DB.Model(&models.Review{}).Where("product_id = ?", uint(pid)), services.NewestFirst("reviews"), &reviews, "Failed to fetch reviews", func(page *gorm.DB) *gorm.DB {
+		return page.Preload("User")
+	})
+	if !ok {
 		return
 	}
 
-	if len(reviews) == 0 {
+	if len(reviews) == 0 && pagination.PrevCursor == "" {
 		utils.SendNotFound(c, "No reviews found for this product")
 		return
 	}
 
-	utils.SendSuccess(c, http.StatusOK, "Reviews retrieved successfully", reviews)
+	utils.SendPage(c, "Reviews retrieved successfully", reviews, pagination)
 }
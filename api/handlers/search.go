@@ -0,0 +1,135 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/geoo115/Ecommerce/config"
+	"github.com/geoo115/Ecommerce/db"
+	"github.com/geoo115/Ecommerce/models"
+	"github.com/geoo115/Ecommerce/services"
+	"github.com/geoo115/Ecommerce/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// maxSuggestLimit caps how many completions a client can ask for
+const maxSuggestLimit = 20
+
+// SuggestProducts completes a partly typed search with matching category and product names
+func SuggestProducts(c *gin.Context) {
+	prefix := utils.SanitizeString(c.Query("q"))
+	if prefix == "" {
+		utils.SendValidationError(c, "Search query is required")
+		return
+	}
+
+	limit := config.GetSearchConfig().SuggestLimit
+	if raw := c.Query("limit"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed < 1 || parsed > maxSuggestLimit {
+			utils.SendValidationError(c, "Limit must be between 1 and 20")
+			return
+		}
+		limit = parsed
+	}
+
+	suggestions, err := services.NewSearchService().Suggest(prefix, limit)
+	if err != nil {
+		utils.SendInternalError(c, "Failed to fetch suggestions")
+		return
+	}
+
+	utils.SendSuccess(c, http.StatusOK, "Suggestions retrieved successfully", suggestions)
+}
+
+// ListSynonyms returns every search synonym group (admin only)
+func ListSynonyms(c *gin.Context) {
+	groups, err := services.NewSynonymService().List()
+	if err != nil {
+		utils.SendInternalError(c, "Failed to fetch synonyms")
+		return
+	}
+
+	utils.SendSuccess(c, http.StatusOK, "Synonyms retrieved successfully", groups)
+}
+
+// CreateSynonyms adds a group of interchangeable search terms (admin only)
+func CreateSynonyms(c *gin.Context) {
+	var input struct {
+		Terms []string `json:"terms" binding:"required"`
+	}
+	if err := Base.BindJSON(c, &input); err != nil {
+		return
+	}
+
+	group, err := services.NewSynonymService().Create(input.Terms)
+	if err != nil {
+		sendSynonymError(c, err)
+		return
+	}
+
+	utils.SendSuccess(c, http.StatusCreated, "Synonyms created successfully", group)
+}
+
+// UpdateSynonyms replaces the terms of a synonym group (admin only)
+func UpdateSynonyms(c *gin.Context) {
+	group, ok := loadSynonymGroup(c)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		Terms []string `json:"terms" binding:"required"`
+	}
+	if err := Base.BindJSON(c, &input); err != nil {
+		return
+	}
+
+	if err := services.NewSynonymService().Update(group, input.Terms); err != nil {
+		sendSynonymError(c, err)
+		return
+	}
+
+	utils.SendSuccess(c, http.StatusOK, "Synonyms updated successfully", group)
+}
+
+// DeleteSynonyms removes a synonym group (admin only)
+func DeleteSynonyms(c *gin.Context) {
+	group, ok := loadSynonymGroup(c)
+	if !ok {
+		return
+	}
+
+	if err := services.NewSynonymService().Delete(group); err != nil {
+		utils.SendInternalError(c, "Failed to delete synonyms")
+		return
+	}
+
+	utils.SendSuccess(c, http.StatusOK, "Synonyms deleted successfully", nil)
+}
+
+// loadSynonymGroup fetches the synonym group named by the :id parameter, responding on failure
+func loadSynonymGroup(c *gin.Context) (*models.SynonymGroup, bool) {
+	groupID, err := Base.ValidateIDParam(c, "id")
+	if err != nil {
+		return nil, false
+	}
+
+	var group models.SynonymGroup
+	if err := db.DB.First(&group, groupID).Error; err != nil {
+		Base.HandleDBError(c, err, "Synonym group not found", "Failed to fetch synonyms")
+		return nil, false
+	}
+	return &group, true
+}
+
+// sendSynonymError maps synonym service errors to responses
+func sendSynonymError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrSynonymTerms):
+		utils.SendValidationError(c, "Synonym groups need at least two distinct terms")
+	default:
+		utils.SendInternalError(c, "Failed to save synonyms")
+	}
+}
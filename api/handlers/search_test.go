@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/geoo115/Ecommerce/db"
+	"github.com/geoo115/Ecommerce/models"
+	"github.com/geoo115/Ecommerce/services"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSearchSuggestionsAndSynonyms(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	SetupTestDB(t)
+
+	apparel := models.Category{Name: "Apparel"}
+	db.DB.Create(&apparel)
+	db.DB.Create(&models.Product{Name: "Tee Shirt", Description: "Organic cotton", Price: 15, CategoryID: apparel.ID})
+	db.DB.Create(&models.Product{Name: "Wireless Headphones", Description: "Noise cancelling", Price: 99})
+	db.DB.Create(&models.Product{Name: "Headphone Stand", Price: 20})
+
+	router := gin.New()
+	router.GET("/products/search", SearchProducts)
+	router.GET("/products/suggest", SuggestProducts)
+	router.GET("/synonyms", ListSynonyms)
+	router.POST("/synonyms", CreateSynonyms)
+	router.PUT("/synonyms/:id", UpdateSynonyms)
+	router.DELETE("/synonyms/:id", DeleteSynonyms)
+
+	send := func(method, path, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, path, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+		return w
+	}
+	productNames := func(w *httptest.ResponseRecorder) []string {
+		var response struct {
+			Data []models.Product `json:"data"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &response)
+		names := make([]string, len(response.Data))
+		for i, product := range response.Data {
+			names[i] = product.Name
+		}
+		return names
+	}
+
+	t.Run("Suggest", func(t *testing.T) {
+		w := send("GET", "/products/suggest?q=head", "")
+		assert.Equal(t, http.StatusOK, w.Code)
+		var response struct {
+			Data []services.Suggestion `json:"data"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &response)
+		if assert.Len(t, response.Data, 2) {
+			assert.Equal(t, "Headphone Stand", response.Data[0].Text)
+			assert.Equal(t, "Wireless Headphones", response.Data[1].Text)
+		}
+
+		w = send("GET", "/products/suggest?q=app&limit=1", "")
+		json.Unmarshal(w.Body.Bytes(), &response)
+		assert.Equal(t, []services.Suggestion{{Type: "category", ID: apparel.ID, Text: "Apparel", Slug: "apparel"}}, response.Data)
+
+		assert.Equal(t, http.StatusBadRequest, send("GET", "/products/suggest", "").Code)
+		assert.Equal(t, http.StatusBadRequest, send("GET", "/products/suggest?q=head&limit=50", "").Code)
+	})
+
+	t.Run("TypoCorrection", func(t *testing.T) {
+		w := send("GET", "/products/search?q=wireles+headphnes", "")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, []string{"Wireless Headphones"}, productNames(w))
+		assert.Equal(t, "wireless headphones", w.Header().Get("X-Search-Corrected-Query"))
+
+		// Exact matches are not corrected
+		w = send("GET", "/products/search?q=headphones", "")
+		assert.Len(t, productNames(w), 2)
+		assert.Empty(t, w.Header().Get("X-Search-Corrected-Query"))
+
+		w = send("GET", "/products/search?q=xylophone", "")
+		assert.Empty(t, productNames(w))
+	})
+
+	t.Run("Synonyms", func(t *testing.T) {
+		assert.Empty(t, productNames(send("GET", "/products/search?q=t-shirt", "")))
+
+		w := send("POST", "/synonyms", `{"terms":["T-Shirt","Tee Shirt"]}`)
+		assert.Equal(t, http.StatusCreated, w.Code)
+		var created struct {
+			Data models.SynonymGroup `json:"data"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &created)
+		assert.Equal(t, []string{"t shirt", "tee shirt"}, created.Data.Terms)
+
+		assert.Equal(t, []string{"Tee Shirt"}, productNames(send("GET", "/products/search?q=t-shirt", "")))
+		assert.Equal(t, http.StatusBadRequest, send("POST", "/synonyms", `{"terms":["top"]}`).Code)
+
+		path := fmt.Sprintf("/synonyms/%d", created.Data.ID)
+		assert.Equal(t, http.StatusOK, send("PUT", path, `{"terms":["tee shirt","top"]}`).Code)
+		assert.Equal(t, []string{"Tee Shirt"}, productNames(send("GET", "/products/search?q=top", "")))
+
+		w = send("GET", "/synonyms", "")
+		var listed struct {
+			Data []models.SynonymGroup `json:"data"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &listed)
+		if assert.Len(t, listed.Data, 1) {
+			assert.Equal(t, []string{"tee shirt", "top"}, listed.Data[0].Terms)
+		}
+
+		assert.Equal(t, http.StatusOK, send("DELETE", path, "").Code)
+		assert.Equal(t, http.StatusNotFound, send("DELETE", path, "").Code)
+		assert.Empty(t, productNames(send("GET", "/products/search?q=top", "")))
+	})
+}
@@ -0,0 +1,180 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/geoo115/Ecommerce/db"
+	"github.com/geoo115/Ecommerce/models"
+	"github.com/geoo115/Ecommerce/services"
+	"github.com/geoo115/Ecommerce/utils"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// CreateSubscription sets up a recurring order for the authenticated user
+func CreateSubscription(c *gin.Context) {
+	uid, err := Base.GetUserID(c)
+	if err != nil {
+		return
+	}
+
+	var input struct {
+		Items []struct {
+			ProductID uint  `json:"product_id" binding:"required"`
+			VariantID *uint `json:"variant_id"`
+			Quantity  int   `json:"quantity" binding:"required,gt=0"`
+		} `json:"items" binding:"required,min=1,dive"`
+		IntervalDays  int        `json:"interval_days" binding:"required,min=1,max=365"`
+		AddressID     uint       `json:"address_id" binding:"required"`
+		PaymentMethod string     `json:"payment_method" binding:"required"`
+		StartAt       *time.Time `json:"start_at"`
+	}
+	if err := Base.BindJSON(c, &input); err != nil {
+		return
+	}
+
+	// The shipping address must belong to the subscriber
+	var address models.Address
+	if err := db.DB.Where("id = ? AND user_id = ?", input.AddressID, uid).First(&address).Error; err != nil {
+		Base.HandleDBError(c, err, "Address not found", "Failed to fetch address")
+		return
+	}
+
+	sub := models.Subscription{
+		UserID:        uid,
+		AddressID:     address.ID,
+		IntervalDays:  input.IntervalDays,
+		NextRunAt:     time.Now(),
+		Status:        services.SubscriptionActive,
+		PaymentMethod: input.PaymentMethod,
+	}
+	if input.StartAt != nil && input.StartAt.After(sub.NextRunAt) {
+		sub.NextRunAt = *input.StartAt
+	}
+
+	for _, item := range input.Items {
+		if _, _, err := services.ResolveVariant(db.DB, item.ProductID, item.VariantID); err != nil {
+			sendVariantError(c, err)
+			return
+		}
+		sub.Items = append(sub.Items, models.SubscriptionItem{ProductID: item.ProductID, VariantID: item.VariantID, Quantity: item.Quantity})
+	}
+
+	if err := db.DB.Create(&sub).Error; err != nil {
+		utils.SendInternalError(c, "Failed to create subscription")
+		return
+	}
+	if err := db.DB.Preload("Items.Product").Preload("Address").First(&sub, sub.ID).Error; err != nil {
+		utils.SendInternalError(c, "Failed to load subscription")
+		return
+	}
+
+	utils.SendSuccess(c, http.StatusCreated, "Subscription created successfully", sub)
+}
+
+// ListSubscriptions returns the authenticated user's subscriptions
+func ListSubscriptions(c *gin.Context) {
+	uid, err := Base.GetUserID(c)
+	if err != nil {
+		return
+	}
+
+	var subs []models.Subscription
+	pagination, ok := Base.FetchPage(c, db.DB.Model(&models.Subscription{}).Where("user_id = ?", uid), services.OrderByID("subscriptions"), &subs, "Failed to fetch subscriptions", func(page *gorm.DB) *gorm.DB {
+		return page.Preload("Items.Product").Preload("Address")
+	})
+	if !ok {
+		return
+	}
+
+	utils.SendPage(c, "Subscriptions retrieved successfully", subs, pagination)
+}
+
+// GetSubscription returns one of the authenticated user's subscriptions
+func GetSubscription(c *gin.Context) {
+	sub, ok := loadUserSubscription(c)
+	if !ok {
+		return
+	}
+	utils.SendSuccess(c, http.StatusOK, "Subscription retrieved successfully", sub)
+}
+
+// SkipSubscription skips the next delivery of a subscription
+func SkipSubscription(c *gin.Context) {
+	updateSubscription(c, "Subscription skipped successfully", func(s services.SubscriptionService, sub *models.Subscription) error {
+		return s.Skip(sub)
+	})
+}
+
+// PauseSubscription stops a subscription from placing orders until resumed
+func PauseSubscription(c *gin.Context) {
+	updateSubscription(c, "Subscription paused successfully", func(s services.SubscriptionService, sub *models.Subscription) error {
+		return s.Pause(sub)
+	})
+}
+
+// ResumeSubscription reactivates a paused subscription
+func ResumeSubscription(c *gin.Context) {
+	updateSubscription(c, "Subscription resumed successfully", func(s services.SubscriptionService, sub *models.Subscription) error {
+		return s.Resume(sub, time.Now())
+	})
+}
+
+// CancelSubscription permanently cancels a subscription
+func CancelSubscription(c *gin.Context) {
+	updateSubscription(c, "Subscription cancelled successfully", func(s services.SubscriptionService, sub *models.Subscription) error {
+		return s.Cancel(sub)
+	})
+}
+
+// RunDueSubscriptions processes all due subscriptions immediately (admin only)
+func RunDueSubscriptions(c *gin.Context) {
+	placed, err := services.NewSubscriptionService().ProcessDueSubscriptions(time.Now())
+	if err != nil {
+		utils.SendInternalError(c, "Failed to process subscriptions")
+		return
+	}
+
+	utils.SendSuccess(c, http.StatusOK, "Subscriptions processed successfully", gin.H{"orders_placed": placed})
+}
+
+// loadUserSubscription fetches the subscription in the :id param, scoped to the authenticated user
+func loadUserSubscription(c *gin.Context) (*models.Subscription, bool) {
+	uid, err := Base.GetUserID(c)
+	if err != nil {
+		return nil, false
+	}
+	id, err := Base.ValidateIDParam(c, "id")
+	if err != nil {
+		return nil, false
+	}
+
+	var sub models.Subscription
+	if err := db.DB.Preload("Items.Product").Preload("Address").
+		Where("id = ? AND user_id = ?", id, uid).First(&sub).Error; err != nil {
+		Base.HandleDBError(c, err, "Subscription not found", "Failed to fetch subscription")
+		return nil, false
+	}
+	return &sub, true
+}
+
+// updateSubscription applies a status action to the user's subscription and returns the result
+func updateSubscription(c *gin.Context, successMsg string, action func(services.SubscriptionService, *models.Subscription) error) {
+	sub, ok := loadUserSubscription(c)
+	if !ok {
+		return
+	}
+
+	if err := action(services.NewSubscriptionService(), sub); err != nil {
+		if errors.Is(err, services.ErrInvalidSubscriptionState) {
+			utils.SendConflict(c, "Action not allowed for a "+sub.Status+" subscription")
+			return
+		}
+		utils.SendInternalError(c, "Failed to update subscription")
+		return
+	}
+
+	utils.SendSuccess(c, http.StatusOK, successMsg, sub)
+}
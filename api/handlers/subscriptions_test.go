@@ -0,0 +1,139 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/geoo115/Ecommerce/db"
+	"github.com/geoo115/Ecommerce/models"
+	"github.com/geoo115/Ecommerce/services"
+	"github.com/geoo115/Ecommerce/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func setupSubscriptionRouter(userID uint) *gin.Engine {
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set("userID", userID)
+		c.Next()
+	})
+	router.POST("/subscriptions", CreateSubscription)
+	router.GET("/subscriptions", ListSubscriptions)
+	router.GET("/subscriptions/:id", GetSubscription)
+	router.PUT("/subscriptions/:id/skip", SkipSubscription)
+	router.PUT("/subscriptions/:id/pause", PauseSubscription)
+	router.PUT("/subscriptions/:id/resume", ResumeSubscription)
+	router.PUT("/subscriptions/:id/cancel", CancelSubscription)
+	router.POST("/admin/subscriptions/run", RunDueSubscriptions)
+	return router
+}
+
+func TestCreateSubscription(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	SetupTestDB(t)
+	utils.AppLogger = utils.NewLogger(utils.INFO)
+
+	user := CreateTestUser(t, db.DB, "subscriber")
+	other := CreateTestUser(t, db.DB, "other")
+	address := models.Address{UserID: user.ID, Address: "1 Main St", City: "Town", ZipCode: "12345"}
+	db.DB.Create(&address)
+	otherAddress := models.Address{UserID: other.ID, Address: "2 Side St", City: "Town", ZipCode: "12345"}
+	db.DB.Create(&otherAddress)
+	product := models.Product{Name: "Coffee Beans", Price: 9}
+	db.DB.Create(&product)
+
+	router := setupSubscriptionRouter(user.ID)
+
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+	}{
+		{"success", fmt.Sprintf(`{"items":[{"product_id":%d,"quantity":2}],"interval_days":14,"address_id":%d,"payment_method":"card"}`, product.ID, address.ID), http.StatusCreated},
+		{"foreign address", fmt.Sprintf(`{"items":[{"product_id":%d,"quantity":2}],"interval_days":14,"address_id":%d,"payment_method":"card"}`, product.ID, otherAddress.ID), http.StatusNotFound},
+		{"unknown product", fmt.Sprintf(`{"items":[{"product_id":9999,"quantity":1}],"interval_days":14,"address_id":%d,"payment_method":"card"}`, address.ID), http.StatusNotFound},
+		{"interval too long", fmt.Sprintf(`{"items":[{"product_id":%d,"quantity":1}],"interval_days":400,"address_id":%d,"payment_method":"card"}`, product.ID, address.ID), http.StatusBadRequest},
+		{"no items", fmt.Sprintf(`{"items":[],"interval_days":7,"address_id":%d,"payment_method":"card"}`, address.ID), http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("POST", "/subscriptions", bytes.NewBufferString(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			router.ServeHTTP(w, req)
+			assert.Equal(t, tt.wantStatus, w.Code)
+		})
+	}
+
+	var subs []models.Subscription
+	db.DB.Preload("Items").Find(&subs)
+	if assert.Len(t, subs, 1) {
+		assert.Equal(t, services.SubscriptionActive, subs[0].Status)
+		assert.Len(t, subs[0].Items, 1)
+	}
+}
+
+func TestSubscriptionLifecycle(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	SetupTestDB(t)
+	utils.AppLogger = utils.NewLogger(utils.INFO)
+
+	user := CreateTestUser(t, db.DB, "lifecycle")
+	address := models.Address{UserID: user.ID, Address: "1 Main St", City: "Town", ZipCode: "12345"}
+	db.DB.Create(&address)
+	product := models.Product{Name: "Filters", Price: 4}
+	db.DB.Create(&product)
+	db.DB.Create(&models.Inventory{ProductID: product.ID, Stock: 10})
+	sub := models.Subscription{
+		UserID:        user.ID,
+		AddressID:     address.ID,
+		IntervalDays:  30,
+		NextRunAt:     time.Now().Add(-time.Minute),
+		Status:        services.SubscriptionActive,
+		PaymentMethod: "card",
+		Items:         []models.SubscriptionItem{{ProductID: product.ID, Quantity: 3}},
+	}
+	db.DB.Create(&sub)
+
+	router := setupSubscriptionRouter(user.ID)
+	do := func(method, path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, path, nil)
+		router.ServeHTTP(w, req)
+		return w
+	}
+	base := fmt.Sprintf("/subscriptions/%d", sub.ID)
+
+	assert.Equal(t, http.StatusOK, do("GET", base).Code)
+	assert.Equal(t, http.StatusOK, do("GET", "/subscriptions").Code)
+
+	w := do("POST", "/admin/subscriptions/run")
+	assert.Equal(t, http.StatusOK, w.Code)
+	var response struct {
+		Data struct {
+			OrdersPlaced int `json:"orders_placed"`
+		} `json:"data"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &response)
+	assert.Equal(t, 1, response.Data.OrdersPlaced)
+
+	var order models.Order
+	assert.NoError(t, db.DB.Where("subscription_id = ?", sub.ID).First(&order).Error)
+	assert.Equal(t, "Paid", order.Status)
+	assert.Equal(t, 12.0, order.TotalAmount)
+
+	assert.Equal(t, http.StatusOK, do("PUT", base+"/skip").Code)
+	assert.Equal(t, http.StatusOK, do("PUT", base+"/pause").Code)
+	assert.Equal(t, http.StatusConflict, do("PUT", base+"/skip").Code)
+	assert.Equal(t, http.StatusOK, do("PUT", base+"/resume").Code)
+	assert.Equal(t, http.StatusOK, do("PUT", base+"/cancel").Code)
+	assert.Equal(t, http.StatusConflict, do("PUT", base+"/resume").Code)
+	assert.Equal(t, http.StatusNotFound, do("PUT", "/subscriptions/9999/pause").Code)
+}
@@ -8,6 +8,7 @@ import (
 
 	"github.com/geoo115/Ecommerce/db"
 	"github.com/geoo115/Ecommerce/models"
+	"github.com/geoo115/Ecommerce/services"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 )
@@ -76,10 +77,47 @@ func SetupTestDB(tb testing.TB) *gorm.DB {
 		&models.Review{},
 		&models.Wishlist{},
 		&models.Payment{},
+		&models.LoyaltyTransaction{},
+		&models.LoyaltyEarnRate{},
+		&models.Subscription{},
+		&models.SubscriptionItem{},
+		&models.Fulfillment{},
+		&models.FulfillmentItem{},
+		&models.CheckoutSession{},
+		&models.CheckoutSessionItem{},
+		&models.OrderRevision{},
+		&models.OrderItemCancellation{},
+		&models.ProductOption{},
+		&models.ProductOptionValue{},
+		&models.ProductVariant{},
+		&models.ProductImage{},
+		&models.ProductImageThumbnail{},
+		&models.Attribute{},
+		&models.AttributeOption{},
+		&models.CategoryAttribute{},
+		&models.ProductAttributeValue{},
+		&models.SynonymGroup{},
+		&models.SlugRedirect{},
+		&models.PriceSchedule{},
+		&models.PriceHistory{},
+		&models.BundleItem{},
+		&models.OrderItemComponent{},
+		&models.ProductRecommendation{},
+		&models.ProductTag{},
+		&models.Collection{},
+		&models.CollectionRule{},
+		&models.CollectionProduct{},
+		&models.DigitalFile{},
+		&models.LicenceKey{},
+		&models.DigitalDownload{},
 	)
 	if err != nil {
 		tb.Fatalf("auto migrate failed: %v", err)
 	}
+	if err := db.EnsureSearchIndex(testDB); err != nil {
+		tb.Fatalf("search index setup failed: %v", err)
+	}
+	services.InvalidateSearchIndex()
 
 	db.DB = testDB
 
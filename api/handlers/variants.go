@@ -0,0 +1,185 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/geoo115/Ecommerce/db"
+	"github.com/geoo115/Ecommerce/models"
+	"github.com/geoo115/Ecommerce/services"
+	"github.com/geoo115/Ecommerce/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// ListProductVariants returns a product's option types and variants
+func ListProductVariants(c *gin.Context) {
+	productID, err := Base.ValidateIDParam(c, "id")
+	if err != nil {
+		return
+	}
+
+	var product models.Product
+	if err := db.DB.Scopes(services.PublishedProducts).Preload("Options.Values").Preload("Variants.OptionValues").
+		First(&product, productID).Error; err != nil {
+		Base.HandleDBError(c, err, "Product not found", "Failed to fetch product")
+		return
+	}
+
+	utils.SendSuccess(c, http.StatusOK, "Product variants retrieved successfully", gin.H{
+		"options":  product.Options,
+		"variants": product.Variants,
+	})
+}
+
+// CreateProductVariant adds a variant with its own SKU, price and stock to a product (admin only)
+func CreateProductVariant(c *gin.Context) {
+	productID, err := Base.ValidateIDParam(c, "id")
+	if err != nil {
+		return
+	}
+
+	var input struct {
+		SKU     string                   `json:"sku" binding:"required,max=64"`
+		Barcode string                   `json:"barcode" binding:"max=64"`
+		Price   *float64                 `json:"price"`
+		Stock   int                      `json:"stock"`
+		Options []services.VariantOption `json:"options" binding:"required,min=1"`
+	}
+	if err := Base.BindJSON(c, &input); err != nil {
+		return
+	}
+	if input.Price != nil && !utils.ValidatePrice(*input.Price) {
+		utils.SendValidationError(c, "Price must be greater than 0 and less than 999999.99")
+		return
+	}
+	if !utils.ValidateStock(input.Stock) {
+		utils.SendValidationError(c, "Stock must be between 0 and 100000")
+		return
+	}
+
+	var product models.Product
+	if err := db.DB.First(&product, productID).Error; err != nil {
+		Base.HandleDBError(c, err, "Product not found", "Failed to fetch product")
+		return
+	}
+
+	variant := models.ProductVariant{
+		SKU:           input.SKU,
+		Barcode:       utils.SanitizeString(input.Barcode),
+		PriceOverride: input.Price,
+		Stock:         input.Stock,
+	}
+	if err := services.NewVariantService().CreateVariant(&product, &variant, input.Options); err != nil {
+		sendVariantError(c, err)
+		return
+	}
+	invalidateProductCache(product.ID)
+
+	utils.SendSuccess(c, http.StatusCreated, "Variant created successfully", variant)
+}
+
+// UpdateProductVariant changes a variant's SKU, barcode, price override or stock (admin only).
+// Sending "clear_price": true removes the override so the product price applies.
+func UpdateProductVariant(c *gin.Context) {
+	variant, ok := loadProductVariant(c)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		SKU        *string  `json:"sku" binding:"omitempty,max=64"`
+		Barcode    *string  `json:"barcode" binding:"omitempty,max=64"`
+		Price      *float64 `json:"price"`
+		ClearPrice bool     `json:"clear_price"`
+		Stock      *int     `json:"stock"`
+	}
+	if err := Base.BindJSON(c, &input); err != nil {
+		return
+	}
+	if input.Price != nil && !utils.ValidatePrice(*input.Price) {
+		utils.SendValidationError(c, "Price must be greater than 0 and less than 999999.99")
+		return
+	}
+	if input.Stock != nil && !utils.ValidateStock(*input.Stock) {
+		utils.SendValidationError(c, "Stock must be between 0 and 100000")
+		return
+	}
+
+	update := services.VariantUpdate{
+		SKU:           input.SKU,
+		Barcode:       input.Barcode,
+		PriceOverride: input.Price,
+		ClearPrice:    input.ClearPrice,
+		Stock:         input.Stock,
+	}
+	if err := services.NewVariantService().UpdateVariant(variant, update); err != nil {
+		sendVariantError(c, err)
+		return
+	}
+	invalidateProductCache(variant.ProductID)
+
+	utils.SendSuccess(c, http.StatusOK, "Variant updated successfully", variant)
+}
+
+// DeleteProductVariant removes a variant from sale (admin only)
+func DeleteProductVariant(c *gin.Context) {
+	variant, ok := loadProductVariant(c)
+	if !ok {
+		return
+	}
+
+	if err := services.NewVariantService().DeleteVariant(variant); err != nil {
+		utils.SendInternalError(c, "Failed to delete variant")
+		return
+	}
+	invalidateProductCache(variant.ProductID)
+
+	utils.SendSuccess(c, http.StatusOK, "Variant deleted successfully", nil)
+}
+
+// loadProductVariant fetches the variant in the :variant_id param, scoped to the product in :id
+func loadProductVariant(c *gin.Context) (*models.ProductVariant, bool) {
+	productID, err := Base.ValidateIDParam(c, "id")
+	if err != nil {
+		return nil, false
+	}
+	variantID, err := Base.ValidateIDParam(c, "variant_id")
+	if err != nil {
+		return nil, false
+	}
+
+	var variant models.ProductVariant
+	if err := db.DB.Preload("OptionValues").Where("id = ? AND product_id = ?", variantID, productID).First(&variant).Error; err != nil {
+		Base.HandleDBError(c, err, "Variant not found", "Failed to fetch variant")
+		return nil, false
+	}
+	return &variant, true
+}
+
+// sendVariantError maps product and variant errors to responses
+func sendVariantError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrProductNotFound):
+		utils.SendNotFound(c, "Product not found")
+	case errors.Is(err, services.ErrVariantNotFound):
+		utils.SendNotFound(c, "Variant not found")
+	case errors.Is(err, services.ErrVariantRequired):
+		utils.SendValidationError(c, "A variant must be selected for this product")
+	case errors.Is(err, services.ErrVariantOptions):
+		utils.SendValidationError(c, "Variant must set one value for each of the product's options")
+	case errors.Is(err, services.ErrBundleVariants):
+		utils.SendValidationError(c, "Bundles and their components cannot have variants")
+	case errors.Is(err, services.ErrDigitalConflict):
+		utils.SendValidationError(c, "Digital products cannot have variants")
+	case errors.Is(err, services.ErrSKURequired):
+		utils.SendValidationError(c, "SKU is required")
+	case errors.Is(err, services.ErrNoChanges):
+		utils.SendValidationError(c, "Update contains no changes")
+	case errors.Is(err, services.ErrDuplicateVariant):
+		utils.SendConflict(c, "A variant with these options already exists")
+	case errors.Is(err, services.ErrDuplicateSKU):
+		utils.SendConflict(c, "SKU is already in use")
+	default:
+		utils.SendInternalError(c, "Failed to save variant")
+	}
+}
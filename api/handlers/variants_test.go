@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/geoo115/Ecommerce/db"
+	"github.com/geoo115/Ecommerce/models"
+	"github.com/geoo115/Ecommerce/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProductVariantEndpoints(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	SetupTestDB(t)
+	utils.AppLogger = utils.NewLogger(utils.INFO)
+
+	product := models.Product{Name: "Sneaker", Price: 60}
+	db.DB.Create(&product)
+	db.DB.Create(&models.Inventory{ProductID: product.ID, Stock: 0})
+
+	router := gin.New()
+	router.GET("/product/:id/variants", ListProductVariants)
+	router.POST("/product/:id/variants", CreateProductVariant)
+	router.PUT("/product/:id/variants/:variant_id", UpdateProductVariant)
+	router.DELETE("/product/:id/variants/:variant_id", DeleteProductVariant)
+
+	send := func(method, path, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, path, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+		return w
+	}
+	base := fmt.Sprintf("/product/%d/variants", product.ID)
+
+	w := send("POST", base, `{"sku":"SN-42","stock":5,"price":65,"options":[{"name":"Size","value":"42"}]}`)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	var created struct {
+		Data models.ProductVariant `json:"data"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &created)
+	assert.Equal(t, "42", created.Data.Title)
+
+	w = send("POST", base, `{"sku":"SN-42","stock":5,"options":[{"name":"Size","value":"43"}]}`)
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	w = send("PUT", fmt.Sprintf("%s/%d", base, created.Data.ID), `{"stock":8,"clear_price":true}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var updated models.ProductVariant
+	db.DB.First(&updated, created.Data.ID)
+	assert.Equal(t, 8, updated.Stock)
+	assert.Nil(t, updated.PriceOverride)
+
+	w = send("GET", base, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	var listed struct {
+		Data struct {
+			Options  []models.ProductOption  `json:"options"`
+			Variants []models.ProductVariant `json:"variants"`
+		} `json:"data"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &listed)
+	assert.Len(t, listed.Data.Options, 1)
+	assert.Len(t, listed.Data.Variants, 1)
+
+	w = send("DELETE", fmt.Sprintf("/product/%d/variants/%d", product.ID+1, created.Data.ID), "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = send("DELETE", fmt.Sprintf("%s/%d", base, created.Data.ID), "")
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestAddToCart_Variants(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	SetupTestDB(t)
+	utils.AppLogger = utils.NewLogger(utils.INFO)
+
+	user := CreateTestUser(t, db.DB, "variantcart")
+	product := models.Product{Name: "Jacket", Price: 80}
+	db.DB.Create(&product)
+	db.DB.Create(&models.Inventory{ProductID: product.ID, Stock: 50})
+	price := 90.0
+	small := models.ProductVariant{ProductID: product.ID, SKU: "JK-S", Title: "S", Stock: 2, PriceOverride: &price}
+	db.DB.Create(&small)
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) { c.Set("userID", user.ID) })
+	router.POST("/cart", AddToCart)
+	router.GET("/cart", ListCart)
+
+	add := func(body string) int {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/cart", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	// A variant must be chosen, and its own stock applies rather than the product's
+	assert.Equal(t, http.StatusBadRequest, add(fmt.Sprintf(`{"product_id":%d,"quantity":1}`, product.ID)))
+	assert.Equal(t, http.StatusBadRequest, add(fmt.Sprintf(`{"product_id":%d,"variant_id":%d,"quantity":3}`, product.ID, small.ID)))
+	assert.Equal(t, http.StatusNotFound, add(fmt.Sprintf(`{"product_id":%d,"variant_id":%d,"quantity":1}`, product.ID, small.ID+100)))
+	assert.Equal(t, http.StatusCreated, add(fmt.Sprintf(`{"product_id":%d,"variant_id":%d,"quantity":2}`, product.ID, small.ID)))
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/cart", nil)
+	router.ServeHTTP(w, req)
+	var response struct {
+		Data struct {
+			TotalAmount float64 `json:"total_amount"`
+		} `json:"data"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &response)
+	assert.Equal(t, 180.0, response.Data.TotalAmount)
+}
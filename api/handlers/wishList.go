@@ -5,8 +5,10 @@ import (
 
 	"github.com/geoo115/Ecommerce/db"
 	"github.com/geoo115/Ecommerce/models"
+	"github.com/geoo115/Ecommerce/services"
 	"github.com/geoo115/Ecommerce/utils"
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 // AddToWishlist adds a product to the user's wishlist
@@ -69,21 +71,19 @@ func ListWishlist(c *gin.Context) {
 	}
 
 	var wishlist []models.Wishlist
-	if err := db.DB.Where("user_id = ?", userID).
-		Preload("Product.Category").
-		Preload("Product.Inventory").
-		Preload("User").
-		Find(&wishlist).Error; err != nil {
-		utils.SendInternalError(c, "Failed to fetch wishlist")
+	pagination, ok := Base.FetchPage(c, db.DB.Model(&models.Wishlist{}).Where("user_id = ?", userID), services.NewestFirst("wishlists"), &wishlist, "Failed to fetch wishlist", func(page *gorm.DB) *gorm.DB {
+		return page.Preload("Product.Category").Preload("Product.Inventory").Preload("User")
+	})
+	if !ok {
 		return
 	}
 
-	if len(wishlist) == 0 {
+	if len(wishlist) == 0 && pagination.PrevCursor == "" {
 		utils.SendNotFound(c, "No items in wishlist")
 		return
 	}
 
-	utils.SendSuccess(c, http.StatusOK, "Wishlist retrieved successfully", wishlist)
+	utils.SendPage(c, "Wishlist retrieved successfully", wishlist, pagination)
 }
 
 // RemoveFromWishlist removes a product from the user's wishlist
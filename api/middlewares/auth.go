@@ -36,3 +36,19 @@ func AuthMiddleware() gin.HandlerFunc {
 		c.Next()
 	}
 }
+
+// OptionalAuthMiddleware sets the user ID and role for requests with a valid bearer token and
+// lets every other request through anonymously, so public pages can still personalise for
+// signed-in users. An expired or invalid token is treated as no token.
+func OptionalAuthMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if token != "" {
+			if claims, err := utils.ValidateToken(token); err == nil {
+				c.Set("userID", claims.UserID)
+				c.Set("userRole", claims.Role)
+			}
+		}
+		c.Next()
+	}
+}
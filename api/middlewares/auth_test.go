@@ -3,8 +3,11 @@ package middlewares
 import (
 	"net/http"
 	"net/http/httptest"
+	"os"
 	"testing"
 
+	"github.com/geoo115/Ecommerce/models"
+	"github.com/geoo115/Ecommerce/utils"
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
 )
@@ -39,3 +42,26 @@ func TestAuthMiddleware_NoToken(t *testing.T) {
 	assert.Equal(t, http.StatusUnauthorized, w.Code)
 	assert.Contains(t, w.Body.String(), "Token is required")
 }
+
+func TestOptionalAuthMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	os.Setenv("JWT_SECRET", "test_secret_key")
+
+	user := models.User{Username: "browser", Email: "browser@example.com", Role: "admin"}
+	user.ID = 12
+	token, _ := utils.GenerateToken(user)
+
+	for header, expected := range map[string]uint{"": 0, "Bearer not-a-token": 0, "Bearer " + token: 12} {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = &http.Request{Header: http.Header{"Authorization": []string{header}}}
+
+		OptionalAuthMiddleware()(c)
+
+		assert.False(t, c.IsAborted())
+		assert.Equal(t, expected, c.GetUint("userID"))
+		if expected != 0 {
+			assert.Equal(t, "admin", c.GetString("userRole"))
+		}
+	}
+}
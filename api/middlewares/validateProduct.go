@@ -3,6 +3,7 @@ package middlewares
 import (
 	"fmt"
 	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
@@ -13,6 +14,10 @@ type ProductInput struct {
 	CategoryID  uint    `json:"category_id" binding:"required"`
 	Description string  `json:"description" binding:"required"`
 	Stock       int     `json:"stock" binding:"required,gte=0"`
+	// Optional publishing workflow; products are published straight away by default
+	Status      string     `json:"status"`
+	PublishAt   *time.Time `json:"publish_at"`
+	UnpublishAt *time.Time `json:"unpublish_at"`
 }
 
 func ValidateProduct() gin.HandlerFunc {
@@ -1,8 +1,11 @@
 package api
 
 import (
+	"strings"
+
 	"github.com/geoo115/Ecommerce/api/handlers"
 	"github.com/geoo115/Ecommerce/api/middlewares"
+	"github.com/geoo115/Ecommerce/config"
 	"github.com/gin-gonic/gin"
 )
 
@@ -31,17 +34,77 @@ func SetupRoutes(r *gin.Engine) {
 	{
 		adminGroup.GET("/reports/sales", handlers.SalesReport)
 		adminGroup.GET("/reports/inventory", handlers.InventoryReport)
+
+		adminGroup.GET("/orders/lookup/:ref", handlers.LookupOrder)
+		adminGroup.POST("/orders/:id/refund", handlers.RefundOrder)
+		adminGroup.POST("/orders/:id/items/cancel", handlers.AdminCancelOrderItems)
+		adminGroup.POST("/orders/:id/fulfillments", handlers.CreateFulfillment)
+		adminGroup.PUT("/fulfillments/:id/status", handlers.UpdateFulfillmentStatus)
+
+		adminGroup.GET("/loyalty/users/:id", handlers.GetUserLoyalty)
+		adminGroup.POST("/loyalty/users/:id/adjust", handlers.AdjustLoyaltyPoints)
+		adminGroup.GET("/loyalty/earn-rates", handlers.ListLoyaltyEarnRates)
+		adminGroup.PUT("/loyalty/earn-rates/:category_id", handlers.SetLoyaltyEarnRate)
+
+		adminGroup.POST("/subscriptions/run", handlers.RunDueSubscriptions)
+
+		adminGroup.GET("/products", handlers.ListAdminProducts)
+
+		adminGroup.POST("/collections", handlers.CreateCollection)
+		adminGroup.GET("/collections/:id", handlers.GetAdminCollection)
+		adminGroup.PUT("/collections/:id", handlers.UpdateCollection)
+		adminGroup.DELETE("/collections/:id", handlers.DeleteCollection)
+	}
+
+	// Uploaded media served from the local blob store
+	if media := config.GetMediaConfig(); strings.HasPrefix(media.BaseURL, "/") {
+		r.Static(media.BaseURL, media.StorageDir)
 	}
 
 	// Categories routes
 	r.GET("/categories", handlers.ListCategories)
+	r.GET("/categories/tree", handlers.CategoryTree)
+	r.GET("/categories/slug/:slug", handlers.GetCategoryBySlug)
 	r.POST("/categories", middlewares.AdminMiddleware(), handlers.AddCategory)
+	r.PUT("/categories/:id", middlewares.AdminMiddleware(), handlers.UpdateCategory)
 	r.DELETE("/categories/:id", middlewares.AdminMiddleware(), handlers.DeleteCategory)
+	r.GET("/categories/:id/attributes", handlers.ListCategoryAttributes)
+	r.POST("/categories/:id/attributes", middlewares.AdminMiddleware(), handlers.AssignCategoryAttribute)
+	r.DELETE("/categories/:id/attributes/:attribute_id", middlewares.AdminMiddleware(), handlers.UnassignCategoryAttribute)
+
+	// Attribute routes
+	r.GET("/attributes", handlers.ListAttributes)
+	attributeAdminGroup := r.Group("/attributes")
+	attributeAdminGroup.Use(middlewares.AdminMiddleware())
+	{
+		attributeAdminGroup.POST("", handlers.CreateAttribute)
+		attributeAdminGroup.PUT("/:id", handlers.UpdateAttribute)
+		attributeAdminGroup.DELETE("/:id", handlers.DeleteAttribute)
+	}
 
 	// Product routes
 	r.GET("/products", handlers.ListProducts)
-	r.GET("/product/:id", handlers.GetProduct)
+	r.GET("/product/:id", middlewares.OptionalAuthMiddleware(), handlers.GetProduct)
+	r.GET("/products/slug/:slug", middlewares.OptionalAuthMiddleware(), handlers.GetProductBySlug)
+	r.GET("/product/:id/variants", handlers.ListProductVariants)
+	r.GET("/product/:id/related", handlers.GetRelatedProducts)
 	r.GET("/products/search", handlers.SearchProducts)
+	r.GET("/products/suggest", handlers.SuggestProducts)
+
+	// Collection routes
+	r.GET("/collections", handlers.ListCollections)
+	r.GET("/collections/:slug", handlers.GetCollection)
+	r.GET("/collections/:slug/products", handlers.ListCollectionProducts)
+
+	// Search synonym routes
+	synonymAdminGroup := r.Group("/synonyms")
+	synonymAdminGroup.Use(middlewares.AdminMiddleware())
+	{
+		synonymAdminGroup.GET("", handlers.ListSynonyms)
+		synonymAdminGroup.POST("", handlers.CreateSynonyms)
+		synonymAdminGroup.PUT("/:id", handlers.UpdateSynonyms)
+		synonymAdminGroup.DELETE("/:id", handlers.DeleteSynonyms)
+	}
 
 	// Admin product routes
 	productAdminGroup := r.Group("/product")
@@ -49,8 +112,29 @@ func SetupRoutes(r *gin.Engine) {
 	productAdminGroup.Use(middlewares.AdminRateLimit())
 	{
 		productAdminGroup.POST("", middlewares.ValidateProduct(), handlers.AddProduct)
+		productAdminGroup.POST("/import", handlers.ImportCatalog)
+		productAdminGroup.GET("/export", handlers.ExportCatalog)
 		productAdminGroup.PUT("/:id", handlers.EditProduct)
 		productAdminGroup.DELETE("/:id", handlers.DeleteProduct)
+		productAdminGroup.POST("/:id/variants", handlers.CreateProductVariant)
+		productAdminGroup.PUT("/:id/variants/:variant_id", handlers.UpdateProductVariant)
+		productAdminGroup.DELETE("/:id/variants/:variant_id", handlers.DeleteProductVariant)
+		productAdminGroup.POST("/:id/images", handlers.UploadProductImage)
+		productAdminGroup.PUT("/:id/images/:image_id", handlers.UpdateProductImage)
+		productAdminGroup.DELETE("/:id/images/:image_id", handlers.DeleteProductImage)
+		productAdminGroup.PUT("/:id/attributes", handlers.SetProductAttributes)
+		productAdminGroup.PUT("/:id/bundle", handlers.SetProductBundle)
+		productAdminGroup.PUT("/:id/publishing", handlers.SetProductPublishing)
+		productAdminGroup.PUT("/:id/tags", handlers.SetProductTags)
+		productAdminGroup.PUT("/:id/type", handlers.SetProductType)
+		productAdminGroup.GET("/:id/files", handlers.ListDigitalFiles)
+		productAdminGroup.POST("/:id/files", handlers.UploadDigitalFile)
+		productAdminGroup.DELETE("/:id/files/:file_id", handlers.DeleteDigitalFile)
+		productAdminGroup.GET("/:id/licence-keys", handlers.GetLicencePool)
+		productAdminGroup.POST("/:id/licence-keys", handlers.AddLicenceKeys)
+		productAdminGroup.GET("/:id/prices", handlers.GetPriceTimeline)
+		productAdminGroup.POST("/:id/price-schedules", handlers.CreatePriceSchedule)
+		productAdminGroup.DELETE("/:id/price-schedules/:schedule_id", handlers.CancelPriceSchedule)
 	}
 
 	// Order routes
@@ -61,17 +145,34 @@ func SetupRoutes(r *gin.Engine) {
 		orderGroup.GET("", handlers.ListOrders)
 		orderGroup.GET("/:id", handlers.GetOrder)
 		orderGroup.PUT("/:id/cancel", handlers.CancelOrder)
+		orderGroup.PUT("/:id/amend", handlers.AmendOrder)
+		orderGroup.PUT("/:id/items/cancel", handlers.CancelOrderItems)
+		orderGroup.POST("/:id/reorder", handlers.ReorderOrder)
+		orderGroup.GET("/:id/revisions", handlers.ListOrderRevisions)
+		orderGroup.GET("/:id/downloads", handlers.ListOrderDownloads)
 	}
 
+	// Signed download links carry their own credentials
+	r.GET("/downloads/:item_id/:file_id", handlers.DownloadDigitalFile)
+
 	// Cart routes
 	cartGroup := r.Group("/cart")
 	cartGroup.Use(middlewares.AuthMiddleware())
 	{
 		cartGroup.POST("", handlers.AddToCart)
 		cartGroup.GET("", handlers.ListCart)
+		cartGroup.GET("/suggestions", handlers.GetCartSuggestions)
 		cartGroup.DELETE("/:id", handlers.RemoveFromCart)
 	}
 
+	// Recently viewed routes, for signed-in users and guests sending X-Guest-Token
+	recentlyViewedGroup := r.Group("/recently-viewed")
+	recentlyViewedGroup.Use(middlewares.OptionalAuthMiddleware())
+	{
+		recentlyViewedGroup.GET("", handlers.GetRecentlyViewed)
+		recentlyViewedGroup.DELETE("", handlers.ClearRecentlyViewed)
+	}
+
 	// Address routes
 	addressGroup := r.Group("/address")
 	addressGroup.Use(middlewares.AuthMiddleware())
@@ -105,6 +206,40 @@ func SetupRoutes(r *gin.Engine) {
 		paymentGroup.GET("/:order_id", handlers.GetPaymentStatus)
 	}
 
+	// Loyalty routes
+	loyaltyGroup := r.Group("/loyalty")
+	loyaltyGroup.Use(middlewares.AuthMiddleware())
+	{
+		loyaltyGroup.GET("", handlers.GetLoyaltySummary)
+		loyaltyGroup.GET("/history", handlers.ListLoyaltyHistory)
+	}
+
+	// Subscription routes
+	subscriptionGroup := r.Group("/subscriptions")
+	subscriptionGroup.Use(middlewares.AuthMiddleware())
+	{
+		subscriptionGroup.POST("", handlers.CreateSubscription)
+		subscriptionGroup.GET("", handlers.ListSubscriptions)
+		subscriptionGroup.GET("/:id", handlers.GetSubscription)
+		subscriptionGroup.PUT("/:id/skip", handlers.SkipSubscription)
+		subscriptionGroup.PUT("/:id/pause", handlers.PauseSubscription)
+		subscriptionGroup.PUT("/:id/resume", handlers.ResumeSubscription)
+		subscriptionGroup.PUT("/:id/cancel", handlers.CancelSubscription)
+	}
+
 	// Checkout route
 	r.POST("/checkout", middlewares.AuthMiddleware(), handlers.Checkout)
+
+	// Multi-step checkout session routes
+	checkoutGroup := r.Group("/checkout")
+	checkoutGroup.Use(middlewares.AuthMiddleware())
+	{
+		checkoutGroup.GET("/delivery-methods", handlers.ListDeliveryMethods)
+		checkoutGroup.POST("/sessions", handlers.StartCheckoutSession)
+		checkoutGroup.GET("/sessions/:id", handlers.GetCheckoutSession)
+		checkoutGroup.PUT("/sessions/:id/address", handlers.SetCheckoutAddress)
+		checkoutGroup.PUT("/sessions/:id/delivery", handlers.SetCheckoutDelivery)
+		checkoutGroup.PUT("/sessions/:id/review", handlers.ReviewCheckoutSession)
+		checkoutGroup.POST("/sessions/:id/pay", handlers.PayCheckoutSession)
+	}
 }
@@ -18,6 +18,7 @@ import (
 // InMemoryCache provides simple in-memory caching
 type InMemoryCache struct {
 	data  map[string]cacheItem
+	views map[string]recentViews // Recently viewed products, kept apart from cached data so Clear keeps them
 	mutex sync.RWMutex
 	ttl   time.Duration
 }
@@ -27,6 +28,12 @@ type cacheItem struct {
 	expiration time.Time
 }
 
+// recentViews is the in-memory equivalent of a recently viewed sorted set
+type recentViews struct {
+	productIDs []uint // Most recent first
+	expiration time.Time
+}
+
 // NewInMemoryCache creates a new in-memory cache
 func NewInMemoryCache() *InMemoryCache {
 	ttl := 30 * time.Minute // Default TTL
@@ -37,8 +44,9 @@ func NewInMemoryCache() *InMemoryCache {
 	}
 
 	cache := &InMemoryCache{
-		data: make(map[string]cacheItem),
-		ttl:  ttl,
+		data:  make(map[string]cacheItem),
+		views: make(map[string]recentViews),
+		ttl:   ttl,
 	}
 
 	// Start cleanup goroutine
@@ -160,6 +168,11 @@ func (c *InMemoryCache) cleanup() {
 				delete(c.data, key)
 			}
 		}
+		for owner, views := range c.views {
+			if time.Now().After(views.expiration) {
+				delete(c.views, owner)
+			}
+		}
 		c.mutex.Unlock()
 	}
 }
@@ -277,6 +290,60 @@ func (c *InMemoryCache) InvalidateProductCache(productID uint) error {
 	return nil
 }
 
+// AddRecentlyViewed moves a product to the front of an owner's recently viewed products,
+// keeping at most limit distinct products for ttl after the last view
+func (c *InMemoryCache) AddRecentlyViewed(owner string, productID uint, limit int, ttl time.Duration) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.views == nil {
+		c.views = make(map[string]recentViews)
+	}
+	productIDs := []uint{productID}
+	if views, ok := c.views[owner]; ok && time.Now().Before(views.expiration) {
+		for _, id := range views.productIDs {
+			if id != productID && len(productIDs) < limit {
+				productIDs = append(productIDs, id)
+			}
+		}
+	}
+	c.views[owner] = recentViews{productIDs: productIDs, expiration: time.Now().Add(ttl)}
+	return nil
+}
+
+// GetRecentlyViewed returns an owner's recently viewed product IDs, most recent first
+func (c *InMemoryCache) GetRecentlyViewed(owner string) ([]uint, error) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	views, ok := c.views[owner]
+	if !ok || time.Now().After(views.expiration) {
+		return []uint{}, nil
+	}
+	return append([]uint{}, views.productIDs...), nil
+}
+
+// ClearRecentlyViewed forgets an owner's recently viewed products
+func (c *InMemoryCache) ClearRecentlyViewed(owner string) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	delete(c.views, owner)
+	return nil
+}
+
+// InvalidateRecommendationCache drops cached related products and basket suggestions
+func (c *InMemoryCache) InvalidateRecommendationCache() error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	for key := range c.data {
+		if strings.HasPrefix(key, RecommendationKeyPrefix) {
+			delete(c.data, key)
+		}
+	}
+	return nil
+}
+
 // GetStats returns Redis cache statistics
 func (c *RedisCache) GetStats() map[string]interface{} {
 	info, err := c.client.Info(c.ctx, "memory", "stats").Result()
@@ -312,6 +379,55 @@ func (c *RedisCache) InvalidateProductCache(productID uint) error {
 	return nil
 }
 
+// InvalidateRecommendationCache drops cached related products and basket suggestions from Redis
+func (c *RedisCache) InvalidateRecommendationCache() error {
+	keys, err := c.client.Keys(c.ctx, RecommendationKeyPrefix+"*").Result()
+	if err != nil {
+		return err
+	}
+	if len(keys) > 0 {
+		return c.client.Del(c.ctx, keys...).Err()
+	}
+	return nil
+}
+
+// recentlyViewedKey is the sorted set of an owner's viewed products, scored by view time
+func recentlyViewedKey(owner string) string {
+	return "recently_viewed:" + owner
+}
+
+// AddRecentlyViewed records a product view in the owner's sorted set and trims it to the
+// limit most recent distinct products, expiring it ttl after the last view
+func (c *RedisCache) AddRecentlyViewed(owner string, productID uint, limit int, ttl time.Duration) error {
+	key := recentlyViewedKey(owner)
+	pipe := c.client.TxPipeline()
+	pipe.ZAdd(c.ctx, key, &redis.Z{Score: float64(time.Now().UnixNano()), Member: productID})
+	pipe.ZRemRangeByRank(c.ctx, key, 0, int64(-limit-1))
+	pipe.Expire(c.ctx, key, ttl)
+	_, err := pipe.Exec(c.ctx)
+	return err
+}
+
+// GetRecentlyViewed returns an owner's recently viewed product IDs, most recent first
+func (c *RedisCache) GetRecentlyViewed(owner string) ([]uint, error) {
+	members, err := c.client.ZRevRange(c.ctx, recentlyViewedKey(owner), 0, -1).Result()
+	if err != nil {
+		return nil, err
+	}
+	productIDs := make([]uint, 0, len(members))
+	for _, member := range members {
+		if id, err := strconv.ParseUint(member, 10, 64); err == nil {
+			productIDs = append(productIDs, uint(id))
+		}
+	}
+	return productIDs, nil
+}
+
+// ClearRecentlyViewed forgets an owner's recently viewed products
+func (c *RedisCache) ClearRecentlyViewed(owner string) error {
+	return c.client.Del(c.ctx, recentlyViewedKey(owner)).Err()
+}
+
 // RedisCache provides Redis-based caching with fallback to in-memory
 type RedisCache struct {
 	client *redis.Client
@@ -517,8 +633,18 @@ type Cache interface {
 	SetCart(userID uint, cart []models.Cart) error
 	InvalidateUserCache(userID uint) error
 	InvalidateProductCache(productID uint) error
+	InvalidateRecommendationCache() error
+
+	// Recently viewed products, per signed-in user or guest
+	AddRecentlyViewed(owner string, productID uint, limit int, ttl time.Duration) error
+	GetRecentlyViewed(owner string) ([]uint, error)
+	ClearRecentlyViewed(owner string) error
 }
 
+// RecommendationKeyPrefix starts the keys of cached recommendations. It falls under the "products:"
+// keys product changes invalidate, so edited and deleted products drop out of cached recommendations.
+const RecommendationKeyPrefix = "products:recommendations:"
+
 // Global cache instance
 var GlobalCache Cache
 
@@ -343,6 +343,30 @@ func TestInMemoryCache_TTLExpiration(t *testing.T) {
 	assert.Error(t, err)
 }
 
+func TestInMemoryCache_RecentlyViewed(t *testing.T) {
+	cache := NewInMemoryCache()
+
+	for _, id := range []uint{1, 2, 3, 2, 4} {
+		assert.NoError(t, cache.AddRecentlyViewed("account:7", id, 3, time.Hour))
+	}
+	viewed, err := cache.GetRecentlyViewed("account:7")
+	assert.NoError(t, err)
+	assert.Equal(t, []uint{4, 2, 3}, viewed)
+
+	// Histories survive clearing cached data but not clearing the history itself
+	assert.NoError(t, cache.Clear())
+	viewed, _ = cache.GetRecentlyViewed("account:7")
+	assert.Len(t, viewed, 3)
+	assert.NoError(t, cache.ClearRecentlyViewed("account:7"))
+	viewed, _ = cache.GetRecentlyViewed("account:7")
+	assert.Empty(t, viewed)
+
+	assert.NoError(t, cache.AddRecentlyViewed("guest:abc", 5, 3, time.Millisecond))
+	time.Sleep(5 * time.Millisecond)
+	viewed, _ = cache.GetRecentlyViewed("guest:abc")
+	assert.Empty(t, viewed)
+}
+
 func TestInMemoryCache_ConcurrentAccess(t *testing.T) {
 	cache := NewInMemoryCache()
 
@@ -549,6 +573,24 @@ func TestRedisCache_Comprehensive_CartOperations(t *testing.T) {
 	assert.Equal(t, cart[1].Quantity, retrievedCart[1].Quantity)
 }
 
+func TestRedisCache_Comprehensive_RecentlyViewed(t *testing.T) {
+	cache := GetCache()
+	if cache == nil {
+		t.Skip("Cache not initialized")
+	}
+
+	if err := cache.AddRecentlyViewed("account:401", 1, 2, time.Minute); err != nil {
+		t.Skip("Recording view failed:", err)
+	}
+	cache.AddRecentlyViewed("account:401", 2, 2, time.Minute)
+	cache.AddRecentlyViewed("account:401", 3, 2, time.Minute)
+
+	viewed, err := cache.GetRecentlyViewed("account:401")
+	assert.NoError(t, err)
+	assert.Equal(t, []uint{3, 2}, viewed)
+	assert.NoError(t, cache.ClearRecentlyViewed("account:401"))
+}
+
 func TestRedisCache_Comprehensive_InvalidateUserCache(t *testing.T) {
 	cache := GetCache()
 	if cache == nil {
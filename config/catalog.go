@@ -0,0 +1,21 @@
+package config
+
+// CatalogConfig holds bulk catalog import and export limits
+type CatalogConfig struct {
+	MaxImportSize int64 // Largest accepted import file in bytes
+	MaxImportRows int   // Most rows accepted in one import
+	BatchSize     int   // Rows written or read per statement
+}
+
+// GetCatalogConfig returns the catalog import configuration from the environment
+func GetCatalogConfig() CatalogConfig {
+	cfg := CatalogConfig{
+		MaxImportSize: int64(GetEnvAsInt("CATALOG_IMPORT_MAX_MB", 50)) << 20,
+		MaxImportRows: GetEnvAsInt("CATALOG_IMPORT_MAX_ROWS", 50000),
+		BatchSize:     GetEnvAsInt("CATALOG_BATCH_SIZE", 500),
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 500
+	}
+	return cfg
+}
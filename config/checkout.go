@@ -0,0 +1,21 @@
+package config
+
+import "time"
+
+// CheckoutConfig holds checkout session and delivery pricing parameters
+type CheckoutConfig struct {
+	SessionTTL            time.Duration // Idle time before an abandoned checkout session expires
+	StandardShippingCost  float64       // Price of standard delivery
+	ExpressShippingCost   float64       // Price of express delivery
+	FreeShippingThreshold float64       // Subtotal at which standard delivery is free (0 disables)
+}
+
+// GetCheckoutConfig returns the checkout configuration from the environment
+func GetCheckoutConfig() CheckoutConfig {
+	return CheckoutConfig{
+		SessionTTL:            GetEnvAsDuration("CHECKOUT_SESSION_TTL", 30*time.Minute),
+		StandardShippingCost:  GetEnvAsFloat("CHECKOUT_STANDARD_SHIPPING_COST", 4.99),
+		ExpressShippingCost:   GetEnvAsFloat("CHECKOUT_EXPRESS_SHIPPING_COST", 9.99),
+		FreeShippingThreshold: GetEnvAsFloat("CHECKOUT_FREE_SHIPPING_THRESHOLD", 50),
+	}
+}
@@ -3,6 +3,7 @@ package config
 import (
 	"os"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -168,3 +169,197 @@ func TestLoadConfig_WithEnvFile(t *testing.T) {
 		assert.Contains(t, err.Error(), "error loading .env file")
 	}
 }
+
+func TestGetEnvHelpers(t *testing.T) {
+	os.Setenv("TEST_ENV_STRING", "value")
+	os.Setenv("TEST_ENV_INT", "42")
+	os.Setenv("TEST_ENV_FLOAT", "2.5")
+	os.Setenv("TEST_ENV_DURATION", "90m")
+	os.Setenv("TEST_ENV_BAD", "not-a-number")
+	defer func() {
+		for _, key := range []string{"TEST_ENV_STRING", "TEST_ENV_INT", "TEST_ENV_FLOAT", "TEST_ENV_DURATION", "TEST_ENV_BAD"} {
+			os.Unsetenv(key)
+		}
+	}()
+
+	assert.Equal(t, "value", GetEnv("TEST_ENV_STRING", "default"))
+	assert.Equal(t, "default", GetEnv("TEST_ENV_MISSING", "default"))
+	assert.Equal(t, 42, GetEnvAsInt("TEST_ENV_INT", 1))
+	assert.Equal(t, 1, GetEnvAsInt("TEST_ENV_BAD", 1))
+	assert.Equal(t, 2.5, GetEnvAsFloat("TEST_ENV_FLOAT", 1))
+	assert.Equal(t, 1.0, GetEnvAsFloat("TEST_ENV_BAD", 1))
+	assert.Equal(t, 90*time.Minute, GetEnvAsDuration("TEST_ENV_DURATION", time.Hour))
+	assert.Equal(t, time.Hour, GetEnvAsDuration("TEST_ENV_BAD", time.Hour))
+}
+
+func TestGetLoyaltyConfig(t *testing.T) {
+	os.Setenv("LOYALTY_POINT_VALUE", "0.05")
+	defer os.Unsetenv("LOYALTY_POINT_VALUE")
+
+	cfg := GetLoyaltyConfig()
+	assert.Equal(t, 0.05, cfg.PointValue)
+	assert.Equal(t, 1.0, cfg.DefaultEarnRate)
+	assert.Equal(t, 365*24*time.Hour, cfg.PointsExpiry)
+}
+
+func TestGetSubscriptionConfig(t *testing.T) {
+	os.Setenv("SUBSCRIPTION_MAX_RETRIES", "5")
+	defer os.Unsetenv("SUBSCRIPTION_MAX_RETRIES")
+
+	cfg := GetSubscriptionConfig()
+	assert.Equal(t, 5, cfg.MaxRetries)
+	assert.Equal(t, time.Hour, cfg.SchedulerInterval)
+	assert.Equal(t, 24*time.Hour, cfg.RetryInterval)
+}
+
+func TestGetCheckoutConfig(t *testing.T) {
+	os.Setenv("CHECKOUT_SESSION_TTL", "15m")
+	defer os.Unsetenv("CHECKOUT_SESSION_TTL")
+
+	cfg := GetCheckoutConfig()
+	assert.Equal(t, 15*time.Minute, cfg.SessionTTL)
+	assert.Equal(t, 4.99, cfg.StandardShippingCost)
+	assert.Equal(t, 50.0, cfg.FreeShippingThreshold)
+}
+
+func TestGetOrderNumberConfig(t *testing.T) {
+	os.Setenv("ORDER_NUMBER_DIGITS", "99")
+	defer os.Unsetenv("ORDER_NUMBER_DIGITS")
+
+	cfg := GetOrderNumberConfig()
+	assert.Equal(t, "ORD", cfg.Prefix)
+	assert.Equal(t, "{prefix}-{year}-{number}", cfg.Format)
+	assert.Equal(t, 6, cfg.Digits)
+
+	// Every number needs its own digits
+	os.Setenv("ORDER_NUMBER_FORMAT", "{prefix}-{year}")
+	defer os.Unsetenv("ORDER_NUMBER_FORMAT")
+	assert.Equal(t, "{prefix}-{year}-{number}", GetOrderNumberConfig().Format)
+
+	// Numbers of only digits would be taken for order IDs
+	os.Setenv("ORDER_NUMBER_FORMAT", "{year}{number}")
+	assert.Equal(t, "{prefix}-{year}-{number}", GetOrderNumberConfig().Format)
+	os.Setenv("ORDER_NUMBER_PREFIX", "42")
+	defer os.Unsetenv("ORDER_NUMBER_PREFIX")
+	os.Setenv("ORDER_NUMBER_FORMAT", "{prefix}{number}")
+	assert.Equal(t, "{prefix}-{year}-{number}", GetOrderNumberConfig().Format)
+	os.Setenv("ORDER_NUMBER_FORMAT", "#{number}")
+	assert.Equal(t, "#{number}", GetOrderNumberConfig().Format)
+}
+
+func TestGetMediaConfig(t *testing.T) {
+	os.Setenv("MEDIA_THUMBNAIL_SIZES", "100, abc,0,300")
+	os.Setenv("MEDIA_BASE_URL", "https://cdn.example.com/media/")
+	defer os.Unsetenv("MEDIA_THUMBNAIL_SIZES")
+	defer os.Unsetenv("MEDIA_BASE_URL")
+
+	cfg := GetMediaConfig()
+	assert.Equal(t, []int{100, 300}, cfg.ThumbnailSizes)
+	assert.Equal(t, "https://cdn.example.com/media", cfg.BaseURL)
+	assert.Equal(t, "uploads", cfg.StorageDir)
+	assert.Equal(t, int64(10<<20), cfg.MaxUploadSize)
+	assert.Equal(t, int64(40_000_000), cfg.MaxPixels)
+}
+
+func TestGetSearchConfig(t *testing.T) {
+	os.Setenv("SEARCH_INDEX_TTL", "30s")
+	defer os.Unsetenv("SEARCH_INDEX_TTL")
+
+	cfg := GetSearchConfig()
+	assert.Equal(t, 30*time.Second, cfg.IndexTTL)
+	assert.Equal(t, 8, cfg.SuggestLimit)
+	assert.Equal(t, 2, cfg.FuzzyMaxDistance)
+}
+
+func TestGetCatalogConfig(t *testing.T) {
+	os.Setenv("CATALOG_IMPORT_MAX_ROWS", "100")
+	defer os.Unsetenv("CATALOG_IMPORT_MAX_ROWS")
+
+	cfg := GetCatalogConfig()
+	assert.Equal(t, 100, cfg.MaxImportRows)
+	assert.Equal(t, int64(50<<20), cfg.MaxImportSize)
+	assert.Equal(t, 500, cfg.BatchSize)
+}
+
+func TestGetSEOConfig(t *testing.T) {
+	os.Setenv("SEO_STOREFRONT_URL", "https://shop.example.com/")
+	os.Setenv("SEO_PRODUCT_PATH", "p/")
+	defer os.Unsetenv("SEO_STOREFRONT_URL")
+	defer os.Unsetenv("SEO_PRODUCT_PATH")
+
+	cfg := GetSEOConfig()
+	assert.Equal(t, "https://shop.example.com", cfg.StorefrontURL)
+	assert.Equal(t, "/p", cfg.ProductPath)
+	assert.Equal(t, "/categories", cfg.CategoryPath)
+}
+
+func TestGetPricingConfig(t *testing.T) {
+	assert.Equal(t, time.Minute, GetPricingConfig().SchedulerInterval)
+
+	os.Setenv("PRICE_SCHEDULER_INTERVAL", "5m")
+	defer os.Unsetenv("PRICE_SCHEDULER_INTERVAL")
+	assert.Equal(t, 5*time.Minute, GetPricingConfig().SchedulerInterval)
+}
+
+func TestGetRecommendationConfig(t *testing.T) {
+	cfg := GetRecommendationConfig()
+	assert.Equal(t, time.Hour, cfg.RefreshInterval)
+	assert.Equal(t, 12, cfg.PerProduct)
+	assert.Equal(t, 0.25, cfg.PriceRange)
+
+	os.Setenv("RECOMMENDATIONS_PER_PRODUCT", "0")
+	os.Setenv("RECOMMENDATIONS_LOOKBACK_DAYS", "30")
+	defer os.Unsetenv("RECOMMENDATIONS_PER_PRODUCT")
+	defer os.Unsetenv("RECOMMENDATIONS_LOOKBACK_DAYS")
+	cfg = GetRecommendationConfig()
+	assert.Equal(t, 12, cfg.PerProduct)
+	assert.Equal(t, 30, cfg.LookbackDays)
+}
+
+func TestGetRecentlyViewedConfig(t *testing.T) {
+	cfg := GetRecentlyViewedConfig()
+	assert.Equal(t, 20, cfg.Limit)
+	assert.Equal(t, 30*24*time.Hour, cfg.TTL)
+
+	os.Setenv("RECENTLY_VIEWED_LIMIT", "-1")
+	os.Setenv("RECENTLY_VIEWED_TTL", "48h")
+	defer os.Unsetenv("RECENTLY_VIEWED_LIMIT")
+	defer os.Unsetenv("RECENTLY_VIEWED_TTL")
+	cfg = GetRecentlyViewedConfig()
+	assert.Equal(t, 20, cfg.Limit)
+	assert.Equal(t, 48*time.Hour, cfg.TTL)
+}
+
+func TestGetPublishingConfig(t *testing.T) {
+	assert.Equal(t, time.Minute, GetPublishingConfig().SchedulerInterval)
+
+	os.Setenv("PUBLISH_SCHEDULER_INTERVAL", "30s")
+	defer os.Unsetenv("PUBLISH_SCHEDULER_INTERVAL")
+	assert.Equal(t, 30*time.Second, GetPublishingConfig().SchedulerInterval)
+}
+
+func TestGetDigitalConfig(t *testing.T) {
+	os.Setenv("JWT_SECRET", "jwt-secret")
+	defer os.Unsetenv("JWT_SECRET")
+	cfg := GetDigitalConfig()
+	assert.Equal(t, "digital", cfg.StorageDir)
+	assert.Equal(t, int64(500<<20), cfg.MaxUploadSize)
+	assert.Equal(t, 15*time.Minute, cfg.LinkTTL)
+	assert.Equal(t, 5, cfg.DownloadLimit)
+	// The key is derived from the JWT secret rather than being the secret itself
+	assert.Len(t, cfg.SigningKey, 64)
+	assert.NotContains(t, cfg.SigningKey, "jwt-secret")
+	assert.Equal(t, cfg.SigningKey, GetDigitalConfig().SigningKey)
+	assert.NotEqual(t, cfg.SigningKey, deriveKey("jwt-secret", "other"))
+
+	os.Unsetenv("JWT_SECRET")
+	assert.Empty(t, GetDigitalConfig().SigningKey)
+
+	os.Setenv("DOWNLOAD_SIGNING_KEY", "download-secret")
+	os.Setenv("DOWNLOAD_LIMIT", "3")
+	defer os.Unsetenv("DOWNLOAD_SIGNING_KEY")
+	defer os.Unsetenv("DOWNLOAD_LIMIT")
+	cfg = GetDigitalConfig()
+	assert.Equal(t, "download-secret", cfg.SigningKey)
+	assert.Equal(t, 3, cfg.DownloadLimit)
+}
@@ -0,0 +1,53 @@
+package config
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"time"
+
+	"golang.org/x/crypto/hkdf"
+)
+
+// downloadKeyLabel separates keys derived for download links from other uses of the JWT secret
+const downloadKeyLabel = "download-links"
+
+// DigitalConfig holds storage and download link parameters for digital products
+type DigitalConfig struct {
+	StorageDir    string        // Directory digital files are kept in; never served directly
+	MaxUploadSize int64         // Largest accepted digital file in bytes
+	LinkTTL       time.Duration // How long a signed download link stays valid
+	DownloadLimit int           // Downloads allowed per file and order item
+	SigningKey    string        // HMAC key download links are signed with
+}
+
+// GetDigitalConfig returns the digital product configuration from the environment.
+// Download links are signed with DOWNLOAD_SIGNING_KEY, or with a key derived from the
+// JWT secret when it is unset, so a leaked link key never reveals the token secret.
+func GetDigitalConfig() DigitalConfig {
+	signingKey := GetEnv("DOWNLOAD_SIGNING_KEY", "")
+	if signingKey == "" {
+		signingKey = deriveKey(GetEnv("JWT_SECRET", ""), downloadKeyLabel)
+	}
+
+	return DigitalConfig{
+		StorageDir:    GetEnv("DIGITAL_STORAGE_DIR", "digital"),
+		MaxUploadSize: int64(GetEnvAsInt("DIGITAL_MAX_UPLOAD_MB", 500)) << 20,
+		LinkTTL:       GetEnvAsDuration("DOWNLOAD_LINK_TTL", 15*time.Minute),
+		DownloadLimit: GetEnvAsInt("DOWNLOAD_LIMIT", 5),
+		SigningKey:    signingKey,
+	}
+}
+
+// deriveKey derives a hex-encoded 256-bit key for label from secret using HKDF-SHA256.
+// An empty secret derives no key.
+func deriveKey(secret, label string) string {
+	if secret == "" {
+		return ""
+	}
+	key := make([]byte, 32)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, []byte(secret), nil, []byte(label)), key); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(key)
+}
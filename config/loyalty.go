@@ -0,0 +1,21 @@
+package config
+
+import "time"
+
+// LoyaltyConfig holds the tunable parameters of the loyalty points program
+type LoyaltyConfig struct {
+	DefaultEarnRate float64       // Points earned per currency unit when a category has no explicit rate
+	PointValue      float64       // Currency value of a single point when redeemed
+	PointsExpiry    time.Duration // How long earned points stay valid
+	TierWindow      time.Duration // Rolling window used to compute spend for tier levels
+}
+
+// GetLoyaltyConfig returns the loyalty program configuration from the environment
+func GetLoyaltyConfig() LoyaltyConfig {
+	return LoyaltyConfig{
+		DefaultEarnRate: GetEnvAsFloat("LOYALTY_DEFAULT_EARN_RATE", 1),
+		PointValue:      GetEnvAsFloat("LOYALTY_POINT_VALUE", 0.01),
+		PointsExpiry:    GetEnvAsDuration("LOYALTY_POINTS_EXPIRY", 365*24*time.Hour),
+		TierWindow:      GetEnvAsDuration("LOYALTY_TIER_WINDOW", 365*24*time.Hour),
+	}
+}
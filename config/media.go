@@ -0,0 +1,38 @@
+package config
+
+import (
+	"strconv"
+	"strings"
+)
+
+// MediaConfig holds product image storage and thumbnail parameters
+type MediaConfig struct {
+	StorageDir     string // Directory the local blob store writes uploads to
+	BaseURL        string // URL prefix uploads are served from
+	ThumbnailSizes []int  // Longest edge, in pixels, of each generated thumbnail
+	MaxUploadSize  int64  // Largest accepted image upload in bytes
+	MaxPixels      int64  // Largest accepted image area; checked before decoding
+}
+
+// GetMediaConfig returns the media configuration from the environment
+func GetMediaConfig() MediaConfig {
+	return MediaConfig{
+		StorageDir:     GetEnv("MEDIA_STORAGE_DIR", "uploads"),
+		BaseURL:        strings.TrimRight(GetEnv("MEDIA_BASE_URL", "/media"), "/"),
+		ThumbnailSizes: parseSizes(GetEnv("MEDIA_THUMBNAIL_SIZES", "150,400")),
+		MaxUploadSize:  int64(GetEnvAsInt("MEDIA_MAX_UPLOAD_MB", 10)) << 20,
+		MaxPixels:      int64(GetEnvAsInt("MEDIA_MAX_MEGAPIXELS", 40)) * 1_000_000,
+	}
+}
+
+// parseSizes reads a comma-separated list of positive pixel sizes, skipping invalid entries
+func parseSizes(value string) []int {
+	var sizes []int
+	for _, part := range strings.Split(value, ",") {
+		size, err := strconv.Atoi(strings.TrimSpace(part))
+		if err == nil && size > 0 {
+			sizes = append(sizes, size)
+		}
+	}
+	return sizes
+}
@@ -0,0 +1,34 @@
+package config
+
+import "strings"
+
+// defaultOrderNumberFormat is used when ORDER_NUMBER_FORMAT is unset or invalid
+const defaultOrderNumberFormat = "{prefix}-{year}-{number}"
+
+// OrderNumberConfig controls the format of human-readable order numbers
+type OrderNumberConfig struct {
+	Prefix string // Leading text, e.g. "ORD"
+	Format string // Template using {prefix}, {year} and {number}
+	Digits int    // Width of the zero-padded random number
+}
+
+// GetOrderNumberConfig returns the order number configuration from the environment. A format
+// without {number} would give every order the same number, and one producing only digits
+// could not be told apart from an order ID, so either falls back to the default.
+func GetOrderNumberConfig() OrderNumberConfig {
+	digits := GetEnvAsInt("ORDER_NUMBER_DIGITS", 6)
+	if digits < 4 || digits > 12 {
+		digits = 6
+	}
+	prefix := GetEnv("ORDER_NUMBER_PREFIX", "ORD")
+	format := GetEnv("ORDER_NUMBER_FORMAT", defaultOrderNumberFormat)
+	sample := strings.NewReplacer("{prefix}", prefix, "{year}", "2006", "{number}", "0").Replace(format)
+	if !strings.Contains(format, "{number}") || strings.Trim(sample, "0123456789") == "" {
+		format = defaultOrderNumberFormat
+	}
+	return OrderNumberConfig{
+		Prefix: prefix,
+		Format: format,
+		Digits: digits,
+	}
+}
@@ -0,0 +1,15 @@
+package config
+
+import "time"
+
+// PricingConfig holds the scheduling parameters for price changes and sales
+type PricingConfig struct {
+	SchedulerInterval time.Duration // How often due price changes and sale starts and ends are applied
+}
+
+// GetPricingConfig returns the pricing configuration from the environment
+func GetPricingConfig() PricingConfig {
+	return PricingConfig{
+		SchedulerInterval: GetEnvAsDuration("PRICE_SCHEDULER_INTERVAL", time.Minute),
+	}
+}
@@ -0,0 +1,15 @@
+package config
+
+import "time"
+
+// PublishingConfig holds the scheduling parameters for publishing and unpublishing products
+type PublishingConfig struct {
+	SchedulerInterval time.Duration // How often scheduled publish and unpublish times are applied
+}
+
+// GetPublishingConfig returns the product publishing configuration from the environment
+func GetPublishingConfig() PublishingConfig {
+	return PublishingConfig{
+		SchedulerInterval: GetEnvAsDuration("PUBLISH_SCHEDULER_INTERVAL", time.Minute),
+	}
+}
@@ -0,0 +1,21 @@
+package config
+
+import "time"
+
+// RecentlyViewedConfig holds how much browsing history is kept per user or guest
+type RecentlyViewedConfig struct {
+	Limit int           // Distinct products remembered, most recent first
+	TTL   time.Duration // How long a history is kept after its last view
+}
+
+// GetRecentlyViewedConfig returns the recently viewed products configuration from the environment
+func GetRecentlyViewedConfig() RecentlyViewedConfig {
+	cfg := RecentlyViewedConfig{
+		Limit: GetEnvAsInt("RECENTLY_VIEWED_LIMIT", 20),
+		TTL:   GetEnvAsDuration("RECENTLY_VIEWED_TTL", 30*24*time.Hour),
+	}
+	if cfg.Limit <= 0 {
+		cfg.Limit = 20
+	}
+	return cfg
+}
@@ -0,0 +1,25 @@
+package config
+
+import "time"
+
+// RecommendationConfig holds how related product recommendations are computed
+type RecommendationConfig struct {
+	RefreshInterval time.Duration // How often recommendations are recomputed
+	PerProduct      int           // Recommendations kept for each product
+	LookbackDays    int           // Age of the oldest orders counted for co-purchases; 0 counts all
+	PriceRange      float64       // Fraction of a product's price that similar-price fallbacks may differ by
+}
+
+// GetRecommendationConfig returns the recommendation configuration from the environment
+func GetRecommendationConfig() RecommendationConfig {
+	cfg := RecommendationConfig{
+		RefreshInterval: GetEnvAsDuration("RECOMMENDATIONS_INTERVAL", time.Hour),
+		PerProduct:      GetEnvAsInt("RECOMMENDATIONS_PER_PRODUCT", 12),
+		LookbackDays:    GetEnvAsInt("RECOMMENDATIONS_LOOKBACK_DAYS", 180),
+		PriceRange:      GetEnvAsFloat("RECOMMENDATIONS_PRICE_RANGE", 0.25),
+	}
+	if cfg.PerProduct <= 0 {
+		cfg.PerProduct = 12
+	}
+	return cfg
+}
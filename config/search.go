@@ -0,0 +1,19 @@
+package config
+
+import "time"
+
+// SearchConfig holds product search tuning parameters
+type SearchConfig struct {
+	IndexTTL         time.Duration // How long the in-process suggestion index is used before it is rebuilt
+	SuggestLimit     int           // Default number of autocomplete suggestions
+	FuzzyMaxDistance int           // Largest edit distance accepted when correcting misspelt words
+}
+
+// GetSearchConfig returns the search configuration from the environment
+func GetSearchConfig() SearchConfig {
+	return SearchConfig{
+		IndexTTL:         GetEnvAsDuration("SEARCH_INDEX_TTL", 5*time.Minute),
+		SuggestLimit:     GetEnvAsInt("SEARCH_SUGGEST_LIMIT", 8),
+		FuzzyMaxDistance: GetEnvAsInt("SEARCH_FUZZY_MAX_DISTANCE", 2),
+	}
+}
@@ -0,0 +1,19 @@
+package config
+
+import "strings"
+
+// SEOConfig holds the storefront URLs canonical links are built from
+type SEOConfig struct {
+	StorefrontURL string // Origin of the storefront, e.g. https://shop.example.com; empty for paths only
+	ProductPath   string // Path of product pages, followed by the product slug
+	CategoryPath  string // Path of category pages, followed by the category slug
+}
+
+// GetSEOConfig returns the SEO configuration from the environment
+func GetSEOConfig() SEOConfig {
+	return SEOConfig{
+		StorefrontURL: strings.TrimRight(GetEnv("SEO_STOREFRONT_URL", ""), "/"),
+		ProductPath:   "/" + strings.Trim(GetEnv("SEO_PRODUCT_PATH", "/products"), "/"),
+		CategoryPath:  "/" + strings.Trim(GetEnv("SEO_CATEGORY_PATH", "/categories"), "/"),
+	}
+}
@@ -0,0 +1,19 @@
+package config
+
+import "time"
+
+// SubscriptionConfig holds the scheduling parameters for recurring orders
+type SubscriptionConfig struct {
+	SchedulerInterval time.Duration // How often due subscriptions are processed
+	MaxRetries        int           // Failed cycles before a subscription is paused
+	RetryInterval     time.Duration // Delay before retrying a failed cycle
+}
+
+// GetSubscriptionConfig returns the subscription configuration from the environment
+func GetSubscriptionConfig() SubscriptionConfig {
+	return SubscriptionConfig{
+		SchedulerInterval: GetEnvAsDuration("SUBSCRIPTION_SCHEDULER_INTERVAL", time.Hour),
+		MaxRetries:        GetEnvAsInt("SUBSCRIPTION_MAX_RETRIES", 3),
+		RetryInterval:     GetEnvAsDuration("SUBSCRIPTION_RETRY_INTERVAL", 24*time.Hour),
+	}
+}
@@ -242,11 +242,120 @@ func ConnectDatabase() error {
 		&models.Review{},
 		&models.Wishlist{},
 		&models.Inventory{},
+		&models.LoyaltyTransaction{},
+		&models.LoyaltyEarnRate{},
+		&models.Subscription{},
+		&models.SubscriptionItem{},
+		&models.Fulfillment{},
+		&models.FulfillmentItem{},
+		&models.CheckoutSession{},
+		&models.CheckoutSessionItem{},
+		&models.OrderRevision{},
+		&models.OrderItemCancellation{},
+		&models.ProductOption{},
+		&models.ProductOptionValue{},
+		&models.ProductVariant{},
+		&models.ProductImage{},
+		&models.ProductImageThumbnail{},
+		&models.Attribute{},
+		&models.AttributeOption{},
+		&models.CategoryAttribute{},
+		&models.ProductAttributeValue{},
+		&models.SynonymGroup{},
+		&models.SlugRedirect{},
+		&models.PriceSchedule{},
+		&models.PriceHistory{},
+		&models.BundleItem{},
+		&models.OrderItemComponent{},
+		&models.ProductRecommendation{},
+		&models.ProductTag{},
+		&models.Collection{},
+		&models.CollectionRule{},
+		&models.CollectionProduct{},
+		&models.DigitalFile{},
+		&models.LicenceKey{},
+		&models.DigitalDownload{},
 	); err != nil {
 		// AutoMigrate failing is not fatal for tests, but log it
 		log.Printf("auto migrate failed: %v", err)
 	}
 
+	if err := backfillOrderNumbers(database); err != nil {
+		log.Printf("order number backfill failed: %v", err)
+	}
+	if err := backfillCategorySlugs(database); err != nil {
+		log.Printf("category slug backfill failed: %v", err)
+	}
+	if err := backfillProductSlugs(database); err != nil {
+		log.Printf("product slug backfill failed: %v", err)
+	}
+	if err := EnsureSearchIndex(database); err != nil {
+		log.Printf("search index setup failed: %v", err)
+	}
+
 	DB = database
 	return nil
 }
+
+// backfillOrderNumbers assigns order numbers to orders created before they existed
+func backfillOrderNumbers(database *gorm.DB) error {
+	var orders []models.Order
+	if err := database.Unscoped().Where("order_number IS NULL OR order_number = ''").Find(&orders).Error; err != nil {
+		return err
+	}
+	for _, order := range orders {
+		number, err := models.NewOrderNumber(database, order.CreatedAt)
+		if err != nil {
+			return err
+		}
+		if err := database.Unscoped().Model(&order).UpdateColumn("order_number", number).Error; err != nil {
+			return err
+		}
+	}
+	if len(orders) > 0 {
+		log.Printf("Assigned order numbers to %d existing orders", len(orders))
+	}
+	return nil
+}
+
+// backfillCategorySlugs assigns slugs to categories created before they existed
+func backfillCategorySlugs(database *gorm.DB) error {
+	var categories []models.Category
+	if err := database.Unscoped().Where("slug IS NULL OR slug = ''").Order("id").Find(&categories).Error; err != nil {
+		return err
+	}
+	for _, category := range categories {
+		slug, err := models.UniqueCategorySlug(database, category.Name, category.ID)
+		if err != nil {
+			return err
+		}
+		if err := database.Unscoped().Model(&category).UpdateColumn("slug", slug).Error; err != nil {
+			return err
+		}
+	}
+	if len(categories) > 0 {
+		log.Printf("Assigned slugs to %d existing categories", len(categories))
+	}
+	return nil
+}
+
+// backfillProductSlugs assigns slugs to products created before they existed
+func backfillProductSlugs(database *gorm.DB) error {
+	var products []models.Product
+	if err := database.Unscoped().Select("id", "name").Where("slug IS NULL OR slug = ''").Order("id").Find(&products).Error; err != nil {
+		return err
+	}
+	for _, product := range products {
+		slug, err := models.UniqueProductSlug(database, product.Name, product.ID)
+		if err != nil {
+			return err
+		}
+		if err := database.Unscoped().Model(&product).UpdateColumn("slug", slug).Error; err != nil {
+			return err
+		}
+	}
+	if len(products) > 0 {
+		log.Printf("Assigned slugs to %d existing products", len(products))
+	}
+	return nil
+}
@@ -46,3 +46,17 @@ func TestDatabase_Models_AutoMigration(t *testing.T) {
 	assert.True(t, db.Migrator().HasTable(&models.Product{}))
 	assert.True(t, db.Migrator().HasTable(&models.Category{}))
 }
+
+func TestBackfillOrderNumbers(t *testing.T) {
+	db := SetupTestDB(t)
+
+	order := models.Order{TotalAmount: 10}
+	db.Create(&order)
+	db.Model(&order).UpdateColumn("order_number", "")
+
+	assert.NoError(t, backfillOrderNumbers(db))
+
+	var stored models.Order
+	db.First(&stored, order.ID)
+	assert.NotEmpty(t, stored.OrderNumber)
+}
@@ -0,0 +1,114 @@
+package db
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// Full-text search index objects. Products are indexed on their name, category name and
+// description, in that order of importance, and kept in sync by database triggers.
+const (
+	// SearchTable is the SQLite full-text table holding one row per product, keyed by product ID
+	SearchTable = "products_fts"
+	// SearchLanguage is the Postgres text search configuration used for stemming
+	SearchLanguage = "english"
+)
+
+// EnsureSearchIndex creates the full-text search index and the triggers that maintain it, then
+// indexes any products not yet covered. It is safe to run on every start-up.
+func EnsureSearchIndex(database *gorm.DB) error {
+	switch database.Dialector.Name() {
+	case "postgres":
+		return ensurePostgresSearchIndex(database)
+	case "sqlite":
+		return ensureSQLiteSearchIndex(database)
+	default:
+		return nil
+	}
+}
+
+// ensurePostgresSearchIndex adds a weighted tsvector column with a GIN index to products
+func ensurePostgresSearchIndex(database *gorm.DB) error {
+	document := fmt.Sprintf(`setweight(to_tsvector('%[1]s', coalesce(NEW.name, '')), 'A') ||
+		setweight(to_tsvector('%[1]s', coalesce((SELECT name FROM categories WHERE id = NEW.category_id), '')), 'B') ||
+		setweight(to_tsvector('%[1]s', coalesce(NEW.description, '')), 'C')`, SearchLanguage)
+
+	statements := []string{
+		`ALTER TABLE products ADD COLUMN IF NOT EXISTS search_vector tsvector`,
+		`CREATE INDEX IF NOT EXISTS idx_products_search_vector ON products USING GIN (search_vector)`,
+		`CREATE OR REPLACE FUNCTION products_search_vector_update() RETURNS trigger AS $$
+		BEGIN
+			NEW.search_vector := ` + document + `;
+			RETURN NEW;
+		END
+		$$ LANGUAGE plpgsql`,
+		`DROP TRIGGER IF EXISTS products_search_vector_trigger ON products`,
+		`CREATE TRIGGER products_search_vector_trigger BEFORE INSERT OR UPDATE OF name, description, category_id
+		ON products FOR EACH ROW EXECUTE PROCEDURE products_search_vector_update()`,
+		// Renaming a category re-indexes its products by touching them
+		`CREATE OR REPLACE FUNCTION categories_search_vector_update() RETURNS trigger AS $$
+		BEGIN
+			UPDATE products SET name = name WHERE category_id = NEW.id;
+			RETURN NEW;
+		END
+		$$ LANGUAGE plpgsql`,
+		`DROP TRIGGER IF EXISTS categories_search_vector_trigger ON categories`,
+		`CREATE TRIGGER categories_search_vector_trigger AFTER UPDATE OF name ON categories
+		FOR EACH ROW WHEN (OLD.name IS DISTINCT FROM NEW.name) EXECUTE PROCEDURE categories_search_vector_update()`,
+		`UPDATE products SET name = name WHERE search_vector IS NULL`,
+	}
+	for _, statement := range statements {
+		if err := database.Exec(statement).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ensureSQLiteSearchIndex creates an FTS5 table with the porter stemmer, falling back to FTS4
+// when SQLite was built without FTS5 (go-sqlite3 needs the sqlite_fts5 build tag)
+func ensureSQLiteSearchIndex(database *gorm.DB) error {
+	if !database.Migrator().HasTable(SearchTable) {
+		create := fmt.Sprintf(`CREATE VIRTUAL TABLE %s USING fts5(name, category, description, tokenize = 'porter unicode61')`, SearchTable)
+		if err := database.Exec(create).Error; err != nil {
+			if !strings.Contains(err.Error(), "no such module") {
+				return err
+			}
+			log.Printf("SQLite FTS5 is unavailable, using FTS4 for product search")
+			create = fmt.Sprintf(`CREATE VIRTUAL TABLE %s USING fts4(name, category, description, tokenize=porter)`, SearchTable)
+			if err := database.Exec(create).Error; err != nil {
+				return err
+			}
+		}
+	}
+
+	row := `(NEW.id, NEW.name, coalesce((SELECT name FROM categories WHERE id = NEW.category_id), ''), coalesce(NEW.description, ''))`
+	statements := []string{
+		`CREATE TRIGGER IF NOT EXISTS products_fts_insert AFTER INSERT ON products BEGIN
+			INSERT INTO products_fts(rowid, name, category, description) VALUES ` + row + `;
+		END`,
+		`CREATE TRIGGER IF NOT EXISTS products_fts_update AFTER UPDATE OF name, description, category_id ON products BEGIN
+			DELETE FROM products_fts WHERE rowid = OLD.id;
+			INSERT INTO products_fts(rowid, name, category, description) VALUES ` + row + `;
+		END`,
+		`CREATE TRIGGER IF NOT EXISTS products_fts_delete AFTER DELETE ON products BEGIN
+			DELETE FROM products_fts WHERE rowid = OLD.id;
+		END`,
+		`CREATE TRIGGER IF NOT EXISTS categories_fts_update AFTER UPDATE OF name ON categories BEGIN
+			UPDATE products_fts SET category = NEW.name WHERE rowid IN (SELECT id FROM products WHERE category_id = NEW.id);
+		END`,
+		`INSERT INTO products_fts(rowid, name, category, description)
+			SELECT products.id, products.name, coalesce(categories.name, ''), coalesce(products.description, '')
+			FROM products LEFT JOIN categories ON categories.id = products.category_id
+			WHERE products.id NOT IN (SELECT rowid FROM products_fts)`,
+	}
+	for _, statement := range statements {
+		if err := database.Exec(statement).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
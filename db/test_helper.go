@@ -36,10 +36,46 @@ func SetupTestDB(tb testing.TB) *gorm.DB {
 		&models.Review{},
 		&models.Wishlist{},
 		&models.Payment{},
+		&models.LoyaltyTransaction{},
+		&models.LoyaltyEarnRate{},
+		&models.Subscription{},
+		&models.SubscriptionItem{},
+		&models.Fulfillment{},
+		&models.FulfillmentItem{},
+		&models.CheckoutSession{},
+		&models.CheckoutSessionItem{},
+		&models.OrderRevision{},
+		&models.OrderItemCancellation{},
+		&models.ProductOption{},
+		&models.ProductOptionValue{},
+		&models.ProductVariant{},
+		&models.ProductImage{},
+		&models.ProductImageThumbnail{},
+		&models.Attribute{},
+		&models.AttributeOption{},
+		&models.CategoryAttribute{},
+		&models.ProductAttributeValue{},
+		&models.SynonymGroup{},
+		&models.SlugRedirect{},
+		&models.PriceSchedule{},
+		&models.PriceHistory{},
+		&models.BundleItem{},
+		&models.OrderItemComponent{},
+		&models.ProductRecommendation{},
+		&models.ProductTag{},
+		&models.Collection{},
+		&models.CollectionRule{},
+		&models.CollectionProduct{},
+		&models.DigitalFile{},
+		&models.LicenceKey{},
+		&models.DigitalDownload{},
 	)
 	if err != nil {
 		tb.Fatalf("auto migrate failed: %v", err)
 	}
+	if err := EnsureSearchIndex(testDB); err != nil {
+		tb.Fatalf("search index setup failed: %v", err)
+	}
 
 	return testDB
 }
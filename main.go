@@ -7,8 +7,10 @@ import (
 
 	"github.com/geoo115/Ecommerce/api"
 	"github.com/geoo115/Ecommerce/api/middlewares"
+	"github.com/geoo115/Ecommerce/cache"
 	"github.com/geoo115/Ecommerce/config"
 	"github.com/geoo115/Ecommerce/db"
+	"github.com/geoo115/Ecommerce/services"
 	"github.com/geoo115/Ecommerce/utils"
 	"github.com/gin-gonic/gin"
 )
@@ -67,6 +69,49 @@ func main() {
 		utils.Info("Database connected successfully")
 	}
 
+	// Start background jobs; each run is skipped until the database is available
+	services.StartJob("subscriptions", config.GetSubscriptionConfig().SchedulerInterval, func(now time.Time) error {
+		placed, err := services.NewSubscriptionService().ProcessDueSubscriptions(now)
+		if placed > 0 {
+			utils.Info("Placed %d subscription orders", placed)
+		}
+		return err
+	})
+	services.StartJob("price-schedules", config.GetPricingConfig().SchedulerInterval, func(now time.Time) error {
+		changed, err := services.NewPriceService().ApplyDueSchedules(now)
+		if len(changed) > 0 {
+			utils.Info("Applied scheduled prices to %d products", len(changed))
+			// Cached product pages and listings would otherwise show the old prices
+			invalidateProducts(changed)
+		}
+		return err
+	})
+	services.StartJob("product-publishing", config.GetPublishingConfig().SchedulerInterval, func(now time.Time) error {
+		changed, err := services.NewPublishingService().ApplyDue(now)
+		if len(changed) > 0 {
+			utils.Info("Published or unpublished %d scheduled products", len(changed))
+			// The search index and cached listings would otherwise keep showing the old catalogue
+			services.InvalidateSearchIndex()
+			invalidateProducts(changed)
+		}
+		return err
+	})
+	services.StartJob("recommendations", config.GetRecommendationConfig().RefreshInterval, func(now time.Time) error {
+		stored, err := services.NewRecommendationService().Rebuild(now)
+		if err != nil {
+			return err
+		}
+		utils.Debug("Recomputed %d product recommendations", stored)
+		if cch := cache.GetCache(); cch != nil {
+			return cch.InvalidateRecommendationCache()
+		}
+		return nil
+	})
+	services.StartJob("checkout-expiry", config.GetCheckoutConfig().SessionTTL, func(now time.Time) error {
+		_, err := services.NewCheckoutService().ExpireSessions(now)
+		return err
+	})
+
 	// Set up routes
 	utils.Info("Setting up routes...")
 	api.SetupRoutes(r)
@@ -83,3 +128,16 @@ func main() {
 		utils.Fatal("Failed to start server: %v", err)
 	}
 }
+
+// invalidateProducts drops the cached pages and listings of products changed by a background job
+func invalidateProducts(ids []uint) {
+	cch := cache.GetCache()
+	if cch == nil {
+		return
+	}
+	for _, id := range ids {
+		if err := cch.InvalidateProductCache(id); err != nil {
+			utils.Warn("Failed to invalidate product cache: %v", err)
+		}
+	}
+}
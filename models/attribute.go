@@ -0,0 +1,53 @@
+package models
+
+import "gorm.io/gorm"
+
+// Attribute types
+const (
+	AttributeTypeString  = "string"
+	AttributeTypeNumber  = "number"
+	AttributeTypeBoolean = "boolean"
+	AttributeTypeEnum    = "enum"
+)
+
+// Attribute is an admin-defined product property, such as brand or screen size, that can be
+// filtered on. Attributes are assigned to categories and apply to products in those categories
+// and their subcategories.
+type Attribute struct {
+	gorm.Model
+	Name       string            `json:"name"`
+	Code       string            `json:"code" gorm:"uniqueIndex;size:64"` // Used in filter query parameters
+	Type       string            `json:"type" gorm:"size:16"`
+	Unit       string            `json:"unit,omitempty"` // Display unit for numbers, e.g. "in"
+	Filterable bool              `json:"filterable"`
+	Options    []AttributeOption `json:"options,omitempty" gorm:"foreignKey:AttributeID"`
+}
+
+// AttributeOption is one allowed value of an enum attribute
+type AttributeOption struct {
+	gorm.Model
+	AttributeID uint   `json:"attribute_id" gorm:"index"`
+	Value       string `json:"value"`
+	Position    int    `json:"position"`
+}
+
+// CategoryAttribute assigns an attribute to a category
+type CategoryAttribute struct {
+	gorm.Model
+	CategoryID  uint      `json:"category_id" gorm:"uniqueIndex:idx_category_attribute"`
+	AttributeID uint      `json:"attribute_id" gorm:"uniqueIndex:idx_category_attribute"`
+	Position    int       `json:"position"`
+	Attribute   Attribute `json:"attribute"`
+}
+
+// ProductAttributeValue is a product's value for one attribute. Value holds the canonical text
+// form used for exact filters and facets; NumberValue is also set for number attributes so
+// they can be range filtered.
+type ProductAttributeValue struct {
+	gorm.Model
+	ProductID   uint       `json:"product_id" gorm:"index:idx_product_attribute"`
+	AttributeID uint       `json:"attribute_id" gorm:"index:idx_product_attribute;index:idx_attribute_value"`
+	Value       string     `json:"value" gorm:"size:255;index:idx_attribute_value"`
+	NumberValue *float64   `json:"number_value,omitempty"`
+	Attribute   *Attribute `json:"attribute,omitempty"`
+}
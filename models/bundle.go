@@ -0,0 +1,34 @@
+package models
+
+import "gorm.io/gorm"
+
+// Product types
+const (
+	ProductTypeSimple  = "simple"
+	ProductTypeBundle  = "bundle"  // Sold as one item; stock is held by its component products
+	ProductTypeDigital = "digital" // Downloaded rather than shipped; holds no stock
+)
+
+// BundleItem is a component product of a bundle and how many of it each bundle contains
+type BundleItem struct {
+	gorm.Model
+	BundleID    uint     `json:"bundle_id" gorm:"uniqueIndex:idx_bundle_items_component"`
+	ComponentID uint     `json:"component_id" gorm:"uniqueIndex:idx_bundle_items_component;index"`
+	Quantity    int      `json:"quantity"`
+	Component   *Product `json:"component,omitempty" gorm:"foreignKey:ComponentID"`
+}
+
+// OrderItemComponent records a component of a bundle order item as it was when ordered
+type OrderItemComponent struct {
+	gorm.Model
+	OrderItemID uint   `json:"order_item_id" gorm:"index"`
+	ProductID   uint   `json:"product_id"`
+	Name        string `json:"name"`
+	SKU         string `json:"sku,omitempty"`
+	Quantity    int    `json:"quantity"` // Per bundle; the order item quantity multiplies it
+}
+
+// IsBundle reports whether the product is sold as a bundle of other products
+func (p Product) IsBundle() bool {
+	return p.Type == ProductTypeBundle
+}
@@ -6,9 +6,20 @@ import (
 
 type Cart struct {
 	gorm.Model
-	UserID    uint    `json:"user_id"`
-	ProductID uint    `json:"product_id"`
-	Quantity  int     `json:"quantity"`
-	User      User    `gorm:"foreignKey:UserID"`
-	Product   Product `gorm:"foreignKey:ProductID"`
+	UserID    uint            `json:"user_id"`
+	ProductID uint            `json:"product_id"`
+	VariantID *uint           `json:"variant_id,omitempty" gorm:"index"`
+	Quantity  int             `json:"quantity"`
+	User      User            `gorm:"foreignKey:UserID"`
+	Product   Product         `gorm:"foreignKey:ProductID"`
+	Variant   *ProductVariant `json:"variant,omitempty" gorm:"foreignKey:VariantID"`
+}
+
+// UnitPrice returns the line's current price, using the variant's price override when set.
+// Product and Variant must be preloaded.
+func (c Cart) UnitPrice() float64 {
+	if c.Variant != nil {
+		return c.Variant.PriceFor(c.Product.Price)
+	}
+	return c.Product.Price
 }
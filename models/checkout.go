@@ -0,0 +1,37 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// CheckoutSession is a snapshot of a user's cart moving through the checkout steps
+type CheckoutSession struct {
+	gorm.Model
+	UserID         uint                  `json:"user_id" gorm:"index"`
+	Status         string                `json:"status"` // e.g., "Open", "Completed", "Expired"
+	Step           string                `json:"step"`   // Last completed step: "cart", "address", "delivery", "review"
+	AddressID      *uint                 `json:"address_id,omitempty"`
+	DeliveryMethod string                `json:"delivery_method"`
+	Subtotal       float64               `json:"subtotal"`
+	ShippingCost   float64               `json:"shipping_cost"`
+	PointsRedeemed int                   `json:"points_redeemed"`
+	DiscountAmount float64               `json:"discount_amount"`
+	Total          float64               `json:"total"`
+	ExpiresAt      time.Time             `json:"expires_at" gorm:"index"`
+	OrderID        *uint                 `json:"order_id,omitempty"`
+	Items          []CheckoutSessionItem `json:"items" gorm:"foreignKey:CheckoutSessionID"`
+	Address        *Address              `json:"address,omitempty" gorm:"foreignKey:AddressID"`
+}
+
+// CheckoutSessionItem is a cart line captured when the session started
+type CheckoutSessionItem struct {
+	gorm.Model
+	CheckoutSessionID uint    `json:"checkout_session_id" gorm:"index"`
+	ProductID         uint    `json:"product_id"`
+	VariantID         *uint   `json:"variant_id,omitempty"`
+	Quantity          int     `json:"quantity"`
+	Price             float64 `json:"price"` // Price when the session started
+	Product           Product `json:"product" gorm:"foreignKey:ProductID"`
+}
@@ -0,0 +1,69 @@
+package models
+
+import "gorm.io/gorm"
+
+// Collection types
+const (
+	CollectionTypeManual = "manual" // An ordered list of products picked by merchandisers
+	CollectionTypeSmart  = "smart"  // Every product matching all of the collection's rules
+)
+
+// Smart collection rule fields
+const (
+	CollectionRuleTag      = "tag"
+	CollectionRulePrice    = "price"
+	CollectionRuleCategory = "category" // Includes subcategories
+	CollectionRuleInStock  = "in_stock"
+)
+
+// Smart collection rule operators
+const (
+	CollectionOperatorEquals    = "eq"
+	CollectionOperatorIn        = "in" // Value is a comma-separated list
+	CollectionOperatorLess      = "lt"
+	CollectionOperatorLessEq    = "lte"
+	CollectionOperatorGreater   = "gt"
+	CollectionOperatorGreaterEq = "gte"
+)
+
+// ProductTag is a free-form merchandising label on a product, stored lowercased
+type ProductTag struct {
+	gorm.Model
+	ProductID uint   `json:"-" gorm:"uniqueIndex:idx_product_tags_name"`
+	Name      string `json:"name" gorm:"uniqueIndex:idx_product_tags_name;index;size:64"`
+}
+
+// Collection groups products for merchandising, either as a manual list or by rules
+type Collection struct {
+	gorm.Model
+	Name        string              `json:"name"`
+	Slug        string              `json:"slug" gorm:"uniqueIndex;size:128"`
+	Description string              `json:"description"`
+	Type        string              `json:"type" gorm:"size:16"`
+	Sort        string              `json:"sort,omitempty" gorm:"size:16"` // Default product sort key; manual collections default to their own order
+	Rules       []CollectionRule    `json:"rules,omitempty" gorm:"foreignKey:CollectionID"`
+	Items       []CollectionProduct `json:"items,omitempty" gorm:"foreignKey:CollectionID"` // Manual collections only
+}
+
+// CollectionRule is one condition products must meet to be in a smart collection, such as
+// tag eq summer or price lt 50
+type CollectionRule struct {
+	gorm.Model
+	CollectionID uint   `json:"-" gorm:"index"`
+	Field        string `json:"field" gorm:"size:16"`
+	Operator     string `json:"operator" gorm:"size:8"`
+	Value        string `json:"value"`
+}
+
+// CollectionProduct places a product in a manual collection
+type CollectionProduct struct {
+	gorm.Model
+	CollectionID uint `json:"-" gorm:"uniqueIndex:idx_collection_products_product"`
+	ProductID    uint `json:"product_id" gorm:"uniqueIndex:idx_collection_products_product;index"`
+	Position     int  `json:"position"`
+}
+
+// IsSmart reports whether the collection's products are chosen by rules
+func (c Collection) IsSmart() bool {
+	return c.Type == CollectionTypeSmart
+}
@@ -0,0 +1,41 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// DigitalFile is a downloadable file of a digital product. Files are kept in private
+// storage and only served through signed download links.
+type DigitalFile struct {
+	gorm.Model
+	ProductID   uint   `json:"product_id" gorm:"index"`
+	Name        string `json:"name"` // File name offered to the customer
+	Key         string `json:"-"`    // Storage key
+	ContentType string `json:"content_type"`
+	Size        int64  `json:"size"`
+}
+
+// LicenceKey is a pre-loaded licence key of a digital product. Keys are drawn from the
+// pool and assigned to an order item when the order is paid.
+type LicenceKey struct {
+	gorm.Model
+	ProductID   uint       `json:"product_id" gorm:"uniqueIndex:idx_licence_keys_key"`
+	Key         string     `json:"key" gorm:"uniqueIndex:idx_licence_keys_key;size:255"`
+	OrderItemID *uint      `json:"order_item_id,omitempty" gorm:"index"`
+	AssignedAt  *time.Time `json:"assigned_at,omitempty"`
+}
+
+// DigitalDownload counts the downloads of one file bought with an order item
+type DigitalDownload struct {
+	gorm.Model
+	OrderItemID   uint `json:"order_item_id" gorm:"uniqueIndex:idx_digital_downloads_file"`
+	DigitalFileID uint `json:"digital_file_id" gorm:"uniqueIndex:idx_digital_downloads_file"`
+	Downloads     int  `json:"downloads"`
+}
+
+// IsDigital reports whether the product is downloaded rather than shipped
+func (p Product) IsDigital() bool {
+	return p.Type == ProductTypeDigital
+}
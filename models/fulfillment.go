@@ -0,0 +1,28 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Fulfillment is a single shipment (parcel) carrying some or all of an order's items
+type Fulfillment struct {
+	gorm.Model
+	OrderID        uint              `json:"order_id" gorm:"index"`
+	Carrier        string            `json:"carrier"`
+	TrackingNumber string            `json:"tracking_number"`
+	Status         string            `json:"status"` // e.g., "Shipped", "In Transit", "Delivered"
+	ShippedAt      time.Time         `json:"shipped_at"`
+	DeliveredAt    *time.Time        `json:"delivered_at,omitempty"`
+	Items          []FulfillmentItem `json:"items" gorm:"foreignKey:FulfillmentID"`
+}
+
+// FulfillmentItem records how many units of an order item travel in a shipment
+type FulfillmentItem struct {
+	gorm.Model
+	FulfillmentID uint      `json:"fulfillment_id" gorm:"index"`
+	OrderItemID   uint      `json:"order_item_id" gorm:"index"`
+	Quantity      int       `json:"quantity"`
+	OrderItem     OrderItem `json:"-" gorm:"foreignKey:OrderItemID"`
+}
@@ -0,0 +1,28 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// LoyaltyTransaction is a single entry in a user's points ledger
+type LoyaltyTransaction struct {
+	gorm.Model
+	UserID    uint       `json:"user_id" gorm:"index"`
+	OrderID   *uint      `json:"order_id,omitempty" gorm:"index;uniqueIndex:idx_loyalty_order_earn,where:type = 'Earn'"`
+	Type      string     `json:"type" gorm:"uniqueIndex:idx_loyalty_order_earn"` // e.g., "Earn", "Redeem", "Expire", "Reversal", "Adjustment"
+	Points    int        `json:"points"`                                         // Positive for credits, negative for debits
+	Remaining int        `json:"remaining"`                                      // Unconsumed points left on a credit entry
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	Reason    string     `json:"reason"`
+	User      User       `json:"-" gorm:"foreignKey:UserID"`
+}
+
+// LoyaltyEarnRate overrides the default points earn rate for a category
+type LoyaltyEarnRate struct {
+	gorm.Model
+	CategoryID    uint     `json:"category_id" gorm:"uniqueIndex"`
+	PointsPerUnit float64  `json:"points_per_unit"` // Points earned per currency unit spent
+	Category      Category `json:"category" gorm:"foreignKey:CategoryID"`
+}
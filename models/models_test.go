@@ -111,6 +111,61 @@ func TestOrderModel(t *testing.T) {
 	}
 	err = db.Create(&order).Error
 	assert.NoError(t, err)
+	assert.Regexp(t, `^ORD-\d{4}-\d{6}$`, order.OrderNumber)
+}
+
+func TestNewOrderNumber_Format(t *testing.T) {
+	t.Setenv("ORDER_NUMBER_PREFIX", "SHOP")
+	t.Setenv("ORDER_NUMBER_FORMAT", "{year}/{prefix}{number}")
+	t.Setenv("ORDER_NUMBER_DIGITS", "8")
+
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	assert.NoError(t, err)
+	assert.NoError(t, db.AutoMigrate(&Order{}))
+
+	seen := map[string]bool{}
+	for i := 0; i < 20; i++ {
+		order := Order{TotalAmount: 1}
+		assert.NoError(t, db.Create(&order).Error)
+		assert.Regexp(t, `^\d{4}/SHOP\d{8}$`, order.OrderNumber)
+		assert.False(t, seen[order.OrderNumber])
+		seen[order.OrderNumber] = true
+	}
+
+	// Explicit numbers are kept and must be unique
+	assert.NoError(t, db.Create(&Order{OrderNumber: "MANUAL-1"}).Error)
+	assert.Error(t, db.Create(&Order{OrderNumber: "MANUAL-1"}).Error)
+}
+
+func TestCreateOrder_RetriesTakenNumber(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	assert.NoError(t, err)
+	assert.NoError(t, db.AutoMigrate(&Order{}))
+
+	// The checked number is taken by another order before the insert
+	taken := Order{OrderNumber: "ORD-TAKEN"}
+	assert.NoError(t, db.Create(&taken).Error)
+	collided := false
+	db.Callback().Create().Before("gorm:create").Register("test:take_number", func(tx *gorm.DB) {
+		if order, ok := tx.Statement.Dest.(*Order); ok && !collided {
+			collided = true
+			order.OrderNumber = taken.OrderNumber
+		}
+	})
+
+	order := Order{TotalAmount: 1}
+	assert.NoError(t, db.Transaction(func(tx *gorm.DB) error {
+		return CreateOrder(tx, &order)
+	}))
+	assert.True(t, collided)
+	assert.NotEqual(t, taken.OrderNumber, order.OrderNumber)
+
+	var count int64
+	db.Model(&Order{}).Count(&count)
+	assert.Equal(t, int64(2), count)
+
+	// Explicit numbers are not replaced
+	assert.Error(t, CreateOrder(db, &Order{OrderNumber: taken.OrderNumber}))
 }
 
 func TestPaymentModel(t *testing.T) {
@@ -193,3 +248,46 @@ func TestAddressModel(t *testing.T) {
 	err = db.Create(&address).Error
 	assert.NoError(t, err)
 }
+
+func TestCategorySlug(t *testing.T) {
+	assert.Equal(t, "men-s-shoes", Slugify("Men's Shoes"))
+	assert.Equal(t, "home-garden", Slugify("  Home & Garden  "))
+	assert.Equal(t, "tvs-4k", Slugify("TVs--4K"))
+	assert.Equal(t, "", Slugify("!!!"))
+
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	assert.NoError(t, err)
+	assert.NoError(t, db.AutoMigrate(&Category{}))
+
+	first := Category{Name: "Home & Garden"}
+	assert.NoError(t, db.Create(&first).Error)
+	assert.Equal(t, "home-garden", first.Slug)
+
+	// Names that slugify the same get a numeric suffix
+	second := Category{Name: "Home Garden"}
+	assert.NoError(t, db.Create(&second).Error)
+	assert.Equal(t, "home-garden-2", second.Slug)
+}
+
+func TestProductSlugAndCanonicalURL(t *testing.T) {
+	t.Setenv("SEO_STOREFRONT_URL", "https://shop.example.com")
+
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	assert.NoError(t, err)
+	assert.NoError(t, db.AutoMigrate(&Category{}, &Product{}))
+
+	first := Product{Name: "Steel Kettle", Price: 30}
+	assert.NoError(t, db.Create(&first).Error)
+	assert.Equal(t, "steel-kettle", first.Slug)
+	assert.Equal(t, "https://shop.example.com/products/steel-kettle", first.CanonicalURL)
+
+	// Deleted products keep their slugs, so a new product with the same name gets a suffix
+	assert.NoError(t, db.Delete(&first).Error)
+	second := Product{Name: "Steel kettle", Price: 32}
+	assert.NoError(t, db.Create(&second).Error)
+	assert.Equal(t, "steel-kettle-2", second.Slug)
+
+	var loaded Product
+	assert.NoError(t, db.First(&loaded, second.ID).Error)
+	assert.Equal(t, "https://shop.example.com/products/steel-kettle-2", loaded.CanonicalURL)
+}
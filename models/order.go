@@ -4,21 +4,59 @@ import "gorm.io/gorm"
 
 type Order struct {
 	gorm.Model
-	UserID                uint        `json:"user_id"`
-	TotalAmount           float64     `json:"total_amount"`
-	Status                string      `json:"status"` // e.g., "Pending", "Shipped", "Delivered", "Cancelled"
-	Items                 []OrderItem `gorm:"foreignKey:OrderID"`
-	User                  User        `gorm:"foreignKey:UserID"`
-	TrackingNumber        string      `json:"tracking_number"`
-	Courier               string      `json:"courier"`
-	EstimatedDeliveryDate string      `json:"estimated_delivery_date"`
+	OrderNumber           string                  `json:"order_number" gorm:"uniqueIndex;size:64"`
+	UserID                uint                    `json:"user_id"`
+	TotalAmount           float64                 `json:"total_amount"`
+	Status                string                  `json:"status"` // e.g., "Pending", "Paid", "Partially Shipped", "Shipped", "Delivered", "Cancelled"
+	Items                 []OrderItem             `gorm:"foreignKey:OrderID"`
+	User                  User                    `gorm:"foreignKey:UserID"`
+	TrackingNumber        string                  `json:"tracking_number"`
+	Courier               string                  `json:"courier"`
+	EstimatedDeliveryDate string                  `json:"estimated_delivery_date"`
+	PointsRedeemed        int                     `json:"points_redeemed"`
+	DiscountAmount        float64                 `json:"discount_amount"`
+	AddressID             *uint                   `json:"address_id,omitempty"`
+	Address               *Address                `json:"address,omitempty" gorm:"foreignKey:AddressID"`
+	SubscriptionID        *uint                   `json:"subscription_id,omitempty" gorm:"index"`
+	DeliveryMethod        string                  `json:"delivery_method,omitempty"`
+	ShippingCost          float64                 `json:"shipping_cost"`
+	Fulfillments          []Fulfillment           `json:"fulfillments" gorm:"foreignKey:OrderID"`
+	Cancellations         []OrderItemCancellation `json:"cancellations,omitempty" gorm:"foreignKey:OrderID"`
 }
 
 type OrderItem struct {
 	gorm.Model
-	OrderID   uint    `json:"order_id"`
-	ProductID uint    `json:"product_id"`
-	Quantity  int     `json:"quantity"`
-	Price     float64 `json:"price"` // Price at the time of order
-	Product   Product `gorm:"foreignKey:ProductID"`
+	OrderID           uint                 `json:"order_id"`
+	ProductID         uint                 `json:"product_id"`
+	VariantID         *uint                `json:"variant_id,omitempty"`
+	SKU               string               `json:"sku,omitempty"`      // Variant SKU at the time of order
+	Quantity          int                  `json:"quantity"`           // Quantity still on the order
+	CancelledQuantity int                  `json:"cancelled_quantity"` // Quantity cancelled after the order was placed
+	Price             float64              `json:"price"`              // Price at the time of order
+	Product           Product              `gorm:"foreignKey:ProductID"`
+	Components        []OrderItemComponent `json:"components,omitempty" gorm:"foreignKey:OrderItemID"` // What a bundle contained when ordered
+}
+
+// OrderItemCancellation records a quantity cancelled from a single order line
+type OrderItemCancellation struct {
+	gorm.Model
+	OrderID     uint    `json:"order_id" gorm:"index"`
+	OrderItemID uint    `json:"order_item_id" gorm:"index"`
+	Quantity    int     `json:"quantity"`
+	Reason      string  `json:"reason"`
+	CancelledBy uint    `json:"cancelled_by"`
+	Amount      float64 `json:"amount"`               // Value of the cancelled quantity
+	PaymentID   *uint   `json:"payment_id,omitempty"` // Refund issued for the cancellation, if any
+}
+
+// OrderRevision records one amendment made to an order after it was placed
+type OrderRevision struct {
+	gorm.Model
+	OrderID       uint    `json:"order_id" gorm:"index"`
+	Revision      int     `json:"revision"`
+	AmendedBy     uint    `json:"amended_by"`
+	Changes       string  `json:"changes" gorm:"type:text"` // Human-readable summary, one change per line
+	PreviousTotal float64 `json:"previous_total"`
+	NewTotal      float64 `json:"new_total"`
+	PaymentID     *uint   `json:"payment_id,omitempty"` // Refund or additional charge caused by the amendment
 }
@@ -0,0 +1,91 @@
+package models
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/geoo115/Ecommerce/config"
+	"gorm.io/gorm"
+)
+
+// maxOrderNumberAttempts bounds how many random candidates are tried before giving up
+const maxOrderNumberAttempts = 10
+
+// BeforeCreate assigns a unique order number to every new order
+func (o *Order) BeforeCreate(tx *gorm.DB) error {
+	if o.OrderNumber != "" {
+		return nil
+	}
+	number, err := NewOrderNumber(tx.Session(&gorm.Session{NewDB: true}), time.Now())
+	if err != nil {
+		return err
+	}
+	o.OrderNumber = number
+	return nil
+}
+
+// CreateOrder inserts an order. When its generated order number was taken by another order
+// between being checked and inserted, a new number is drawn and the insert retried.
+func CreateOrder(tx *gorm.DB, order *Order) error {
+	generated := order.OrderNumber == ""
+	for attempt := 1; ; attempt++ {
+		// The savepoint keeps a failed insert from aborting the caller's transaction
+		err := tx.Transaction(func(tx *gorm.DB) error {
+			return tx.Create(order).Error
+		})
+		if err == nil || !generated || attempt == maxOrderNumberAttempts || !isOrderNumberConflict(err) {
+			return err
+		}
+		order.OrderNumber = ""
+	}
+}
+
+// isOrderNumberConflict reports whether an insert failed on the unique order number index
+func isOrderNumberConflict(err error) bool {
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return true
+	}
+	msg := err.Error()
+	return strings.Contains(msg, "order_number") &&
+		(strings.Contains(msg, "UNIQUE constraint failed") || strings.Contains(msg, "duplicate key"))
+}
+
+// NewOrderNumber generates an order number that is not yet in use. Numbers are random
+// rather than sequential so they do not reveal order volume; the unique index on
+// orders.order_number guarantees uniqueness if two requests race for the same candidate,
+// and CreateOrder retries with a new number when that happens.
+func NewOrderNumber(tx *gorm.DB, now time.Time) (string, error) {
+	cfg := config.GetOrderNumberConfig()
+	limit := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(cfg.Digits)), nil)
+
+	for attempt := 0; attempt < maxOrderNumberAttempts; attempt++ {
+		n, err := rand.Int(rand.Reader, limit)
+		if err != nil {
+			return "", err
+		}
+		number := formatOrderNumber(cfg, now, fmt.Sprintf("%0*d", cfg.Digits, n))
+
+		var count int64
+		if err := tx.Model(&Order{}).Unscoped().Where("order_number = ?", number).Count(&count).Error; err != nil {
+			return "", err
+		}
+		if count == 0 {
+			return number, nil
+		}
+	}
+	return "", fmt.Errorf("could not allocate a unique order number after %d attempts", maxOrderNumberAttempts)
+}
+
+// formatOrderNumber expands the configured template
+func formatOrderNumber(cfg config.OrderNumberConfig, now time.Time, number string) string {
+	return strings.NewReplacer(
+		"{prefix}", cfg.Prefix,
+		"{year}", strconv.Itoa(now.Year()),
+		"{number}", number,
+	).Replace(cfg.Format)
+}
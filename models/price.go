@@ -0,0 +1,64 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Price schedule kinds
+const (
+	PriceChangeKind = "price_change" // Replaces the regular price from StartsAt
+	PriceSaleKind   = "sale"         // Sells at Price between StartsAt and EndsAt, showing the regular price as compare-at
+)
+
+// Price schedule statuses
+const (
+	PriceScheduleScheduled = "scheduled"
+	PriceScheduleActive    = "active" // A sale that is running
+	PriceScheduleCompleted = "completed"
+	PriceScheduleCancelled = "cancelled"
+)
+
+// Sources of price changes
+const (
+	PriceSourceManual    = "manual"
+	PriceSourceImport    = "import"
+	PriceSourceScheduled = "scheduled"
+	PriceSourceSaleStart = "sale_start"
+	PriceSourceSaleEnd   = "sale_end"
+)
+
+// PriceSchedule is a future-dated price change or a sale for a product
+type PriceSchedule struct {
+	gorm.Model
+	ProductID uint       `json:"product_id" gorm:"index"`
+	Kind      string     `json:"kind" gorm:"size:16"`
+	Price     float64    `json:"price"`
+	StartsAt  time.Time  `json:"starts_at" gorm:"index"`
+	EndsAt    *time.Time `json:"ends_at,omitempty"` // Sales only; open-ended sales run until cancelled
+	Status    string     `json:"status" gorm:"size:16;index"`
+	Reason    string     `json:"reason"`
+	CreatedBy uint       `json:"created_by"`
+}
+
+// PriceHistory records one change to a product's selling or compare-at price
+type PriceHistory struct {
+	gorm.Model
+	ProductID      uint     `json:"product_id" gorm:"index"`
+	OldPrice       float64  `json:"old_price"`
+	NewPrice       float64  `json:"new_price"`
+	CompareAtPrice *float64 `json:"compare_at_price,omitempty"` // Regular price while a sale runs
+	Source         string   `json:"source" gorm:"size:16"`
+	Reason         string   `json:"reason"`
+	ChangedBy      uint     `json:"changed_by"`            // Admin who made or scheduled the change
+	ScheduleID     *uint    `json:"schedule_id,omitempty"` // Schedule that applied the change
+}
+
+// RegularPrice is the price the product sells at when no sale is running
+func (p Product) RegularPrice() float64 {
+	if p.CompareAtPrice != nil {
+		return *p.CompareAtPrice
+	}
+	return p.Price
+}
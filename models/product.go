@@ -1,22 +1,63 @@
 package models
 
 import (
+	"time"
+
 	"gorm.io/gorm"
 )
 
 type Category struct {
 	gorm.Model
-	Name     string    `json:"name" gorm:"unique"`
-	Products []Product `gorm:"foreignKey:CategoryID"`
+	Name         string     `json:"name" gorm:"unique"`
+	Slug         string     `json:"slug" gorm:"uniqueIndex;size:128"`
+	CanonicalURL string     `json:"canonical_url,omitempty" gorm:"-"` // Storefront URL built from the slug
+	Description  string     `json:"description"`
+	ParentID     *uint      `json:"parent_id" gorm:"index"` // nil for top-level categories
+	SortOrder    int        `json:"sort_order"`
+	Children     []Category `json:"children,omitempty" gorm:"foreignKey:ParentID"`
+	Products     []Product  `gorm:"foreignKey:CategoryID"`
+}
+
+// Breadcrumb is one step of a category path, ordered from the top-level category down
+type Breadcrumb struct {
+	ID   uint   `json:"id"`
+	Name string `json:"name"`
+	Slug string `json:"slug"`
 }
 
 type Product struct {
 	gorm.Model
-	Name        string    `json:"name"`
-	Price       float64   `json:"price"`
-	CategoryID  uint      `json:"category_id"`
-	Description string    `json:"description"`
-	Category    Category  `json:"category" gorm:"foreignKey:CategoryID"`
-	Cart        []Cart    `json:"-" gorm:"foreignKey:ProductID"` // Hide in JSON
-	Inventory   Inventory `json:"inventory" gorm:"foreignKey:ProductID"`
+	SKU            *string                 `json:"sku,omitempty" gorm:"uniqueIndex;size:64"` // External catalogue SKU, used to match bulk imports
+	Name           string                  `json:"name"`
+	Slug           string                  `json:"slug" gorm:"uniqueIndex;size:128"`
+	CanonicalURL   string                  `json:"canonical_url,omitempty" gorm:"-"` // Storefront URL built from the slug
+	Price          float64                 `json:"price"`                            // Current selling price, the sale price during a sale
+	CompareAtPrice *float64                `json:"compare_at_price,omitempty"`       // Regular price while a sale runs
+	Type           string                  `json:"type" gorm:"size:16;default:simple"`
+	Status         string                  `json:"status" gorm:"size:16;default:published;index"` // Drafts are hidden from the public catalogue
+	PublishAt      *time.Time              `json:"publish_at,omitempty"`                          // When a draft is scheduled to be published
+	UnpublishAt    *time.Time              `json:"unpublish_at,omitempty"`                        // When a published product is scheduled to become a draft again
+	CategoryID     uint                    `json:"category_id"`
+	Description    string                  `json:"description"`
+	Category       Category                `json:"category" gorm:"foreignKey:CategoryID"`
+	Cart           []Cart                  `json:"-" gorm:"foreignKey:ProductID"` // Hide in JSON
+	Inventory      Inventory               `json:"inventory" gorm:"foreignKey:ProductID"`
+	Options        []ProductOption         `json:"options,omitempty" gorm:"foreignKey:ProductID"`
+	Variants       []ProductVariant        `json:"variants,omitempty" gorm:"foreignKey:ProductID"`
+	Images         []ProductImage          `json:"images" gorm:"foreignKey:ProductID"`
+	Attributes     []ProductAttributeValue `json:"attributes,omitempty" gorm:"foreignKey:ProductID"`
+	BundleItems    []BundleItem            `json:"bundle_items,omitempty" gorm:"foreignKey:BundleID"`
+	Tags           []ProductTag            `json:"tags,omitempty" gorm:"foreignKey:ProductID"`
+	Breadcrumbs    []Breadcrumb            `json:"breadcrumbs,omitempty" gorm:"-"` // Category path, filled in for responses
+}
+
+// Product statuses
+const (
+	ProductStatusDraft     = "draft"
+	ProductStatusPublished = "published"
+)
+
+// IsPublished reports whether the product is shown in the public catalogue and can be bought
+func (p Product) IsPublished() bool {
+	return p.Status != ProductStatusDraft
 }
@@ -0,0 +1,29 @@
+package models
+
+import "gorm.io/gorm"
+
+// ProductImage is an uploaded product photo with its generated thumbnails
+type ProductImage struct {
+	gorm.Model
+	ProductID   uint                    `json:"product_id" gorm:"index"`
+	Key         string                  `json:"-"` // Blob store key of the original upload
+	URL         string                  `json:"url"`
+	ContentType string                  `json:"content_type"`
+	AltText     string                  `json:"alt_text"`
+	Position    int                     `json:"position"`
+	IsPrimary   bool                    `json:"is_primary"`
+	Width       int                     `json:"width"`
+	Height      int                     `json:"height"`
+	Thumbnails  []ProductImageThumbnail `json:"thumbnails" gorm:"foreignKey:ImageID"`
+}
+
+// ProductImageThumbnail is a resized copy of a product image
+type ProductImageThumbnail struct {
+	gorm.Model
+	ImageID uint   `json:"image_id" gorm:"index"`
+	Size    int    `json:"size"` // Configured longest edge in pixels
+	Key     string `json:"-"`
+	URL     string `json:"url"`
+	Width   int    `json:"width"`
+	Height  int    `json:"height"`
+}
@@ -0,0 +1,21 @@
+package models
+
+import "gorm.io/gorm"
+
+// Reasons a product is recommended alongside another
+const (
+	RecommendationBoughtTogether = "bought_together"
+	RecommendationSameCategory   = "same_category"
+	RecommendationSimilarPrice   = "similar_price"
+)
+
+// ProductRecommendation is a precomputed related product. The whole table is replaced each
+// time recommendations are recomputed.
+type ProductRecommendation struct {
+	gorm.Model
+	ProductID uint    `json:"product_id" gorm:"index"`
+	RelatedID uint    `json:"related_id"`
+	Position  int     `json:"position"` // 1 is the strongest recommendation
+	Reason    string  `json:"reason" gorm:"size:32"`
+	Score     float64 `json:"score"` // Orders containing both products; 0 for fallbacks
+}
@@ -0,0 +1,148 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/geoo115/Ecommerce/config"
+	"gorm.io/gorm"
+)
+
+// Slugify converts a name into a lowercase, hyphen-separated URL slug
+func Slugify(input string) string {
+	var b strings.Builder
+	pendingHyphen := false
+	for _, r := range strings.ToLower(input) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			if pendingHyphen && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			pendingHyphen = false
+			b.WriteRune(r)
+			continue
+		}
+		pendingHyphen = true
+	}
+	return b.String()
+}
+
+// Resource types that have slugs
+const (
+	SlugResourceProduct  = "product"
+	SlugResourceCategory = "category"
+)
+
+// SlugRedirect points a slug a product or category used to have at the record, so links to
+// the old slug keep working after it is changed
+type SlugRedirect struct {
+	gorm.Model
+	ResourceType string `json:"resource_type" gorm:"uniqueIndex:idx_slug_redirects_slug;size:16"`
+	Slug         string `json:"slug" gorm:"uniqueIndex:idx_slug_redirects_slug;size:128"`
+	ResourceID   uint   `json:"resource_id" gorm:"index"`
+}
+
+// BeforeCreate assigns a unique slug derived from the name to categories created without one
+func (c *Category) BeforeCreate(tx *gorm.DB) error {
+	if c.Slug != "" {
+		return nil
+	}
+	slug, err := UniqueCategorySlug(tx.Session(&gorm.Session{NewDB: true}), c.Name, 0)
+	if err != nil {
+		return err
+	}
+	c.Slug = slug
+	return nil
+}
+
+// BeforeCreate assigns a unique slug derived from the name to products created without one
+func (p *Product) BeforeCreate(tx *gorm.DB) error {
+	if p.Slug != "" {
+		return nil
+	}
+	slug, err := UniqueProductSlug(tx.Session(&gorm.Session{NewDB: true}), p.Name, 0)
+	if err != nil {
+		return err
+	}
+	p.Slug = slug
+	return nil
+}
+
+// UniqueCategorySlug derives a slug from a name, adding a numeric suffix when it is already
+// taken by a category other than categoryID. Soft-deleted categories keep their slugs.
+func UniqueCategorySlug(tx *gorm.DB, name string, categoryID uint) (string, error) {
+	return UniqueSlug(name, "category", func(slug string) (bool, error) {
+		return SlugInUse(tx, &Category{}, slug, categoryID)
+	})
+}
+
+// UniqueProductSlug derives a slug from a name, adding a numeric suffix when it is already
+// taken by a product other than productID. Soft-deleted products keep their slugs.
+func UniqueProductSlug(tx *gorm.DB, name string, productID uint) (string, error) {
+	return UniqueSlug(name, "product", func(slug string) (bool, error) {
+		return SlugInUse(tx, &Product{}, slug, productID)
+	})
+}
+
+// UniqueSlug slugifies a name, using fallback when nothing is left, and adds a numeric suffix
+// for as long as taken reports the candidate in use
+func UniqueSlug(name, fallback string, taken func(slug string) (bool, error)) (string, error) {
+	base := Slugify(name)
+	if base == "" {
+		base = fallback
+	}
+	if len(base) > 120 {
+		base = strings.TrimRight(base[:120], "-")
+	}
+	slug := base
+	for n := 2; ; n++ {
+		inUse, err := taken(slug)
+		if err != nil {
+			return "", err
+		}
+		if !inUse {
+			return slug, nil
+		}
+		slug = fmt.Sprintf("%s-%d", base, n)
+	}
+}
+
+// SlugInUse reports whether a record of model's table other than id holds slug, including deleted ones
+func SlugInUse(tx *gorm.DB, model interface{}, slug string, id uint) (bool, error) {
+	var count int64
+	if err := tx.Unscoped().Model(model).Where("slug = ? AND id <> ?", slug, id).Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// AfterFind fills in the product's canonical storefront URL
+func (p *Product) AfterFind(tx *gorm.DB) error {
+	cfg := config.GetSEOConfig()
+	p.CanonicalURL = canonicalURL(cfg.StorefrontURL+cfg.ProductPath, p.Slug)
+	return nil
+}
+
+// AfterSave keeps the canonical URL in step with a new or changed slug
+func (p *Product) AfterSave(tx *gorm.DB) error {
+	return p.AfterFind(tx)
+}
+
+// AfterFind fills in the category's canonical storefront URL
+func (c *Category) AfterFind(tx *gorm.DB) error {
+	cfg := config.GetSEOConfig()
+	c.CanonicalURL = canonicalURL(cfg.StorefrontURL+cfg.CategoryPath, c.Slug)
+	return nil
+}
+
+// AfterSave keeps the canonical URL in step with a new or changed slug
+func (c *Category) AfterSave(tx *gorm.DB) error {
+	return c.AfterFind(tx)
+}
+
+// canonicalURL appends a slug to a page URL, or returns "" for records without a slug
+func canonicalURL(page, slug string) string {
+	if slug == "" {
+		return ""
+	}
+	return page + "/" + slug
+}
@@ -0,0 +1,32 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type Subscription struct {
+	gorm.Model
+	UserID         uint               `json:"user_id" gorm:"index"`
+	AddressID      uint               `json:"address_id"`
+	IntervalDays   int                `json:"interval_days"`
+	NextRunAt      time.Time          `json:"next_run_at" gorm:"index"`
+	Status         string             `json:"status"` // e.g., "Active", "Paused", "Cancelled"
+	PaymentMethod  string             `json:"payment_method"`
+	FailedAttempts int                `json:"failed_attempts"`
+	LastError      string             `json:"last_error"`
+	LastOrderID    *uint              `json:"last_order_id,omitempty"`
+	Items          []SubscriptionItem `json:"items" gorm:"foreignKey:SubscriptionID"`
+	User           User               `json:"-" gorm:"foreignKey:UserID"`
+	Address        Address            `json:"address" gorm:"foreignKey:AddressID"`
+}
+
+type SubscriptionItem struct {
+	gorm.Model
+	SubscriptionID uint    `json:"subscription_id"`
+	ProductID      uint    `json:"product_id"`
+	VariantID      *uint   `json:"variant_id,omitempty"`
+	Quantity       int     `json:"quantity"`
+	Product        Product `json:"product" gorm:"foreignKey:ProductID"`
+}
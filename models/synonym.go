@@ -0,0 +1,10 @@
+package models
+
+import "gorm.io/gorm"
+
+// SynonymGroup is a set of interchangeable search phrases, such as "t-shirt", "tee shirt" and
+// "tee". A search for any of them also matches products described with the others.
+type SynonymGroup struct {
+	gorm.Model
+	Terms []string `json:"terms" gorm:"serializer:json"` // Normalised to lowercase words separated by spaces
+}
@@ -0,0 +1,51 @@
+package models
+
+import "gorm.io/gorm"
+
+// ProductOption is a configurable attribute of a product, such as size or colour
+type ProductOption struct {
+	gorm.Model
+	ProductID uint                 `json:"product_id" gorm:"index"`
+	Name      string               `json:"name"`
+	Position  int                  `json:"position"`
+	Values    []ProductOptionValue `json:"values" gorm:"foreignKey:OptionID"`
+}
+
+// ProductOptionValue is one choice of a product option, such as "M" for size
+type ProductOptionValue struct {
+	gorm.Model
+	OptionID uint   `json:"option_id" gorm:"index"`
+	Value    string `json:"value"`
+}
+
+// ProductVariant is a sellable combination of option values with its own SKU, price and stock.
+// Products without variants are sold as a single item using their Inventory row.
+type ProductVariant struct {
+	gorm.Model
+	ProductID     uint                 `json:"product_id" gorm:"index"`
+	SKU           string               `json:"sku" gorm:"uniqueIndex;size:64"`
+	Barcode       string               `json:"barcode,omitempty"`
+	Title         string               `json:"title"`                    // Option values joined, e.g. "M / Red"
+	PriceOverride *float64             `json:"price_override,omitempty"` // Replaces the product price when set
+	Stock         int                  `json:"stock"`
+	OptionValues  []ProductOptionValue `json:"option_values" gorm:"many2many:product_variant_option_values"`
+}
+
+// PriceFor returns the variant's selling price given its product's base price
+func (v ProductVariant) PriceFor(basePrice float64) float64 {
+	if v.PriceOverride != nil {
+		return *v.PriceOverride
+	}
+	return basePrice
+}
+
+// WhereVariant scopes a query on a table with a nullable variant_id column to one variant,
+// or to rows without a variant when variantID is nil
+func WhereVariant(variantID *uint) func(*gorm.DB) *gorm.DB {
+	return func(query *gorm.DB) *gorm.DB {
+		if variantID == nil {
+			return query.Where("variant_id IS NULL")
+		}
+		return query.Where("variant_id = ?", *variantID)
+	}
+}
@@ -0,0 +1,594 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/geoo115/Ecommerce/db"
+	"github.com/geoo115/Ecommerce/models"
+	"github.com/geoo115/Ecommerce/utils"
+	"gorm.io/gorm"
+)
+
+// Attribute errors surfaced to handlers
+var (
+	ErrAttributeNotFound      = errors.New("attribute not found")
+	ErrInvalidAttributeType   = errors.New("attribute type must be string, number, boolean or enum")
+	ErrInvalidAttributeCode   = errors.New("attribute code may only contain lowercase letters, digits and hyphens")
+	ErrAttributeOptions       = errors.New("enum attributes need at least one option")
+	ErrDuplicateAttribute     = errors.New("attribute code is already in use")
+	ErrInvalidAttributeValue  = errors.New("invalid attribute value")
+	ErrAttributeNotAssigned   = errors.New("attribute is not assigned to the product's category")
+	ErrInvalidAttributeFilter = errors.New("invalid attribute filter")
+)
+
+// attributeFilterPrefix starts query parameters that filter on attributes, e.g.
+// attr.brand=acme,globex or attr.screen-size.min=13
+const attributeFilterPrefix = "attr."
+
+// AttributeUpdate describes changes to an attribute; nil fields are left unchanged.
+// AddOptions appends allowed values to an enum attribute.
+type AttributeUpdate struct {
+	Name       *string
+	Unit       *string
+	Filterable *bool
+	AddOptions []string
+}
+
+// AttributeFilter restricts products to those whose value for an attribute is one of Values,
+// or for number attributes lies within Min and Max
+type AttributeFilter struct {
+	Code   string
+	Values []string
+	Min    *float64
+	Max    *float64
+}
+
+// String renders the filter in a stable form for cache keys
+func (f AttributeFilter) String() string {
+	s := f.Code + "=" + strings.Join(f.Values, "|")
+	if f.Min != nil {
+		s += ">=" + strconv.FormatFloat(*f.Min, 'f', -1, 64)
+	}
+	if f.Max != nil {
+		s += "<=" + strconv.FormatFloat(*f.Max, 'f', -1, 64)
+	}
+	return s
+}
+
+// FacetValue is one attribute value and how many products in a result set have it
+type FacetValue struct {
+	Value string `json:"value"`
+	Count int64  `json:"count"`
+}
+
+// Facet summarises the values of one filterable attribute across a result set
+type Facet struct {
+	Code   string       `json:"code"`
+	Name   string       `json:"name"`
+	Type   string       `json:"type"`
+	Unit   string       `json:"unit,omitempty"`
+	Values []FacetValue `json:"values"`
+	Min    *float64     `json:"min,omitempty"` // Number attributes only
+	Max    *float64     `json:"max,omitempty"`
+}
+
+// AttributeService interface defines product attribute business logic
+type AttributeService interface {
+	CreateAttribute(attribute *models.Attribute, options []string) error
+	UpdateAttribute(attribute *models.Attribute, update AttributeUpdate) error
+	DeleteAttribute(attribute *models.Attribute) error
+	AssignToCategory(categoryID, attributeID uint, position int) (*models.CategoryAttribute, error)
+	UnassignFromCategory(categoryID, attributeID uint) error
+	CategoryAttributes(categoryID uint) ([]models.Attribute, error)
+	SetProductValues(product *models.Product, values map[string]interface{}) error
+	FilterScope(filters []AttributeFilter) (func(*gorm.DB) *gorm.DB, error)
+	Facets(productIDs *gorm.DB) ([]Facet, error)
+}
+
+// attributeService implements AttributeService interface
+type attributeService struct {
+	db *gorm.DB
+}
+
+// NewAttributeService creates a new attribute service instance
+func NewAttributeService() AttributeService {
+	return NewAttributeServiceWithDB(db.DB)
+}
+
+// NewAttributeServiceWithDB creates an attribute service bound to a specific database handle
+func NewAttributeServiceWithDB(database *gorm.DB) AttributeService {
+	return &attributeService{db: database}
+}
+
+// CreateAttribute validates and saves a new attribute. The code defaults to a slug of the
+// name; enum attributes must list their allowed values.
+func (s *attributeService) CreateAttribute(attribute *models.Attribute, options []string) error {
+	switch attribute.Type {
+	case models.AttributeTypeString, models.AttributeTypeNumber, models.AttributeTypeBoolean:
+		options = nil
+	case models.AttributeTypeEnum:
+		options = uniqueOptions(options)
+		if len(options) == 0 {
+			return ErrAttributeOptions
+		}
+	default:
+		return ErrInvalidAttributeType
+	}
+
+	if attribute.Code == "" {
+		attribute.Code = models.Slugify(attribute.Name)
+	}
+	if !utils.ValidateSlug(attribute.Code) || len(attribute.Code) > 64 {
+		return ErrInvalidAttributeCode
+	}
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		var count int64
+		if err := tx.Model(&models.Attribute{}).Where("code = ?", attribute.Code).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return ErrDuplicateAttribute
+		}
+
+		attribute.Options = make([]models.AttributeOption, len(options))
+		for i, value := range options {
+			attribute.Options[i] = models.AttributeOption{Value: value, Position: i}
+		}
+		return tx.Create(attribute).Error
+	})
+}
+
+// UpdateAttribute changes an attribute's display fields or adds enum options.
+// Codes and types are fixed once created because stored values and filters depend on them.
+func (s *attributeService) UpdateAttribute(attribute *models.Attribute, update AttributeUpdate) error {
+	if len(update.AddOptions) > 0 && attribute.Type != models.AttributeTypeEnum {
+		return ErrAttributeOptions
+	}
+
+	updates := map[string]interface{}{}
+	if update.Name != nil {
+		attribute.Name = *update.Name
+		updates["name"] = attribute.Name
+	}
+	if update.Unit != nil {
+		attribute.Unit = *update.Unit
+		updates["unit"] = attribute.Unit
+	}
+	if update.Filterable != nil {
+		attribute.Filterable = *update.Filterable
+		updates["filterable"] = attribute.Filterable
+	}
+	if len(updates) == 0 && len(update.AddOptions) == 0 {
+		return ErrNoChanges
+	}
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if len(updates) > 0 {
+			if err := tx.Model(attribute).Updates(updates).Error; err != nil {
+				return err
+			}
+		}
+
+		existing := make(map[string]bool, len(attribute.Options))
+		for _, option := range attribute.Options {
+			existing[strings.ToLower(option.Value)] = true
+		}
+		for _, value := range uniqueOptions(update.AddOptions) {
+			if existing[strings.ToLower(value)] {
+				continue
+			}
+			option := models.AttributeOption{AttributeID: attribute.ID, Value: value, Position: len(attribute.Options)}
+			if err := tx.Create(&option).Error; err != nil {
+				return err
+			}
+			attribute.Options = append(attribute.Options, option)
+		}
+		return nil
+	})
+}
+
+// DeleteAttribute removes an attribute with its options, category assignments and product values.
+// Rows are removed permanently so the code can be reused.
+func (s *attributeService) DeleteAttribute(attribute *models.Attribute) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		for _, model := range []interface{}{&models.ProductAttributeValue{}, &models.CategoryAttribute{}, &models.AttributeOption{}} {
+			if err := tx.Unscoped().Where("attribute_id = ?", attribute.ID).Delete(model).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Unscoped().Delete(attribute).Error
+	})
+}
+
+// AssignToCategory makes an attribute available to products in a category and its subcategories.
+// Assigning an attribute that is already assigned updates its position.
+func (s *attributeService) AssignToCategory(categoryID, attributeID uint, position int) (*models.CategoryAttribute, error) {
+	var category models.Category
+	if err := s.db.First(&category, categoryID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrCategoryNotFound
+		}
+		return nil, err
+	}
+	var attribute models.Attribute
+	if err := s.db.Preload("Options").First(&attribute, attributeID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrAttributeNotFound
+		}
+		return nil, err
+	}
+
+	var assignment models.CategoryAttribute
+	err := s.db.Where("category_id = ? AND attribute_id = ?", categoryID, attributeID).First(&assignment).Error
+	switch {
+	case err == nil:
+		if err := s.db.Model(&assignment).Update("position", position).Error; err != nil {
+			return nil, err
+		}
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		assignment = models.CategoryAttribute{CategoryID: categoryID, AttributeID: attributeID, Position: position}
+		if err := s.db.Create(&assignment).Error; err != nil {
+			return nil, err
+		}
+	default:
+		return nil, err
+	}
+	assignment.Attribute = attribute
+	return &assignment, nil
+}
+
+// UnassignFromCategory removes an attribute from a category, reporting ErrAttributeNotFound when
+// it was not assigned. Values already stored on products are kept so reassigning restores them.
+func (s *attributeService) UnassignFromCategory(categoryID, attributeID uint) error {
+	result := s.db.Unscoped().Where("category_id = ? AND attribute_id = ?", categoryID, attributeID).
+		Delete(&models.CategoryAttribute{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrAttributeNotFound
+	}
+	return nil
+}
+
+// CategoryAttributes returns the attributes that apply to a category: those assigned to it
+// and to each of its ancestors, top-level category first
+func (s *attributeService) CategoryAttributes(categoryID uint) ([]models.Attribute, error) {
+	path, err := NewCategoryServiceWithDB(s.db).Breadcrumbs(categoryID)
+	if err != nil {
+		return nil, err
+	}
+	depth := make(map[uint]int, len(path))
+	ids := make([]uint, len(path))
+	for i, crumb := range path {
+		depth[crumb.ID] = i
+		ids[i] = crumb.ID
+	}
+
+	var assignments []models.CategoryAttribute
+	if err := s.db.Preload("Attribute.Options", func(options *gorm.DB) *gorm.DB {
+		return options.Order("position, id")
+	}).Where("category_id IN ?", ids).Order("position, id").Find(&assignments).Error; err != nil {
+		return nil, err
+	}
+	sort.SliceStable(assignments, func(i, j int) bool {
+		return depth[assignments[i].CategoryID] < depth[assignments[j].CategoryID]
+	})
+
+	attributes := make([]models.Attribute, 0, len(assignments))
+	seen := make(map[uint]bool, len(assignments))
+	for _, assignment := range assignments {
+		if assignment.Attribute.ID == 0 || seen[assignment.AttributeID] {
+			continue
+		}
+		seen[assignment.AttributeID] = true
+		attributes = append(attributes, assignment.Attribute)
+	}
+	return attributes, nil
+}
+
+// SetProductValues sets a product's attribute values keyed by attribute code. Values must match
+// the attribute's type and the attribute must apply to the product's category; a nil value
+// removes the product's value for that attribute.
+func (s *attributeService) SetProductValues(product *models.Product, values map[string]interface{}) error {
+	if len(values) == 0 {
+		return ErrNoChanges
+	}
+
+	applicable, err := s.CategoryAttributes(product.CategoryID)
+	if err != nil && !errors.Is(err, ErrCategoryNotFound) {
+		return err
+	}
+	byCode := make(map[string]models.Attribute, len(applicable))
+	for _, attribute := range applicable {
+		byCode[attribute.Code] = attribute
+	}
+
+	codes := make([]string, 0, len(values))
+	for code := range values {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		for _, code := range codes {
+			attribute, ok := byCode[code]
+			if !ok {
+				return fmt.Errorf("%w: %s", ErrAttributeNotAssigned, code)
+			}
+			if err := tx.Unscoped().Where("product_id = ? AND attribute_id = ?", product.ID, attribute.ID).
+				Delete(&models.ProductAttributeValue{}).Error; err != nil {
+				return err
+			}
+			if values[code] == nil {
+				continue
+			}
+
+			value, err := attributeValue(attribute, values[code])
+			if err != nil {
+				return err
+			}
+			value.ProductID = product.ID
+			if err := tx.Create(&value).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// attributeValue converts a decoded JSON value into a stored value for the attribute's type
+func attributeValue(attribute models.Attribute, raw interface{}) (models.ProductAttributeValue, error) {
+	value := models.ProductAttributeValue{AttributeID: attribute.ID}
+	invalid := fmt.Errorf("%w for %s: expected %s", ErrInvalidAttributeValue, attribute.Code, attribute.Type)
+
+	switch attribute.Type {
+	case models.AttributeTypeString:
+		text, ok := raw.(string)
+		text = utils.SanitizeString(text)
+		if !ok || text == "" || len(text) > 255 {
+			return value, invalid
+		}
+		value.Value = text
+	case models.AttributeTypeNumber:
+		number, ok := raw.(float64)
+		if !ok {
+			return value, invalid
+		}
+		value.Value = strconv.FormatFloat(number, 'f', -1, 64)
+		value.NumberValue = &number
+	case models.AttributeTypeBoolean:
+		flag, ok := raw.(bool)
+		if !ok {
+			return value, invalid
+		}
+		value.Value = strconv.FormatBool(flag)
+	case models.AttributeTypeEnum:
+		text, ok := raw.(string)
+		if !ok {
+			return value, invalid
+		}
+		for _, option := range attribute.Options {
+			if strings.EqualFold(option.Value, strings.TrimSpace(text)) {
+				value.Value = option.Value
+				return value, nil
+			}
+		}
+		return value, fmt.Errorf("%w for %s: %q is not an allowed option", ErrInvalidAttributeValue, attribute.Code, text)
+	default:
+		return value, invalid
+	}
+	return value, nil
+}
+
+// ParseAttributeFilters reads attr.<code>=a,b and attr.<code>.min / attr.<code>.max query
+// parameters. Filters are returned sorted by code.
+func ParseAttributeFilters(query url.Values) ([]AttributeFilter, error) {
+	byCode := map[string]*AttributeFilter{}
+	for key, params := range query {
+		if !strings.HasPrefix(key, attributeFilterPrefix) {
+			continue
+		}
+		code := strings.TrimPrefix(key, attributeFilterPrefix)
+		bound := ""
+		if strings.HasSuffix(code, ".min") || strings.HasSuffix(code, ".max") {
+			bound = code[len(code)-3:]
+			code = code[:len(code)-4]
+		}
+		if !utils.ValidateSlug(code) {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidAttributeFilter, key)
+		}
+
+		filter, ok := byCode[code]
+		if !ok {
+			filter = &AttributeFilter{Code: code}
+			byCode[code] = filter
+		}
+		for _, param := range params {
+			if bound != "" {
+				number, err := strconv.ParseFloat(strings.TrimSpace(param), 64)
+				if err != nil {
+					return nil, fmt.Errorf("%w: %s must be a number", ErrInvalidAttributeFilter, key)
+				}
+				if bound == "min" {
+					filter.Min = &number
+				} else {
+					filter.Max = &number
+				}
+				continue
+			}
+			for _, value := range strings.Split(param, ",") {
+				if value = strings.TrimSpace(value); value != "" {
+					filter.Values = append(filter.Values, value)
+				}
+			}
+		}
+	}
+
+	filters := make([]AttributeFilter, 0, len(byCode))
+	for _, filter := range byCode {
+		sort.Strings(filter.Values)
+		filters = append(filters, *filter)
+	}
+	sort.Slice(filters, func(i, j int) bool { return filters[i].Code < filters[j].Code })
+	return filters, nil
+}
+
+// FilterScope resolves attribute filters into a query scope on products. Each filter narrows
+// the result; the values within a filter are alternatives.
+func (s *attributeService) FilterScope(filters []AttributeFilter) (func(*gorm.DB) *gorm.DB, error) {
+	if len(filters) == 0 {
+		return func(query *gorm.DB) *gorm.DB { return query }, nil
+	}
+
+	codes := make([]string, len(filters))
+	for i, filter := range filters {
+		codes[i] = filter.Code
+	}
+	var attributes []models.Attribute
+	if err := s.db.Where("code IN ?", codes).Find(&attributes).Error; err != nil {
+		return nil, err
+	}
+	byCode := make(map[string]models.Attribute, len(attributes))
+	for _, attribute := range attributes {
+		byCode[attribute.Code] = attribute
+	}
+
+	var conditions []*gorm.DB
+	for _, filter := range filters {
+		attribute, ok := byCode[filter.Code]
+		if !ok {
+			return nil, fmt.Errorf("%w: unknown attribute %s", ErrInvalidAttributeFilter, filter.Code)
+		}
+		if (filter.Min != nil || filter.Max != nil) && attribute.Type != models.AttributeTypeNumber {
+			return nil, fmt.Errorf("%w: %s is not a number attribute", ErrInvalidAttributeFilter, filter.Code)
+		}
+
+		condition := s.db.Model(&models.ProductAttributeValue{}).Select("product_id").Where("attribute_id = ?", attribute.ID)
+		if len(filter.Values) > 0 {
+			switch attribute.Type {
+			case models.AttributeTypeNumber:
+				numbers := make([]float64, len(filter.Values))
+				for i, value := range filter.Values {
+					number, err := strconv.ParseFloat(value, 64)
+					if err != nil {
+						return nil, fmt.Errorf("%w: %s must be a number", ErrInvalidAttributeFilter, filter.Code)
+					}
+					numbers[i] = number
+				}
+				condition = condition.Where("number_value IN ?", numbers)
+			default:
+				lowered := make([]string, len(filter.Values))
+				for i, value := range filter.Values {
+					lowered[i] = strings.ToLower(value)
+				}
+				condition = condition.Where("LOWER(value) IN ?", lowered)
+			}
+		}
+		if filter.Min != nil {
+			condition = condition.Where("number_value >= ?", *filter.Min)
+		}
+		if filter.Max != nil {
+			condition = condition.Where("number_value <= ?", *filter.Max)
+		}
+		conditions = append(conditions, condition)
+	}
+
+	return func(query *gorm.DB) *gorm.DB {
+		for _, condition := range conditions {
+			query = query.Where("products.id IN (?)", condition)
+		}
+		return query
+	}, nil
+}
+
+// Facets counts the products having each value of every filterable attribute, over the
+// products selected by productIDs (a query selecting product IDs)
+func (s *attributeService) Facets(productIDs *gorm.DB) ([]Facet, error) {
+	var rows []struct {
+		AttributeID uint
+		Value       string
+		Count       int64
+	}
+	if err := s.db.Model(&models.ProductAttributeValue{}).
+		Select("attribute_id, value, COUNT(DISTINCT product_id) AS count").
+		Where("product_id IN (?)", productIDs).
+		Group("attribute_id, value").
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return []Facet{}, nil
+	}
+
+	ids := make([]uint, 0, len(rows))
+	for _, row := range rows {
+		ids = append(ids, row.AttributeID)
+	}
+	var attributes []models.Attribute
+	if err := s.db.Preload("Options").Where("id IN ? AND filterable = ?", ids, true).
+		Order("name, id").Find(&attributes).Error; err != nil {
+		return nil, err
+	}
+
+	values := make(map[uint][]FacetValue)
+	for _, row := range rows {
+		values[row.AttributeID] = append(values[row.AttributeID], FacetValue{Value: row.Value, Count: row.Count})
+	}
+
+	facets := make([]Facet, 0, len(attributes))
+	for _, attribute := range attributes {
+		facet := Facet{Code: attribute.Code, Name: attribute.Name, Type: attribute.Type, Unit: attribute.Unit, Values: values[attribute.ID]}
+		sortFacetValues(&facet, attribute)
+		facets = append(facets, facet)
+	}
+	return facets, nil
+}
+
+// sortFacetValues orders numbers numerically (recording the range), enum values by option
+// position and everything else alphabetically
+func sortFacetValues(facet *Facet, attribute models.Attribute) {
+	switch attribute.Type {
+	case models.AttributeTypeNumber:
+		number := func(v FacetValue) float64 {
+			n, _ := strconv.ParseFloat(v.Value, 64)
+			return n
+		}
+		sort.Slice(facet.Values, func(i, j int) bool { return number(facet.Values[i]) < number(facet.Values[j]) })
+		if len(facet.Values) > 0 {
+			low, high := number(facet.Values[0]), number(facet.Values[len(facet.Values)-1])
+			facet.Min, facet.Max = &low, &high
+		}
+	case models.AttributeTypeEnum:
+		position := make(map[string]int, len(attribute.Options))
+		for _, option := range attribute.Options {
+			position[option.Value] = option.Position
+		}
+		sort.Slice(facet.Values, func(i, j int) bool {
+			return position[facet.Values[i].Value] < position[facet.Values[j].Value]
+		})
+	default:
+		sort.Slice(facet.Values, func(i, j int) bool { return facet.Values[i].Value < facet.Values[j].Value })
+	}
+}
+
+// uniqueOptions trims enum options and drops blanks and case-insensitive duplicates
+func uniqueOptions(options []string) []string {
+	var unique []string
+	seen := map[string]bool{}
+	for _, option := range options {
+		option = utils.SanitizeString(option)
+		if option == "" || seen[strings.ToLower(option)] {
+			continue
+		}
+		seen[strings.ToLower(option)] = true
+		unique = append(unique, option)
+	}
+	return unique
+}
@@ -0,0 +1,103 @@
+package services
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/geoo115/Ecommerce/db"
+	"github.com/geoo115/Ecommerce/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAttributeService_ValuesAndFacets(t *testing.T) {
+	originalDB := db.DB
+	testDB := db.SetupTestDB(t)
+	db.DB = testDB
+	defer func() { db.DB = originalDB }()
+
+	electronics := models.Category{Name: "Electronics"}
+	testDB.Create(&electronics)
+	laptops := models.Category{Name: "Laptops", ParentID: &electronics.ID}
+	testDB.Create(&laptops)
+
+	service := NewAttributeService()
+	brand := models.Attribute{Name: "Brand", Type: models.AttributeTypeEnum, Filterable: true}
+	assert.NoError(t, service.CreateAttribute(&brand, []string{"Acme", "Globex", "acme"}))
+	assert.Equal(t, "brand", brand.Code)
+	assert.Len(t, brand.Options, 2)
+	screen := models.Attribute{Name: "Screen Size", Type: models.AttributeTypeNumber, Unit: "in", Filterable: true}
+	assert.NoError(t, service.CreateAttribute(&screen, nil))
+	assert.ErrorIs(t, service.CreateAttribute(&models.Attribute{Name: "Brand", Type: models.AttributeTypeString}, nil), ErrDuplicateAttribute)
+	assert.ErrorIs(t, service.CreateAttribute(&models.Attribute{Name: "Colour", Type: models.AttributeTypeEnum}, nil), ErrAttributeOptions)
+	assert.ErrorIs(t, service.CreateAttribute(&models.Attribute{Name: "Weight", Type: "decimal"}, nil), ErrInvalidAttributeType)
+
+	// Brand applies to all electronics, screen size only to laptops
+	_, err := service.AssignToCategory(electronics.ID, brand.ID, 0)
+	assert.NoError(t, err)
+	_, err = service.AssignToCategory(laptops.ID, screen.ID, 0)
+	assert.NoError(t, err)
+
+	applicable, err := service.CategoryAttributes(laptops.ID)
+	assert.NoError(t, err)
+	if assert.Len(t, applicable, 2) {
+		assert.Equal(t, "brand", applicable[0].Code)
+	}
+
+	small := createStockedProduct(t, "Netbook", 300, 1)
+	large := createStockedProduct(t, "Workstation", 1500, 1)
+	speaker := createStockedProduct(t, "Speaker", 80, 1)
+	testDB.Model(&small).Update("category_id", laptops.ID)
+	testDB.Model(&large).Update("category_id", laptops.ID)
+	testDB.Model(&speaker).Update("category_id", electronics.ID)
+	small.CategoryID, large.CategoryID, speaker.CategoryID = laptops.ID, laptops.ID, electronics.ID
+
+	assert.NoError(t, service.SetProductValues(&small, map[string]interface{}{"brand": "acme", "screen-size": 11.6}))
+	assert.NoError(t, service.SetProductValues(&large, map[string]interface{}{"brand": "Globex", "screen-size": 17.0}))
+	assert.NoError(t, service.SetProductValues(&speaker, map[string]interface{}{"brand": "Acme"}))
+	assert.ErrorIs(t, service.SetProductValues(&speaker, map[string]interface{}{"screen-size": 5.0}), ErrAttributeNotAssigned)
+	assert.ErrorIs(t, service.SetProductValues(&small, map[string]interface{}{"brand": "Initech"}), ErrInvalidAttributeValue)
+	assert.ErrorIs(t, service.SetProductValues(&small, map[string]interface{}{"screen-size": "big"}), ErrInvalidAttributeValue)
+
+	var stored models.ProductAttributeValue
+	testDB.Where("product_id = ? AND attribute_id = ?", small.ID, brand.ID).First(&stored)
+	assert.Equal(t, "Acme", stored.Value) // Enum values are stored in their canonical case
+
+	filters, err := ParseAttributeFilters(url.Values{"attr.brand": {"acme"}, "attr.screen-size.min": {"10"}, "page": {"1"}})
+	assert.NoError(t, err)
+	assert.Len(t, filters, 2)
+	scope, err := service.FilterScope(filters)
+	assert.NoError(t, err)
+	var matched []models.Product
+	testDB.Model(&models.Product{}).Scopes(scope).Find(&matched)
+	if assert.Len(t, matched, 1) {
+		assert.Equal(t, small.ID, matched[0].ID)
+	}
+
+	_, err = service.FilterScope([]AttributeFilter{{Code: "weight", Values: []string{"1"}}})
+	assert.ErrorIs(t, err, ErrInvalidAttributeFilter)
+	min := 1.0
+	_, err = service.FilterScope([]AttributeFilter{{Code: "brand", Min: &min}})
+	assert.ErrorIs(t, err, ErrInvalidAttributeFilter)
+
+	facets, err := service.Facets(testDB.Model(&models.Product{}).Select("products.id"))
+	assert.NoError(t, err)
+	if assert.Len(t, facets, 2) {
+		assert.Equal(t, "brand", facets[0].Code)
+		assert.Equal(t, []FacetValue{{Value: "Acme", Count: 2}, {Value: "Globex", Count: 1}}, facets[0].Values)
+		assert.Equal(t, 11.6, *facets[1].Min)
+		assert.Equal(t, 17.0, *facets[1].Max)
+	}
+
+	// A null value removes the product's value
+	assert.NoError(t, service.SetProductValues(&large, map[string]interface{}{"screen-size": nil}))
+	var count int64
+	testDB.Model(&models.ProductAttributeValue{}).Where("product_id = ?", large.ID).Count(&count)
+	assert.Equal(t, int64(1), count)
+}
+
+func TestParseAttributeFilters_Invalid(t *testing.T) {
+	_, err := ParseAttributeFilters(url.Values{"attr.screen-size.max": {"large"}})
+	assert.ErrorIs(t, err, ErrInvalidAttributeFilter)
+	_, err = ParseAttributeFilters(url.Values{"attr.Bad Code": {"x"}})
+	assert.ErrorIs(t, err, ErrInvalidAttributeFilter)
+}
@@ -0,0 +1,103 @@
+package services
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ErrInvalidBlobKey is returned for keys that would escape the store
+var ErrInvalidBlobKey = errors.New("invalid blob key")
+
+// BlobStore saves and serves uploaded files such as product images
+type BlobStore interface {
+	Put(key string, data io.Reader, contentType string) error
+	Delete(key string) error
+	URL(key string) string
+}
+
+// FileStore keeps private files, such as digital products, that the application reads back
+// itself rather than exposing at a public URL
+type FileStore interface {
+	Put(key string, data io.Reader, contentType string) error
+	Open(key string) (io.ReadCloser, error)
+	Delete(key string) error
+}
+
+// LocalBlobStore keeps blobs on the local filesystem and serves them under a base URL
+type LocalBlobStore struct {
+	Dir     string
+	BaseURL string
+}
+
+// NewLocalBlobStore creates a filesystem blob store rooted at dir
+func NewLocalBlobStore(dir, baseURL string) *LocalBlobStore {
+	return &LocalBlobStore{Dir: dir, BaseURL: strings.TrimRight(baseURL, "/")}
+}
+
+// Blobs is the store used for uploaded media. When nil, a local store configured
+// from the MEDIA_* settings is used.
+var Blobs BlobStore
+
+// DigitalFiles is the store used for digital product files. When nil, a local store
+// configured from the DIGITAL_* settings is used.
+var DigitalFiles FileStore
+
+// Put writes the blob, creating parent directories as needed
+func (s *LocalBlobStore) Put(key string, data io.Reader, contentType string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(file, data); err != nil {
+		file.Close()
+		os.Remove(path)
+		return err
+	}
+	return file.Close()
+}
+
+// Open reads the blob back
+func (s *LocalBlobStore) Open(key string) (io.ReadCloser, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+	return os.Open(path)
+}
+
+// Delete removes the blob; deleting a missing blob is not an error
+func (s *LocalBlobStore) Delete(key string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// URL returns the public address of the blob
+func (s *LocalBlobStore) URL(key string) string {
+	return s.BaseURL + "/" + key
+}
+
+// path maps a slash-separated key to a file inside the store directory
+func (s *LocalBlobStore) path(key string) (string, error) {
+	clean := filepath.Clean(filepath.FromSlash(key))
+	if key == "" || filepath.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, ".."+string(filepath.Separator)) {
+		return "", ErrInvalidBlobKey
+	}
+	return filepath.Join(s.Dir, clean), nil
+}
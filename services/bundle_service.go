@@ -0,0 +1,189 @@
+package services
+
+import (
+	"errors"
+
+	"github.com/geoo115/Ecommerce/db"
+	"github.com/geoo115/Ecommerce/models"
+	"gorm.io/gorm"
+)
+
+// Bundle errors surfaced to handlers
+var (
+	ErrInvalidBundle   = errors.New("a bundle needs at least one component, each with a quantity between 1 and 100")
+	ErrBundleComponent = errors.New("bundle components must be other existing products that are not bundles")
+	ErrBundleVariants  = errors.New("bundles and their components cannot have variants")
+)
+
+// maxBundleQuantity caps how many of one component a bundle can contain
+const maxBundleQuantity = 100
+
+// BundleComponent is a component product and how many of it each bundle contains
+type BundleComponent struct {
+	ProductID uint `json:"product_id"`
+	Quantity  int  `json:"quantity"`
+}
+
+// BundleService interface defines product bundle business logic
+type BundleService interface {
+	SetComponents(product *models.Product, components []BundleComponent) error
+}
+
+// bundleService implements BundleService interface
+type bundleService struct {
+	db *gorm.DB
+}
+
+// NewBundleService creates a new bundle service instance
+func NewBundleService() BundleService {
+	return NewBundleServiceWithDB(db.DB)
+}
+
+// NewBundleServiceWithDB creates a bundle service bound to a specific database handle
+func NewBundleServiceWithDB(database *gorm.DB) BundleService {
+	return &bundleService{db: database}
+}
+
+// SetComponents makes the product a bundle of the given components, replacing any it had.
+// Repeated components are merged. A bundle cannot contain itself or another bundle, and
+// neither a bundle nor its components can have variants.
+func (s *bundleService) SetComponents(product *models.Product, components []BundleComponent) error {
+	if len(components) == 0 {
+		return ErrInvalidBundle
+	}
+	if product.IsDigital() {
+		return ErrDigitalConflict
+	}
+	var componentIDs []uint
+	quantities := make(map[uint]int, len(components))
+	for _, component := range components {
+		if component.ProductID == product.ID {
+			return ErrBundleComponent
+		}
+		if _, seen := quantities[component.ProductID]; !seen {
+			componentIDs = append(componentIDs, component.ProductID)
+		}
+		quantities[component.ProductID] += component.Quantity
+	}
+	for _, quantity := range quantities {
+		if quantity < 1 || quantity > maxBundleQuantity {
+			return ErrInvalidBundle
+		}
+	}
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		var count int64
+		if err := tx.Model(&models.Product{}).Where("id IN ? AND type <> ?", componentIDs, models.ProductTypeBundle).
+			Count(&count).Error; err != nil {
+			return err
+		}
+		if int(count) != len(componentIDs) {
+			return ErrBundleComponent
+		}
+		if err := tx.Model(&models.Product{}).Where("id IN ? AND type = ?", componentIDs, models.ProductTypeDigital).
+			Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return ErrDigitalConflict
+		}
+		// A product already inside a bundle would nest bundles if it became one
+		if err := tx.Model(&models.BundleItem{}).Where("component_id = ?", product.ID).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return ErrBundleComponent
+		}
+		if err := tx.Model(&models.ProductVariant{}).Where("product_id IN ?", append(componentIDs, product.ID)).
+			Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return ErrBundleVariants
+		}
+
+		if err := tx.Unscoped().Where("bundle_id = ?", product.ID).Delete(&models.BundleItem{}).Error; err != nil {
+			return err
+		}
+		items := make([]models.BundleItem, len(componentIDs))
+		for i, id := range componentIDs {
+			items[i] = models.BundleItem{BundleID: product.ID, ComponentID: id, Quantity: quantities[id]}
+		}
+		if err := tx.Create(&items).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.Product{}).Where("id = ?", product.ID).Update("type", models.ProductTypeBundle).Error; err != nil {
+			return err
+		}
+		product.Type = models.ProductTypeBundle
+		product.BundleItems = items
+		return nil
+	})
+}
+
+// bundleComponents loads a bundle's components with their stock; other products have none.
+// Deleted components are left without a Component.
+func bundleComponents(database *gorm.DB, productID uint) ([]models.BundleItem, error) {
+	var items []models.BundleItem
+	err := database.Preload("Component.Inventory").Where("bundle_id = ?", productID).Order("id").Find(&items).Error
+	return items, err
+}
+
+// bundleStock returns how many complete bundles the components' stock makes up
+func bundleStock(items []models.BundleItem) int {
+	available := 0
+	for i, item := range items {
+		stock := 0
+		if item.Component != nil {
+			stock = item.Component.Inventory.Stock / item.Quantity
+		}
+		if i == 0 || stock < available {
+			available = stock
+		}
+	}
+	return available
+}
+
+// AttachComputedStock replaces the stored inventory of each bundle among the products with the
+// number of complete bundles its components' stock makes up, and of each digital product with
+// its available licence keys
+func AttachComputedStock(database *gorm.DB, products []models.Product) error {
+	for i := range products {
+		if products[i].IsDigital() {
+			stock, err := digitalStock(database, products[i].ID)
+			if err != nil {
+				return err
+			}
+			products[i].Inventory.Stock = stock
+			continue
+		}
+		if !products[i].IsBundle() {
+			continue
+		}
+		items, err := bundleComponents(database, products[i].ID)
+		if err != nil {
+			return err
+		}
+		products[i].Inventory.Stock = bundleStock(items)
+	}
+	return nil
+}
+
+// BundleOrderComponents records what a bundle contains for an order item; other products have none
+func BundleOrderComponents(database *gorm.DB, productID uint) ([]models.OrderItemComponent, error) {
+	items, err := bundleComponents(database, productID)
+	if err != nil || len(items) == 0 {
+		return nil, err
+	}
+	components := make([]models.OrderItemComponent, len(items))
+	for i, item := range items {
+		components[i] = models.OrderItemComponent{ProductID: item.ComponentID, Quantity: item.Quantity}
+		if item.Component != nil {
+			components[i].Name = item.Component.Name
+			if item.Component.SKU != nil {
+				components[i].SKU = *item.Component.SKU
+			}
+		}
+	}
+	return components, nil
+}
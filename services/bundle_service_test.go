@@ -0,0 +1,116 @@
+package services
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/geoo115/Ecommerce/db"
+	"github.com/geoo115/Ecommerce/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBundleService(t *testing.T) {
+	originalDB := db.DB
+	testDB := db.SetupTestDB(t)
+	db.DB = testDB
+	defer func() { db.DB = originalDB }()
+
+	camera := createStockedProduct(t, "Camera", 600, 5)
+	lens := createStockedProduct(t, "Lens", 250, 4)
+	bag := createStockedProduct(t, "Bag", 60, 1)
+	// The kit's own inventory row is ignored once it is a bundle
+	kit := createStockedProduct(t, "Camera Kit", 850, 50)
+	bundles := NewBundleService()
+	orders := NewOrderService()
+
+	stockOf := func(product models.Product) int {
+		t.Helper()
+		var inventory models.Inventory
+		testDB.Where("product_id = ?", product.ID).First(&inventory)
+		return inventory.Stock
+	}
+	inStock := func() []string {
+		t.Helper()
+		options, _ := ParseProductListOptions(url.Values{"in_stock": {"true"}, "sort": {"name"}})
+		scope, err := options.FilterScope(testDB)
+		assert.NoError(t, err)
+		var names []string
+		testDB.Model(&models.Product{}).Scopes(scope).Order("name").Pluck("name", &names)
+		return names
+	}
+
+	t.Run("Validation", func(t *testing.T) {
+		assert.ErrorIs(t, bundles.SetComponents(&kit, nil), ErrInvalidBundle)
+		assert.ErrorIs(t, bundles.SetComponents(&kit, []BundleComponent{{ProductID: camera.ID, Quantity: 0}}), ErrInvalidBundle)
+		assert.ErrorIs(t, bundles.SetComponents(&kit, []BundleComponent{{ProductID: kit.ID, Quantity: 1}}), ErrBundleComponent)
+		assert.ErrorIs(t, bundles.SetComponents(&kit, []BundleComponent{{ProductID: 9999, Quantity: 1}}), ErrBundleComponent)
+
+		tripod := createStockedProduct(t, "Tripod", 40, 3)
+		testDB.Create(&models.ProductVariant{ProductID: tripod.ID, SKU: "TRI-1", Stock: 3})
+		assert.ErrorIs(t, bundles.SetComponents(&kit, []BundleComponent{{ProductID: tripod.ID, Quantity: 1}}), ErrBundleVariants)
+	})
+
+	t.Run("Availability", func(t *testing.T) {
+		assert.NoError(t, bundles.SetComponents(&kit, []BundleComponent{
+			{ProductID: camera.ID, Quantity: 1}, {ProductID: lens.ID, Quantity: 1}, {ProductID: bag.ID, Quantity: 1}, {ProductID: lens.ID, Quantity: 1},
+		}))
+		assert.True(t, kit.IsBundle())
+		assert.Len(t, kit.BundleItems, 3)
+
+		// Two lenses per kit: camera 5, lens 4/2, bag 1
+		stock, err := AvailableStock(testDB, kit.ID, nil)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, stock)
+		assert.Contains(t, inStock(), "Camera Kit")
+
+		// Bundles do not nest, and their components cannot gain variants
+		other := createStockedProduct(t, "Travel Kit", 700, 0)
+		assert.ErrorIs(t, bundles.SetComponents(&other, []BundleComponent{{ProductID: kit.ID, Quantity: 1}}), ErrBundleComponent)
+		assert.ErrorIs(t, bundles.SetComponents(&camera, []BundleComponent{{ProductID: bag.ID, Quantity: 1}}), ErrBundleComponent)
+		err = NewVariantService().CreateVariant(&camera, &models.ProductVariant{SKU: "CAM-BLK"}, []VariantOption{{Name: "Colour", Value: "Black"}})
+		assert.ErrorIs(t, err, ErrBundleVariants)
+	})
+
+	t.Run("Orders", func(t *testing.T) {
+		user := models.User{Username: "photographer", Email: "photographer@example.com"}
+		testDB.Create(&user)
+
+		order := models.Order{UserID: user.ID}
+		assert.NoError(t, orders.CreateOrder(&order, []OrderLine{{ProductID: kit.ID, Quantity: 1}}))
+		assert.Equal(t, 850.0, order.TotalAmount)
+		assert.Equal(t, []int{4, 2, 0, 50}, []int{stockOf(camera), stockOf(lens), stockOf(bag), stockOf(kit)})
+		assert.NotContains(t, inStock(), "Camera Kit")
+
+		var item models.OrderItem
+		testDB.Preload("Components").Where("order_id = ?", order.ID).First(&item)
+		if assert.Len(t, item.Components, 3) {
+			assert.Equal(t, "Lens", item.Components[1].Name)
+			assert.Equal(t, 2, item.Components[1].Quantity)
+		}
+
+		// Without every component in stock nothing is reserved
+		err := orders.CreateOrder(&models.Order{UserID: user.ID}, []OrderLine{{ProductID: kit.ID, Quantity: 1}})
+		assert.ErrorIs(t, err, ErrInsufficientStock)
+		assert.Equal(t, []int{4, 2, 0}, []int{stockOf(camera), stockOf(lens), stockOf(bag)})
+
+		// Cancelling returns the components as ordered, even after the bundle changes
+		assert.NoError(t, bundles.SetComponents(&kit, []BundleComponent{{ProductID: camera.ID, Quantity: 2}}))
+		var stored models.Order
+		testDB.First(&stored, order.ID)
+		assert.NoError(t, orders.CancelOrder(&stored))
+		assert.Equal(t, []int{5, 4, 1}, []int{stockOf(camera), stockOf(lens), stockOf(bag)})
+	})
+
+	t.Run("ReplaceComponents", func(t *testing.T) {
+		assert.NoError(t, bundles.SetComponents(&kit, []BundleComponent{{ProductID: camera.ID, Quantity: 1}}))
+		stock, err := AvailableStock(testDB, kit.ID, nil)
+		assert.NoError(t, err)
+		assert.Equal(t, 5, stock)
+
+		// A deleted component makes the bundle unavailable
+		testDB.Delete(&camera)
+		stock, _ = AvailableStock(testDB, kit.ID, nil)
+		assert.Equal(t, 0, stock)
+		assert.NotContains(t, inStock(), "Camera Kit")
+	})
+}
@@ -2,6 +2,8 @@ package services
 
 import (
 	"errors"
+	"fmt"
+	"strings"
 
 	"github.com/geoo115/Ecommerce/db"
 	"github.com/geoo115/Ecommerce/models"
@@ -14,8 +16,29 @@ type CartService interface {
 	GetUserCart(userID uint) ([]models.Cart, error)
 	UpdateCartItem(userID uint, productID uint, quantity int) error
 	RemoveFromCart(userID uint, productID uint) error
-	CheckStock(productID uint, quantity int) (bool, error)
+	CheckStock(productID uint, variantID *uint, quantity int) (bool, error)
 	CalculateCartTotal(cartItems []models.Cart) float64
+	Reorder(userID uint, items []models.OrderItem) (*ReorderResult, error)
+}
+
+// ReorderLine describes what happened to one product of a past order when reordering
+type ReorderLine struct {
+	ProductID     uint    `json:"product_id"`
+	VariantID     *uint   `json:"variant_id,omitempty"`
+	SKU           string  `json:"sku,omitempty"`
+	Name          string  `json:"name,omitempty"`
+	Requested     int     `json:"requested"`
+	Quantity      int     `json:"quantity"`
+	PreviousPrice float64 `json:"previous_price"`
+	Price         float64 `json:"price,omitempty"`
+	Reason        string  `json:"reason,omitempty"`
+}
+
+// ReorderResult lists the products added unchanged, added with adjustments, and skipped
+type ReorderResult struct {
+	Added    []ReorderLine `json:"added"`
+	Adjusted []ReorderLine `json:"adjusted"`
+	Skipped  []ReorderLine `json:"skipped"`
 }
 
 // cartService implements CartService interface
@@ -33,7 +56,7 @@ func NewCartService() CartService {
 // AddToCart adds a product to user's cart
 func (s *cartService) AddToCart(userID uint, productID uint, quantity int) error {
 	// Check if product exists and has sufficient stock
-	if available, err := s.CheckStock(productID, quantity); err != nil {
+	if available, err := s.CheckStock(productID, nil, quantity); err != nil {
 		return err
 	} else if !available {
 		return errors.New("insufficient stock")
@@ -65,6 +88,7 @@ func (s *cartService) GetUserCart(userID uint) ([]models.Cart, error) {
 	var cartItems []models.Cart
 	err := s.db.Where("user_id = ?", userID).
 		Preload("Product").
+		Preload("Variant").
 		Find(&cartItems).Error
 	return cartItems, err
 }
@@ -76,7 +100,7 @@ func (s *cartService) UpdateCartItem(userID uint, productID uint, quantity int)
 	}
 
 	// Check stock availability
-	if available, err := s.CheckStock(productID, quantity); err != nil {
+	if available, err := s.CheckStock(productID, nil, quantity); err != nil {
 		return err
 	} else if !available {
 		return errors.New("insufficient stock")
@@ -93,14 +117,13 @@ func (s *cartService) RemoveFromCart(userID uint, productID uint) error {
 		Delete(&models.Cart{}).Error
 }
 
-// CheckStock verifies if product has sufficient stock
-func (s *cartService) CheckStock(productID uint, quantity int) (bool, error) {
-	var inventory models.Inventory
-	err := s.db.Where("product_id = ?", productID).First(&inventory).Error
+// CheckStock verifies if a product, or the chosen variant, has sufficient stock
+func (s *cartService) CheckStock(productID uint, variantID *uint, quantity int) (bool, error) {
+	stock, err := AvailableStock(s.db, productID, variantID)
 	if err != nil {
 		return false, err
 	}
-	return inventory.Stock >= quantity, nil
+	return stock >= quantity, nil
 }
 
 // CalculateCartTotal calculates the total price of cart items
@@ -108,8 +131,118 @@ func (s *cartService) CalculateCartTotal(cartItems []models.Cart) float64 {
 	var total float64
 	for _, item := range cartItems {
 		if item.Product.ID != 0 {
-			total += item.Product.Price * float64(item.Quantity)
+			total += item.UnitPrice() * float64(item.Quantity)
 		}
 	}
 	return total
 }
+
+// Reorder adds the items of a past order to the user's cart at today's prices. Deleted and
+// out-of-stock products are skipped and quantities are capped to the stock not already in the cart.
+func (s *cartService) Reorder(userID uint, items []models.OrderItem) (*ReorderResult, error) {
+	result := &ReorderResult{Added: []ReorderLine{}, Adjusted: []ReorderLine{}, Skipped: []ReorderLine{}}
+
+	// Merge repeated products and drop fully cancelled lines
+	var order []lineKey
+	lines := make(map[lineKey]*ReorderLine)
+	for _, item := range items {
+		if item.Quantity <= 0 {
+			continue
+		}
+		key := keyFor(item.ProductID, item.VariantID)
+		if line, ok := lines[key]; ok {
+			line.Requested += item.Quantity
+			continue
+		}
+		lines[key] = &ReorderLine{
+			ProductID:     item.ProductID,
+			VariantID:     item.VariantID,
+			SKU:           item.SKU,
+			Requested:     item.Quantity,
+			PreviousPrice: item.Price,
+		}
+		order = append(order, key)
+	}
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		for _, key := range order {
+			line := lines[key]
+
+			product, variant, err := ResolveVariant(tx, line.ProductID, line.VariantID)
+			if err != nil {
+				switch {
+				case errors.Is(err, ErrProductNotFound):
+					line.Reason = "Product is no longer available"
+				case errors.Is(err, ErrVariantNotFound):
+					line.Reason = "Variant is no longer available"
+				case errors.Is(err, ErrVariantRequired):
+					line.Reason = "Product now requires choosing a variant"
+				default:
+					return err
+				}
+				result.Skipped = append(result.Skipped, *line)
+				continue
+			}
+			line.Name = product.Name
+			line.Price = product.Price
+			if variant != nil {
+				line.Name = product.Name + " (" + variant.Title + ")"
+				line.SKU = variant.SKU
+				line.Price = variant.PriceFor(product.Price)
+			}
+
+			stock, err := AvailableStock(tx, line.ProductID, line.VariantID)
+			if err != nil {
+				return err
+			}
+
+			var cart models.Cart
+			inCart := 0
+			err = tx.Scopes(models.WhereVariant(line.VariantID)).
+				Where("user_id = ? AND product_id = ?", userID, line.ProductID).First(&cart).Error
+			if err == nil {
+				inCart = cart.Quantity
+			} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+				return err
+			}
+
+			available := stock - inCart
+			if available <= 0 {
+				line.Reason = "Out of stock"
+				result.Skipped = append(result.Skipped, *line)
+				continue
+			}
+
+			var reasons []string
+			line.Quantity = line.Requested
+			if line.Quantity > available {
+				line.Quantity = available
+				reasons = append(reasons, fmt.Sprintf("Only %d available", available))
+			}
+			if line.Price != line.PreviousPrice {
+				reasons = append(reasons, fmt.Sprintf("Price changed from %.2f to %.2f", line.PreviousPrice, line.Price))
+			}
+
+			if cart.ID != 0 {
+				err = tx.Model(&cart).Update("quantity", inCart+line.Quantity).Error
+			} else {
+				err = tx.Create(&models.Cart{UserID: userID, ProductID: line.ProductID, VariantID: line.VariantID, Quantity: line.Quantity}).Error
+			}
+			if err != nil {
+				return err
+			}
+
+			if len(reasons) > 0 {
+				line.Reason = strings.Join(reasons, "; ")
+				result.Adjusted = append(result.Adjusted, *line)
+			} else {
+				result.Added = append(result.Added, *line)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
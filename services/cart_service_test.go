@@ -274,12 +274,12 @@ func TestCartService_CheckStock(t *testing.T) {
 	service := NewCartService()
 
 	// Test sufficient stock
-	available, err := service.CheckStock(product.ID, 5)
+	available, err := service.CheckStock(product.ID, nil, 5)
 	assert.NoError(t, err)
 	assert.True(t, available)
 
 	// Test insufficient stock
-	available, err = service.CheckStock(product.ID, 15)
+	available, err = service.CheckStock(product.ID, nil, 15)
 	assert.NoError(t, err)
 	assert.False(t, available)
 }
@@ -314,3 +314,55 @@ func TestCartService_CalculateCartTotal(t *testing.T) {
 	expectedTotal := (10.99 * 2) + (20.99 * 1)
 	assert.Equal(t, expectedTotal, total)
 }
+
+func TestCartService_Reorder(t *testing.T) {
+	originalDB := db.DB
+	testDB := db.SetupTestDB(t)
+	db.DB = testDB
+	defer func() { db.DB = originalDB }()
+
+	user := models.User{Username: "rebuyer", Email: "rebuyer@example.com"}
+	testDB.Create(&user)
+	same := createStockedProduct(t, "Same", 5, 10)
+	pricier := createStockedProduct(t, "Pricier", 8, 10)
+	scarce := createStockedProduct(t, "Scarce", 3, 2)
+	soldOut := createStockedProduct(t, "Sold Out", 4, 0)
+	removed := createStockedProduct(t, "Removed", 6, 10)
+	testDB.Delete(&removed)
+
+	// One unit of "Scarce" is already in the cart
+	testDB.Create(&models.Cart{UserID: user.ID, ProductID: scarce.ID, Quantity: 1})
+
+	items := []models.OrderItem{
+		{ProductID: same.ID, Quantity: 2, Price: 5},
+		{ProductID: pricier.ID, Quantity: 1, Price: 7},
+		{ProductID: scarce.ID, Quantity: 3, Price: 3},
+		{ProductID: soldOut.ID, Quantity: 1, Price: 4},
+		{ProductID: removed.ID, Quantity: 1, Price: 6},
+		{ProductID: same.ID, Quantity: 1, Price: 5},
+		{ProductID: pricier.ID, Quantity: 0, Price: 7},
+	}
+
+	result, err := NewCartService().Reorder(user.ID, items)
+	assert.NoError(t, err)
+
+	if assert.Len(t, result.Added, 1) {
+		assert.Equal(t, same.ID, result.Added[0].ProductID)
+		assert.Equal(t, 3, result.Added[0].Quantity)
+	}
+	if assert.Len(t, result.Adjusted, 2) {
+		assert.Equal(t, pricier.ID, result.Adjusted[0].ProductID)
+		assert.Contains(t, result.Adjusted[0].Reason, "Price changed from 7.00 to 8.00")
+		assert.Equal(t, scarce.ID, result.Adjusted[1].ProductID)
+		assert.Equal(t, 1, result.Adjusted[1].Quantity)
+		assert.Equal(t, "Only 1 available", result.Adjusted[1].Reason)
+	}
+	if assert.Len(t, result.Skipped, 2) {
+		assert.Equal(t, "Out of stock", result.Skipped[0].Reason)
+		assert.Equal(t, "Product is no longer available", result.Skipped[1].Reason)
+	}
+
+	var cart models.Cart
+	testDB.Where("user_id = ? AND product_id = ?", user.ID, scarce.ID).First(&cart)
+	assert.Equal(t, 2, cart.Quantity)
+}
@@ -0,0 +1,558 @@
+package services
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/geoo115/Ecommerce/config"
+	"github.com/geoo115/Ecommerce/db"
+	"github.com/geoo115/Ecommerce/models"
+	"github.com/geoo115/Ecommerce/utils"
+	"gorm.io/gorm"
+)
+
+// Catalog import errors surfaced to handlers
+var (
+	ErrCatalogFormat   = errors.New("format must be csv or ndjson")
+	ErrCatalogFile     = errors.New("invalid catalog file")
+	ErrCatalogTooLarge = errors.New("import has too many rows")
+)
+
+// Catalog file formats
+const (
+	CatalogFormatCSV    = "csv"
+	CatalogFormatNDJSON = "ndjson"
+)
+
+// catalogColumns are the CSV columns in export order. sku, name and price are required on import.
+var catalogColumns = []string{"sku", "name", "description", "price", "stock", "category"}
+
+// CatalogRow is one product in an import or export. On import, a missing description, stock or
+// category leaves the existing value unchanged; new products need a category.
+type CatalogRow struct {
+	SKU         string   `json:"sku"`
+	Name        string   `json:"name"`
+	Description *string  `json:"description,omitempty"`
+	Price       *float64 `json:"price"`
+	Stock       *int     `json:"stock,omitempty"`
+	Category    string   `json:"category,omitempty"` // Category name or slug, created when missing
+}
+
+// ImportRowError describes one problem with an import row. Rows are numbered by their line in the file.
+type ImportRowError struct {
+	Row     int    `json:"row"`
+	SKU     string `json:"sku,omitempty"`
+	Field   string `json:"field,omitempty"`
+	Message string `json:"message"`
+}
+
+// ImportReport summarises an import. Nothing is written unless every row is valid.
+type ImportReport struct {
+	DryRun            bool             `json:"dry_run"`
+	Committed         bool             `json:"committed"`
+	Rows              int              `json:"rows"`
+	Created           int              `json:"created"`
+	Updated           int              `json:"updated"`
+	CategoriesCreated []string         `json:"categories_created,omitempty"`
+	Errors            []ImportRowError `json:"errors,omitempty"`
+}
+
+// CatalogService interface defines bulk catalog import and export
+type CatalogService interface {
+	Import(source io.Reader, format string, dryRun bool, actorID uint) (*ImportReport, error)
+	Export(w io.Writer, format string) error
+}
+
+// catalogService implements CatalogService interface
+type catalogService struct {
+	db     *gorm.DB
+	config config.CatalogConfig
+}
+
+// NewCatalogService creates a new catalog service instance
+func NewCatalogService() CatalogService {
+	return NewCatalogServiceWithDB(db.DB)
+}
+
+// NewCatalogServiceWithDB creates a catalog service bound to a specific database handle
+func NewCatalogServiceWithDB(database *gorm.DB) CatalogService {
+	return &catalogService{db: database, config: config.GetCatalogConfig()}
+}
+
+// ParseCatalogFormat accepts a format name or the matching content type
+func ParseCatalogFormat(value string) (string, error) {
+	value = strings.ToLower(strings.TrimSpace(value))
+	if i := strings.IndexByte(value, ';'); i >= 0 {
+		value = strings.TrimSpace(value[:i])
+	}
+	switch value {
+	case "csv", "text/csv":
+		return CatalogFormatCSV, nil
+	case "ndjson", "jsonl", "application/x-ndjson", "application/ndjson", "application/jsonl":
+		return CatalogFormatNDJSON, nil
+	}
+	return "", ErrCatalogFormat
+}
+
+// importRow is a parsed row and the line it started on
+type importRow struct {
+	line int
+	CatalogRow
+}
+
+// Import reads every row and validates it first. When all rows are valid and this is not a dry
+// run, products are upserted by SKU, with their categories, prices and stock, in one transaction.
+// Price changes are recorded in the price history against actorID.
+func (s *catalogService) Import(source io.Reader, format string, dryRun bool, actorID uint) (*ImportReport, error) {
+	report := &ImportReport{DryRun: dryRun}
+	var rows []importRow
+	var err error
+	switch format {
+	case CatalogFormatCSV:
+		rows, err = s.readCSV(source, report)
+	case CatalogFormatNDJSON:
+		rows, err = s.readNDJSON(source, report)
+	default:
+		return nil, ErrCatalogFormat
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	plan, err := s.plan(rows, report)
+	if err != nil {
+		return nil, err
+	}
+	slices.SortStableFunc(report.Errors, func(a, b ImportRowError) int { return a.Row - b.Row })
+	if len(report.Errors) > 0 || dryRun {
+		return report, nil
+	}
+
+	if err := s.db.Transaction(func(tx *gorm.DB) error {
+		return s.apply(tx, plan, actorID)
+	}); err != nil {
+		return nil, err
+	}
+	report.Committed = true
+	return report, nil
+}
+
+// readCSV parses a header row naming the columns followed by one product per record.
+// Records that cannot be parsed are reported and left out of the returned rows.
+func (s *catalogService) readCSV(source io.Reader, report *ImportReport) ([]importRow, error) {
+	reader := csv.NewReader(source)
+	reader.TrimLeadingSpace = true
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, s.readError(err)
+	}
+
+	columns := map[string]int{}
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		if !slices.Contains(catalogColumns, name) {
+			return nil, fmt.Errorf("%w: unknown column %q", ErrCatalogFile, name)
+		}
+		if _, ok := columns[name]; ok {
+			return nil, fmt.Errorf("%w: column %q appears twice", ErrCatalogFile, name)
+		}
+		columns[name] = i
+	}
+	for _, name := range []string{"sku", "name", "price"} {
+		if _, ok := columns[name]; !ok {
+			return nil, fmt.Errorf("%w: missing required column %q", ErrCatalogFile, name)
+		}
+	}
+
+	var rows []importRow
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return rows, nil
+		}
+		if err := s.countRow(report); err != nil {
+			return nil, err
+		}
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			report.Errors = append(report.Errors, ImportRowError{Row: parseErr.StartLine, Message: parseErr.Err.Error()})
+			continue
+		}
+		if err != nil {
+			return nil, s.readError(err)
+		}
+
+		line, _ := reader.FieldPos(0)
+		row := importRow{line: line}
+		cell := func(name string) (string, bool) {
+			i, ok := columns[name]
+			if !ok {
+				return "", false
+			}
+			return strings.TrimSpace(record[i]), true
+		}
+		row.SKU, _ = cell("sku")
+		row.Name, _ = cell("name")
+		row.Category, _ = cell("category")
+		if value, ok := cell("description"); ok && value != "" {
+			row.Description = &value
+		}
+
+		valid := true
+		if value, _ := cell("price"); value != "" {
+			price, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				report.Errors = append(report.Errors, ImportRowError{Row: line, SKU: row.SKU, Field: "price", Message: "Price must be a number"})
+				valid = false
+			} else {
+				row.Price = &price
+			}
+		}
+		if value, ok := cell("stock"); ok && value != "" {
+			stock, err := strconv.Atoi(value)
+			if err != nil {
+				report.Errors = append(report.Errors, ImportRowError{Row: line, SKU: row.SKU, Field: "stock", Message: "Stock must be a whole number"})
+				valid = false
+			} else {
+				row.Stock = &stock
+			}
+		}
+		if valid {
+			rows = append(rows, row)
+		}
+	}
+}
+
+// readNDJSON parses one JSON object per line, skipping blank lines
+func (s *catalogService) readNDJSON(source io.Reader, report *ImportReport) ([]importRow, error) {
+	scanner := bufio.NewScanner(source)
+	scanner.Buffer(make([]byte, 64*1024), 1<<20)
+	var rows []importRow
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if line == 1 {
+			text = strings.TrimPrefix(text, "\ufeff")
+		}
+		if text == "" {
+			continue
+		}
+		if err := s.countRow(report); err != nil {
+			return nil, err
+		}
+
+		decoder := json.NewDecoder(strings.NewReader(text))
+		decoder.DisallowUnknownFields()
+		row := importRow{line: line}
+		if err := decoder.Decode(&row.CatalogRow); err != nil {
+			report.Errors = append(report.Errors, ImportRowError{Row: line, Message: "Invalid JSON: " + err.Error()})
+		} else {
+			row.SKU = strings.TrimSpace(row.SKU)
+			row.Name = strings.TrimSpace(row.Name)
+			row.Category = strings.TrimSpace(row.Category)
+			rows = append(rows, row)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, s.readError(err)
+	}
+	return rows, nil
+}
+
+// readError passes oversized-body errors through untouched so handlers can report them
+func (s *catalogService) readError(err error) error {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		return err
+	}
+	if errors.Is(err, bufio.ErrTooLong) {
+		return fmt.Errorf("%w: line longer than 1MB", ErrCatalogFile)
+	}
+	return err
+}
+
+// countRow counts a row read from the file and stops once the configured limit is exceeded
+func (s *catalogService) countRow(report *ImportReport) error {
+	report.Rows++
+	if report.Rows > s.config.MaxImportRows {
+		return fmt.Errorf("%w: the limit is %d", ErrCatalogTooLarge, s.config.MaxImportRows)
+	}
+	return nil
+}
+
+// importPlan is the outcome of the validation pass: what the commit pass will write
+type importPlan struct {
+	rows          []importRow
+	existing      map[string]models.Product // By SKU, including soft-deleted products
+	categoryIDs   map[string]uint           // By lowercased name or slug
+	newCategories []string
+}
+
+// plan validates every row against the file and the database and records what will change
+func (s *catalogService) plan(rows []importRow, report *ImportReport) (*importPlan, error) {
+	plan := &importPlan{rows: rows, existing: map[string]models.Product{}, categoryIDs: map[string]uint{}}
+
+	skus := make([]string, 0, len(rows))
+	for _, row := range rows {
+		if row.SKU != "" {
+			skus = append(skus, row.SKU)
+		}
+	}
+	for start := 0; start < len(skus); start += s.config.BatchSize {
+		end := min(start+s.config.BatchSize, len(skus))
+		var products []models.Product
+		if err := s.db.Unscoped().Select("id", "sku", "price", "compare_at_price", "deleted_at").Where("sku IN ?", skus[start:end]).Find(&products).Error; err != nil {
+			return nil, err
+		}
+		for _, product := range products {
+			plan.existing[*product.SKU] = product
+		}
+	}
+
+	var categories []models.Category
+	if err := s.db.Select("id", "name", "slug").Find(&categories).Error; err != nil {
+		return nil, err
+	}
+	for _, category := range categories {
+		plan.categoryIDs[strings.ToLower(category.Name)] = category.ID
+		plan.categoryIDs[category.Slug] = category.ID
+	}
+
+	seen := map[string]int{}
+	for _, row := range rows {
+		fail := func(field, message string) {
+			report.Errors = append(report.Errors, ImportRowError{Row: row.line, SKU: row.SKU, Field: field, Message: message})
+		}
+		_, exists := plan.existing[row.SKU]
+
+		switch {
+		case row.SKU == "":
+			fail("sku", "SKU is required")
+		case len(row.SKU) > 64:
+			fail("sku", "SKU must be at most 64 characters")
+		case seen[row.SKU] > 0:
+			fail("sku", fmt.Sprintf("SKU already appears on row %d", seen[row.SKU]))
+		default:
+			seen[row.SKU] = row.line
+		}
+		if !utils.ValidateProductName(row.Name) {
+			fail("name", "Product name must be 2-200 characters long")
+		}
+		if row.Price == nil {
+			fail("price", "Price is required")
+		} else if !(*row.Price == 0 || utils.ValidatePrice(*row.Price)) {
+			fail("price", "Price must be greater than 0 and less than 999999.99")
+		}
+		if row.Description != nil && !utils.ValidateDescription(*row.Description) {
+			fail("description", "Description must be less than 1000 characters")
+		}
+		if row.Stock != nil && !utils.ValidateStock(*row.Stock) {
+			fail("stock", "Stock must be between 0 and 100000")
+		}
+		switch {
+		case row.Category == "" && !exists:
+			fail("category", "Category is required for new products")
+		case len(row.Category) > 100:
+			fail("category", "Category must be at most 100 characters")
+		case row.Category != "":
+			key := strings.ToLower(row.Category)
+			if _, ok := plan.categoryIDs[key]; !ok && !slices.Contains(plan.newCategories, key) {
+				plan.newCategories = append(plan.newCategories, key)
+				report.CategoriesCreated = append(report.CategoriesCreated, row.Category)
+			}
+		}
+
+		if exists {
+			report.Updated++
+		} else {
+			report.Created++
+		}
+	}
+	return plan, nil
+}
+
+// apply writes a validated plan: missing categories first, then updates, then new products in batches
+func (s *catalogService) apply(tx *gorm.DB, plan *importPlan, actorID uint) error {
+	for i, key := range plan.newCategories {
+		// Keep the spelling of the first row that named the category
+		name := key
+		for _, row := range plan.rows {
+			if strings.ToLower(row.Category) == key {
+				name = row.Category
+				break
+			}
+		}
+		category := models.Category{Name: name}
+		if err := tx.Create(&category).Error; err != nil {
+			return err
+		}
+		plan.categoryIDs[key] = category.ID
+		plan.newCategories[i] = name
+	}
+
+	var created []models.Product
+	var stock []int
+	for _, row := range plan.rows {
+		sku := row.SKU
+		product := models.Product{
+			SKU:   &sku,
+			Name:  utils.SanitizeString(row.Name),
+			Price: *row.Price,
+		}
+		if row.Description != nil {
+			product.Description = utils.SanitizeString(*row.Description)
+		}
+		if row.Category != "" {
+			product.CategoryID = plan.categoryIDs[strings.ToLower(row.Category)]
+		}
+
+		existing, ok := plan.existing[row.SKU]
+		if !ok {
+			created = append(created, product)
+			if row.Stock != nil {
+				stock = append(stock, *row.Stock)
+			} else {
+				stock = append(stock, 0)
+			}
+			continue
+		}
+
+		// Importing a deleted product's SKU restores it
+		updates := map[string]interface{}{"name": product.Name, "deleted_at": nil}
+		if row.Description != nil {
+			updates["description"] = product.Description
+		}
+		if row.Category != "" {
+			updates["category_id"] = product.CategoryID
+		}
+		if err := tx.Unscoped().Model(&models.Product{}).Where("id = ?", existing.ID).Updates(updates).Error; err != nil {
+			return err
+		}
+		// During a sale the imported price becomes the regular price the sale ends on
+		if err := setRegularPrice(tx, &existing, product.Price, models.PriceHistory{
+			Source: models.PriceSourceImport, Reason: "Catalog import", ChangedBy: actorID,
+		}); err != nil {
+			return err
+		}
+		if row.Stock != nil {
+			result := tx.Model(&models.Inventory{}).Where("product_id = ?", existing.ID).Update("stock", *row.Stock)
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected == 0 {
+				if err := tx.Create(&models.Inventory{ProductID: existing.ID, Stock: *row.Stock}).Error; err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	if len(created) == 0 {
+		return nil
+	}
+	// Slugs are assigned here rather than by the model hook, which cannot see the other
+	// new products in the same batch
+	reserved := map[string]bool{}
+	for i := range created {
+		slug, err := models.UniqueSlug(created[i].Name, "product", func(slug string) (bool, error) {
+			if reserved[slug] {
+				return true, nil
+			}
+			return models.SlugInUse(tx, &models.Product{}, slug, 0)
+		})
+		if err != nil {
+			return err
+		}
+		reserved[slug] = true
+		created[i].Slug = slug
+	}
+	if err := tx.CreateInBatches(&created, s.config.BatchSize).Error; err != nil {
+		return err
+	}
+	inventories := make([]models.Inventory, len(created))
+	for i, product := range created {
+		inventories[i] = models.Inventory{ProductID: product.ID, Stock: stock[i]}
+	}
+	if err := tx.CreateInBatches(&inventories, s.config.BatchSize).Error; err != nil {
+		return err
+	}
+	history := make([]models.PriceHistory, len(created))
+	for i, product := range created {
+		history[i] = models.PriceHistory{ProductID: product.ID, NewPrice: product.Price,
+			Source: models.PriceSourceImport, Reason: "Catalog import", ChangedBy: actorID}
+	}
+	return tx.CreateInBatches(&history, s.config.BatchSize).Error
+}
+
+// Export streams every product in the import format, flushing after each batch so large
+// catalogs are not held in memory
+func (s *catalogService) Export(w io.Writer, format string) error {
+	var writeRow func(CatalogRow) error
+	var flush func() error
+	switch format {
+	case CatalogFormatCSV:
+		writer := csv.NewWriter(w)
+		if err := writer.Write(catalogColumns); err != nil {
+			return err
+		}
+		writeRow = func(row CatalogRow) error {
+			var description, stock string
+			if row.Description != nil {
+				description = *row.Description
+			}
+			if row.Stock != nil {
+				stock = strconv.Itoa(*row.Stock)
+			}
+			return writer.Write([]string{row.SKU, row.Name, description, strconv.FormatFloat(*row.Price, 'f', -1, 64), stock, row.Category})
+		}
+		flush = func() error {
+			writer.Flush()
+			return writer.Error()
+		}
+	case CatalogFormatNDJSON:
+		encoder := json.NewEncoder(w)
+		writeRow = func(row CatalogRow) error { return encoder.Encode(row) }
+		flush = func() error { return nil }
+	default:
+		return ErrCatalogFormat
+	}
+
+	var products []models.Product
+	return s.db.Preload("Category").Preload("Inventory").Order("id").
+		FindInBatches(&products, s.config.BatchSize, func(batch *gorm.DB, _ int) error {
+			for _, product := range products {
+				// The regular price, so a re-import during a sale does not make the sale price permanent
+				price := product.RegularPrice()
+				row := CatalogRow{
+					Name:        product.Name,
+					Description: &product.Description,
+					Price:       &price,
+					Stock:       &product.Inventory.Stock,
+					Category:    product.Category.Name,
+				}
+				if product.SKU != nil {
+					row.SKU = *product.SKU
+				}
+				if err := writeRow(row); err != nil {
+					return err
+				}
+			}
+			if err := flush(); err != nil {
+				return err
+			}
+			if flusher, ok := w.(http.Flusher); ok {
+				flusher.Flush()
+			}
+			return nil
+		}).Error
+}
@@ -0,0 +1,154 @@
+package services
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/geoo115/Ecommerce/db"
+	"github.com/geoo115/Ecommerce/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCatalogImport(t *testing.T) {
+	originalDB := db.DB
+	testDB := db.SetupTestDB(t)
+	db.DB = testDB
+	defer func() { db.DB = originalDB }()
+
+	kitchen := models.Category{Name: "Kitchen"}
+	testDB.Create(&kitchen)
+	kettle := createStockedProduct(t, "Kettle", 30, 4)
+	sku := "KT-1"
+	testDB.Model(&kettle).Update("sku", &sku)
+	catalog := NewCatalogServiceWithDB(testDB)
+
+	t.Run("ValidationErrorsSaveNothing", func(t *testing.T) {
+		file := "sku,name,price,stock,category\n" +
+			"KT-1,Kettle,32.5,,\n" +
+			",No SKU,5,1,Kitchen\n" +
+			"MG-1,Mug,abc,1,Kitchen\n" +
+			"TP-1,Teapot,-3,1,\n" +
+			"MG-2,Mug,4,1,Kitchen\n" +
+			"MG-2,Mug again,4,1,Kitchen\n" +
+			"too,few\n"
+		report, err := catalog.Import(strings.NewReader(file), CatalogFormatCSV, false, 0)
+		assert.NoError(t, err)
+		assert.False(t, report.Committed)
+		assert.Equal(t, 7, report.Rows)
+
+		fields := map[int]string{}
+		for _, e := range report.Errors {
+			fields[e.Row] += e.Field + ";"
+		}
+		assert.Equal(t, map[int]string{3: "sku;", 4: "price;", 5: "price;category;", 7: "sku;", 8: ";"}, fields)
+
+		var reloaded models.Product
+		testDB.First(&reloaded, kettle.ID)
+		assert.Equal(t, 30.0, reloaded.Price)
+	})
+
+	t.Run("DryRun", func(t *testing.T) {
+		file := `{"sku":"KT-1","name":"Kettle","price":35}` + "\n\n" +
+			`{"sku":"PL-1","name":"Plate","price":6,"stock":12,"category":"Tableware"}` + "\n"
+		report, err := catalog.Import(strings.NewReader(file), CatalogFormatNDJSON, true, 0)
+		assert.NoError(t, err)
+		assert.Empty(t, report.Errors)
+		assert.False(t, report.Committed)
+		assert.Equal(t, 1, report.Created)
+		assert.Equal(t, 1, report.Updated)
+		assert.Equal(t, []string{"Tableware"}, report.CategoriesCreated)
+
+		var count int64
+		testDB.Model(&models.Product{}).Where("sku = ?", "PL-1").Count(&count)
+		assert.Zero(t, count)
+	})
+
+	t.Run("Commit", func(t *testing.T) {
+		file := "\ufeffSKU,Name,Description,Price,Stock,Category\n" +
+			"KT-1,Steel Kettle,,35,9,kitchen\n" +
+			"PL-1,Plate,\"Stoneware, 27cm\",6,12,Tableware\n" +
+			"BW-1,Bowl,,4.5,,Tableware\n" +
+			"BW-2,Bowl,,5.5,,Tableware\n"
+		report, err := catalog.Import(strings.NewReader(file), CatalogFormatCSV, false, 0)
+		assert.NoError(t, err)
+		assert.Empty(t, report.Errors)
+		assert.True(t, report.Committed)
+		assert.Equal(t, 3, report.Created)
+		assert.Equal(t, 1, report.Updated)
+
+		var updated models.Product
+		testDB.Preload("Inventory").First(&updated, kettle.ID)
+		assert.Equal(t, "Steel Kettle", updated.Name)
+		assert.Equal(t, 35.0, updated.Price)
+		assert.Equal(t, 9, updated.Inventory.Stock)
+		assert.Equal(t, kettle.Description, updated.Description, "blank cells leave values unchanged")
+		assert.Equal(t, kitchen.ID, updated.CategoryID)
+
+		var plate models.Product
+		testDB.Preload("Category").Preload("Inventory").Where("sku = ?", "PL-1").First(&plate)
+		assert.Equal(t, "Stoneware, 27cm", plate.Description)
+		assert.Equal(t, "Tableware", plate.Category.Name)
+		assert.Equal(t, 12, plate.Inventory.Stock)
+
+		// New products sharing a name get distinct slugs
+		var bowls []models.Product
+		testDB.Where("name = ?", "Bowl").Order("id").Find(&bowls)
+		if assert.Len(t, bowls, 2) {
+			assert.Equal(t, "bowl", bowls[0].Slug)
+			assert.Equal(t, "bowl-2", bowls[1].Slug)
+		}
+
+		// Importing the SKU of a deleted product restores it
+		testDB.Delete(&plate)
+		report, err = catalog.Import(strings.NewReader(`{"sku":"PL-1","name":"Plate","price":7}`), CatalogFormatNDJSON, false, 0)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, report.Updated)
+		testDB.First(&plate, plate.ID)
+		assert.Equal(t, 7.0, plate.Price)
+	})
+
+	t.Run("ExportRoundTrip", func(t *testing.T) {
+		for _, format := range []string{CatalogFormatCSV, CatalogFormatNDJSON} {
+			var buf bytes.Buffer
+			assert.NoError(t, catalog.Export(&buf, format))
+			assert.Contains(t, buf.String(), "Stoneware, 27cm")
+
+			report, err := catalog.Import(&buf, format, true, 0)
+			assert.NoError(t, err, format)
+			assert.Empty(t, report.Errors, format)
+			assert.Equal(t, 4, report.Updated, format)
+			assert.Zero(t, report.Created, format)
+		}
+	})
+
+	t.Run("FileErrors", func(t *testing.T) {
+		_, err := catalog.Import(strings.NewReader("sku,name,colour\n"), CatalogFormatCSV, false, 0)
+		assert.ErrorIs(t, err, ErrCatalogFile)
+		_, err = catalog.Import(strings.NewReader("sku,name\n"), CatalogFormatCSV, false, 0)
+		assert.ErrorIs(t, err, ErrCatalogFile)
+		_, err = catalog.Import(strings.NewReader(""), "xml", false, 0)
+		assert.ErrorIs(t, err, ErrCatalogFormat)
+
+		limited := &catalogService{db: testDB, config: catalog.(*catalogService).config}
+		limited.config.MaxImportRows = 1
+		_, err = limited.Import(strings.NewReader("sku,name,price\nA,Aa,1\nB,Bb,2\n"), CatalogFormatCSV, true, 0)
+		assert.ErrorIs(t, err, ErrCatalogTooLarge)
+	})
+}
+
+func TestParseCatalogFormat(t *testing.T) {
+	for value, want := range map[string]string{
+		"csv":                     CatalogFormatCSV,
+		"text/csv; charset=utf-8": CatalogFormatCSV,
+		"NDJSON":                  CatalogFormatNDJSON,
+		"jsonl":                   CatalogFormatNDJSON,
+		"application/x-ndjson":    CatalogFormatNDJSON,
+	} {
+		format, err := ParseCatalogFormat(value)
+		assert.NoError(t, err, value)
+		assert.Equal(t, want, format, value)
+	}
+	_, err := ParseCatalogFormat("application/json")
+	assert.ErrorIs(t, err, ErrCatalogFormat)
+}
@@ -0,0 +1,293 @@
+package services
+
+import (
+	"errors"
+
+	"github.com/geoo115/Ecommerce/db"
+	"github.com/geoo115/Ecommerce/models"
+	"github.com/geoo115/Ecommerce/utils"
+	"gorm.io/gorm"
+)
+
+// Category errors surfaced to handlers
+var (
+	ErrCategoryNotFound = errors.New("category not found")
+	ErrParentNotFound   = errors.New("parent category not found")
+	ErrCategoryCycle    = errors.New("category cannot be moved under itself or one of its descendants")
+	ErrInvalidSlug      = errors.New("slug may only contain lowercase letters, digits and hyphens")
+	ErrDuplicateSlug    = errors.New("slug is already in use")
+)
+
+// CategoryUpdate describes changes to a category; nil fields are left unchanged.
+// A ParentID of 0 moves the category to the top level.
+type CategoryUpdate struct {
+	Name        *string
+	Slug        *string
+	Description *string
+	ParentID    *uint
+	SortOrder   *int
+}
+
+// CategoryService interface defines category tree business logic
+type CategoryService interface {
+	Create(category *models.Category) error
+	Update(category *models.Category, update CategoryUpdate) error
+	Delete(category *models.Category) error
+	Tree() ([]models.Category, error)
+	DescendantIDs(categoryID uint) ([]uint, error)
+	Breadcrumbs(categoryID uint) ([]models.Breadcrumb, error)
+	AttachBreadcrumbs(products []models.Product) error
+}
+
+// categoryService implements CategoryService interface
+type categoryService struct {
+	db *gorm.DB
+}
+
+// NewCategoryService creates a new category service instance
+func NewCategoryService() CategoryService {
+	return NewCategoryServiceWithDB(db.DB)
+}
+
+// NewCategoryServiceWithDB creates a category service bound to a specific database handle
+func NewCategoryServiceWithDB(database *gorm.DB) CategoryService {
+	return &categoryService{db: database}
+}
+
+// Create validates the parent and any requested slug before saving a new category
+func (s *categoryService) Create(category *models.Category) error {
+	if category.ParentID != nil {
+		if *category.ParentID == 0 {
+			category.ParentID = nil
+		} else if err := s.ensureExists(*category.ParentID); err != nil {
+			return err
+		}
+	}
+
+	// Categories without a slug get one derived from their name when created
+	if category.Slug != "" {
+		if err := s.ensureSlugAvailable(category.Slug, 0); err != nil {
+			return err
+		}
+	}
+	return s.db.Create(category).Error
+}
+
+// Update renames, re-slugs, re-orders or moves a category. Moving a category under
+// itself or one of its descendants is rejected so the tree stays acyclic.
+func (s *categoryService) Update(category *models.Category, update CategoryUpdate) error {
+	oldSlug := category.Slug
+	updates := map[string]interface{}{}
+	if update.Name != nil {
+		category.Name = *update.Name
+		updates["name"] = category.Name
+	}
+	if update.Slug != nil {
+		if err := s.ensureSlugAvailable(*update.Slug, category.ID); err != nil {
+			return err
+		}
+		category.Slug = *update.Slug
+		updates["slug"] = category.Slug
+	}
+	if update.Description != nil {
+		category.Description = *update.Description
+		updates["description"] = category.Description
+	}
+	if update.SortOrder != nil {
+		category.SortOrder = *update.SortOrder
+		updates["sort_order"] = category.SortOrder
+	}
+	if update.ParentID != nil {
+		if *update.ParentID == 0 {
+			category.ParentID = nil
+		} else {
+			if err := s.ensureExists(*update.ParentID); err != nil {
+				return err
+			}
+			descendants, err := s.DescendantIDs(category.ID)
+			if err != nil {
+				return err
+			}
+			for _, id := range descendants {
+				if id == *update.ParentID {
+					return ErrCategoryCycle
+				}
+			}
+			parentID := *update.ParentID
+			category.ParentID = &parentID
+		}
+		updates["parent_id"] = category.ParentID
+	}
+	if len(updates) == 0 {
+		return ErrNoChanges
+	}
+	// The old slug keeps working as a redirect
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(category).Updates(updates).Error; err != nil {
+			return err
+		}
+		return recordSlugChange(tx, models.SlugResourceCategory, category.ID, oldSlug, category.Slug)
+	})
+}
+
+// Delete removes a category and moves its children up to its parent
+func (s *categoryService) Delete(category *models.Category) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Category{}).Where("parent_id = ?", category.ID).
+			Update("parent_id", category.ParentID).Error; err != nil {
+			return err
+		}
+		return tx.Delete(category).Error
+	})
+}
+
+// Tree returns every category nested under its parent, siblings ordered by sort order then name
+func (s *categoryService) Tree() ([]models.Category, error) {
+	var categories []models.Category
+	if err := s.db.Order("sort_order, name").Find(&categories).Error; err != nil {
+		return nil, err
+	}
+
+	children := make(map[uint][]models.Category)
+	known := make(map[uint]bool, len(categories))
+	for _, category := range categories {
+		known[category.ID] = true
+	}
+	var roots []models.Category
+	for _, category := range categories {
+		// Categories whose parent was deleted are shown at the top level
+		if category.ParentID == nil || !known[*category.ParentID] {
+			roots = append(roots, category)
+			continue
+		}
+		children[*category.ParentID] = append(children[*category.ParentID], category)
+	}
+
+	var attach func(nodes []models.Category, depth int) []models.Category
+	attach = func(nodes []models.Category, depth int) []models.Category {
+		if depth > len(categories) {
+			return nodes
+		}
+		for i := range nodes {
+			nodes[i].Children = attach(children[nodes[i].ID], depth+1)
+		}
+		return nodes
+	}
+	return attach(roots, 0), nil
+}
+
+// DescendantIDs returns the category's ID followed by the IDs of all categories below it
+func (s *categoryService) DescendantIDs(categoryID uint) ([]uint, error) {
+	var links []struct {
+		ID       uint
+		ParentID *uint
+	}
+	if err := s.db.Model(&models.Category{}).Select("id, parent_id").Scan(&links).Error; err != nil {
+		return nil, err
+	}
+
+	children := make(map[uint][]uint)
+	for _, link := range links {
+		if link.ParentID != nil {
+			children[*link.ParentID] = append(children[*link.ParentID], link.ID)
+		}
+	}
+
+	ids := []uint{categoryID}
+	seen := map[uint]bool{categoryID: true}
+	for i := 0; i < len(ids); i++ {
+		for _, child := range children[ids[i]] {
+			if !seen[child] {
+				seen[child] = true
+				ids = append(ids, child)
+			}
+		}
+	}
+	return ids, nil
+}
+
+// Breadcrumbs returns the path from the top-level category down to the given category
+func (s *categoryService) Breadcrumbs(categoryID uint) ([]models.Breadcrumb, error) {
+	index, err := s.categoryIndex()
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := index[categoryID]; !ok {
+		return nil, ErrCategoryNotFound
+	}
+	return breadcrumbPath(index, categoryID), nil
+}
+
+// AttachBreadcrumbs fills in the category path of each product
+func (s *categoryService) AttachBreadcrumbs(products []models.Product) error {
+	if len(products) == 0 {
+		return nil
+	}
+	index, err := s.categoryIndex()
+	if err != nil {
+		return err
+	}
+	for i := range products {
+		products[i].Breadcrumbs = breadcrumbPath(index, products[i].CategoryID)
+	}
+	return nil
+}
+
+// categoryIndex loads every category keyed by ID
+func (s *categoryService) categoryIndex() (map[uint]models.Category, error) {
+	var categories []models.Category
+	if err := s.db.Select("id, name, slug, parent_id").Find(&categories).Error; err != nil {
+		return nil, err
+	}
+	index := make(map[uint]models.Category, len(categories))
+	for _, category := range categories {
+		index[category.ID] = category
+	}
+	return index, nil
+}
+
+// breadcrumbPath walks up from a category to the top level, stopping at missing parents or loops
+func breadcrumbPath(index map[uint]models.Category, categoryID uint) []models.Breadcrumb {
+	var path []models.Breadcrumb
+	seen := map[uint]bool{}
+	for id := categoryID; !seen[id]; {
+		category, ok := index[id]
+		if !ok {
+			break
+		}
+		seen[id] = true
+		path = append([]models.Breadcrumb{{ID: category.ID, Name: category.Name, Slug: category.Slug}}, path...)
+		if category.ParentID == nil {
+			break
+		}
+		id = *category.ParentID
+	}
+	return path
+}
+
+// ensureExists checks a category exists, reporting a missing parent
+func (s *categoryService) ensureExists(categoryID uint) error {
+	var count int64
+	if err := s.db.Model(&models.Category{}).Where("id = ?", categoryID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		return ErrParentNotFound
+	}
+	return nil
+}
+
+// ensureSlugAvailable validates a slug's format and that no other category uses it
+func (s *categoryService) ensureSlugAvailable(slug string, categoryID uint) error {
+	if !utils.ValidateSlug(slug) {
+		return ErrInvalidSlug
+	}
+	var count int64
+	if err := s.db.Unscoped().Model(&models.Category{}).Where("slug = ? AND id <> ?", slug, categoryID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrDuplicateSlug
+	}
+	return nil
+}
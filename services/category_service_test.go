@@ -0,0 +1,95 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/geoo115/Ecommerce/db"
+	"github.com/geoo115/Ecommerce/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCategoryService_TreeAndMove(t *testing.T) {
+	originalDB := db.DB
+	testDB := db.SetupTestDB(t)
+	db.DB = testDB
+	defer func() { db.DB = originalDB }()
+
+	service := NewCategoryService()
+	clothing := models.Category{Name: "Clothing", SortOrder: 2}
+	home := models.Category{Name: "Home", SortOrder: 1}
+	assert.NoError(t, service.Create(&clothing))
+	assert.NoError(t, service.Create(&home))
+	shoes := models.Category{Name: "Shoes", ParentID: &clothing.ID}
+	assert.NoError(t, service.Create(&shoes))
+	boots := models.Category{Name: "Boots", ParentID: &shoes.ID, Slug: "winter-boots"}
+	assert.NoError(t, service.Create(&boots))
+	assert.Equal(t, "shoes", shoes.Slug)
+
+	missing := uint(9999)
+	assert.ErrorIs(t, service.Create(&models.Category{Name: "Orphan", ParentID: &missing}), ErrParentNotFound)
+	assert.ErrorIs(t, service.Create(&models.Category{Name: "Other Boots", Slug: "winter-boots"}), ErrDuplicateSlug)
+
+	tree, err := service.Tree()
+	assert.NoError(t, err)
+	if assert.Len(t, tree, 2) {
+		assert.Equal(t, "Home", tree[0].Name)
+		assert.Equal(t, "Clothing", tree[1].Name)
+		if assert.Len(t, tree[1].Children, 1) {
+			assert.Equal(t, "Boots", tree[1].Children[0].Children[0].Name)
+		}
+	}
+
+	ids, err := service.DescendantIDs(clothing.ID)
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []uint{clothing.ID, shoes.ID, boots.ID}, ids)
+
+	// A category cannot move under itself or a descendant
+	assert.ErrorIs(t, service.Update(&clothing, CategoryUpdate{ParentID: &boots.ID}), ErrCategoryCycle)
+	assert.ErrorIs(t, service.Update(&clothing, CategoryUpdate{ParentID: &clothing.ID}), ErrCategoryCycle)
+
+	assert.NoError(t, service.Update(&shoes, CategoryUpdate{ParentID: &home.ID}))
+	crumbs, err := service.Breadcrumbs(boots.ID)
+	assert.NoError(t, err)
+	if assert.Len(t, crumbs, 3) {
+		assert.Equal(t, "Home", crumbs[0].Name)
+		assert.Equal(t, "winter-boots", crumbs[2].Slug)
+	}
+
+	root := uint(0)
+	assert.NoError(t, service.Update(&shoes, CategoryUpdate{ParentID: &root}))
+	var reloaded models.Category
+	testDB.First(&reloaded, shoes.ID)
+	assert.Nil(t, reloaded.ParentID)
+}
+
+func TestCategoryService_DeleteReparentsChildren(t *testing.T) {
+	originalDB := db.DB
+	testDB := db.SetupTestDB(t)
+	db.DB = testDB
+	defer func() { db.DB = originalDB }()
+
+	service := NewCategoryService()
+	parent := models.Category{Name: "Electronics"}
+	assert.NoError(t, service.Create(&parent))
+	middle := models.Category{Name: "Audio", ParentID: &parent.ID}
+	assert.NoError(t, service.Create(&middle))
+	child := models.Category{Name: "Headphones", ParentID: &middle.ID}
+	assert.NoError(t, service.Create(&child))
+
+	product := models.Product{Name: "Earbuds", Price: 50, CategoryID: child.ID}
+	testDB.Create(&product)
+
+	assert.NoError(t, service.Delete(&middle))
+	var reloaded models.Category
+	testDB.First(&reloaded, child.ID)
+	if assert.NotNil(t, reloaded.ParentID) {
+		assert.Equal(t, parent.ID, *reloaded.ParentID)
+	}
+
+	products := []models.Product{product}
+	assert.NoError(t, service.AttachBreadcrumbs(products))
+	if assert.Len(t, products[0].Breadcrumbs, 2) {
+		assert.Equal(t, "electronics", products[0].Breadcrumbs[0].Slug)
+		assert.Equal(t, "Headphones", products[0].Breadcrumbs[1].Name)
+	}
+}
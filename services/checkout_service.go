@@ -0,0 +1,409 @@
+package services
+
+import (
+	"errors"
+	"math"
+	"time"
+
+	"github.com/geoo115/Ecommerce/config"
+	"github.com/geoo115/Ecommerce/db"
+	"github.com/geoo115/Ecommerce/models"
+	"github.com/geoo115/Ecommerce/utils"
+	"gorm.io/gorm"
+)
+
+// Checkout session statuses
+const (
+	CheckoutOpen      = "Open"
+	CheckoutCompleted = "Completed"
+	CheckoutExpired   = "Expired"
+)
+
+// Checkout steps, in order
+const (
+	StepCart     = "cart"
+	StepAddress  = "address"
+	StepDelivery = "delivery"
+	StepReview   = "review"
+)
+
+// Checkout errors surfaced to handlers
+var (
+	ErrCartEmpty              = errors.New("cart is empty")
+	ErrSessionExpired         = errors.New("checkout session has expired")
+	ErrSessionClosed          = errors.New("checkout session is no longer open")
+	ErrCheckoutStep           = errors.New("previous checkout step not completed")
+	ErrAddressNotFound        = errors.New("address not found")
+	ErrUnknownDeliveryMethod  = errors.New("unknown delivery method")
+	ErrDeliveryMethodRequired = errors.New("delivery method required")
+	ErrShippingNotRequired    = errors.New("digital-only orders are not shipped")
+)
+
+// stepOrder ranks the checkout steps so a session can tell which have been completed
+var stepOrder = map[string]int{StepCart: 0, StepAddress: 1, StepDelivery: 2, StepReview: 3}
+
+// DeliveryMethod is a shipping option offered at checkout
+type DeliveryMethod struct {
+	Code          string  `json:"code"`
+	Name          string  `json:"name"`
+	Cost          float64 `json:"cost"`
+	EstimatedDays int     `json:"estimated_days"`
+}
+
+// DeliveryMethods returns the shipping options with their configured prices
+func DeliveryMethods() []DeliveryMethod {
+	cfg := config.GetCheckoutConfig()
+	return []DeliveryMethod{
+		{Code: "standard", Name: "Standard Delivery", Cost: cfg.StandardShippingCost, EstimatedDays: 5},
+		{Code: "express", Name: "Express Delivery", Cost: cfg.ExpressShippingCost, EstimatedDays: 2},
+		{Code: "pickup", Name: "Store Pickup", Cost: 0, EstimatedDays: 1},
+	}
+}
+
+// findDeliveryMethod looks up a delivery method by code
+func findDeliveryMethod(code string) (DeliveryMethod, bool) {
+	for _, method := range DeliveryMethods() {
+		if method.Code == code {
+			return method, true
+		}
+	}
+	return DeliveryMethod{}, false
+}
+
+// CheckoutService interface defines multi-step checkout business logic
+type CheckoutService interface {
+	StartSession(userID uint) (*models.CheckoutSession, error)
+	SetAddress(session *models.CheckoutSession, addressID uint) error
+	SetDeliveryMethod(session *models.CheckoutSession, code string) error
+	Review(session *models.CheckoutSession, redeemPoints int) error
+	Complete(session *models.CheckoutSession, paymentMethod string) (*models.Order, *models.Payment, error)
+	ExpireSessions(now time.Time) (int64, error)
+}
+
+// checkoutService implements CheckoutService interface
+type checkoutService struct {
+	db     *gorm.DB
+	config config.CheckoutConfig
+}
+
+// NewCheckoutService creates a new checkout service instance
+func NewCheckoutService() CheckoutService {
+	return &checkoutService{db: db.DB, config: config.GetCheckoutConfig()}
+}
+
+// StartSession snapshots the user's cart at current prices into a new open session
+func (s *checkoutService) StartSession(userID uint) (*models.CheckoutSession, error) {
+	var cartItems []models.Cart
+	if err := s.db.Where("user_id = ?", userID).Preload("Product").Preload("Variant").Find(&cartItems).Error; err != nil {
+		return nil, err
+	}
+	if len(cartItems) == 0 {
+		return nil, ErrCartEmpty
+	}
+
+	session := models.CheckoutSession{
+		UserID:    userID,
+		Status:    CheckoutOpen,
+		Step:      StepCart,
+		ExpiresAt: time.Now().Add(s.config.SessionTTL),
+	}
+	for _, item := range cartItems {
+		session.Items = append(session.Items, models.CheckoutSessionItem{
+			ProductID: item.ProductID,
+			VariantID: item.VariantID,
+			Quantity:  item.Quantity,
+			Price:     item.UnitPrice(),
+		})
+		session.Subtotal += item.UnitPrice() * float64(item.Quantity)
+	}
+	session.Subtotal = roundCents(session.Subtotal)
+	session.Total = session.Subtotal
+
+	// A user checks out one cart at a time, so a new session supersedes any still open
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := expireOpenSessions(tx, userID, 0); err != nil {
+			return err
+		}
+		return tx.Create(&session).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// SetAddress chooses one of the user's saved addresses for delivery
+func (s *checkoutService) SetAddress(session *models.CheckoutSession, addressID uint) error {
+	if err := s.ensureOpen(session); err != nil {
+		return err
+	}
+
+	var address models.Address
+	if err := s.db.Where("id = ? AND user_id = ?", addressID, session.UserID).First(&address).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrAddressNotFound
+		}
+		return err
+	}
+
+	session.AddressID = &address.ID
+	session.Address = &address
+	s.advance(session, StepAddress)
+	return s.save(session)
+}
+
+// SetDeliveryMethod chooses how the order is shipped and prices it
+func (s *checkoutService) SetDeliveryMethod(session *models.CheckoutSession, code string) error {
+	if err := s.ensureOpen(session); err != nil {
+		return err
+	}
+	if session.AddressID == nil {
+		return ErrCheckoutStep
+	}
+	digitalOnly, err := s.digitalOnly(session)
+	if err != nil {
+		return err
+	}
+	if digitalOnly {
+		return ErrShippingNotRequired
+	}
+
+	method, ok := findDeliveryMethod(code)
+	if !ok {
+		return ErrUnknownDeliveryMethod
+	}
+
+	session.DeliveryMethod = method.Code
+	session.ShippingCost = method.Cost
+	if method.Code == "standard" && s.config.FreeShippingThreshold > 0 && session.Subtotal >= s.config.FreeShippingThreshold {
+		session.ShippingCost = 0
+	}
+	s.advance(session, StepDelivery)
+	return s.save(session)
+}
+
+// Review finalises the totals, optionally redeeming loyalty points as a discount. Sessions
+// with only digital products skip the address and delivery steps.
+func (s *checkoutService) Review(session *models.CheckoutSession, redeemPoints int) error {
+	if err := s.ensureOpen(session); err != nil {
+		return err
+	}
+	if session.DeliveryMethod == "" {
+		digitalOnly, err := s.digitalOnly(session)
+		if err != nil {
+			return err
+		}
+		if !digitalOnly {
+			return ErrDeliveryMethodRequired
+		}
+	}
+
+	total := session.Subtotal + session.ShippingCost
+	loyalty := NewLoyaltyServiceWithDB(s.db)
+	session.PointsRedeemed = 0
+	session.DiscountAmount = 0
+	if redeemPoints > 0 {
+		balance, err := loyalty.GetBalance(session.UserID)
+		if err != nil {
+			return err
+		}
+		if balance < redeemPoints {
+			return ErrInsufficientPoints
+		}
+		session.DiscountAmount = loyalty.DiscountForPoints(redeemPoints)
+		session.PointsRedeemed = redeemPoints
+		if session.DiscountAmount > total {
+			session.DiscountAmount = total
+			session.PointsRedeemed = loyalty.PointsForDiscount(total)
+		}
+	}
+	session.Total = roundCents(total - session.DiscountAmount)
+	session.Step = StepReview
+	return s.save(session)
+}
+
+// Complete places the reviewed order, reserves stock and redeems points, then charges the
+// payment. The session is claimed first so it can only be completed once, and the order is
+// committed before the charge so every payment attempt is recorded against it. A declined
+// payment cancels the order and leaves the session open for retry.
+func (s *checkoutService) Complete(session *models.CheckoutSession, paymentMethod string) (*models.Order, *models.Payment, error) {
+	if err := s.ensureOpen(session); err != nil {
+		return nil, nil, err
+	}
+	if session.Step != StepReview {
+		return nil, nil, ErrCheckoutStep
+	}
+
+	claim := s.db.Model(&models.CheckoutSession{}).Where("id = ? AND status = ?", session.ID, CheckoutOpen).
+		Update("status", CheckoutCompleted)
+	if claim.Error != nil {
+		return nil, nil, claim.Error
+	}
+	if claim.RowsAffected != 1 {
+		return nil, nil, ErrSessionClosed
+	}
+	// Other open sessions snapshot the same cart and would order it again
+	if err := expireOpenSessions(s.db, session.UserID, session.ID); err != nil {
+		s.reopen(session)
+		return nil, nil, err
+	}
+
+	var order models.Order
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		order = models.Order{
+			UserID:         session.UserID,
+			TotalAmount:    session.Total,
+			Status:         "Pending",
+			AddressID:      session.AddressID,
+			DeliveryMethod: session.DeliveryMethod,
+			ShippingCost:   session.ShippingCost,
+			PointsRedeemed: session.PointsRedeemed,
+			DiscountAmount: session.DiscountAmount,
+		}
+		if method, ok := findDeliveryMethod(session.DeliveryMethod); ok {
+			order.EstimatedDeliveryDate = time.Now().AddDate(0, 0, method.EstimatedDays).Format("2006-01-02")
+		}
+
+		orders := NewOrderServiceWithDB(tx)
+		for _, item := range session.Items {
+			_, variant, err := ResolveVariant(tx, item.ProductID, item.VariantID)
+			if err != nil {
+				return err
+			}
+			if err := orders.ReserveStock(item.ProductID, item.VariantID, item.Quantity); err != nil {
+				return err
+			}
+			orderItem := models.OrderItem{
+				ProductID: item.ProductID,
+				VariantID: item.VariantID,
+				Quantity:  item.Quantity,
+				Price:     item.Price,
+			}
+			if variant != nil {
+				orderItem.SKU = variant.SKU
+			}
+			if orderItem.Components, err = BundleOrderComponents(tx, item.ProductID); err != nil {
+				return err
+			}
+			order.Items = append(order.Items, orderItem)
+		}
+		if err := models.CreateOrder(tx, &order); err != nil {
+			return err
+		}
+		if err := NewLoyaltyServiceWithDB(tx).RedeemPoints(session.UserID, order.ID, session.PointsRedeemed); err != nil {
+			return err
+		}
+		return tx.Model(&models.CheckoutSession{}).Where("id = ?", session.ID).Update("order_id", order.ID).Error
+	})
+	if err != nil {
+		s.reopen(session)
+		return nil, nil, err
+	}
+
+	payment, err := NewPaymentServiceWithDB(s.db).ChargeOrder(&order, paymentMethod)
+	if err != nil {
+		orders := NewOrderServiceWithDB(s.db)
+		if cancelErr := orders.CancelOrder(&order); cancelErr != nil {
+			utils.Warn("Failed to cancel unpaid checkout order %d: %v", order.ID, cancelErr)
+		}
+		if reverseErr := NewLoyaltyServiceWithDB(s.db).ReverseOrderPoints(order.ID); reverseErr != nil {
+			utils.Warn("Failed to restore points redeemed on unpaid checkout order %d: %v", order.ID, reverseErr)
+		}
+		s.reopen(session)
+		return nil, nil, err
+	}
+
+	for _, item := range session.Items {
+		if err := s.db.Scopes(models.WhereVariant(item.VariantID)).
+			Where("user_id = ? AND product_id = ?", session.UserID, item.ProductID).
+			Delete(&models.Cart{}).Error; err != nil {
+			utils.Warn("Failed to clear cart of user %d after checkout: %v", session.UserID, err)
+		}
+	}
+
+	session.Status = CheckoutCompleted
+	session.OrderID = &order.ID
+	return &order, payment, nil
+}
+
+// reopen releases a session claimed by Complete so the customer can try again
+func (s *checkoutService) reopen(session *models.CheckoutSession) {
+	if err := s.db.Model(&models.CheckoutSession{}).Where("id = ?", session.ID).
+		Updates(map[string]interface{}{"status": CheckoutOpen, "order_id": nil}).Error; err != nil {
+		utils.Warn("Failed to reopen checkout session %d: %v", session.ID, err)
+	}
+}
+
+// digitalOnly reports whether every product in the session is digital, so nothing is shipped
+func (s *checkoutService) digitalOnly(session *models.CheckoutSession) (bool, error) {
+	productIDs := make([]uint, len(session.Items))
+	for i, item := range session.Items {
+		productIDs[i] = item.ProductID
+	}
+	var physical int64
+	if err := s.db.Model(&models.Product{}).Where("id IN ? AND type <> ?", productIDs, models.ProductTypeDigital).
+		Count(&physical).Error; err != nil {
+		return false, err
+	}
+	return len(productIDs) > 0 && physical == 0, nil
+}
+
+// ExpireSessions closes every open session idle past its expiry time
+func (s *checkoutService) ExpireSessions(now time.Time) (int64, error) {
+	result := s.db.Model(&models.CheckoutSession{}).
+		Where("status = ? AND expires_at <= ?", CheckoutOpen, now).
+		Update("status", CheckoutExpired)
+	return result.RowsAffected, result.Error
+}
+
+// expireOpenSessions expires the user's open sessions other than the one with keepID
+func expireOpenSessions(tx *gorm.DB, userID, keepID uint) error {
+	return tx.Model(&models.CheckoutSession{}).
+		Where("user_id = ? AND status = ? AND id <> ?", userID, CheckoutOpen, keepID).
+		Update("status", CheckoutExpired).Error
+}
+
+// ensureOpen rejects completed sessions and expires sessions idle past their TTL
+func (s *checkoutService) ensureOpen(session *models.CheckoutSession) error {
+	if session.Status == CheckoutOpen && time.Now().After(session.ExpiresAt) {
+		session.Status = CheckoutExpired
+		if err := s.db.Model(session).Update("status", CheckoutExpired).Error; err != nil {
+			return err
+		}
+	}
+	switch session.Status {
+	case CheckoutOpen:
+		return nil
+	case CheckoutExpired:
+		return ErrSessionExpired
+	default:
+		return ErrSessionClosed
+	}
+}
+
+// advance records a completed step. Any change before review clears the discount so the
+// totals must be reviewed again; going back to the address step also clears delivery.
+func (s *checkoutService) advance(session *models.CheckoutSession, step string) {
+	if step == StepAddress && stepOrder[session.Step] > stepOrder[StepAddress] {
+		session.DeliveryMethod = ""
+		session.ShippingCost = 0
+	}
+	session.PointsRedeemed = 0
+	session.DiscountAmount = 0
+	session.Total = roundCents(session.Subtotal + session.ShippingCost)
+	session.Step = step
+}
+
+// save persists the session's step fields and extends its expiry
+func (s *checkoutService) save(session *models.CheckoutSession) error {
+	session.ExpiresAt = time.Now().Add(s.config.SessionTTL)
+	return s.db.Model(session).Select(
+		"step", "address_id", "delivery_method", "shipping_cost",
+		"points_redeemed", "discount_amount", "total", "expires_at",
+	).Updates(session).Error
+}
+
+// roundCents rounds a currency amount to two decimal places
+func roundCents(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
@@ -0,0 +1,214 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/geoo115/Ecommerce/db"
+	"github.com/geoo115/Ecommerce/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func setupCheckoutUser(t *testing.T, price float64, quantity, stock int) (models.User, models.Address, models.Product) {
+	t.Helper()
+	user := models.User{Username: "checkout", Email: "checkout@example.com"}
+	db.DB.Create(&user)
+	address := models.Address{UserID: user.ID, Address: "1 Main St", City: "Town", ZipCode: "12345"}
+	db.DB.Create(&address)
+	product := createStockedProduct(t, "Lamp", price, stock)
+	db.DB.Create(&models.Cart{UserID: user.ID, ProductID: product.ID, Quantity: quantity})
+	return user, address, product
+}
+
+func TestCheckoutService_CompleteFlow(t *testing.T) {
+	originalDB := db.DB
+	testDB := db.SetupTestDB(t)
+	db.DB = testDB
+	defer func() { db.DB = originalDB }()
+
+	user, address, product := setupCheckoutUser(t, 10, 2, 5)
+	service := NewCheckoutService()
+
+	session, err := service.StartSession(user.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, 20.0, session.Subtotal)
+
+	// Steps must be taken in order
+	assert.ErrorIs(t, service.SetDeliveryMethod(session, "express"), ErrCheckoutStep)
+	_, _, err = service.Complete(session, "card")
+	assert.ErrorIs(t, err, ErrCheckoutStep)
+
+	assert.NoError(t, service.SetAddress(session, address.ID))
+	assert.ErrorIs(t, service.SetDeliveryMethod(session, "drone"), ErrUnknownDeliveryMethod)
+	assert.NoError(t, service.SetDeliveryMethod(session, "express"))
+	assert.NoError(t, service.Review(session, 0))
+	assert.Equal(t, 29.99, session.Total)
+
+	// Prices are snapshotted when the session starts
+	testDB.Model(&product).Update("price", 99)
+
+	order, payment, err := service.Complete(session, "card")
+	assert.NoError(t, err)
+	assert.Equal(t, 29.99, order.TotalAmount)
+	assert.Equal(t, "Paid", order.Status)
+	assert.Equal(t, "express", order.DeliveryMethod)
+	assert.Equal(t, address.ID, *order.AddressID)
+	assert.Equal(t, order.ID, payment.OrderID)
+	assert.Equal(t, CheckoutCompleted, session.Status)
+
+	var inv models.Inventory
+	testDB.Where("product_id = ?", product.ID).First(&inv)
+	assert.Equal(t, 3, inv.Stock)
+
+	var cartCount int64
+	testDB.Model(&models.Cart{}).Where("user_id = ?", user.ID).Count(&cartCount)
+	assert.Equal(t, int64(0), cartCount)
+
+	_, _, err = service.Complete(session, "card")
+	assert.ErrorIs(t, err, ErrSessionClosed)
+}
+
+func TestCheckoutService_DeclinedPaymentCancelsOrder(t *testing.T) {
+	originalDB := db.DB
+	testDB := db.SetupTestDB(t)
+	db.DB = testDB
+	originalGateway := Gateway
+	Gateway = decliningGateway{}
+	defer func() {
+		db.DB = originalDB
+		Gateway = originalGateway
+	}()
+
+	user, address, product := setupCheckoutUser(t, 30, 2, 5)
+	service := NewCheckoutService()
+
+	session, _ := service.StartSession(user.ID)
+	service.SetAddress(session, address.ID)
+	assert.NoError(t, service.SetDeliveryMethod(session, "standard"))
+	assert.Equal(t, 0.0, session.ShippingCost, "standard delivery is free above the threshold")
+	service.Review(session, 0)
+
+	_, _, err := service.Complete(session, "card")
+	assert.ErrorIs(t, err, ErrPaymentDeclined)
+	assert.Equal(t, CheckoutOpen, session.Status)
+
+	// The failed attempt is kept against the cancelled order
+	var order models.Order
+	assert.NoError(t, testDB.First(&order).Error)
+	assert.Equal(t, "Cancelled", order.Status)
+	var payment models.Payment
+	assert.NoError(t, testDB.Where("order_id = ?", order.ID).First(&payment).Error)
+	assert.Equal(t, "Failed", payment.Status)
+
+	var inv models.Inventory
+	testDB.Where("product_id = ?", product.ID).First(&inv)
+	assert.Equal(t, 5, inv.Stock)
+	var cartCount int64
+	testDB.Model(&models.Cart{}).Where("user_id = ?", user.ID).Count(&cartCount)
+	assert.Equal(t, int64(1), cartCount)
+
+	// The reopened session can be retried
+	var stored models.CheckoutSession
+	testDB.First(&stored, session.ID)
+	assert.Equal(t, CheckoutOpen, stored.Status)
+	assert.Nil(t, stored.OrderID)
+	Gateway = originalGateway
+	retried, _, err := service.Complete(session, "card")
+	assert.NoError(t, err)
+	assert.Equal(t, "Paid", retried.Status)
+}
+
+func TestCheckoutService_CompleteOnce(t *testing.T) {
+	originalDB := db.DB
+	testDB := db.SetupTestDB(t)
+	db.DB = testDB
+	defer func() { db.DB = originalDB }()
+
+	user, address, _ := setupCheckoutUser(t, 10, 1, 5)
+	service := NewCheckoutService()
+	session, _ := service.StartSession(user.ID)
+	service.SetAddress(session, address.ID)
+	service.SetDeliveryMethod(session, "standard")
+	service.Review(session, 0)
+
+	// A second request holding the same open session cannot place another order
+	concurrent := *session
+	_, _, err := service.Complete(session, "card")
+	assert.NoError(t, err)
+	_, _, err = service.Complete(&concurrent, "card")
+	assert.ErrorIs(t, err, ErrSessionClosed)
+
+	var orderCount, paymentCount int64
+	testDB.Model(&models.Order{}).Count(&orderCount)
+	testDB.Model(&models.Payment{}).Count(&paymentCount)
+	assert.Equal(t, int64(1), orderCount)
+	assert.Equal(t, int64(1), paymentCount)
+}
+
+func TestCheckoutService_OneOpenSessionPerUser(t *testing.T) {
+	originalDB := db.DB
+	testDB := db.SetupTestDB(t)
+	db.DB = testDB
+	defer func() { db.DB = originalDB }()
+
+	user, address, _ := setupCheckoutUser(t, 10, 1, 5)
+	service := NewCheckoutService()
+	review := func(session *models.CheckoutSession) {
+		service.SetAddress(session, address.ID)
+		service.SetDeliveryMethod(session, "standard")
+		service.Review(session, 0)
+	}
+	status := func(session *models.CheckoutSession) string {
+		var stored models.CheckoutSession
+		testDB.First(&stored, session.ID)
+		return stored.Status
+	}
+
+	// Starting a new checkout supersedes the open one
+	first, _ := service.StartSession(user.ID)
+	review(first)
+	second, err := service.StartSession(user.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, CheckoutExpired, status(first))
+	_, _, err = service.Complete(first, "card")
+	assert.ErrorIs(t, err, ErrSessionClosed)
+
+	// Completing one session expires any other still open
+	third, _ := service.StartSession(user.ID)
+	testDB.Model(second).Update("status", CheckoutOpen)
+	review(third)
+	_, _, err = service.Complete(third, "card")
+	assert.NoError(t, err)
+	assert.Equal(t, CheckoutExpired, status(second))
+
+	var orderCount int64
+	testDB.Model(&models.Order{}).Count(&orderCount)
+	assert.Equal(t, int64(1), orderCount)
+}
+
+func TestCheckoutService_Expiry(t *testing.T) {
+	originalDB := db.DB
+	testDB := db.SetupTestDB(t)
+	db.DB = testDB
+	defer func() { db.DB = originalDB }()
+
+	user, address, _ := setupCheckoutUser(t, 10, 1, 5)
+	service := NewCheckoutService()
+
+	_, err := service.StartSession(9999)
+	assert.ErrorIs(t, err, ErrCartEmpty)
+
+	stale, _ := service.StartSession(user.ID)
+	stale.ExpiresAt = time.Now().Add(-time.Minute)
+	testDB.Model(stale).Update("expires_at", stale.ExpiresAt)
+	assert.ErrorIs(t, service.SetAddress(stale, address.ID), ErrSessionExpired)
+
+	idle, _ := service.StartSession(user.ID)
+	expired, err := service.ExpireSessions(time.Now().Add(time.Hour))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), expired)
+
+	var stored models.CheckoutSession
+	testDB.First(&stored, idle.ID)
+	assert.Equal(t, CheckoutExpired, stored.Status)
+}
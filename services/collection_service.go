@@ -0,0 +1,359 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/geoo115/Ecommerce/db"
+	"github.com/geoo115/Ecommerce/models"
+	"github.com/geoo115/Ecommerce/utils"
+	"gorm.io/gorm"
+)
+
+// Limits on a collection's definition
+const (
+	maxCollectionRules    = 20
+	maxCollectionProducts = 1000
+)
+
+// Collection errors surfaced to handlers
+var (
+	ErrInvalidCollection     = errors.New("invalid collection")
+	ErrInvalidCollectionRule = errors.New("invalid collection rule")
+)
+
+// CollectionInput is the full definition of a collection. Manual collections list their
+// products in order; smart collections have rules instead.
+type CollectionInput struct {
+	Name        string
+	Slug        string // Derived from the name when empty
+	Description string
+	Type        string
+	Sort        string
+	Rules       []models.CollectionRule
+	ProductIDs  []uint
+}
+
+// CollectionService interface defines manual and smart product collections
+type CollectionService interface {
+	Create(input CollectionInput) (*models.Collection, error)
+	Update(collection *models.Collection, input CollectionInput) error
+	Delete(collection *models.Collection) error
+	ProductScope(collection *models.Collection) (func(*gorm.DB) *gorm.DB, error)
+}
+
+// collectionService implements CollectionService interface
+type collectionService struct {
+	db *gorm.DB
+}
+
+// NewCollectionService creates a new collection service instance
+func NewCollectionService() CollectionService {
+	return NewCollectionServiceWithDB(db.DB)
+}
+
+// NewCollectionServiceWithDB creates a collection service bound to a specific database handle
+func NewCollectionServiceWithDB(database *gorm.DB) CollectionService {
+	return &collectionService{db: database}
+}
+
+// Create validates and stores a collection with its rules or products
+func (s *collectionService) Create(input CollectionInput) (*models.Collection, error) {
+	collection := &models.Collection{}
+	if err := s.apply(collection, input); err != nil {
+		return nil, err
+	}
+	if err := s.db.Create(collection).Error; err != nil {
+		return nil, err
+	}
+	return collection, nil
+}
+
+// Update replaces a collection's definition, including its rules or products
+func (s *collectionService) Update(collection *models.Collection, input CollectionInput) error {
+	updated := *collection
+	if err := s.apply(&updated, input); err != nil {
+		return err
+	}
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Collection{}).Where("id = ?", collection.ID).Updates(map[string]interface{}{
+			"name": updated.Name, "slug": updated.Slug, "description": updated.Description,
+			"type": updated.Type, "sort": updated.Sort,
+		}).Error; err != nil {
+			return err
+		}
+		if err := deleteCollectionContents(tx, collection.ID); err != nil {
+			return err
+		}
+		for i := range updated.Rules {
+			updated.Rules[i].CollectionID = collection.ID
+		}
+		for i := range updated.Items {
+			updated.Items[i].CollectionID = collection.ID
+		}
+		if len(updated.Rules) > 0 {
+			if err := tx.Create(&updated.Rules).Error; err != nil {
+				return err
+			}
+		}
+		if len(updated.Items) > 0 {
+			return tx.Create(&updated.Items).Error
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	*collection = updated
+	return nil
+}
+
+// Delete removes a collection. Its slug stays reserved, as for deleted products.
+func (s *collectionService) Delete(collection *models.Collection) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := deleteCollectionContents(tx, collection.ID); err != nil {
+			return err
+		}
+		return tx.Delete(collection).Error
+	})
+}
+
+// deleteCollectionContents removes a collection's rules and products
+func deleteCollectionContents(tx *gorm.DB, collectionID uint) error {
+	if err := tx.Unscoped().Where("collection_id = ?", collectionID).Delete(&models.CollectionRule{}).Error; err != nil {
+		return err
+	}
+	return tx.Unscoped().Where("collection_id = ?", collectionID).Delete(&models.CollectionProduct{}).Error
+}
+
+// apply validates a definition and sets it on a collection without saving it
+func (s *collectionService) apply(collection *models.Collection, input CollectionInput) error {
+	if input.Name == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidCollection)
+	}
+	if _, ok := productSortKeys[input.Sort]; input.Sort != "" && !ok {
+		return fmt.Errorf("%w: sort must be one of price_asc, price_desc, newest, name, best_selling or rating", ErrInvalidCollection)
+	}
+
+	var rules []models.CollectionRule
+	var items []models.CollectionProduct
+	switch input.Type {
+	case models.CollectionTypeManual:
+		if len(input.Rules) > 0 {
+			return fmt.Errorf("%w: manual collections list products instead of rules", ErrInvalidCollection)
+		}
+		if len(input.ProductIDs) > maxCollectionProducts {
+			return fmt.Errorf("%w: a collection may list at most %d products", ErrInvalidCollection, maxCollectionProducts)
+		}
+		seen := map[uint]bool{}
+		for i, id := range input.ProductIDs {
+			if seen[id] {
+				return fmt.Errorf("%w: product %d is listed twice", ErrInvalidCollection, id)
+			}
+			seen[id] = true
+			items = append(items, models.CollectionProduct{ProductID: id, Position: i})
+		}
+		if len(seen) > 0 {
+			var count int64
+			if err := s.db.Model(&models.Product{}).Where("id IN ?", input.ProductIDs).Count(&count).Error; err != nil {
+				return err
+			}
+			if int(count) != len(seen) {
+				return ErrProductNotFound
+			}
+		}
+	case models.CollectionTypeSmart:
+		if len(input.ProductIDs) > 0 {
+			return fmt.Errorf("%w: smart collections have rules instead of products", ErrInvalidCollection)
+		}
+		if len(input.Rules) == 0 || len(input.Rules) > maxCollectionRules {
+			return fmt.Errorf("%w: smart collections need 1 to %d rules", ErrInvalidCollection, maxCollectionRules)
+		}
+		for _, rule := range input.Rules {
+			normalized, err := normalizeCollectionRule(rule)
+			if err != nil {
+				return err
+			}
+			rules = append(rules, normalized)
+		}
+	default:
+		return fmt.Errorf("%w: type must be manual or smart", ErrInvalidCollection)
+	}
+
+	slug := input.Slug
+	switch {
+	case slug != "":
+		if !utils.ValidateSlug(slug) {
+			return ErrInvalidSlug
+		}
+		inUse, err := models.SlugInUse(s.db, &models.Collection{}, slug, collection.ID)
+		if err != nil {
+			return err
+		}
+		if inUse {
+			return ErrDuplicateSlug
+		}
+	case collection.Slug != "":
+		slug = collection.Slug
+	default:
+		var err error
+		slug, err = models.UniqueSlug(input.Name, "collection", func(candidate string) (bool, error) {
+			return models.SlugInUse(s.db, &models.Collection{}, candidate, collection.ID)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	collection.Name = input.Name
+	collection.Slug = slug
+	collection.Description = input.Description
+	collection.Type = input.Type
+	collection.Sort = input.Sort
+	collection.Rules = rules
+	collection.Items = items
+	return nil
+}
+
+// normalizeCollectionRule validates a rule and stores its value in a canonical form
+func normalizeCollectionRule(rule models.CollectionRule) (models.CollectionRule, error) {
+	normalized := models.CollectionRule{Field: rule.Field, Operator: rule.Operator}
+	single := rule.Operator == models.CollectionOperatorEquals
+	values := []string{rule.Value}
+	if rule.Operator == models.CollectionOperatorIn {
+		values = strings.Split(rule.Value, ",")
+	}
+
+	switch rule.Field {
+	case models.CollectionRuleTag:
+		if !single && rule.Operator != models.CollectionOperatorIn {
+			return normalized, fmt.Errorf("%w: tag rules use eq or in", ErrInvalidCollectionRule)
+		}
+		tags, err := NormalizeTags(values)
+		if err != nil {
+			return normalized, fmt.Errorf("%w: %v", ErrInvalidCollectionRule, err)
+		}
+		normalized.Value = strings.Join(tags, ",")
+	case models.CollectionRulePrice:
+		if _, ok := priceComparisons[rule.Operator]; !ok {
+			return normalized, fmt.Errorf("%w: price rules use eq, lt, lte, gt or gte", ErrInvalidCollectionRule)
+		}
+		price, err := strconv.ParseFloat(strings.TrimSpace(rule.Value), 64)
+		if err != nil || price < 0 {
+			return normalized, fmt.Errorf("%w: price rules compare with a non-negative number", ErrInvalidCollectionRule)
+		}
+		normalized.Value = strconv.FormatFloat(price, 'f', -1, 64)
+	case models.CollectionRuleCategory:
+		if !single && rule.Operator != models.CollectionOperatorIn {
+			return normalized, fmt.Errorf("%w: category rules use eq or in", ErrInvalidCollectionRule)
+		}
+		ids, err := parseRuleIDs(values)
+		if err != nil {
+			return normalized, err
+		}
+		parts := make([]string, len(ids))
+		for i, id := range ids {
+			parts[i] = strconv.FormatUint(uint64(id), 10)
+		}
+		normalized.Value = strings.Join(parts, ",")
+	case models.CollectionRuleInStock:
+		if !single || rule.Value != "true" {
+			return normalized, fmt.Errorf("%w: in_stock rules are eq true", ErrInvalidCollectionRule)
+		}
+		normalized.Value = "true"
+	default:
+		return normalized, fmt.Errorf("%w: field must be tag, price, category or in_stock", ErrInvalidCollectionRule)
+	}
+	return normalized, nil
+}
+
+// priceComparisons maps price rule operators to SQL
+var priceComparisons = map[string]string{
+	models.CollectionOperatorEquals:    "=",
+	models.CollectionOperatorLess:      "<",
+	models.CollectionOperatorLessEq:    "<=",
+	models.CollectionOperatorGreater:   ">",
+	models.CollectionOperatorGreaterEq: ">=",
+}
+
+// parseRuleIDs reads the category IDs of a rule
+func parseRuleIDs(values []string) ([]uint, error) {
+	ids := make([]uint, 0, len(values))
+	for _, value := range values {
+		id, err := strconv.ParseUint(strings.TrimSpace(value), 10, 32)
+		if err != nil || id == 0 {
+			return nil, fmt.Errorf("%w: category rules take category IDs", ErrInvalidCollectionRule)
+		}
+		ids = append(ids, uint(id))
+	}
+	return ids, nil
+}
+
+// ProductScope restricts a product query to a collection's products. Smart collections are
+// evaluated on every query, so products join and leave them as they change.
+func (s *collectionService) ProductScope(collection *models.Collection) (func(*gorm.DB) *gorm.DB, error) {
+	if !collection.IsSmart() {
+		return func(query *gorm.DB) *gorm.DB {
+			return query.Where("products.id IN (SELECT product_id FROM collection_products WHERE collection_id = ? AND deleted_at IS NULL)", collection.ID)
+		}, nil
+	}
+
+	var rules []models.CollectionRule
+	if err := s.db.Where("collection_id = ?", collection.ID).Order("id").Find(&rules).Error; err != nil {
+		return nil, err
+	}
+	scopes := make([]func(*gorm.DB) *gorm.DB, 0, len(rules))
+	for _, rule := range rules {
+		var scope func(*gorm.DB) *gorm.DB
+		switch rule.Field {
+		case models.CollectionRuleTag:
+			tags := strings.Split(rule.Value, ",")
+			scope = func(query *gorm.DB) *gorm.DB {
+				return query.Where(`EXISTS (SELECT 1 FROM product_tags WHERE product_tags.product_id = products.id
+					AND product_tags.deleted_at IS NULL AND product_tags.name IN ?)`, tags)
+			}
+		case models.CollectionRulePrice:
+			comparison := priceComparisons[rule.Operator]
+			price, _ := strconv.ParseFloat(rule.Value, 64)
+			scope = func(query *gorm.DB) *gorm.DB {
+				return query.Where("products.price "+comparison+" ?", price)
+			}
+		case models.CollectionRuleCategory:
+			ids, err := parseRuleIDs(strings.Split(rule.Value, ","))
+			if err != nil {
+				return nil, err
+			}
+			var filterErr error
+			if scope, filterErr = (ProductListOptions{CategoryIDs: ids, IncludeDescendants: true}).FilterScope(s.db); filterErr != nil {
+				return nil, filterErr
+			}
+		case models.CollectionRuleInStock:
+			scope, _ = ProductListOptions{InStock: true}.FilterScope(s.db)
+		default:
+			return nil, ErrInvalidCollectionRule
+		}
+		scopes = append(scopes, scope)
+	}
+	return func(query *gorm.DB) *gorm.DB {
+		return query.Scopes(scopes...)
+	}, nil
+}
+
+// CollectionPageOrder orders a collection's products by the requested sort key, else by the
+// collection's default sort key, else manual collections in their own order and smart ones by ID
+func CollectionPageOrder(collection *models.Collection, sort string) PageOrder {
+	if sort == "" {
+		sort = collection.Sort
+	}
+	if sort != "" || collection.IsSmart() {
+		return ProductListOptions{Sort: sort}.PageOrder()
+	}
+	return PageOrder{Table: "products", Keys: []SortKey{
+		{Column: fmt.Sprintf(`(SELECT collection_products.position FROM collection_products
+			WHERE collection_products.product_id = products.id AND collection_products.collection_id = %d
+			AND collection_products.deleted_at IS NULL)`, collection.ID)},
+		{Column: "products.id"},
+	}}
+}
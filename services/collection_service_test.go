@@ -0,0 +1,160 @@
+package services
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/geoo115/Ecommerce/db"
+	"github.com/geoo115/Ecommerce/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCollectionService(t *testing.T) {
+	originalDB := db.DB
+	testDB := db.SetupTestDB(t)
+	db.DB = testDB
+	defer func() { db.DB = originalDB }()
+
+	collections := NewCollectionServiceWithDB(testDB)
+	tags := NewTagServiceWithDB(testDB)
+
+	outdoor := models.Category{Name: "Outdoor"}
+	testDB.Create(&outdoor)
+	camping := models.Category{Name: "Camping", ParentID: &outdoor.ID}
+	testDB.Create(&camping)
+
+	hat := createStockedProduct(t, "Sun Hat", 20, 5)
+	tent := createStockedProduct(t, "Tent", 180, 2)
+	towel := createStockedProduct(t, "Beach Towel", 15, 0)
+	testDB.Model(&tent).Update("category_id", camping.ID)
+	assert.NoError(t, tags.SetTags(&hat, []string{"Summer", " summer ", "Beach  Wear"}))
+	assert.NoError(t, tags.SetTags(&towel, []string{"summer"}))
+	assert.NoError(t, tags.SetTags(&tent, []string{"camping"}))
+
+	members := func(collection *models.Collection) []string {
+		scope, err := collections.ProductScope(collection)
+		assert.NoError(t, err)
+		var products []models.Product
+		order := CollectionPageOrder(collection, "")
+		query := testDB.Model(&models.Product{}).Scopes(scope)
+		for _, key := range order.Keys {
+			query = query.Order(key.Column)
+		}
+		query.Find(&products)
+		names := make([]string, len(products))
+		for i, product := range products {
+			names[i] = product.Name
+		}
+		return names
+	}
+
+	t.Run("Tags", func(t *testing.T) {
+		assert.Equal(t, []string{"summer", "beach wear"}, tagNames(hat.Tags))
+		_, err := NormalizeTag("a,b")
+		assert.ErrorIs(t, err, ErrInvalidTag)
+		assert.ErrorIs(t, tags.SetTags(&hat, []string{" "}), ErrInvalidTag)
+
+		var stored int64
+		testDB.Model(&models.ProductTag{}).Where("product_id = ?", hat.ID).Count(&stored)
+		assert.Equal(t, int64(2), stored)
+	})
+
+	t.Run("Validation", func(t *testing.T) {
+		for _, input := range []CollectionInput{
+			{Type: models.CollectionTypeManual},
+			{Name: "Picks", Type: "random"},
+			{Name: "Picks", Type: models.CollectionTypeManual, Sort: "cheapest"},
+			{Name: "Picks", Type: models.CollectionTypeManual, ProductIDs: []uint{hat.ID, hat.ID}},
+			{Name: "Picks", Type: models.CollectionTypeManual, Rules: []models.CollectionRule{{Field: "tag", Operator: "eq", Value: "summer"}}},
+			{Name: "Picks", Type: models.CollectionTypeSmart},
+			{Name: "Picks", Type: models.CollectionTypeSmart, ProductIDs: []uint{hat.ID}},
+		} {
+			_, err := collections.Create(input)
+			assert.ErrorIs(t, err, ErrInvalidCollection, "%+v", input)
+		}
+		for _, rule := range []models.CollectionRule{
+			{Field: "colour", Operator: "eq", Value: "red"},
+			{Field: "tag", Operator: "lt", Value: "summer"},
+			{Field: "price", Operator: "in", Value: "10,20"},
+			{Field: "price", Operator: "lt", Value: "cheap"},
+			{Field: "category", Operator: "in", Value: "1,x"},
+			{Field: "in_stock", Operator: "eq", Value: "false"},
+		} {
+			_, err := collections.Create(CollectionInput{Name: "Picks", Type: models.CollectionTypeSmart, Rules: []models.CollectionRule{rule}})
+			assert.ErrorIs(t, err, ErrInvalidCollectionRule, "%+v", rule)
+		}
+		_, err := collections.Create(CollectionInput{Name: "Picks", Type: models.CollectionTypeManual, ProductIDs: []uint{9999}})
+		assert.ErrorIs(t, err, ErrProductNotFound)
+		_, err = collections.Create(CollectionInput{Name: "Picks", Slug: "Not A Slug", Type: models.CollectionTypeManual})
+		assert.ErrorIs(t, err, ErrInvalidSlug)
+	})
+
+	t.Run("ManualCollectionsKeepTheirOrder", func(t *testing.T) {
+		picks, err := collections.Create(CollectionInput{Name: "Staff Picks", Type: models.CollectionTypeManual, ProductIDs: []uint{tent.ID, hat.ID}})
+		assert.NoError(t, err)
+		assert.Equal(t, "staff-picks", picks.Slug)
+		assert.Equal(t, []string{"Tent", "Sun Hat"}, members(picks))
+
+		_, err = collections.Create(CollectionInput{Name: "Other Picks", Slug: "staff-picks", Type: models.CollectionTypeManual})
+		assert.ErrorIs(t, err, ErrDuplicateSlug)
+
+		assert.NoError(t, collections.Update(picks, CollectionInput{Name: "Staff Picks", Type: models.CollectionTypeManual, ProductIDs: []uint{towel.ID, hat.ID, tent.ID}}))
+		assert.Equal(t, "staff-picks", picks.Slug)
+		assert.Equal(t, []string{"Beach Towel", "Sun Hat", "Tent"}, members(picks))
+
+		assert.NoError(t, collections.Delete(picks))
+		var remaining int64
+		testDB.Model(&models.CollectionProduct{}).Where("collection_id = ?", picks.ID).Count(&remaining)
+		assert.Zero(t, remaining)
+	})
+
+	t.Run("SmartCollectionsMatchAllRules", func(t *testing.T) {
+		summer, err := collections.Create(CollectionInput{Name: "Summer Under 50", Type: models.CollectionTypeSmart, Rules: []models.CollectionRule{
+			{Field: "tag", Operator: "eq", Value: " Summer"},
+			{Field: "price", Operator: "lt", Value: "50"},
+		}})
+		assert.NoError(t, err)
+		assert.Equal(t, "summer", summer.Rules[0].Value)
+		assert.Equal(t, []string{"Sun Hat", "Beach Towel"}, members(summer))
+
+		inStock, err := collections.Create(CollectionInput{Name: "Summer In Stock", Type: models.CollectionTypeSmart, Rules: []models.CollectionRule{
+			{Field: "tag", Operator: "in", Value: "summer,camping"},
+			{Field: "in_stock", Operator: "eq", Value: "true"},
+		}})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"Sun Hat", "Tent"}, members(inStock))
+
+		// Category rules include subcategories
+		outdoors, err := collections.Create(CollectionInput{Name: "Outdoors", Type: models.CollectionTypeSmart, Rules: []models.CollectionRule{
+			{Field: "category", Operator: "in", Value: fmt.Sprintf("9999, %d", outdoor.ID)},
+		}})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"Tent"}, members(outdoors))
+	})
+
+	t.Run("SmartCollectionsFollowProductChanges", func(t *testing.T) {
+		summer, err := collections.Create(CollectionInput{Name: "Cheap Summer", Type: models.CollectionTypeSmart, Rules: []models.CollectionRule{
+			{Field: "tag", Operator: "eq", Value: "summer"},
+			{Field: "price", Operator: "lte", Value: "20"},
+		}})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"Sun Hat", "Beach Towel"}, members(summer))
+
+		testDB.Model(&hat).Update("price", 25)
+		assert.NoError(t, tags.SetTags(&tent, []string{"camping", "summer"}))
+		testDB.Model(&tent).Update("price", 19)
+		assert.Equal(t, []string{"Tent", "Beach Towel"}, members(summer))
+
+		assert.NoError(t, tags.SetTags(&towel, nil))
+		assert.Equal(t, []string{"Tent"}, members(summer))
+	})
+}
+
+// tagNames lists tag names in order
+func tagNames(tags []models.ProductTag) []string {
+	names := make([]string, len(tags))
+	for i, tag := range tags {
+		names[i] = tag.Name
+	}
+	return names
+}
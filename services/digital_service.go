@@ -0,0 +1,576 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"path"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/geoo115/Ecommerce/config"
+	"github.com/geoo115/Ecommerce/db"
+	"github.com/geoo115/Ecommerce/models"
+	"github.com/geoo115/Ecommerce/utils"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Digital product errors surfaced to handlers
+var (
+	ErrInvalidProductType   = errors.New("type must be simple or digital")
+	ErrDigitalConflict      = errors.New("digital products cannot be bundles, bundle components or have variants")
+	ErrNotDigital           = errors.New("product is not digital")
+	ErrInvalidDigitalFile   = errors.New("digital files need a name and non-empty contents within the upload limit")
+	ErrInvalidLicenceKey    = errors.New("licence keys must be 1-255 characters, at most 10000 per request")
+	ErrDownloadUnavailable  = errors.New("downloads are available once the order is paid")
+	ErrInvalidDownloadLink  = errors.New("download link is invalid or has expired")
+	ErrDownloadLimitReached = errors.New("download limit reached")
+)
+
+// maxLicenceKeysPerRequest bounds how many licence keys are loaded at once
+const maxLicenceKeysPerRequest = 10000
+
+// unlimitedStock is the stock of digital products without licence keys, which never run out
+const unlimitedStock = math.MaxInt32
+
+// DigitalFileUpload is a new file for a digital product
+type DigitalFileUpload struct {
+	Name        string
+	ContentType string
+	Data        io.Reader
+}
+
+// LicencePool counts a digital product's licence keys
+type LicencePool struct {
+	Total     int64 `json:"total"`
+	Available int64 `json:"available"` // Not yet assigned to an order
+}
+
+// DownloadLink is a signed, time-limited link to one file of a digital order item
+type DownloadLink struct {
+	FileID    uint      `json:"file_id"`
+	Name      string    `json:"name"`
+	Size      int64     `json:"size"`
+	URL       string    `json:"url"`
+	ExpiresAt time.Time `json:"expires_at"`
+	Remaining int       `json:"downloads_remaining"`
+}
+
+// DigitalItem is what a customer received for a digital order item
+type DigitalItem struct {
+	OrderItemID uint           `json:"order_item_id"`
+	ProductID   uint           `json:"product_id"`
+	Name        string         `json:"name"`
+	LicenceKeys []string       `json:"licence_keys,omitempty"`
+	Downloads   []DownloadLink `json:"downloads"`
+}
+
+// DigitalService interface defines digital product files, licence keys and downloads
+type DigitalService interface {
+	SetType(product *models.Product, productType string) error
+	AddFile(product *models.Product, upload DigitalFileUpload) (*models.DigitalFile, error)
+	DeleteFile(file *models.DigitalFile) error
+	AddLicenceKeys(product *models.Product, keys []string) (int, error)
+	LicencePool(productID uint) (LicencePool, error)
+	OrderDownloads(order *models.Order, now time.Time) ([]DigitalItem, error)
+	Download(orderItemID, fileID uint, expires int64, signature string, now time.Time) (*models.DigitalFile, io.ReadCloser, error)
+}
+
+// digitalService implements DigitalService interface
+type digitalService struct {
+	db     *gorm.DB
+	store  FileStore
+	config config.DigitalConfig
+}
+
+// NewDigitalService creates a new digital product service instance using the configured file store
+func NewDigitalService() DigitalService {
+	return NewDigitalServiceWithDB(db.DB)
+}
+
+// NewDigitalServiceWithDB creates a digital product service bound to a specific database handle
+func NewDigitalServiceWithDB(database *gorm.DB) DigitalService {
+	cfg := config.GetDigitalConfig()
+	store := DigitalFiles
+	if store == nil {
+		store = NewLocalBlobStore(cfg.StorageDir, "")
+	}
+	return &digitalService{db: database, store: store, config: cfg}
+}
+
+// SetType makes a simple product digital or a digital product simple. Bundles, their
+// components and products with variants hold stock, so they cannot become digital.
+func (s *digitalService) SetType(product *models.Product, productType string) error {
+	if productType != models.ProductTypeSimple && productType != models.ProductTypeDigital {
+		return ErrInvalidProductType
+	}
+	if product.IsBundle() {
+		return ErrDigitalConflict
+	}
+	if product.Type == productType {
+		return nil
+	}
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if productType == models.ProductTypeDigital {
+			var count int64
+			if err := tx.Model(&models.ProductVariant{}).Where("product_id = ?", product.ID).Count(&count).Error; err != nil {
+				return err
+			}
+			if count == 0 {
+				if err := tx.Model(&models.BundleItem{}).Where("component_id = ?", product.ID).Count(&count).Error; err != nil {
+					return err
+				}
+			}
+			if count > 0 {
+				return ErrDigitalConflict
+			}
+		}
+		if err := tx.Model(&models.Product{}).Where("id = ?", product.ID).Update("type", productType).Error; err != nil {
+			return err
+		}
+		product.Type = productType
+		return nil
+	})
+}
+
+// AddFile stores a file of a digital product. The file is streamed to storage and
+// rejected afterwards if it turns out empty or over the upload limit.
+func (s *digitalService) AddFile(product *models.Product, upload DigitalFileUpload) (*models.DigitalFile, error) {
+	if !product.IsDigital() {
+		return nil, ErrNotDigital
+	}
+	name := strings.TrimSpace(path.Base(strings.ReplaceAll(upload.Name, "\\", "/")))
+	if name == "" || name == "." || name == "/" || len(name) > 255 {
+		return nil, ErrInvalidDigitalFile
+	}
+	contentType := upload.ContentType
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+
+	random, err := randomName()
+	if err != nil {
+		return nil, err
+	}
+	file := models.DigitalFile{
+		ProductID:   product.ID,
+		Name:        name,
+		Key:         fmt.Sprintf("products/%d/%s", product.ID, random),
+		ContentType: contentType,
+	}
+
+	data := &countingReader{reader: io.LimitReader(upload.Data, s.config.MaxUploadSize+1)}
+	if err := s.store.Put(file.Key, data, contentType); err != nil {
+		return nil, err
+	}
+	file.Size = data.count
+	if file.Size == 0 || file.Size > s.config.MaxUploadSize {
+		s.removeBlob(file.Key)
+		return nil, ErrInvalidDigitalFile
+	}
+
+	if err := s.db.Create(&file).Error; err != nil {
+		s.removeBlob(file.Key)
+		return nil, err
+	}
+	return &file, nil
+}
+
+// DeleteFile removes a digital file and its download counts. Customers who bought the
+// product can no longer download it.
+func (s *digitalService) DeleteFile(file *models.DigitalFile) error {
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("digital_file_id = ?", file.ID).Delete(&models.DigitalDownload{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(file).Error
+	})
+	if err != nil {
+		return err
+	}
+	s.removeBlob(file.Key)
+	return nil
+}
+
+// removeBlob deletes a stored file, logging failures since the database is already consistent
+func (s *digitalService) removeBlob(key string) {
+	if err := s.store.Delete(key); err != nil {
+		utils.Warn("Failed to remove digital file %s: %v", key, err)
+	}
+}
+
+// AddLicenceKeys loads licence keys into a digital product's pool and returns how many were
+// added. Keys already in the pool, including assigned ones, are skipped.
+func (s *digitalService) AddLicenceKeys(product *models.Product, keys []string) (int, error) {
+	if !product.IsDigital() {
+		return 0, ErrNotDigital
+	}
+	if len(keys) == 0 || len(keys) > maxLicenceKeysPerRequest {
+		return 0, ErrInvalidLicenceKey
+	}
+
+	seen := make(map[string]bool, len(keys))
+	var unique []string
+	for _, key := range keys {
+		key = strings.TrimSpace(key)
+		if key == "" || len(key) > 255 {
+			return 0, ErrInvalidLicenceKey
+		}
+		if !seen[key] {
+			seen[key] = true
+			unique = append(unique, key)
+		}
+	}
+
+	added := 0
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		for start := 0; start < len(unique); start += 500 {
+			batch := unique[start:min(start+500, len(unique))]
+			var existing []string
+			if err := tx.Unscoped().Model(&models.LicenceKey{}).Where("product_id = ? AND key IN ?", product.ID, batch).
+				Pluck("key", &existing).Error; err != nil {
+				return err
+			}
+			var rows []models.LicenceKey
+			for _, key := range batch {
+				if !slices.Contains(existing, key) {
+					rows = append(rows, models.LicenceKey{ProductID: product.ID, Key: key})
+				}
+			}
+			if len(rows) == 0 {
+				continue
+			}
+			if err := tx.Create(&rows).Error; err != nil {
+				return err
+			}
+			added += len(rows)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return added, nil
+}
+
+// LicencePool counts a product's licence keys and those not yet assigned
+func (s *digitalService) LicencePool(productID uint) (LicencePool, error) {
+	return licencePool(s.db, productID)
+}
+
+// OrderDownloads lists the licence keys and fresh download links of a paid order's digital items
+func (s *digitalService) OrderDownloads(order *models.Order, now time.Time) ([]DigitalItem, error) {
+	if !slices.Contains(paidOrderStatuses, order.Status) {
+		return nil, ErrDownloadUnavailable
+	}
+	items, _, err := digitalOrderItems(s.db, order.ID)
+	if err != nil {
+		return nil, err
+	}
+	digital := []DigitalItem{}
+	if len(items) == 0 {
+		return digital, nil
+	}
+
+	productIDs := make([]uint, 0, len(items))
+	itemIDs := make([]uint, 0, len(items))
+	for _, item := range items {
+		productIDs = append(productIDs, item.ProductID)
+		itemIDs = append(itemIDs, item.ID)
+	}
+	var products []models.Product
+	if err := s.db.Unscoped().Where("id IN ?", productIDs).Find(&products).Error; err != nil {
+		return nil, err
+	}
+	var files []models.DigitalFile
+	if err := s.db.Where("product_id IN ?", productIDs).Order("id").Find(&files).Error; err != nil {
+		return nil, err
+	}
+	var keys []models.LicenceKey
+	if err := s.db.Where("order_item_id IN ?", itemIDs).Order("id").Find(&keys).Error; err != nil {
+		return nil, err
+	}
+	var downloads []models.DigitalDownload
+	if err := s.db.Where("order_item_id IN ?", itemIDs).Find(&downloads).Error; err != nil {
+		return nil, err
+	}
+
+	expires := now.Add(s.config.LinkTTL).Truncate(time.Second)
+	for _, item := range items {
+		entry := DigitalItem{OrderItemID: item.ID, ProductID: item.ProductID, Downloads: []DownloadLink{}}
+		for _, product := range products {
+			if product.ID == item.ProductID {
+				entry.Name = product.Name
+			}
+		}
+		for _, key := range keys {
+			if key.OrderItemID != nil && *key.OrderItemID == item.ID {
+				entry.LicenceKeys = append(entry.LicenceKeys, key.Key)
+			}
+		}
+		for _, file := range files {
+			if file.ProductID != item.ProductID {
+				continue
+			}
+			signature, err := s.sign(item.ID, file.ID, expires.Unix())
+			if err != nil {
+				return nil, err
+			}
+			link := DownloadLink{
+				FileID:    file.ID,
+				Name:      file.Name,
+				Size:      file.Size,
+				URL:       fmt.Sprintf("/downloads/%d/%d?expires=%d&signature=%s", item.ID, file.ID, expires.Unix(), signature),
+				ExpiresAt: expires,
+				Remaining: s.config.DownloadLimit,
+			}
+			for _, download := range downloads {
+				if download.OrderItemID == item.ID && download.DigitalFileID == file.ID {
+					link.Remaining = max(s.config.DownloadLimit-download.Downloads, 0)
+				}
+			}
+			entry.Downloads = append(entry.Downloads, link)
+		}
+		digital = append(digital, entry)
+	}
+	return digital, nil
+}
+
+// Download checks a signed download link and counts the download, returning the file and
+// its contents for the caller to stream and close
+func (s *digitalService) Download(orderItemID, fileID uint, expires int64, signature string, now time.Time) (*models.DigitalFile, io.ReadCloser, error) {
+	expected, err := s.sign(orderItemID, fileID, expires)
+	if err != nil {
+		return nil, nil, err
+	}
+	if now.Unix() > expires || !hmac.Equal([]byte(expected), []byte(signature)) {
+		return nil, nil, ErrInvalidDownloadLink
+	}
+
+	var item models.OrderItem
+	if err := s.db.First(&item, orderItemID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, ErrInvalidDownloadLink
+		}
+		return nil, nil, err
+	}
+	var order models.Order
+	if err := s.db.Select("id", "status").First(&order, item.OrderID).Error; err != nil {
+		return nil, nil, err
+	}
+	if !slices.Contains(paidOrderStatuses, order.Status) || item.Quantity <= 0 {
+		return nil, nil, ErrDownloadUnavailable
+	}
+	var file models.DigitalFile
+	if err := s.db.Where("id = ? AND product_id = ?", fileID, item.ProductID).First(&file).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, ErrInvalidDownloadLink
+		}
+		return nil, nil, err
+	}
+
+	contents, err := s.store.Open(file.Key)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := s.countDownload(item.ID, file.ID); err != nil {
+		contents.Close()
+		return nil, nil, err
+	}
+	return &file, contents, nil
+}
+
+// countDownload records a download unless the item has used up its downloads of the file
+func (s *digitalService) countDownload(orderItemID, fileID uint) error {
+	if err := s.db.Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&models.DigitalDownload{OrderItemID: orderItemID, DigitalFileID: fileID}).Error; err != nil {
+		return err
+	}
+	result := s.db.Model(&models.DigitalDownload{}).
+		Where("order_item_id = ? AND digital_file_id = ? AND downloads < ?", orderItemID, fileID, s.config.DownloadLimit).
+		Update("downloads", gorm.Expr("downloads + 1"))
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrDownloadLimitReached
+	}
+	return nil
+}
+
+// sign computes the signature of a download link
+func (s *digitalService) sign(orderItemID, fileID uint, expires int64) (string, error) {
+	if s.config.SigningKey == "" {
+		return "", errors.New("download signing key is not configured")
+	}
+	mac := hmac.New(sha256.New, []byte(s.config.SigningKey))
+	fmt.Fprintf(mac, "%d/%d/%d", orderItemID, fileID, expires)
+	return hex.EncodeToString(mac.Sum(nil)), nil
+}
+
+// countingReader counts the bytes read through it
+type countingReader struct {
+	reader io.Reader
+	count  int64
+}
+
+// Read reads from the underlying reader, adding to the count
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	r.count += int64(n)
+	return n, err
+}
+
+// isDigitalProduct reports whether a product, including a deleted one, is digital
+func isDigitalProduct(database *gorm.DB, productID uint) (bool, error) {
+	var count int64
+	err := database.Unscoped().Model(&models.Product{}).
+		Where("id = ? AND type = ?", productID, models.ProductTypeDigital).Count(&count).Error
+	return count > 0, err
+}
+
+// licencePool counts a product's licence keys and those not yet assigned
+func licencePool(database *gorm.DB, productID uint) (LicencePool, error) {
+	var counts struct {
+		Total    int64
+		Assigned int64
+	}
+	err := database.Model(&models.LicenceKey{}).Select("COUNT(*) AS total, COUNT(order_item_id) AS assigned").
+		Where("product_id = ?", productID).Scan(&counts).Error
+	return LicencePool{Total: counts.Total, Available: counts.Total - counts.Assigned}, err
+}
+
+// digitalStock returns how many of a digital product can be sold: the licence keys left in
+// its pool, or unlimited when it is sold without licence keys
+func digitalStock(database *gorm.DB, productID uint) (int, error) {
+	pool, err := licencePool(database, productID)
+	if err != nil {
+		return 0, err
+	}
+	if pool.Total == 0 {
+		return unlimitedStock, nil
+	}
+	return int(pool.Available), nil
+}
+
+// digitalOrderItems loads an order's remaining items of digital products, and reports
+// whether the order has nothing else to ship
+func digitalOrderItems(database *gorm.DB, orderID uint) ([]models.OrderItem, bool, error) {
+	var items []models.OrderItem
+	if err := database.Where("order_id = ? AND quantity > 0", orderID).Order("id").Find(&items).Error; err != nil {
+		return nil, false, err
+	}
+	if len(items) == 0 {
+		return nil, false, nil
+	}
+	productIDs := make([]uint, len(items))
+	for i, item := range items {
+		productIDs[i] = item.ProductID
+	}
+	var digitalIDs []uint
+	if err := database.Unscoped().Model(&models.Product{}).Where("id IN ? AND type = ?", productIDs, models.ProductTypeDigital).
+		Pluck("id", &digitalIDs).Error; err != nil {
+		return nil, false, err
+	}
+
+	var digital []models.OrderItem
+	for _, item := range items {
+		if slices.Contains(digitalIDs, item.ProductID) {
+			digital = append(digital, item)
+		}
+	}
+	return digital, len(digital) == len(items), nil
+}
+
+// licenceKeysNeeded returns an order's digital items with how many licence keys each is still
+// owed, leaving out products sold without licence keys, and whether the order is digital only
+func licenceKeysNeeded(database *gorm.DB, orderID uint) ([]models.OrderItem, map[uint]int, bool, error) {
+	items, digitalOnly, err := digitalOrderItems(database, orderID)
+	if err != nil {
+		return nil, nil, false, err
+	}
+	needed := map[uint]int{}
+	for _, item := range items {
+		pool, err := licencePool(database, item.ProductID)
+		if err != nil {
+			return nil, nil, false, err
+		}
+		if pool.Total == 0 {
+			continue
+		}
+		var assigned int64
+		if err := database.Model(&models.LicenceKey{}).Where("order_item_id = ?", item.ID).Count(&assigned).Error; err != nil {
+			return nil, nil, false, err
+		}
+		if owed := item.Quantity - int(assigned); owed > 0 {
+			needed[item.ID] = owed
+		}
+	}
+	return items, needed, digitalOnly, nil
+}
+
+// ensureLicenceKeys fails with ErrInsufficientStock when a product's pool no longer holds
+// the licence keys an order needs, so the order is not charged for keys it cannot get
+func ensureLicenceKeys(database *gorm.DB, orderID uint) error {
+	items, needed, _, err := licenceKeysNeeded(database, orderID)
+	if err != nil {
+		return err
+	}
+	perProduct := map[uint]int{}
+	for _, item := range items {
+		perProduct[item.ProductID] += needed[item.ID]
+	}
+	for productID, count := range perProduct {
+		if count == 0 {
+			continue
+		}
+		pool, err := licencePool(database, productID)
+		if err != nil {
+			return err
+		}
+		if pool.Available < int64(count) {
+			return ErrInsufficientStock
+		}
+	}
+	return nil
+}
+
+// issueDigitalGoods draws licence keys from the pool for a paid order's digital items and
+// reports whether the order has nothing left to ship
+func issueDigitalGoods(tx *gorm.DB, orderID uint, now time.Time) (bool, error) {
+	items, needed, digitalOnly, err := licenceKeysNeeded(tx, orderID)
+	if err != nil {
+		return false, err
+	}
+	for _, item := range items {
+		count := needed[item.ID]
+		if count == 0 {
+			continue
+		}
+		var keyIDs []uint
+		if err := tx.Model(&models.LicenceKey{}).Where("product_id = ? AND order_item_id IS NULL", item.ProductID).
+			Order("id").Limit(count).Pluck("id", &keyIDs).Error; err != nil {
+			return false, err
+		}
+		if len(keyIDs) < count {
+			return false, ErrInsufficientStock
+		}
+		// Only keys still unassigned are taken, so concurrent orders never share a key
+		result := tx.Model(&models.LicenceKey{}).Where("id IN ? AND order_item_id IS NULL", keyIDs).
+			Updates(map[string]interface{}{"order_item_id": item.ID, "assigned_at": now})
+		if result.Error != nil {
+			return false, result.Error
+		}
+		if int(result.RowsAffected) != count {
+			return false, ErrInsufficientStock
+		}
+	}
+	return digitalOnly, nil
+}
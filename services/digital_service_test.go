@@ -0,0 +1,278 @@
+package services
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/geoo115/Ecommerce/db"
+	"github.com/geoo115/Ecommerce/models"
+	"github.com/stretchr/testify/assert"
+)
+
+// setupDigitalStore points digital files at a temporary directory and fixes the signing key
+func setupDigitalStore(t *testing.T) {
+	t.Helper()
+	originalStore := DigitalFiles
+	DigitalFiles = NewLocalBlobStore(t.TempDir(), "")
+	os.Setenv("DOWNLOAD_SIGNING_KEY", "test-signing-key")
+	os.Setenv("DOWNLOAD_LIMIT", "2")
+	t.Cleanup(func() {
+		DigitalFiles = originalStore
+		os.Unsetenv("DOWNLOAD_SIGNING_KEY")
+		os.Unsetenv("DOWNLOAD_LIMIT")
+	})
+}
+
+func createDigitalProduct(t *testing.T, name string, price float64) models.Product {
+	t.Helper()
+	product := createStockedProduct(t, name, price, 0)
+	if err := NewDigitalService().SetType(&product, models.ProductTypeDigital); err != nil {
+		t.Fatalf("failed to make product digital: %v", err)
+	}
+	return product
+}
+
+func TestDigitalService_SetType(t *testing.T) {
+	originalDB := db.DB
+	testDB := db.SetupTestDB(t)
+	db.DB = testDB
+	defer func() { db.DB = originalDB }()
+
+	service := NewDigitalService()
+	product := createStockedProduct(t, "E-book", 9, 0)
+	assert.ErrorIs(t, service.SetType(&product, models.ProductTypeBundle), ErrInvalidProductType)
+	assert.NoError(t, service.SetType(&product, models.ProductTypeDigital))
+
+	var stored models.Product
+	testDB.First(&stored, product.ID)
+	assert.True(t, stored.IsDigital())
+
+	// Digital products cannot take part in bundles or have variants
+	bundle := createStockedProduct(t, "Bundle", 20, 0)
+	assert.ErrorIs(t, NewBundleService().SetComponents(&bundle, []BundleComponent{{ProductID: product.ID, Quantity: 1}}), ErrDigitalConflict)
+	assert.ErrorIs(t, NewVariantService().CreateVariant(&product, &models.ProductVariant{SKU: "EB-1"}, []VariantOption{{Name: "Format", Value: "EPUB"}}), ErrDigitalConflict)
+
+	shirt := createStockedProduct(t, "Shirt", 15, 3)
+	testDB.Create(&models.ProductVariant{ProductID: shirt.ID, SKU: "SH-M", Stock: 3})
+	assert.ErrorIs(t, service.SetType(&shirt, models.ProductTypeDigital), ErrDigitalConflict)
+
+	// Back to a simple product
+	assert.NoError(t, service.SetType(&product, models.ProductTypeSimple))
+	assert.False(t, product.IsDigital())
+}
+
+func TestDigitalService_FilesAndLicenceKeys(t *testing.T) {
+	originalDB := db.DB
+	testDB := db.SetupTestDB(t)
+	db.DB = testDB
+	defer func() { db.DB = originalDB }()
+	setupDigitalStore(t)
+
+	service := NewDigitalService()
+	physical := createStockedProduct(t, "Mug", 8, 3)
+	_, err := service.AddFile(&physical, DigitalFileUpload{Name: "mug.pdf", Data: strings.NewReader("x")})
+	assert.ErrorIs(t, err, ErrNotDigital)
+
+	product := createDigitalProduct(t, "Guide", 12)
+	_, err = service.AddFile(&product, DigitalFileUpload{Name: "empty.pdf", Data: strings.NewReader("")})
+	assert.ErrorIs(t, err, ErrInvalidDigitalFile)
+
+	file, err := service.AddFile(&product, DigitalFileUpload{Name: "guide.pdf", ContentType: "application/pdf", Data: strings.NewReader("chapter one")})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(11), file.Size)
+
+	added, err := service.AddLicenceKeys(&product, []string{" AAA-111 ", "BBB-222", "AAA-111"})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, added)
+	added, err = service.AddLicenceKeys(&product, []string{"BBB-222", "CCC-333"})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, added)
+	_, err = service.AddLicenceKeys(&product, []string{"DDD-444", " "})
+	assert.ErrorIs(t, err, ErrInvalidLicenceKey)
+
+	pool, err := service.LicencePool(product.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, LicencePool{Total: 3, Available: 3}, pool)
+
+	assert.NoError(t, service.DeleteFile(file))
+	var count int64
+	testDB.Model(&models.DigitalFile{}).Where("product_id = ?", product.ID).Count(&count)
+	assert.Equal(t, int64(0), count)
+}
+
+func TestDigitalService_PaidOrderIssuesKeysWithoutStock(t *testing.T) {
+	originalDB := db.DB
+	testDB := db.SetupTestDB(t)
+	db.DB = testDB
+	defer func() { db.DB = originalDB }()
+	setupDigitalStore(t)
+
+	user := models.User{Username: "reader", Email: "reader@example.com"}
+	testDB.Create(&user)
+	service := NewDigitalService()
+	product := createDigitalProduct(t, "Licence", 30)
+	service.AddLicenceKeys(&product, []string{"KEY-1", "KEY-2", "KEY-3"})
+
+	// The pool bounds how many can be ordered
+	orders := NewOrderService()
+	err := orders.CreateOrder(&models.Order{UserID: user.ID}, []OrderLine{{ProductID: product.ID, Quantity: 4}})
+	assert.ErrorIs(t, err, ErrInsufficientStock)
+
+	order := models.Order{UserID: user.ID}
+	assert.NoError(t, orders.CreateOrder(&order, []OrderLine{{ProductID: product.ID, Quantity: 2}}))
+
+	// Keys are only drawn once the order is paid
+	pool, _ := service.LicencePool(product.ID)
+	assert.Equal(t, int64(3), pool.Available)
+	_, err = service.OrderDownloads(&order, time.Now())
+	assert.ErrorIs(t, err, ErrDownloadUnavailable)
+
+	_, err = NewPaymentService().ChargeOrder(&order, "card")
+	assert.NoError(t, err)
+	assert.Equal(t, "Delivered", order.Status)
+
+	pool, _ = service.LicencePool(product.ID)
+	assert.Equal(t, LicencePool{Total: 3, Available: 1}, pool)
+	items, err := service.OrderDownloads(&order, time.Now())
+	assert.NoError(t, err)
+	if assert.Len(t, items, 1) {
+		assert.Equal(t, []string{"KEY-1", "KEY-2"}, items[0].LicenceKeys)
+	}
+
+	var inv models.Inventory
+	testDB.Where("product_id = ?", product.ID).First(&inv)
+	assert.Equal(t, 0, inv.Stock)
+
+	// A second order cannot be paid once another has taken the remaining key
+	second := models.Order{UserID: user.ID}
+	assert.NoError(t, orders.CreateOrder(&second, []OrderLine{{ProductID: product.ID, Quantity: 1}}))
+	third := models.Order{UserID: user.ID}
+	assert.NoError(t, orders.CreateOrder(&third, []OrderLine{{ProductID: product.ID, Quantity: 1}}))
+	_, err = NewPaymentService().ChargeOrder(&second, "card")
+	assert.NoError(t, err)
+	_, err = NewPaymentService().ChargeOrder(&third, "card")
+	assert.ErrorIs(t, err, ErrInsufficientStock)
+
+	// Listings count the remaining keys as stock
+	ebook := createDigitalProduct(t, "E-book", 5)
+	inStock, _ := ProductListOptions{InStock: true}.FilterScope(testDB)
+	var listed []models.Product
+	testDB.Scopes(inStock).Preload("Inventory").Order("id").Find(&listed)
+	if assert.Len(t, listed, 1) {
+		assert.Equal(t, ebook.ID, listed[0].ID)
+	}
+	products := []models.Product{product, ebook}
+	assert.NoError(t, AttachComputedStock(testDB, products))
+	assert.Equal(t, 0, products[0].Inventory.Stock)
+	assert.Equal(t, unlimitedStock, products[1].Inventory.Stock)
+}
+
+func TestDigitalService_MixedOrderShipsPhysicalItemsOnly(t *testing.T) {
+	originalDB := db.DB
+	testDB := db.SetupTestDB(t)
+	db.DB = testDB
+	defer func() { db.DB = originalDB }()
+	setupDigitalStore(t)
+
+	user := models.User{Username: "mixed", Email: "mixed@example.com"}
+	testDB.Create(&user)
+	ebook := createDigitalProduct(t, "E-book", 10)
+	lamp := createStockedProduct(t, "Lamp", 25, 4)
+
+	order := models.Order{UserID: user.ID}
+	assert.NoError(t, NewOrderService().CreateOrder(&order, []OrderLine{
+		{ProductID: ebook.ID, Quantity: 1},
+		{ProductID: lamp.ID, Quantity: 2},
+	}))
+	_, err := NewPaymentService().ChargeOrder(&order, "card")
+	assert.NoError(t, err)
+	assert.Equal(t, "Paid", order.Status)
+
+	var lampItem models.OrderItem
+	testDB.Where("order_id = ? AND product_id = ?", order.ID, lamp.ID).First(&lampItem)
+	unshipped, err := unshippedQuantities(testDB, order.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, map[uint]int{lampItem.ID: 2}, unshipped)
+}
+
+func TestDigitalService_Download(t *testing.T) {
+	originalDB := db.DB
+	testDB := db.SetupTestDB(t)
+	db.DB = testDB
+	defer func() { db.DB = originalDB }()
+	setupDigitalStore(t)
+
+	user := models.User{Username: "downloader", Email: "downloader@example.com"}
+	testDB.Create(&user)
+	service := NewDigitalService()
+	product := createDigitalProduct(t, "Album", 8)
+	file, _ := service.AddFile(&product, DigitalFileUpload{Name: "album.zip", Data: strings.NewReader("tracks")})
+
+	order := models.Order{UserID: user.ID}
+	NewOrderService().CreateOrder(&order, []OrderLine{{ProductID: product.ID, Quantity: 1}})
+	NewPaymentService().ChargeOrder(&order, "card")
+
+	now := time.Now()
+	items, err := service.OrderDownloads(&order, now)
+	assert.NoError(t, err)
+	if !assert.Len(t, items, 1) || !assert.Len(t, items[0].Downloads, 1) {
+		return
+	}
+	link := items[0].Downloads[0]
+	assert.Equal(t, 2, link.Remaining)
+	itemID := items[0].OrderItemID
+	signature := link.URL[strings.Index(link.URL, "signature=")+len("signature="):]
+	expires := link.ExpiresAt.Unix()
+	assert.Equal(t, fmt.Sprintf("/downloads/%d/%d?expires=%d&signature=%s", itemID, file.ID, expires, signature), link.URL)
+
+	// Tampered and expired links are rejected
+	_, _, err = service.Download(itemID, file.ID, expires+60, signature, now)
+	assert.ErrorIs(t, err, ErrInvalidDownloadLink)
+	_, _, err = service.Download(itemID, file.ID, expires, signature, link.ExpiresAt.Add(time.Second))
+	assert.ErrorIs(t, err, ErrInvalidDownloadLink)
+
+	for i := 0; i < 2; i++ {
+		served, contents, err := service.Download(itemID, file.ID, expires, signature, now)
+		if assert.NoError(t, err) {
+			var buf bytes.Buffer
+			io.Copy(&buf, contents)
+			contents.Close()
+			assert.Equal(t, "tracks", buf.String())
+			assert.Equal(t, "album.zip", served.Name)
+		}
+	}
+	_, _, err = service.Download(itemID, file.ID, expires, signature, now)
+	assert.ErrorIs(t, err, ErrDownloadLimitReached)
+
+	items, _ = service.OrderDownloads(&order, now)
+	assert.Equal(t, 0, items[0].Downloads[0].Remaining)
+}
+
+func TestCheckoutService_DigitalOnlySkipsDelivery(t *testing.T) {
+	originalDB := db.DB
+	testDB := db.SetupTestDB(t)
+	db.DB = testDB
+	defer func() { db.DB = originalDB }()
+	setupDigitalStore(t)
+
+	user, address, product := setupCheckoutUser(t, 15, 1, 0)
+	NewDigitalService().SetType(&product, models.ProductTypeDigital)
+	service := NewCheckoutService()
+
+	session, err := service.StartSession(user.ID)
+	assert.NoError(t, err)
+	assert.NoError(t, service.SetAddress(session, address.ID))
+	assert.ErrorIs(t, service.SetDeliveryMethod(session, "standard"), ErrShippingNotRequired)
+	assert.NoError(t, service.Review(session, 0))
+	assert.Equal(t, 15.0, session.Total)
+
+	order, _, err := service.Complete(session, "card")
+	assert.NoError(t, err)
+	assert.Equal(t, "Delivered", order.Status)
+	assert.Empty(t, order.DeliveryMethod)
+}
@@ -0,0 +1,233 @@
+package services
+
+import (
+	"errors"
+	"time"
+
+	"github.com/geoo115/Ecommerce/db"
+	"github.com/geoo115/Ecommerce/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Fulfillment statuses
+const (
+	FulfillmentShipped   = "Shipped"
+	FulfillmentInTransit = "In Transit"
+	FulfillmentDelivered = "Delivered"
+)
+
+// Fulfillment errors surfaced to handlers
+var (
+	ErrOrderNotFulfillable      = errors.New("order cannot be fulfilled in its current status")
+	ErrInvalidFulfillment       = errors.New("fulfillment quantities exceed unshipped items")
+	ErrInvalidFulfillmentStatus = errors.New("invalid fulfillment status")
+)
+
+// fulfillableOrderStatuses are the order statuses that can receive new shipments
+var fulfillableOrderStatuses = map[string]bool{"Paid": true, "Partially Shipped": true}
+
+// FulfillmentLine is a quantity of an order item to include in a shipment
+type FulfillmentLine struct {
+	OrderItemID uint `json:"order_item_id"`
+	Quantity    int  `json:"quantity"`
+}
+
+// FulfillmentService interface defines shipment business logic
+type FulfillmentService interface {
+	CreateFulfillment(order *models.Order, fulfillment *models.Fulfillment, lines []FulfillmentLine) error
+	UpdateStatus(fulfillment *models.Fulfillment, status string) error
+	SyncOrderStatus(orderID uint) (string, error)
+}
+
+// fulfillmentService implements FulfillmentService interface
+type fulfillmentService struct {
+	db *gorm.DB
+}
+
+// NewFulfillmentService creates a new fulfillment service instance
+func NewFulfillmentService() FulfillmentService {
+	return NewFulfillmentServiceWithDB(db.DB)
+}
+
+// NewFulfillmentServiceWithDB creates a fulfillment service bound to a specific database handle
+func NewFulfillmentServiceWithDB(database *gorm.DB) FulfillmentService {
+	return &fulfillmentService{db: database}
+}
+
+// CreateFulfillment records a shipment for part of a paid order and updates the order status.
+// The caller fills the carrier and tracking number; lines select the items in the parcel.
+func (s *fulfillmentService) CreateFulfillment(order *models.Order, fulfillment *models.Fulfillment, lines []FulfillmentLine) error {
+	if len(lines) == 0 {
+		return ErrInvalidFulfillment
+	}
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		// Lock the order so a concurrent cancel or parcel cannot change what is left to ship
+		var locked models.Order
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&locked, order.ID).Error; err != nil {
+			return err
+		}
+		if !fulfillableOrderStatuses[locked.Status] {
+			return ErrOrderNotFulfillable
+		}
+
+		unshipped, err := unshippedQuantities(tx, order.ID)
+		if err != nil {
+			return err
+		}
+
+		fulfillment.OrderID = order.ID
+		fulfillment.Items = nil
+		for _, line := range lines {
+			remaining, ok := unshipped[line.OrderItemID]
+			if !ok || line.Quantity <= 0 || line.Quantity > remaining {
+				return ErrInvalidFulfillment
+			}
+			unshipped[line.OrderItemID] = remaining - line.Quantity
+			fulfillment.Items = append(fulfillment.Items, models.FulfillmentItem{
+				OrderItemID: line.OrderItemID,
+				Quantity:    line.Quantity,
+			})
+		}
+
+		if fulfillment.Status == "" {
+			fulfillment.Status = FulfillmentShipped
+		}
+		if fulfillment.ShippedAt.IsZero() {
+			fulfillment.ShippedAt = time.Now()
+		}
+		if err := tx.Create(fulfillment).Error; err != nil {
+			return err
+		}
+
+		// Keep the order's tracking fields pointing at the latest parcel
+		if err := tx.Model(order).Updates(map[string]interface{}{
+			"tracking_number": fulfillment.TrackingNumber,
+			"courier":         fulfillment.Carrier,
+		}).Error; err != nil {
+			return err
+		}
+
+		status, err := (&fulfillmentService{db: tx}).SyncOrderStatus(order.ID)
+		if err != nil {
+			return err
+		}
+		order.Status = status
+		return nil
+	})
+}
+
+// UpdateStatus changes a shipment's delivery status and re-derives the order status
+func (s *fulfillmentService) UpdateStatus(fulfillment *models.Fulfillment, status string) error {
+	switch status {
+	case FulfillmentShipped, FulfillmentInTransit, FulfillmentDelivered:
+	default:
+		return ErrInvalidFulfillmentStatus
+	}
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		fulfillment.Status = status
+		fulfillment.DeliveredAt = nil
+		if status == FulfillmentDelivered {
+			now := time.Now()
+			fulfillment.DeliveredAt = &now
+		}
+		if err := tx.Model(fulfillment).Updates(map[string]interface{}{
+			"status":       fulfillment.Status,
+			"delivered_at": fulfillment.DeliveredAt,
+		}).Error; err != nil {
+			return err
+		}
+
+		_, err := (&fulfillmentService{db: tx}).SyncOrderStatus(fulfillment.OrderID)
+		return err
+	})
+}
+
+// SyncOrderStatus derives the order status from its shipments: "Partially Shipped" while
+// items remain unshipped, "Shipped" once everything has shipped and "Delivered" once
+// every parcel has arrived. Orders without shipments or outside the fulfilment flow are left as is.
+func (s *fulfillmentService) SyncOrderStatus(orderID uint) (string, error) {
+	var order models.Order
+	if err := s.db.First(&order, orderID).Error; err != nil {
+		return "", err
+	}
+	switch order.Status {
+	case "Paid", "Partially Shipped", "Shipped", "Delivered":
+	default:
+		return order.Status, nil
+	}
+
+	var fulfillments []models.Fulfillment
+	if err := s.db.Where("order_id = ?", orderID).Find(&fulfillments).Error; err != nil {
+		return "", err
+	}
+	if len(fulfillments) == 0 {
+		return order.Status, nil
+	}
+
+	unshipped, err := unshippedQuantities(s.db, orderID)
+	if err != nil {
+		return "", err
+	}
+
+	status := "Shipped"
+	for _, remaining := range unshipped {
+		if remaining > 0 {
+			status = "Partially Shipped"
+			break
+		}
+	}
+	if status == "Shipped" {
+		delivered := true
+		for _, f := range fulfillments {
+			if f.Status != FulfillmentDelivered {
+				delivered = false
+				break
+			}
+		}
+		if delivered {
+			status = "Delivered"
+		}
+	}
+
+	if status != order.Status {
+		if err := s.db.Model(&order).Update("status", status).Error; err != nil {
+			return "", err
+		}
+	}
+	return status, nil
+}
+
+// unshippedQuantities returns, per order item, the quantity not yet in any shipment.
+// Digital items are never shipped, so they are left out.
+func unshippedQuantities(tx *gorm.DB, orderID uint) (map[uint]int, error) {
+	var items []models.OrderItem
+	if err := tx.Where("order_id = ? AND product_id NOT IN (SELECT id FROM products WHERE type = ?)", orderID, models.ProductTypeDigital).
+		Find(&items).Error; err != nil {
+		return nil, err
+	}
+
+	var shipped []struct {
+		OrderItemID uint
+		Total       int
+	}
+	if err := tx.Model(&models.FulfillmentItem{}).
+		Select("fulfillment_items.order_item_id, SUM(fulfillment_items.quantity) AS total").
+		Joins("JOIN fulfillments ON fulfillments.id = fulfillment_items.fulfillment_id AND fulfillments.deleted_at IS NULL").
+		Where("fulfillments.order_id = ?", orderID).
+		Group("fulfillment_items.order_item_id").
+		Scan(&shipped).Error; err != nil {
+		return nil, err
+	}
+
+	unshipped := make(map[uint]int, len(items))
+	for _, item := range items {
+		unshipped[item.ID] = item.Quantity
+	}
+	for _, row := range shipped {
+		unshipped[row.OrderItemID] -= row.Total
+	}
+	return unshipped, nil
+}
@@ -0,0 +1,97 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/geoo115/Ecommerce/db"
+	"github.com/geoo115/Ecommerce/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFulfillmentService_SplitShipment(t *testing.T) {
+	originalDB := db.DB
+	testDB := db.SetupTestDB(t)
+	db.DB = testDB
+	defer func() { db.DB = originalDB }()
+
+	order := models.Order{
+		UserID: 1,
+		Status: "Paid",
+		Items: []models.OrderItem{
+			{ProductID: 1, Quantity: 2, Price: 5},
+			{ProductID: 2, Quantity: 1, Price: 8},
+		},
+	}
+	testDB.Create(&order)
+	first, second := order.Items[0], order.Items[1]
+
+	service := NewFulfillmentService()
+
+	parcel1 := models.Fulfillment{Carrier: "UPS", TrackingNumber: "1Z001"}
+	assert.NoError(t, service.CreateFulfillment(&order, &parcel1, []FulfillmentLine{{OrderItemID: first.ID, Quantity: 1}}))
+	assert.Equal(t, "Partially Shipped", order.Status)
+	assert.Equal(t, FulfillmentShipped, parcel1.Status)
+
+	// Cannot ship more than remains
+	err := service.CreateFulfillment(&order, &models.Fulfillment{Carrier: "UPS"}, []FulfillmentLine{{OrderItemID: first.ID, Quantity: 2}})
+	assert.ErrorIs(t, err, ErrInvalidFulfillment)
+
+	parcel2 := models.Fulfillment{Carrier: "DHL", TrackingNumber: "JD002"}
+	assert.NoError(t, service.CreateFulfillment(&order, &parcel2, []FulfillmentLine{
+		{OrderItemID: first.ID, Quantity: 1},
+		{OrderItemID: second.ID, Quantity: 1},
+	}))
+	assert.Equal(t, "Shipped", order.Status)
+
+	var stored models.Order
+	testDB.First(&stored, order.ID)
+	assert.Equal(t, "JD002", stored.TrackingNumber)
+	assert.Equal(t, "DHL", stored.Courier)
+
+	// Fully shipped orders accept no further parcels
+	err = service.CreateFulfillment(&order, &models.Fulfillment{}, []FulfillmentLine{{OrderItemID: first.ID, Quantity: 1}})
+	assert.ErrorIs(t, err, ErrOrderNotFulfillable)
+
+	assert.NoError(t, service.UpdateStatus(&parcel1, FulfillmentDelivered))
+	testDB.First(&stored, order.ID)
+	assert.Equal(t, "Shipped", stored.Status)
+
+	assert.NoError(t, service.UpdateStatus(&parcel2, FulfillmentDelivered))
+	testDB.First(&stored, order.ID)
+	assert.Equal(t, "Delivered", stored.Status)
+	assert.NotNil(t, parcel2.DeliveredAt)
+
+	assert.ErrorIs(t, service.UpdateStatus(&parcel2, "Lost"), ErrInvalidFulfillmentStatus)
+}
+
+func TestFulfillmentService_RequiresPaidOrder(t *testing.T) {
+	originalDB := db.DB
+	testDB := db.SetupTestDB(t)
+	db.DB = testDB
+	defer func() { db.DB = originalDB }()
+
+	order := models.Order{UserID: 1, Status: "Pending", Items: []models.OrderItem{{ProductID: 1, Quantity: 1}}}
+	testDB.Create(&order)
+
+	err := NewFulfillmentService().CreateFulfillment(&order, &models.Fulfillment{Carrier: "UPS"}, []FulfillmentLine{{OrderItemID: order.Items[0].ID, Quantity: 1}})
+	assert.ErrorIs(t, err, ErrOrderNotFulfillable)
+}
+
+func TestFulfillmentService_ChecksStoredStatus(t *testing.T) {
+	originalDB := db.DB
+	testDB := db.SetupTestDB(t)
+	db.DB = testDB
+	defer func() { db.DB = originalDB }()
+
+	order := models.Order{UserID: 1, Status: "Paid", Items: []models.OrderItem{{ProductID: 1, Quantity: 1}}}
+	testDB.Create(&order)
+	// The order is cancelled after the caller loaded it
+	testDB.Model(&models.Order{}).Where("id = ?", order.ID).Update("status", "Cancelled")
+
+	err := NewFulfillmentService().CreateFulfillment(&order, &models.Fulfillment{Carrier: "UPS"}, []FulfillmentLine{{OrderItemID: order.Items[0].ID, Quantity: 1}})
+	assert.ErrorIs(t, err, ErrOrderNotFulfillable)
+
+	var count int64
+	testDB.Model(&models.Fulfillment{}).Count(&count)
+	assert.Zero(t, count)
+}
@@ -0,0 +1,459 @@
+package services
+
+import (
+	"errors"
+	"math"
+	"time"
+
+	"github.com/geoo115/Ecommerce/config"
+	"github.com/geoo115/Ecommerce/db"
+	"github.com/geoo115/Ecommerce/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrInsufficientPoints is returned when a user does not have enough points for a debit
+var ErrInsufficientPoints = errors.New("insufficient loyalty points")
+
+// LoyaltyTier describes a tier level unlocked by rolling spend
+type LoyaltyTier struct {
+	Name     string  `json:"name"`
+	MinSpend float64 `json:"min_spend"`
+}
+
+// LoyaltyTiers lists the tier levels in ascending order of required spend
+var LoyaltyTiers = []LoyaltyTier{
+	{Name: "Bronze", MinSpend: 0},
+	{Name: "Silver", MinSpend: 500},
+	{Name: "Gold", MinSpend: 2000},
+	{Name: "Platinum", MinSpend: 5000},
+}
+
+// paidOrderStatuses are the order statuses that count as spend for tier levels
+var paidOrderStatuses = []string{"Paid", "Partially Shipped", "Shipped", "Delivered"}
+
+// LoyaltySummary is the customer-facing view of a points account
+type LoyaltySummary struct {
+	Balance      int          `json:"balance"`
+	BalanceValue float64      `json:"balance_value"`
+	Tier         LoyaltyTier  `json:"tier"`
+	NextTier     *LoyaltyTier `json:"next_tier,omitempty"`
+	RollingSpend float64      `json:"rolling_spend"`
+}
+
+// LoyaltyService interface defines loyalty points business logic
+type LoyaltyService interface {
+	GetBalance(userID uint) (int, error)
+	GetSummary(userID uint) (*LoyaltySummary, error)
+	GetHistory(userID uint, page, limit int) ([]models.LoyaltyTransaction, int64, error)
+	CalculateOrderPoints(orderID uint) (int, error)
+	AwardOrderPoints(orderID uint) (int, error)
+	RedeemPoints(userID, orderID uint, points int) error
+	ReverseOrderPoints(orderID uint) error
+	AdjustPoints(userID uint, points int, reason string) (*models.LoyaltyTransaction, error)
+	ExpirePoints(userID uint) error
+	DiscountForPoints(points int) float64
+	PointsForDiscount(amount float64) int
+}
+
+// loyaltyService implements LoyaltyService interface
+type loyaltyService struct {
+	db     *gorm.DB
+	config config.LoyaltyConfig
+}
+
+// NewLoyaltyService creates a new loyalty service instance
+func NewLoyaltyService() LoyaltyService {
+	return NewLoyaltyServiceWithDB(db.DB)
+}
+
+// NewLoyaltyServiceWithDB creates a loyalty service bound to a specific database handle, such as a transaction
+func NewLoyaltyServiceWithDB(database *gorm.DB) LoyaltyService {
+	return &loyaltyService{
+		db:     database,
+		config: config.GetLoyaltyConfig(),
+	}
+}
+
+// GetBalance returns the user's current spendable points after expiring stale credits
+func (s *loyaltyService) GetBalance(userID uint) (int, error) {
+	if err := s.ExpirePoints(userID); err != nil {
+		return 0, err
+	}
+	return s.balance(s.db, userID)
+}
+
+// GetSummary returns the balance together with the tier computed from rolling spend
+func (s *loyaltyService) GetSummary(userID uint) (*LoyaltySummary, error) {
+	balance, err := s.GetBalance(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var spend float64
+	since := time.Now().Add(-s.config.TierWindow)
+	if err := s.db.Model(&models.Order{}).
+		Where("user_id = ? AND status IN ? AND created_at >= ?", userID, paidOrderStatuses, since).
+		Select("COALESCE(SUM(total_amount), 0)").
+		Scan(&spend).Error; err != nil {
+		return nil, err
+	}
+
+	summary := &LoyaltySummary{
+		Balance:      balance,
+		BalanceValue: s.DiscountForPoints(balance),
+		RollingSpend: spend,
+	}
+	for i, tier := range LoyaltyTiers {
+		if spend >= tier.MinSpend {
+			summary.Tier = tier
+			summary.NextTier = nil
+			if i+1 < len(LoyaltyTiers) {
+				next := LoyaltyTiers[i+1]
+				summary.NextTier = &next
+			}
+		}
+	}
+	return summary, nil
+}
+
+// GetHistory returns a page of the user's ledger, newest first, with the total entry count
+func (s *loyaltyService) GetHistory(userID uint, page, limit int) ([]models.LoyaltyTransaction, int64, error) {
+	if page <= 0 {
+		page = 1
+	}
+	if limit <= 0 {
+		limit = 10
+	}
+
+	var total int64
+	if err := s.db.Model(&models.LoyaltyTransaction{}).Where("user_id = ?", userID).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var entries []models.LoyaltyTransaction
+	err := s.db.Where("user_id = ?", userID).
+		Order("created_at DESC, id DESC").
+		Offset((page - 1) * limit).
+		Limit(limit).
+		Find(&entries).Error
+	return entries, total, err
+}
+
+// CalculateOrderPoints computes the points an order earns using per-category earn rates
+func (s *loyaltyService) CalculateOrderPoints(orderID uint) (int, error) {
+	var order models.Order
+	if err := s.db.Preload("Items.Product").First(&order, orderID).Error; err != nil {
+		return 0, err
+	}
+
+	var rates []models.LoyaltyEarnRate
+	if err := s.db.Find(&rates).Error; err != nil {
+		return 0, err
+	}
+	rateByCategory := make(map[uint]float64, len(rates))
+	for _, rate := range rates {
+		rateByCategory[rate.CategoryID] = rate.PointsPerUnit
+	}
+
+	var subtotal, raw float64
+	for _, item := range order.Items {
+		lineTotal := item.Price * float64(item.Quantity)
+		rate, ok := rateByCategory[item.Product.CategoryID]
+		if !ok {
+			rate = s.config.DefaultEarnRate
+		}
+		subtotal += lineTotal
+		raw += lineTotal * rate
+	}
+
+	// Points are earned on what was actually paid, so scale down by any discount
+	if order.DiscountAmount > 0 && subtotal > 0 {
+		raw *= math.Max(subtotal-order.DiscountAmount, 0) / subtotal
+	}
+
+	return int(math.Floor(raw)), nil
+}
+
+// AwardOrderPoints credits the points earned by a paid order; awarding twice is a no-op
+func (s *loyaltyService) AwardOrderPoints(orderID uint) (int, error) {
+	var existing int64
+	if err := s.db.Model(&models.LoyaltyTransaction{}).
+		Where("order_id = ? AND type = ?", orderID, "Earn").
+		Count(&existing).Error; err != nil {
+		return 0, err
+	}
+	if existing > 0 {
+		return 0, nil
+	}
+
+	var order models.Order
+	if err := s.db.First(&order, orderID).Error; err != nil {
+		return 0, err
+	}
+
+	points, err := s.CalculateOrderPoints(orderID)
+	if err != nil || points <= 0 {
+		return 0, err
+	}
+
+	// Orders earn once; a concurrent award that got there first leaves nothing to insert
+	entry, err := s.credit(s.db.Clauses(clause.OnConflict{DoNothing: true}), order.UserID, &order.ID, "Earn", points, "Points earned on order")
+	if err != nil {
+		return 0, err
+	}
+	if entry.ID == 0 {
+		return 0, nil
+	}
+	return points, nil
+}
+
+// RedeemPoints debits points from the user's balance against an order
+func (s *loyaltyService) RedeemPoints(userID, orderID uint, points int) error {
+	if points <= 0 {
+		return nil
+	}
+	if err := s.ExpirePoints(userID); err != nil {
+		return err
+	}
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := s.consume(tx, userID, points, nil); err != nil {
+			return err
+		}
+		entry := models.LoyaltyTransaction{
+			UserID:  userID,
+			OrderID: &orderID,
+			Type:    "Redeem",
+			Points:  -points,
+			Reason:  "Points redeemed at checkout",
+		}
+		return tx.Create(&entry).Error
+	})
+}
+
+// ReverseOrderPoints claws back points earned by an order and restores points redeemed on it
+func (s *loyaltyService) ReverseOrderPoints(orderID uint) error {
+	var entries []models.LoyaltyTransaction
+	if err := s.db.Where("order_id = ?", orderID).Find(&entries).Error; err != nil {
+		return err
+	}
+	if len(entries) == 0 {
+		return nil
+	}
+
+	var earned, redeemed int
+	var earnEntry *models.LoyaltyTransaction
+	for i := range entries {
+		switch entries[i].Type {
+		case "Reversal":
+			// Already reversed
+			return nil
+		case "Earn":
+			earned += entries[i].Points
+			earnEntry = &entries[i]
+		case "Redeem":
+			redeemed -= entries[i].Points
+		}
+	}
+
+	userID := entries[0].UserID
+	if err := s.ExpirePoints(userID); err != nil {
+		return err
+	}
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if earned > 0 {
+			balance, err := s.balance(tx, userID)
+			if err != nil {
+				return err
+			}
+			// Points already spent elsewhere cannot be recovered
+			clawback := earned
+			if balance < clawback {
+				clawback = balance
+			}
+			if clawback > 0 {
+				if err := s.consume(tx, userID, clawback, earnEntry); err != nil {
+					return err
+				}
+				entry := models.LoyaltyTransaction{
+					UserID:  userID,
+					OrderID: &orderID,
+					Type:    "Reversal",
+					Points:  -clawback,
+					Reason:  "Earned points reversed",
+				}
+				if err := tx.Create(&entry).Error; err != nil {
+					return err
+				}
+			}
+		}
+
+		if redeemed > 0 {
+			if _, err := s.credit(tx, userID, &orderID, "Reversal", redeemed, "Redeemed points restored"); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// AdjustPoints applies a manual credit or debit to a user's balance
+func (s *loyaltyService) AdjustPoints(userID uint, points int, reason string) (*models.LoyaltyTransaction, error) {
+	if points == 0 {
+		return nil, errors.New("adjustment must be non-zero")
+	}
+	if err := s.ExpirePoints(userID); err != nil {
+		return nil, err
+	}
+
+	var entry models.LoyaltyTransaction
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if points > 0 {
+			credited, err := s.credit(tx, userID, nil, "Adjustment", points, reason)
+			if err != nil {
+				return err
+			}
+			entry = *credited
+			return nil
+		}
+
+		if err := s.consume(tx, userID, -points, nil); err != nil {
+			return err
+		}
+		entry = models.LoyaltyTransaction{
+			UserID: userID,
+			Type:   "Adjustment",
+			Points: points,
+			Reason: reason,
+		}
+		return tx.Create(&entry).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// ExpirePoints writes off the unconsumed remainder of credits past their expiry date
+func (s *loyaltyService) ExpirePoints(userID uint) error {
+	var expired []models.LoyaltyTransaction
+	if err := s.db.Where("user_id = ? AND remaining > 0 AND expires_at IS NOT NULL AND expires_at <= ?", userID, time.Now()).
+		Find(&expired).Error; err != nil {
+		return err
+	}
+	if len(expired) == 0 {
+		return nil
+	}
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		for _, credit := range expired {
+			entry := models.LoyaltyTransaction{
+				UserID: userID,
+				Type:   "Expire",
+				Points: -credit.Remaining,
+				Reason: "Points expired",
+			}
+			if err := tx.Create(&entry).Error; err != nil {
+				return err
+			}
+			if err := tx.Model(&models.LoyaltyTransaction{}).Where("id = ?", credit.ID).Update("remaining", 0).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// DiscountForPoints converts points into their currency value
+func (s *loyaltyService) DiscountForPoints(points int) float64 {
+	return math.Round(float64(points)*s.config.PointValue*100) / 100
+}
+
+// PointsForDiscount returns the points needed to cover a currency amount
+func (s *loyaltyService) PointsForDiscount(amount float64) int {
+	if s.config.PointValue <= 0 {
+		return 0
+	}
+	return int(math.Ceil(math.Round(amount/s.config.PointValue*100) / 100))
+}
+
+// balance sums the ledger for a user
+func (s *loyaltyService) balance(tx *gorm.DB, userID uint) (int, error) {
+	var balance int
+	err := tx.Model(&models.LoyaltyTransaction{}).
+		Where("user_id = ?", userID).
+		Select("COALESCE(SUM(points), 0)").
+		Scan(&balance).Error
+	return balance, err
+}
+
+// credit records a new expiring credit entry
+func (s *loyaltyService) credit(tx *gorm.DB, userID uint, orderID *uint, kind string, points int, reason string) (*models.LoyaltyTransaction, error) {
+	expiresAt := time.Now().Add(s.config.PointsExpiry)
+	entry := models.LoyaltyTransaction{
+		UserID:    userID,
+		OrderID:   orderID,
+		Type:      kind,
+		Points:    points,
+		Remaining: points,
+		ExpiresAt: &expiresAt,
+		Reason:    reason,
+	}
+	if err := tx.Create(&entry).Error; err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// consume draws points from open credits, soonest-expiring first, optionally starting with a preferred credit
+func (s *loyaltyService) consume(tx *gorm.DB, userID uint, points int, preferred *models.LoyaltyTransaction) error {
+	var credits []models.LoyaltyTransaction
+	if err := tx.Where("user_id = ? AND remaining > 0", userID).
+		Order("expires_at ASC, id ASC").
+		Find(&credits).Error; err != nil {
+		return err
+	}
+
+	if preferred != nil {
+		for i := range credits {
+			if credits[i].ID == preferred.ID {
+				first := credits[i]
+				copy(credits[1:i+1], credits[:i])
+				credits[0] = first
+				break
+			}
+		}
+	}
+
+	available := 0
+	for _, credit := range credits {
+		available += credit.Remaining
+	}
+	if available < points {
+		return ErrInsufficientPoints
+	}
+
+	for _, credit := range credits {
+		if points == 0 {
+			break
+		}
+		take := credit.Remaining
+		if take > points {
+			take = points
+		}
+		// Only draw what is still there, in case a concurrent debit spent it since the read
+		result := tx.Model(&models.LoyaltyTransaction{}).
+			Where("id = ? AND remaining >= ?", credit.ID, take).
+			Update("remaining", gorm.Expr("remaining - ?", take))
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrInsufficientPoints
+		}
+		points -= take
+	}
+	return nil
+}
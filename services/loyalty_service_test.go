@@ -0,0 +1,242 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/geoo115/Ecommerce/db"
+	"github.com/geoo115/Ecommerce/models"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func createLoyaltyOrder(t *testing.T, userID uint, categoryID uint, price float64, qty int, status string) models.Order {
+	t.Helper()
+	product := models.Product{Name: "Loyalty Product", Price: price, CategoryID: categoryID}
+	db.DB.Create(&product)
+
+	order := models.Order{
+		UserID:      userID,
+		TotalAmount: price * float64(qty),
+		Status:      status,
+		Items:       []models.OrderItem{{ProductID: product.ID, Quantity: qty, Price: price}},
+	}
+	if err := db.DB.Create(&order).Error; err != nil {
+		t.Fatalf("failed to create order: %v", err)
+	}
+	return order
+}
+
+func TestLoyaltyService_AwardOrderPoints_UsesCategoryRates(t *testing.T) {
+	originalDB := db.DB
+	testDB := db.SetupTestDB(t)
+	db.DB = testDB
+	defer func() { db.DB = originalDB }()
+
+	user := models.User{Username: "loyal", Email: "loyal@example.com"}
+	testDB.Create(&user)
+	boosted := models.Category{Name: "Boosted"}
+	plain := models.Category{Name: "Plain"}
+	testDB.Create(&boosted)
+	testDB.Create(&plain)
+	testDB.Create(&models.LoyaltyEarnRate{CategoryID: boosted.ID, PointsPerUnit: 3})
+
+	service := NewLoyaltyService()
+
+	boostedOrder := createLoyaltyOrder(t, user.ID, boosted.ID, 10, 2, "Paid")
+	points, err := service.AwardOrderPoints(boostedOrder.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, 60, points)
+
+	plainOrder := createLoyaltyOrder(t, user.ID, plain.ID, 10.5, 1, "Paid")
+	points, err = service.AwardOrderPoints(plainOrder.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, 10, points)
+
+	// Awarding the same order twice is a no-op
+	points, err = service.AwardOrderPoints(plainOrder.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, points)
+
+	balance, err := service.GetBalance(user.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, 70, balance)
+}
+
+func TestLoyaltyService_RedeemAndReverse(t *testing.T) {
+	originalDB := db.DB
+	testDB := db.SetupTestDB(t)
+	db.DB = testDB
+	defer func() { db.DB = originalDB }()
+
+	user := models.User{Username: "redeemer", Email: "redeemer@example.com"}
+	testDB.Create(&user)
+	category := models.Category{Name: "Redeem Category"}
+	testDB.Create(&category)
+
+	service := NewLoyaltyService()
+	_, err := service.AdjustPoints(user.ID, 500, "Welcome bonus")
+	assert.NoError(t, err)
+
+	order := createLoyaltyOrder(t, user.ID, category.ID, 20, 1, "Pending")
+	assert.NoError(t, service.RedeemPoints(user.ID, order.ID, 200))
+
+	balance, _ := service.GetBalance(user.ID)
+	assert.Equal(t, 300, balance)
+
+	err = service.RedeemPoints(user.ID, order.ID, 1000)
+	assert.ErrorIs(t, err, ErrInsufficientPoints)
+
+	// Cancelling restores the redeemed points exactly once
+	assert.NoError(t, service.ReverseOrderPoints(order.ID))
+	assert.NoError(t, service.ReverseOrderPoints(order.ID))
+	balance, _ = service.GetBalance(user.ID)
+	assert.Equal(t, 500, balance)
+}
+
+func TestLoyaltyService_ReverseEarnedPoints(t *testing.T) {
+	originalDB := db.DB
+	testDB := db.SetupTestDB(t)
+	db.DB = testDB
+	defer func() { db.DB = originalDB }()
+
+	user := models.User{Username: "refunded", Email: "refunded@example.com"}
+	testDB.Create(&user)
+	category := models.Category{Name: "Refund Category"}
+	testDB.Create(&category)
+
+	service := NewLoyaltyService()
+	order := createLoyaltyOrder(t, user.ID, category.ID, 100, 1, "Paid")
+	points, err := service.AwardOrderPoints(order.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, 100, points)
+
+	assert.NoError(t, service.ReverseOrderPoints(order.ID))
+	balance, _ := service.GetBalance(user.ID)
+	assert.Equal(t, 0, balance)
+}
+
+func TestLoyaltyService_ExpirePoints(t *testing.T) {
+	originalDB := db.DB
+	testDB := db.SetupTestDB(t)
+	db.DB = testDB
+	defer func() { db.DB = originalDB }()
+
+	user := models.User{Username: "expiring", Email: "expiring@example.com"}
+	testDB.Create(&user)
+
+	past := time.Now().Add(-time.Hour)
+	testDB.Create(&models.LoyaltyTransaction{UserID: user.ID, Type: "Earn", Points: 40, Remaining: 25, ExpiresAt: &past})
+	testDB.Create(&models.LoyaltyTransaction{UserID: user.ID, Type: "Redeem", Points: -15})
+
+	service := NewLoyaltyService()
+	_, err := service.AdjustPoints(user.ID, 10, "Goodwill")
+	assert.NoError(t, err)
+
+	balance, err := service.GetBalance(user.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, 10, balance)
+
+	var expired models.LoyaltyTransaction
+	assert.NoError(t, testDB.Where("user_id = ? AND type = ?", user.ID, "Expire").First(&expired).Error)
+	assert.Equal(t, -25, expired.Points)
+}
+
+func TestLoyaltyService_GetSummary_Tiers(t *testing.T) {
+	originalDB := db.DB
+	testDB := db.SetupTestDB(t)
+	db.DB = testDB
+	defer func() { db.DB = originalDB }()
+
+	user := models.User{Username: "tiered", Email: "tiered@example.com"}
+	testDB.Create(&user)
+	testDB.Create(&models.Order{UserID: user.ID, TotalAmount: 450, Status: "Paid"})
+	testDB.Create(&models.Order{UserID: user.ID, TotalAmount: 100, Status: "Delivered"})
+	testDB.Create(&models.Order{UserID: user.ID, TotalAmount: 5000, Status: "Cancelled"})
+
+	summary, err := NewLoyaltyService().GetSummary(user.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, 550.0, summary.RollingSpend)
+	assert.Equal(t, "Silver", summary.Tier.Name)
+	if assert.NotNil(t, summary.NextTier) {
+		assert.Equal(t, "Gold", summary.NextTier.Name)
+	}
+}
+
+func TestLoyaltyService_AdjustPoints_InsufficientBalance(t *testing.T) {
+	originalDB := db.DB
+	testDB := db.SetupTestDB(t)
+	db.DB = testDB
+	defer func() { db.DB = originalDB }()
+
+	user := models.User{Username: "adjusted", Email: "adjusted@example.com"}
+	testDB.Create(&user)
+
+	service := NewLoyaltyService()
+	_, err := service.AdjustPoints(user.ID, -5, "Correction")
+	assert.ErrorIs(t, err, ErrInsufficientPoints)
+
+	_, err = service.AdjustPoints(user.ID, 0, "Nothing")
+	assert.Error(t, err)
+}
+
+// onFirstLoyaltyQuery runs change once, right after the next query of the points ledger,
+// to stand in for a concurrent request
+func onFirstLoyaltyQuery(t *testing.T, testDB *gorm.DB, change func(tx *gorm.DB)) {
+	t.Helper()
+	fired := false
+	testDB.Callback().Query().After("gorm:query").Register("test:concurrent_loyalty", func(tx *gorm.DB) {
+		if !fired && tx.Statement.Table == "loyalty_transactions" {
+			fired = true
+			change(tx.Session(&gorm.Session{NewDB: true}))
+		}
+	})
+	t.Cleanup(func() { testDB.Callback().Query().Remove("test:concurrent_loyalty") })
+}
+
+func TestLoyaltyService_ConcurrentSpend(t *testing.T) {
+	originalDB := db.DB
+	testDB := db.SetupTestDB(t)
+	db.DB = testDB
+	defer func() { db.DB = originalDB }()
+
+	user := models.User{Username: "racer", Email: "racer@example.com"}
+	testDB.Create(&user)
+	service := NewLoyaltyService()
+	_, err := service.AdjustPoints(user.ID, 100, "Welcome bonus")
+	assert.NoError(t, err)
+
+	// Another checkout spends the points after this one has read the open credits
+	onFirstLoyaltyQuery(t, testDB, func(tx *gorm.DB) {
+		tx.Model(&models.LoyaltyTransaction{}).Where("user_id = ?", user.ID).Update("remaining", 0)
+	})
+	err = testDB.Transaction(func(tx *gorm.DB) error {
+		return service.(*loyaltyService).consume(tx, user.ID, 100, nil)
+	})
+	assert.ErrorIs(t, err, ErrInsufficientPoints)
+}
+
+func TestLoyaltyService_ConcurrentAward(t *testing.T) {
+	originalDB := db.DB
+	testDB := db.SetupTestDB(t)
+	db.DB = testDB
+	defer func() { db.DB = originalDB }()
+
+	user := models.User{Username: "earner", Email: "earner@example.com"}
+	testDB.Create(&user)
+	category := models.Category{Name: "Award Category"}
+	testDB.Create(&category)
+	order := createLoyaltyOrder(t, user.ID, category.ID, 10, 1, "Paid")
+	service := NewLoyaltyService()
+
+	// Another award lands after this one checked for an existing entry
+	onFirstLoyaltyQuery(t, testDB, func(tx *gorm.DB) {
+		tx.Create(&models.LoyaltyTransaction{UserID: user.ID, OrderID: &order.ID, Type: "Earn", Points: 10, Remaining: 10})
+	})
+	points, err := service.AwardOrderPoints(order.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, points)
+
+	balance, _ := service.GetBalance(user.ID)
+	assert.Equal(t, 10, balance)
+}
@@ -0,0 +1,317 @@
+package services
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"io"
+
+	_ "image/gif" // Register GIF decoding for uploads
+
+	"github.com/geoo115/Ecommerce/config"
+	"github.com/geoo115/Ecommerce/db"
+	"github.com/geoo115/Ecommerce/models"
+	"github.com/geoo115/Ecommerce/utils"
+	"gorm.io/gorm"
+)
+
+// Media errors surfaced to handlers
+var (
+	ErrUnsupportedImage = errors.New("unsupported image format")
+	ErrImageTooLarge    = errors.New("image exceeds the maximum upload size")
+	ErrImageDimensions  = errors.New("image exceeds the maximum dimensions")
+)
+
+// ImageUpload is a new product image and its display settings
+type ImageUpload struct {
+	Data      io.Reader
+	AltText   string
+	Position  *int // Defaults to after the existing images
+	IsPrimary bool // The product's first image is always primary
+}
+
+// ImageUpdate describes changes to an image's display settings; nil fields are left unchanged
+type ImageUpdate struct {
+	AltText   *string
+	Position  *int
+	IsPrimary *bool
+}
+
+// MediaService interface defines product image business logic
+type MediaService interface {
+	AddImage(product *models.Product, upload ImageUpload) (*models.ProductImage, error)
+	UpdateImage(img *models.ProductImage, update ImageUpdate) error
+	DeleteImage(img *models.ProductImage) error
+}
+
+// mediaService implements MediaService interface
+type mediaService struct {
+	db     *gorm.DB
+	store  BlobStore
+	config config.MediaConfig
+}
+
+// NewMediaService creates a new media service instance using the configured blob store
+func NewMediaService() MediaService {
+	cfg := config.GetMediaConfig()
+	store := Blobs
+	if store == nil {
+		store = NewLocalBlobStore(cfg.StorageDir, cfg.BaseURL)
+	}
+	return &mediaService{db: db.DB, store: store, config: cfg}
+}
+
+// AddImage validates and stores an uploaded image, generates its thumbnails and
+// attaches it to the product
+func (s *mediaService) AddImage(product *models.Product, upload ImageUpload) (*models.ProductImage, error) {
+	data, err := io.ReadAll(io.LimitReader(upload.Data, s.config.MaxUploadSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > s.config.MaxUploadSize {
+		return nil, ErrImageTooLarge
+	}
+
+	// A small file can declare a huge image, so check its size before decoding it
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrUnsupportedImage
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 || int64(cfg.Width)*int64(cfg.Height) > s.config.MaxPixels {
+		return nil, ErrImageDimensions
+	}
+
+	src, format, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrUnsupportedImage
+	}
+	ext, contentType := "png", "image/png"
+	switch format {
+	case "jpeg":
+		ext, contentType = "jpg", "image/jpeg"
+	case "png":
+	case "gif":
+		ext, contentType = "gif", "image/gif"
+	default:
+		return nil, ErrUnsupportedImage
+	}
+
+	name, err := randomName()
+	if err != nil {
+		return nil, err
+	}
+	bounds := src.Bounds()
+	img := models.ProductImage{
+		ProductID:   product.ID,
+		Key:         fmt.Sprintf("products/%d/%s.%s", product.ID, name, ext),
+		ContentType: contentType,
+		AltText:     utils.SanitizeString(upload.AltText),
+		IsPrimary:   upload.IsPrimary,
+		Width:       bounds.Dx(),
+		Height:      bounds.Dy(),
+	}
+	img.URL = s.store.URL(img.Key)
+
+	// Write the original and every thumbnail before touching the database
+	written := []string{}
+	cleanup := func() {
+		for _, key := range written {
+			if err := s.store.Delete(key); err != nil {
+				utils.Warn("Failed to remove orphaned blob %s: %v", key, err)
+			}
+		}
+	}
+	if err := s.store.Put(img.Key, bytes.NewReader(data), contentType); err != nil {
+		return nil, err
+	}
+	written = append(written, img.Key)
+
+	for _, size := range s.config.ThumbnailSizes {
+		thumb := resizeToFit(src, size)
+		var buf bytes.Buffer
+		thumbExt, thumbType := "png", "image/png"
+		if format == "jpeg" {
+			thumbExt, thumbType = "jpg", "image/jpeg"
+			err = jpeg.Encode(&buf, thumb, &jpeg.Options{Quality: 85})
+		} else {
+			err = png.Encode(&buf, thumb)
+		}
+		if err != nil {
+			cleanup()
+			return nil, err
+		}
+
+		key := fmt.Sprintf("products/%d/%s_%d.%s", product.ID, name, size, thumbExt)
+		if err := s.store.Put(key, &buf, thumbType); err != nil {
+			cleanup()
+			return nil, err
+		}
+		written = append(written, key)
+		img.Thumbnails = append(img.Thumbnails, models.ProductImageThumbnail{
+			Size:   size,
+			Key:    key,
+			URL:    s.store.URL(key),
+			Width:  thumb.Bounds().Dx(),
+			Height: thumb.Bounds().Dy(),
+		})
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		var existing int64
+		if err := tx.Model(&models.ProductImage{}).Where("product_id = ?", product.ID).Count(&existing).Error; err != nil {
+			return err
+		}
+		img.Position = int(existing)
+		if upload.Position != nil {
+			img.Position = *upload.Position
+		}
+		if existing == 0 {
+			img.IsPrimary = true
+		}
+		if img.IsPrimary {
+			if err := clearPrimary(tx, product.ID); err != nil {
+				return err
+			}
+		}
+		return tx.Create(&img).Error
+	})
+	if err != nil {
+		cleanup()
+		return nil, err
+	}
+	return &img, nil
+}
+
+// UpdateImage changes an image's alt text, position or primary flag
+func (s *mediaService) UpdateImage(img *models.ProductImage, update ImageUpdate) error {
+	updates := map[string]interface{}{}
+	if update.AltText != nil {
+		img.AltText = utils.SanitizeString(*update.AltText)
+		updates["alt_text"] = img.AltText
+	}
+	if update.Position != nil {
+		img.Position = *update.Position
+		updates["position"] = img.Position
+	}
+	if update.IsPrimary != nil && *update.IsPrimary && !img.IsPrimary {
+		img.IsPrimary = true
+		updates["is_primary"] = true
+	}
+	if len(updates) == 0 {
+		return ErrNoChanges
+	}
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if img.IsPrimary {
+			if err := clearPrimary(tx, img.ProductID); err != nil {
+				return err
+			}
+		}
+		return tx.Model(img).Updates(updates).Error
+	})
+}
+
+// DeleteImage removes an image and its thumbnails. When the primary image is
+// deleted the next image in order becomes primary.
+func (s *mediaService) DeleteImage(img *models.ProductImage) error {
+	var thumbs []models.ProductImageThumbnail
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("image_id = ?", img.ID).Find(&thumbs).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("image_id = ?", img.ID).Delete(&models.ProductImageThumbnail{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Delete(img).Error; err != nil {
+			return err
+		}
+		if !img.IsPrimary {
+			return nil
+		}
+
+		var next models.ProductImage
+		err := tx.Where("product_id = ?", img.ProductID).Order("position, id").First(&next).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		return tx.Model(&next).Update("is_primary", true).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	// The records are gone; failing to remove a file only leaves an orphan behind
+	keys := []string{img.Key}
+	for _, thumb := range thumbs {
+		keys = append(keys, thumb.Key)
+	}
+	for _, key := range keys {
+		if err := s.store.Delete(key); err != nil {
+			utils.Warn("Failed to delete blob %s: %v", key, err)
+		}
+	}
+	return nil
+}
+
+// clearPrimary unsets the primary flag on all of a product's images
+func clearPrimary(tx *gorm.DB, productID uint) error {
+	return tx.Model(&models.ProductImage{}).
+		Where("product_id = ? AND is_primary = ?", productID, true).
+		Update("is_primary", false).Error
+}
+
+// randomName returns a random hex file name for a new upload
+func randomName() (string, error) {
+	buf := make([]byte, 12)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// resizeToFit scales an image down so its longest edge is at most size pixels, averaging
+// the source pixels each output pixel covers. Smaller images are copied unscaled.
+func resizeToFit(src image.Image, size int) *image.NRGBA {
+	bounds := src.Bounds()
+	srcW, srcH := bounds.Dx(), bounds.Dy()
+	dstW, dstH := srcW, srcH
+	if srcW > size || srcH > size {
+		if srcW >= srcH {
+			dstW, dstH = size, max(1, srcH*size/srcW)
+		} else {
+			dstW, dstH = max(1, srcW*size/srcH), size
+		}
+	}
+
+	dst := image.NewNRGBA(image.Rect(0, 0, dstW, dstH))
+	for y := 0; y < dstH; y++ {
+		y0 := bounds.Min.Y + y*srcH/dstH
+		y1 := max(y0+1, bounds.Min.Y+(y+1)*srcH/dstH)
+		for x := 0; x < dstW; x++ {
+			x0 := bounds.Min.X + x*srcW/dstW
+			x1 := max(x0+1, bounds.Min.X+(x+1)*srcW/dstW)
+
+			var r, g, b, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					c := color.NRGBAModel.Convert(src.At(sx, sy)).(color.NRGBA)
+					r += uint64(c.R)
+					g += uint64(c.G)
+					b += uint64(c.B)
+					a += uint64(c.A)
+					n++
+				}
+			}
+			dst.SetNRGBA(x, y, color.NRGBA{R: uint8(r / n), G: uint8(g / n), B: uint8(b / n), A: uint8(a / n)})
+		}
+	}
+	return dst
+}
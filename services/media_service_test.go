@@ -0,0 +1,122 @@
+package services
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/geoo115/Ecommerce/db"
+	"github.com/geoo115/Ecommerce/models"
+	"github.com/stretchr/testify/assert"
+)
+
+// pngBytes encodes a solid-colour PNG of the given size
+func pngBytes(t *testing.T, width, height int) []byte {
+	t.Helper()
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.SetNRGBA(x, y, color.NRGBA{R: 200, G: 40, B: 40, A: 255})
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatalf("failed to encode png: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestLocalBlobStore(t *testing.T) {
+	store := NewLocalBlobStore(t.TempDir(), "/media/")
+
+	assert.NoError(t, store.Put("products/1/a.png", strings.NewReader("data"), "image/png"))
+	content, err := os.ReadFile(filepath.Join(store.Dir, "products", "1", "a.png"))
+	assert.NoError(t, err)
+	assert.Equal(t, "data", string(content))
+	assert.Equal(t, "/media/products/1/a.png", store.URL("products/1/a.png"))
+	if file, err := store.Open("products/1/a.png"); assert.NoError(t, err) {
+		content, _ := io.ReadAll(file)
+		file.Close()
+		assert.Equal(t, "data", string(content))
+	}
+
+	assert.ErrorIs(t, store.Put("../escape.png", strings.NewReader("x"), "image/png"), ErrInvalidBlobKey)
+	assert.NoError(t, store.Delete("products/1/a.png"))
+	assert.NoError(t, store.Delete("products/1/a.png"))
+}
+
+func TestMediaService_AddImage(t *testing.T) {
+	originalDB := db.DB
+	testDB := db.SetupTestDB(t)
+	db.DB = testDB
+	defer func() { db.DB = originalDB }()
+
+	originalBlobs := Blobs
+	store := NewLocalBlobStore(t.TempDir(), "/media")
+	Blobs = store
+	defer func() { Blobs = originalBlobs }()
+	os.Setenv("MEDIA_THUMBNAIL_SIZES", "50,100")
+	defer os.Unsetenv("MEDIA_THUMBNAIL_SIZES")
+
+	product := createStockedProduct(t, "Lamp", 25, 3)
+	service := NewMediaService()
+
+	first, err := service.AddImage(&product, ImageUpload{Data: bytes.NewReader(pngBytes(t, 200, 80)), AltText: "Front"})
+	assert.NoError(t, err)
+	assert.True(t, first.IsPrimary)
+	assert.Equal(t, 0, first.Position)
+	assert.Equal(t, "image/png", first.ContentType)
+	if assert.Len(t, first.Thumbnails, 2) {
+		assert.Equal(t, 50, first.Thumbnails[0].Width)
+		assert.Equal(t, 20, first.Thumbnails[0].Height)
+		assert.Equal(t, 100, first.Thumbnails[1].Width)
+		assert.FileExists(t, filepath.Join(store.Dir, filepath.FromSlash(first.Thumbnails[1].Key)))
+	}
+	assert.FileExists(t, filepath.Join(store.Dir, filepath.FromSlash(first.Key)))
+
+	second, err := service.AddImage(&product, ImageUpload{Data: bytes.NewReader(pngBytes(t, 30, 30)), IsPrimary: true})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, second.Position)
+	assert.Equal(t, 30, second.Thumbnails[0].Width) // Small images are not upscaled
+
+	var reloaded models.ProductImage
+	testDB.First(&reloaded, first.ID)
+	assert.False(t, reloaded.IsPrimary)
+
+	_, err = service.AddImage(&product, ImageUpload{Data: strings.NewReader("not an image")})
+	assert.ErrorIs(t, err, ErrUnsupportedImage)
+
+	// Deleting the primary image promotes the next one and removes its files
+	assert.NoError(t, service.DeleteImage(second))
+	testDB.First(&reloaded, first.ID)
+	assert.True(t, reloaded.IsPrimary)
+	assert.NoFileExists(t, filepath.Join(store.Dir, filepath.FromSlash(second.Key)))
+}
+
+func TestMediaService_RejectsLargeUploads(t *testing.T) {
+	originalDB := db.DB
+	testDB := db.SetupTestDB(t)
+	db.DB = testDB
+	defer func() { db.DB = originalDB }()
+
+	originalBlobs := Blobs
+	Blobs = NewLocalBlobStore(t.TempDir(), "/media")
+	defer func() { Blobs = originalBlobs }()
+	os.Setenv("MEDIA_MAX_UPLOAD_MB", "1")
+	defer os.Unsetenv("MEDIA_MAX_UPLOAD_MB")
+
+	product := createStockedProduct(t, "Poster", 5, 1)
+	_, err := NewMediaService().AddImage(&product, ImageUpload{Data: bytes.NewReader(make([]byte, 2<<20))})
+	assert.ErrorIs(t, err, ErrImageTooLarge)
+
+	// A few bytes declaring a 65535x65535 GIF are rejected without decoding the image
+	header := []byte("GIF89a\xff\xff\xff\xff\x00\x00\x00")
+	_, err = NewMediaService().AddImage(&product, ImageUpload{Data: bytes.NewReader(header)})
+	assert.ErrorIs(t, err, ErrImageDimensions)
+}
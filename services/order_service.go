@@ -0,0 +1,561 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/geoo115/Ecommerce/db"
+	"github.com/geoo115/Ecommerce/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Order errors surfaced to handlers and schedulers
+var (
+	ErrProductNotFound   = errors.New("product not found")
+	ErrInsufficientStock = errors.New("insufficient stock for product")
+	ErrOrderNotAmendable = errors.New("order can no longer be amended")
+	ErrNoChanges         = errors.New("amendment contains no changes")
+	ErrEmptyOrder        = errors.New("order must keep at least one item")
+	ErrInvalidCancel     = errors.New("cancellation quantity exceeds the item's remaining quantity")
+)
+
+// amendableOrderStatuses are the order statuses that can still be changed before shipping
+var amendableOrderStatuses = map[string]bool{"Pending": true, "Paid": true}
+
+// OrderLine is a requested product, or product variant, and quantity
+type OrderLine struct {
+	ProductID uint  `json:"product_id"`
+	VariantID *uint `json:"variant_id,omitempty"`
+	Quantity  int   `json:"quantity"`
+}
+
+// lineKey identifies an order line by product and variant (0 for simple products)
+type lineKey struct {
+	productID uint
+	variantID uint
+}
+
+// keyFor builds the line key for a product and optional variant
+func keyFor(productID uint, variantID *uint) lineKey {
+	key := lineKey{productID: productID}
+	if variantID != nil {
+		key.variantID = *variantID
+	}
+	return key
+}
+
+// String describes the line for revision summaries
+func (k lineKey) String() string {
+	if k.variantID != 0 {
+		return fmt.Sprintf("Product #%d (variant #%d)", k.productID, k.variantID)
+	}
+	return fmt.Sprintf("Product #%d", k.productID)
+}
+
+// OrderAmendment describes requested changes to an order that has not shipped yet
+type OrderAmendment struct {
+	AddressID *uint       // New shipping address, if changing
+	Items     []OrderLine // Target quantity per product or variant; 0 removes the line, new lines are added at the current price
+}
+
+// CancelLine is a quantity to cancel from one order item
+type CancelLine struct {
+	OrderItemID uint `json:"order_item_id"`
+	Quantity    int  `json:"quantity"`
+}
+
+// OrderService interface defines order placement business logic
+type OrderService interface {
+	CreateOrder(order *models.Order, lines []OrderLine) error
+	AmendOrder(order *models.Order, amendment OrderAmendment, amendedBy uint) (*models.OrderRevision, error)
+	CancelItems(order *models.Order, lines []CancelLine, reason string, cancelledBy uint) ([]models.OrderItemCancellation, error)
+	CancelOrder(order *models.Order) error
+	ReserveStock(productID uint, variantID *uint, quantity int) error
+	Restock(productID uint, variantID *uint, quantity int) error
+	RestockItem(item *models.OrderItem, quantity int) error
+}
+
+// orderService implements OrderService interface
+type orderService struct {
+	db *gorm.DB
+}
+
+// NewOrderService creates a new order service instance
+func NewOrderService() OrderService {
+	return NewOrderServiceWithDB(db.DB)
+}
+
+// NewOrderServiceWithDB creates an order service bound to a specific database handle, such as a transaction
+func NewOrderServiceWithDB(database *gorm.DB) OrderService {
+	return &orderService{db: database}
+}
+
+// CreateOrder prices the lines at current product or variant prices, reserves stock and persists the order.
+// The caller pre-fills the order header (user, address, ...); items, total and status are set here.
+func (s *orderService) CreateOrder(order *models.Order, lines []OrderLine) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		txService := &orderService{db: tx}
+		order.Items = nil
+		order.TotalAmount = 0
+
+		for _, line := range lines {
+			item, err := txService.newItem(line)
+			if err != nil {
+				return err
+			}
+			order.TotalAmount += item.Price * float64(item.Quantity)
+			order.Items = append(order.Items, item)
+		}
+
+		if order.Status == "" {
+			order.Status = "Pending"
+		}
+		return models.CreateOrder(tx, order)
+	})
+}
+
+// CancelOrder marks the order cancelled and returns its items to stock
+func (s *orderService) CancelOrder(order *models.Order) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		txService := &orderService{db: tx}
+		if err := tx.Model(order).Update("status", "Cancelled").Error; err != nil {
+			return err
+		}
+
+		items := order.Items
+		if items == nil {
+			if err := tx.Where("order_id = ?", order.ID).Find(&items).Error; err != nil {
+				return err
+			}
+		}
+		for _, item := range items {
+			if err := txService.RestockItem(&item, item.Quantity); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// newItem resolves a line's product and variant, reserves its stock and prices it
+func (s *orderService) newItem(line OrderLine) (models.OrderItem, error) {
+	product, variant, err := ResolveVariant(s.db, line.ProductID, line.VariantID)
+	if err != nil {
+		return models.OrderItem{}, err
+	}
+	if err := s.ReserveStock(line.ProductID, line.VariantID, line.Quantity); err != nil {
+		return models.OrderItem{}, err
+	}
+
+	item := models.OrderItem{ProductID: line.ProductID, Quantity: line.Quantity, Price: product.Price}
+	if variant != nil {
+		item.VariantID = &variant.ID
+		item.SKU = variant.SKU
+		item.Price = variant.PriceFor(product.Price)
+	}
+	item.Components, err = BundleOrderComponents(s.db, line.ProductID)
+	return item, err
+}
+
+// stockQuery targets the stock row of a variant, or the inventory row of a simple product
+func (s *orderService) stockQuery(productID uint, variantID *uint) *gorm.DB {
+	if variantID != nil {
+		return s.db.Model(&models.ProductVariant{}).Where("id = ? AND product_id = ?", *variantID, productID)
+	}
+	return s.db.Model(&models.Inventory{}).Where("product_id = ?", productID)
+}
+
+// ReserveStock atomically decrements stock, failing if not enough is available. A bundle
+// holds no stock of its own, so each of its components is reserved instead, all or none.
+// Digital products hold no stock either; only their licence key pool is checked, and keys
+// are drawn when the order is paid.
+func (s *orderService) ReserveStock(productID uint, variantID *uint, quantity int) error {
+	if variantID == nil {
+		digital, err := isDigitalProduct(s.db, productID)
+		if err != nil {
+			return err
+		}
+		if digital {
+			stock, err := digitalStock(s.db, productID)
+			if err != nil {
+				return err
+			}
+			if stock < quantity {
+				return ErrInsufficientStock
+			}
+			return nil
+		}
+		components, err := bundleComponents(s.db, productID)
+		if err != nil {
+			return err
+		}
+		if len(components) > 0 {
+			return s.db.Transaction(func(tx *gorm.DB) error {
+				txService := &orderService{db: tx}
+				for _, component := range components {
+					if component.Component == nil {
+						return ErrInsufficientStock
+					}
+					if err := txService.reserve(component.ComponentID, nil, component.Quantity*quantity); err != nil {
+						return err
+					}
+				}
+				return nil
+			})
+		}
+	}
+	return s.reserve(productID, variantID, quantity)
+}
+
+// reserve decrements a variant's or simple product's stock if enough is available
+func (s *orderService) reserve(productID uint, variantID *uint, quantity int) error {
+	result := s.stockQuery(productID, variantID).
+		Where("stock >= ?", quantity).
+		Update("stock", gorm.Expr("stock - ?", quantity))
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrInsufficientStock
+	}
+	return nil
+}
+
+// Restock returns quantity to a variant's or simple product's stock, or to each of a bundle's
+// components. Digital products hold no stock, so nothing is returned for them.
+func (s *orderService) Restock(productID uint, variantID *uint, quantity int) error {
+	if variantID == nil {
+		digital, err := isDigitalProduct(s.db, productID)
+		if err != nil || digital {
+			return err
+		}
+		components, err := bundleComponents(s.db, productID)
+		if err != nil {
+			return err
+		}
+		if len(components) > 0 {
+			return s.db.Transaction(func(tx *gorm.DB) error {
+				txService := &orderService{db: tx}
+				for _, component := range components {
+					if err := txService.restock(component.ComponentID, nil, component.Quantity*quantity); err != nil {
+						return err
+					}
+				}
+				return nil
+			})
+		}
+	}
+	return s.restock(productID, variantID, quantity)
+}
+
+// RestockItem returns quantity of an order item to stock. A bundle's components are restocked
+// as they were when it was ordered, so later changes to the bundle do not affect the return;
+// items ordered without a snapshot fall back to the bundle's current components.
+func (s *orderService) RestockItem(item *models.OrderItem, quantity int) error {
+	if item.VariantID == nil {
+		components := item.Components
+		if len(components) == 0 {
+			if err := s.db.Where("order_item_id = ?", item.ID).Find(&components).Error; err != nil {
+				return err
+			}
+		}
+		if len(components) > 0 {
+			return s.db.Transaction(func(tx *gorm.DB) error {
+				txService := &orderService{db: tx}
+				for _, component := range components {
+					if err := txService.restock(component.ProductID, nil, component.Quantity*quantity); err != nil {
+						return err
+					}
+				}
+				return nil
+			})
+		}
+	}
+	return s.Restock(item.ProductID, item.VariantID, quantity)
+}
+
+// restock adds quantity to a variant's or simple product's stock
+func (s *orderService) restock(productID uint, variantID *uint, quantity int) error {
+	return s.stockQuery(productID, variantID).
+		Update("stock", gorm.Expr("stock + ?", quantity)).Error
+}
+
+// lockAmendableOrder re-reads the order with its row locked until the transaction ends, so
+// a concurrent amendment, cancellation or shipment waits, and checks it can still be changed
+func lockAmendableOrder(tx *gorm.DB, order *models.Order) error {
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(order, order.ID).Error; err != nil {
+		return err
+	}
+	if !amendableOrderStatuses[order.Status] {
+		return ErrOrderNotAmendable
+	}
+	return nil
+}
+
+// AmendOrder applies an amendment to an unshipped order: stock is reserved or returned for
+// quantity changes, the total is recalculated, and a paid order is partially refunded or
+// charged the difference. Each amendment is recorded as a numbered revision.
+func (s *orderService) AmendOrder(order *models.Order, amendment OrderAmendment, amendedBy uint) (*models.OrderRevision, error) {
+	if !amendableOrderStatuses[order.Status] {
+		return nil, ErrOrderNotAmendable
+	}
+
+	var revision models.OrderRevision
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := lockAmendableOrder(tx, order); err != nil {
+			return err
+		}
+		txService := &orderService{db: tx}
+		var changes []string
+
+		if amendment.AddressID != nil && (order.AddressID == nil || *order.AddressID != *amendment.AddressID) {
+			var address models.Address
+			if err := tx.Where("id = ? AND user_id = ?", *amendment.AddressID, order.UserID).First(&address).Error; err != nil {
+				if errors.Is(err, gorm.ErrRecordNotFound) {
+					return ErrAddressNotFound
+				}
+				return err
+			}
+			changes = append(changes, fmt.Sprintf("Shipping address changed to #%d", address.ID))
+			order.AddressID = &address.ID
+		}
+
+		var items []models.OrderItem
+		if err := tx.Where("order_id = ?", order.ID).Find(&items).Error; err != nil {
+			return err
+		}
+		byLine := make(map[lineKey]*models.OrderItem, len(items))
+		for i := range items {
+			byLine[keyFor(items[i].ProductID, items[i].VariantID)] = &items[i]
+		}
+
+		for _, line := range amendment.Items {
+			key := keyFor(line.ProductID, line.VariantID)
+			item, exists := byLine[key]
+			if (exists && line.Quantity == item.Quantity) || (!exists && line.Quantity <= 0) {
+				continue
+			}
+			// Licence keys and downloads are issued as soon as an order is paid
+			if order.Status != "Pending" {
+				digital, err := isDigitalProduct(tx, line.ProductID)
+				if err != nil {
+					return err
+				}
+				if digital {
+					return ErrOrderNotAmendable
+				}
+			}
+
+			switch {
+			case exists:
+				if delta := line.Quantity - item.Quantity; delta > 0 {
+					if err := txService.ReserveStock(line.ProductID, line.VariantID, delta); err != nil {
+						return err
+					}
+				} else if err := txService.RestockItem(item, -delta); err != nil {
+					return err
+				}
+
+				if line.Quantity == 0 {
+					if err := tx.Delete(item).Error; err != nil {
+						return err
+					}
+					changes = append(changes, fmt.Sprintf("%s removed (was %d)", key, item.Quantity))
+					delete(byLine, key)
+				} else {
+					changes = append(changes, fmt.Sprintf("%s quantity changed from %d to %d", key, item.Quantity, line.Quantity))
+					if err := tx.Model(item).Update("quantity", line.Quantity).Error; err != nil {
+						return err
+					}
+				}
+			case line.Quantity > 0:
+				added, err := txService.newItem(line)
+				if err != nil {
+					return err
+				}
+				added.OrderID = order.ID
+				if err := tx.Create(&added).Error; err != nil {
+					return err
+				}
+				byLine[key] = &added
+				changes = append(changes, fmt.Sprintf("%s added (quantity %d)", key, line.Quantity))
+			}
+		}
+
+		if len(changes) == 0 {
+			return ErrNoChanges
+		}
+
+		newTotal, active, err := recalculateTotal(tx, order)
+		if err != nil {
+			return err
+		}
+		if active == 0 {
+			return ErrEmptyOrder
+		}
+
+		revision = models.OrderRevision{
+			OrderID:       order.ID,
+			AmendedBy:     amendedBy,
+			Changes:       strings.Join(changes, "\n"),
+			PreviousTotal: order.TotalAmount,
+			NewTotal:      newTotal,
+		}
+		revision.PaymentID, err = settleDifference(tx, order, newTotal)
+		if err != nil {
+			return err
+		}
+
+		var previous int64
+		if err := tx.Model(&models.OrderRevision{}).Where("order_id = ?", order.ID).Count(&previous).Error; err != nil {
+			return err
+		}
+		revision.Revision = int(previous) + 1
+		if err := tx.Create(&revision).Error; err != nil {
+			return err
+		}
+
+		order.TotalAmount = newTotal
+		return tx.Model(order).Updates(map[string]interface{}{
+			"total_amount": order.TotalAmount,
+			"address_id":   order.AddressID,
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &revision, nil
+}
+
+// CancelItems cancels quantities from individual order lines of an unshipped order. Exactly
+// the cancelled quantities are restocked, the total is recalculated and a paid order is
+// refunded the difference. Cancelling every remaining unit cancels the whole order.
+func (s *orderService) CancelItems(order *models.Order, lines []CancelLine, reason string, cancelledBy uint) ([]models.OrderItemCancellation, error) {
+	if !amendableOrderStatuses[order.Status] {
+		return nil, ErrOrderNotAmendable
+	}
+	if len(lines) == 0 {
+		return nil, ErrNoChanges
+	}
+
+	var cancellations []models.OrderItemCancellation
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := lockAmendableOrder(tx, order); err != nil {
+			return err
+		}
+		txService := &orderService{db: tx}
+
+		var items []models.OrderItem
+		if err := tx.Where("order_id = ?", order.ID).Find(&items).Error; err != nil {
+			return err
+		}
+		byID := make(map[uint]*models.OrderItem, len(items))
+		for i := range items {
+			byID[items[i].ID] = &items[i]
+		}
+
+		for _, line := range lines {
+			item, ok := byID[line.OrderItemID]
+			if !ok || line.Quantity <= 0 || line.Quantity > item.Quantity {
+				return ErrInvalidCancel
+			}
+			if err := txService.RestockItem(item, line.Quantity); err != nil {
+				return err
+			}
+			if err := tx.Model(item).Updates(map[string]interface{}{
+				"quantity":           item.Quantity - line.Quantity,
+				"cancelled_quantity": item.CancelledQuantity + line.Quantity,
+			}).Error; err != nil {
+				return err
+			}
+			cancellations = append(cancellations, models.OrderItemCancellation{
+				OrderID:     order.ID,
+				OrderItemID: item.ID,
+				Quantity:    line.Quantity,
+				Reason:      reason,
+				CancelledBy: cancelledBy,
+				Amount:      roundCents(item.Price * float64(line.Quantity)),
+			})
+		}
+
+		newTotal, active, err := recalculateTotal(tx, order)
+		if err != nil {
+			return err
+		}
+		if active == 0 {
+			newTotal = 0
+		}
+
+		paymentID, err := settleDifference(tx, order, newTotal)
+		if err != nil {
+			return err
+		}
+		for i := range cancellations {
+			cancellations[i].PaymentID = paymentID
+		}
+		if err := tx.Create(&cancellations).Error; err != nil {
+			return err
+		}
+
+		updates := map[string]interface{}{"total_amount": newTotal}
+		if active == 0 {
+			updates["status"] = "Cancelled"
+			if err := NewLoyaltyServiceWithDB(tx).ReverseOrderPoints(order.ID); err != nil {
+				return err
+			}
+		}
+		return tx.Model(order).Updates(updates).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return cancellations, nil
+}
+
+// recalculateTotal prices the order's remaining items, returning the new total and
+// the number of items that still have a quantity
+func recalculateTotal(tx *gorm.DB, order *models.Order) (float64, int, error) {
+	var items []models.OrderItem
+	if err := tx.Where("order_id = ?", order.ID).Find(&items).Error; err != nil {
+		return 0, 0, err
+	}
+
+	var subtotal float64
+	active := 0
+	for _, item := range items {
+		if item.Quantity > 0 {
+			active++
+		}
+		subtotal += item.Price * float64(item.Quantity)
+	}
+
+	total := roundCents(subtotal + order.ShippingCost - order.DiscountAmount)
+	if total < 0 {
+		total = 0
+	}
+	return total, active, nil
+}
+
+// settleDifference charges or refunds a paid order for a change in its total,
+// returning the resulting payment's ID
+func settleDifference(tx *gorm.DB, order *models.Order, newTotal float64) (*uint, error) {
+	delta := roundCents(newTotal - order.TotalAmount)
+	if order.Status != "Paid" || delta == 0 {
+		return nil, nil
+	}
+
+	payments := NewPaymentServiceWithDB(tx)
+	var payment *models.Payment
+	var err error
+	if delta > 0 {
+		payment, err = payments.ChargeAdditional(order, delta)
+	} else {
+		payment, err = payments.RefundPartial(order, -delta)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &payment.ID, nil
+}
@@ -0,0 +1,243 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/geoo115/Ecommerce/db"
+	"github.com/geoo115/Ecommerce/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func createStockedProduct(t *testing.T, name string, price float64, stock int) models.Product {
+	t.Helper()
+	product := models.Product{Name: name, Price: price}
+	if err := db.DB.Create(&product).Error; err != nil {
+		t.Fatalf("failed to create product: %v", err)
+	}
+	db.DB.Create(&models.Inventory{ProductID: product.ID, Stock: stock})
+	return product
+}
+
+func TestOrderService_CreateAndCancelOrder(t *testing.T) {
+	originalDB := db.DB
+	testDB := db.SetupTestDB(t)
+	db.DB = testDB
+	defer func() { db.DB = originalDB }()
+
+	user := models.User{Username: "orderer", Email: "orderer@example.com"}
+	testDB.Create(&user)
+	product := createStockedProduct(t, "Coffee", 12.5, 5)
+
+	service := NewOrderService()
+	order := models.Order{UserID: user.ID}
+	assert.NoError(t, service.CreateOrder(&order, []OrderLine{{ProductID: product.ID, Quantity: 2}}))
+	assert.Equal(t, 25.0, order.TotalAmount)
+	assert.Equal(t, "Pending", order.Status)
+
+	var inv models.Inventory
+	testDB.Where("product_id = ?", product.ID).First(&inv)
+	assert.Equal(t, 3, inv.Stock)
+
+	assert.NoError(t, service.CancelOrder(&order))
+	testDB.Where("product_id = ?", product.ID).First(&inv)
+	assert.Equal(t, 5, inv.Stock)
+	assert.Equal(t, "Cancelled", order.Status)
+}
+
+func TestOrderService_CreateOrder_Errors(t *testing.T) {
+	originalDB := db.DB
+	testDB := db.SetupTestDB(t)
+	db.DB = testDB
+	defer func() { db.DB = originalDB }()
+
+	user := models.User{Username: "shortage", Email: "shortage@example.com"}
+	testDB.Create(&user)
+	product := createStockedProduct(t, "Tea", 4, 1)
+
+	service := NewOrderService()
+	err := service.CreateOrder(&models.Order{UserID: user.ID}, []OrderLine{{ProductID: product.ID, Quantity: 2}})
+	assert.ErrorIs(t, err, ErrInsufficientStock)
+
+	err = service.CreateOrder(&models.Order{UserID: user.ID}, []OrderLine{{ProductID: 9999, Quantity: 1}})
+	assert.ErrorIs(t, err, ErrProductNotFound)
+
+	var count int64
+	testDB.Model(&models.Order{}).Count(&count)
+	assert.Equal(t, int64(0), count)
+}
+
+func TestOrderService_AmendOrder(t *testing.T) {
+	originalDB := db.DB
+	testDB := db.SetupTestDB(t)
+	db.DB = testDB
+	defer func() { db.DB = originalDB }()
+
+	user := models.User{Username: "amender", Email: "amender@example.com"}
+	testDB.Create(&user)
+	home := models.Address{UserID: user.ID, Address: "1 Main St", City: "Town", ZipCode: "11111"}
+	work := models.Address{UserID: user.ID, Address: "2 Office Rd", City: "Town", ZipCode: "22222"}
+	testDB.Create(&home)
+	testDB.Create(&work)
+	mug := createStockedProduct(t, "Mug", 10, 10)
+	plate := createStockedProduct(t, "Plate", 5, 10)
+	bowl := createStockedProduct(t, "Bowl", 7, 10)
+
+	service := NewOrderService()
+	order := models.Order{UserID: user.ID, AddressID: &home.ID}
+	assert.NoError(t, service.CreateOrder(&order, []OrderLine{{ProductID: mug.ID, Quantity: 2}, {ProductID: plate.ID, Quantity: 1}}))
+	assert.NoError(t, testDB.Create(&models.Payment{OrderID: order.ID, PaymentMode: "card", Amount: 25, Status: "Success"}).Error)
+	testDB.Model(&order).Update("status", "Paid")
+
+	// Bump a quantity, drop an item, add a new one and move the address
+	revision, err := service.AmendOrder(&order, OrderAmendment{
+		AddressID: &work.ID,
+		Items: []OrderLine{
+			{ProductID: mug.ID, Quantity: 3},
+			{ProductID: plate.ID, Quantity: 0},
+			{ProductID: bowl.ID, Quantity: 1},
+		},
+	}, user.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, revision.Revision)
+	assert.Equal(t, 25.0, revision.PreviousTotal)
+	assert.Equal(t, 37.0, revision.NewTotal)
+	assert.Contains(t, revision.Changes, "removed")
+	assert.Equal(t, work.ID, *order.AddressID)
+
+	stock := func(productID uint) int {
+		var inv models.Inventory
+		testDB.Where("product_id = ?", productID).First(&inv)
+		return inv.Stock
+	}
+	assert.Equal(t, 7, stock(mug.ID))
+	assert.Equal(t, 10, stock(plate.ID))
+	assert.Equal(t, 9, stock(bowl.ID))
+
+	var charge models.Payment
+	testDB.First(&charge, *revision.PaymentID)
+	assert.Equal(t, "Success", charge.Status)
+	assert.Equal(t, 12.0, charge.Amount)
+	assert.Equal(t, "card", charge.PaymentMode)
+
+	// Reducing the order refunds the difference
+	revision, err = service.AmendOrder(&order, OrderAmendment{Items: []OrderLine{{ProductID: mug.ID, Quantity: 1}}}, user.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, revision.Revision)
+	var refund models.Payment
+	testDB.First(&refund, *revision.PaymentID)
+	assert.Equal(t, "Refunded", refund.Status)
+	assert.Equal(t, 20.0, refund.Amount)
+
+	_, err = service.AmendOrder(&order, OrderAmendment{Items: []OrderLine{{ProductID: mug.ID, Quantity: 1}}}, user.ID)
+	assert.ErrorIs(t, err, ErrNoChanges)
+
+	_, err = service.AmendOrder(&order, OrderAmendment{Items: []OrderLine{{ProductID: mug.ID, Quantity: 0}, {ProductID: bowl.ID, Quantity: 0}}}, user.ID)
+	assert.ErrorIs(t, err, ErrEmptyOrder)
+
+	_, err = service.AmendOrder(&order, OrderAmendment{Items: []OrderLine{{ProductID: bowl.ID, Quantity: 50}}}, user.ID)
+	assert.ErrorIs(t, err, ErrInsufficientStock)
+
+	order.Status = "Shipped"
+	_, err = service.AmendOrder(&order, OrderAmendment{Items: []OrderLine{{ProductID: mug.ID, Quantity: 2}}}, user.ID)
+	assert.ErrorIs(t, err, ErrOrderNotAmendable)
+
+	// An order that shipped after the caller loaded it is not amended either
+	mugStock := stock(mug.ID)
+	testDB.Model(&models.Order{}).Where("id = ?", order.ID).Update("status", "Shipped")
+	order.Status = "Paid"
+	_, err = service.AmendOrder(&order, OrderAmendment{Items: []OrderLine{{ProductID: mug.ID, Quantity: 2}}}, user.ID)
+	assert.ErrorIs(t, err, ErrOrderNotAmendable)
+	assert.Equal(t, mugStock, stock(mug.ID))
+}
+
+func TestOrderService_AmendOrder_DeclinedChargeRollsBack(t *testing.T) {
+	originalDB := db.DB
+	testDB := db.SetupTestDB(t)
+	db.DB = testDB
+	originalGateway := Gateway
+	Gateway = decliningGateway{}
+	defer func() {
+		db.DB = originalDB
+		Gateway = originalGateway
+	}()
+
+	product := createStockedProduct(t, "Vase", 20, 5)
+	service := NewOrderService()
+	order := models.Order{UserID: 1}
+	assert.NoError(t, service.CreateOrder(&order, []OrderLine{{ProductID: product.ID, Quantity: 1}}))
+	testDB.Model(&order).Update("status", "Paid")
+	testDB.Create(&models.Payment{OrderID: order.ID, PaymentMode: "card", Amount: 20, Status: "Success"})
+
+	_, err := service.AmendOrder(&order, OrderAmendment{Items: []OrderLine{{ProductID: product.ID, Quantity: 3}}}, 1)
+	assert.ErrorIs(t, err, ErrPaymentDeclined)
+
+	var inv models.Inventory
+	testDB.Where("product_id = ?", product.ID).First(&inv)
+	assert.Equal(t, 4, inv.Stock)
+
+	var stored models.Order
+	testDB.First(&stored, order.ID)
+	assert.Equal(t, 20.0, stored.TotalAmount)
+}
+
+func TestOrderService_CancelItems(t *testing.T) {
+	originalDB := db.DB
+	testDB := db.SetupTestDB(t)
+	db.DB = testDB
+	defer func() { db.DB = originalDB }()
+
+	user := models.User{Username: "canceller", Email: "canceller@example.com"}
+	testDB.Create(&user)
+	pen := createStockedProduct(t, "Pen", 2, 10)
+	pad := createStockedProduct(t, "Pad", 5, 10)
+
+	service := NewOrderService()
+	order := models.Order{UserID: user.ID}
+	assert.NoError(t, service.CreateOrder(&order, []OrderLine{{ProductID: pen.ID, Quantity: 4}, {ProductID: pad.ID, Quantity: 1}}))
+	testDB.Create(&models.Payment{OrderID: order.ID, PaymentMode: "card", Amount: 13, Status: "Success"})
+	testDB.Model(&order).Update("status", "Paid")
+	penItem, padItem := order.Items[0], order.Items[1]
+
+	cancellations, err := service.CancelItems(&order, []CancelLine{{OrderItemID: penItem.ID, Quantity: 3}}, "Ordered too many", user.ID)
+	assert.NoError(t, err)
+	if assert.Len(t, cancellations, 1) {
+		assert.Equal(t, 6.0, cancellations[0].Amount)
+		assert.Equal(t, "Ordered too many", cancellations[0].Reason)
+		assert.NotNil(t, cancellations[0].PaymentID)
+	}
+	assert.Equal(t, 7.0, order.TotalAmount)
+	assert.Equal(t, "Paid", order.Status)
+
+	var inv models.Inventory
+	testDB.Where("product_id = ?", pen.ID).First(&inv)
+	assert.Equal(t, 9, inv.Stock)
+
+	var item models.OrderItem
+	testDB.First(&item, penItem.ID)
+	assert.Equal(t, 1, item.Quantity)
+	assert.Equal(t, 3, item.CancelledQuantity)
+
+	_, err = service.CancelItems(&order, []CancelLine{{OrderItemID: penItem.ID, Quantity: 2}}, "Too many", user.ID)
+	assert.ErrorIs(t, err, ErrInvalidCancel)
+
+	// Cancelling the rest cancels the order and refunds what remains
+	_, err = service.CancelItems(&order, []CancelLine{{OrderItemID: penItem.ID, Quantity: 1}, {OrderItemID: padItem.ID, Quantity: 1}}, "Changed mind", user.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "Cancelled", order.Status)
+	assert.Equal(t, 0.0, order.TotalAmount)
+
+	var refunded float64
+	testDB.Model(&models.Payment{}).Where("order_id = ? AND status = ?", order.ID, "Refunded").Select("SUM(amount)").Scan(&refunded)
+	assert.Equal(t, 13.0, refunded)
+
+	_, err = service.CancelItems(&order, []CancelLine{{OrderItemID: padItem.ID, Quantity: 1}}, "Again", user.ID)
+	assert.ErrorIs(t, err, ErrOrderNotAmendable)
+
+	// A stale copy of the order still sees the stored status
+	stale := order
+	stale.Status = "Paid"
+	_, err = service.CancelItems(&stale, []CancelLine{{OrderItemID: padItem.ID, Quantity: 1}}, "Again", user.ID)
+	assert.ErrorIs(t, err, ErrOrderNotAmendable)
+	testDB.Model(&models.Payment{}).Where("order_id = ? AND status = ?", order.ID, "Refunded").Select("SUM(amount)").Scan(&refunded)
+	assert.Equal(t, 13.0, refunded)
+}
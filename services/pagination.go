@@ -0,0 +1,279 @@
+package services
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/geoo115/Ecommerce/utils"
+	"gorm.io/gorm"
+)
+
+// ErrInvalidCursor is returned for cursors that were not issued for the listing being paged
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// SortKey is one column of a keyset ordering
+type SortKey struct {
+	Column string // SQL expression, e.g. "orders.created_at"
+	Desc   bool
+	Time   bool // Values are timestamps
+}
+
+// PageOrder orders a listing for cursor pagination. Keys must end with a unique column,
+// normally the primary key, so every row has a distinct position and a cursor stays valid
+// when rows are inserted before it. Without keys the query keeps its own order and cursors
+// are positional, for results ranked by relevance.
+type PageOrder struct {
+	Table string // Table the keys are read from for the first and last row of a page
+	Keys  []SortKey
+}
+
+// OrderByID pages through a table in insertion order
+func OrderByID(table string) PageOrder {
+	return PageOrder{Table: table, Keys: []SortKey{{Column: table + ".id"}}}
+}
+
+// NewestFirst pages through a table by creation time, newest first
+func NewestFirst(table string) PageOrder {
+	return PageOrder{Table: table, Keys: []SortKey{
+		{Column: table + ".created_at", Desc: true, Time: true},
+		{Column: table + ".id", Desc: true},
+	}}
+}
+
+// PageRequest asks for up to Limit rows after, or before, the row a cursor points to
+type PageRequest struct {
+	Limit        int
+	Cursor       string
+	Offset       int // Rows to skip when there is no cursor, for the legacy page parameter
+	IncludeTotal bool
+}
+
+// pageCursor is the decoded form of a cursor. Keyset cursors hold the sort values of the row
+// they point to; positional cursors hold an offset.
+type pageCursor struct {
+	Order      string        `json:"k"` // Signature of the PageOrder that issued the cursor
+	Values     []interface{} `json:"v,omitempty"`
+	Before     bool          `json:"b,omitempty"` // The page ends before the row instead of starting after it
+	Positional bool          `json:"p,omitempty"`
+	Offset     int           `json:"o,omitempty"`
+}
+
+// FetchPage loads one page of query into dest, a pointer to a slice of models, and describes
+// the neighbouring pages. Scopes such as preloads apply to the page but not to the total count.
+func FetchPage(query *gorm.DB, order PageOrder, request PageRequest, dest interface{}, scopes ...func(*gorm.DB) *gorm.DB) (*utils.Pagination, error) {
+	cursor, err := decodeCursor(request.Cursor, order)
+	if err != nil {
+		return nil, err
+	}
+
+	pagination := &utils.Pagination{Limit: request.Limit}
+	if request.IncludeTotal {
+		var total int64
+		if err := query.Session(&gorm.Session{}).Model(dest).Count(&total).Error; err != nil {
+			return nil, err
+		}
+		pagination.Total = &total
+	}
+
+	page := query.Session(&gorm.Session{}).Scopes(scopes...)
+	before := cursor != nil && cursor.Before
+	offset := request.Offset
+	switch {
+	case len(order.Keys) == 0:
+		if cursor != nil {
+			offset = cursor.Offset
+		}
+		page = page.Offset(offset)
+	case cursor != nil:
+		condition, vars := keysetCondition(order.Keys, cursor.Values, before)
+		page = page.Where(condition, vars...)
+		offset = 0
+	default:
+		page = page.Offset(offset)
+	}
+	for _, key := range order.Keys {
+		direction := " ASC"
+		if key.Desc != before {
+			direction = " DESC"
+		}
+		page = page.Order(key.Column + direction)
+	}
+	if err := page.Limit(request.Limit + 1).Find(dest).Error; err != nil {
+		return nil, err
+	}
+
+	// One row beyond the limit shows whether there is more in the direction of travel
+	rows := reflect.ValueOf(dest).Elem()
+	more := rows.Len() > request.Limit
+	if more {
+		rows.Set(rows.Slice(0, request.Limit))
+	}
+	if before {
+		swap := reflect.Swapper(rows.Interface())
+		for i, j := 0, rows.Len()-1; i < j; i, j = i+1, j-1 {
+			swap(i, j)
+		}
+	}
+
+	if len(order.Keys) == 0 {
+		if more {
+			pagination.NextCursor = encodeCursor(order, pageCursor{Positional: true, Offset: offset + request.Limit})
+		}
+		if offset > 0 {
+			pagination.PrevCursor = encodeCursor(order, pageCursor{Positional: true, Offset: max(offset-request.Limit, 0)})
+		}
+		return pagination, nil
+	}
+	if rows.Len() == 0 {
+		return pagination, nil
+	}
+
+	hasNext, hasPrev := more, cursor != nil || offset > 0
+	if before {
+		hasNext, hasPrev = true, more
+	}
+	if hasNext {
+		values, err := sortValues(query, order, rows.Index(rows.Len()-1))
+		if err != nil {
+			return nil, err
+		}
+		pagination.NextCursor = encodeCursor(order, pageCursor{Values: values})
+	}
+	if hasPrev {
+		values, err := sortValues(query, order, rows.Index(0))
+		if err != nil {
+			return nil, err
+		}
+		pagination.PrevCursor = encodeCursor(order, pageCursor{Values: values, Before: true})
+	}
+	return pagination, nil
+}
+
+// keysetCondition matches rows after the given sort values, or before them when before is set:
+// (k1 > v1) OR (k1 = v1 AND k2 > v2) and so on, with the comparison flipped for descending keys
+func keysetCondition(keys []SortKey, values []interface{}, before bool) (string, []interface{}) {
+	var alternatives []string
+	var vars []interface{}
+	for i, key := range keys {
+		var conditions []string
+		for _, equal := range keys[:i] {
+			conditions = append(conditions, equal.Column+" = ?")
+		}
+		operator := " > ?"
+		if key.Desc != before {
+			operator = " < ?"
+		}
+		conditions = append(conditions, key.Column+operator)
+		vars = append(vars, values[:i+1]...)
+		alternatives = append(alternatives, "("+strings.Join(conditions, " AND ")+")")
+	}
+	return "(" + strings.Join(alternatives, " OR ") + ")", vars
+}
+
+// sortValues reads the sort key values of a row on the page
+func sortValues(query *gorm.DB, order PageOrder, row reflect.Value) ([]interface{}, error) {
+	columns := make([]string, len(order.Keys))
+	for i, key := range order.Keys {
+		columns[i] = key.Column
+	}
+	id := reflect.Indirect(row).FieldByName("ID").Interface()
+
+	rows, err := query.Session(&gorm.Session{NewDB: true}).Table(order.Table).
+		Select(strings.Join(columns, ", ")).Where(order.Table+".id = ?", id).Rows()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	if !rows.Next() {
+		return nil, fmt.Errorf("row %v not found for cursor", id)
+	}
+
+	values := make([]interface{}, len(columns))
+	targets := make([]interface{}, len(columns))
+	for i := range values {
+		targets[i] = &values[i]
+	}
+	if err := rows.Scan(targets...); err != nil {
+		return nil, err
+	}
+	for i, value := range values {
+		if raw, ok := value.([]byte); ok {
+			values[i] = string(raw)
+		}
+	}
+	return values, rows.Err()
+}
+
+// orderSignature identifies an ordering so cursors cannot be replayed against another one
+func orderSignature(order PageOrder) string {
+	hash := fnv.New32a()
+	for _, key := range order.Keys {
+		fmt.Fprintf(hash, "%s:%t;", key.Column, key.Desc)
+	}
+	return fmt.Sprintf("%x", hash.Sum32())
+}
+
+// encodeCursor renders a cursor as opaque URL-safe text
+func encodeCursor(order PageOrder, cursor pageCursor) string {
+	cursor.Order = orderSignature(order)
+	encoded, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(encoded)
+}
+
+// decodeCursor parses a cursor issued for order, returning nil when there is none
+func decodeCursor(text string, order PageOrder) (*pageCursor, error) {
+	if text == "" {
+		return nil, nil
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(text)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+	var cursor pageCursor
+	if err := decoder.Decode(&cursor); err != nil || cursor.Order != orderSignature(order) {
+		return nil, ErrInvalidCursor
+	}
+
+	if cursor.Positional != (len(order.Keys) == 0) || cursor.Offset < 0 {
+		return nil, ErrInvalidCursor
+	}
+	if cursor.Positional {
+		return &cursor, nil
+	}
+	if len(cursor.Values) != len(order.Keys) {
+		return nil, ErrInvalidCursor
+	}
+	for i, key := range order.Keys {
+		switch value := cursor.Values[i].(type) {
+		case json.Number:
+			// Integers stay integers so they compare exactly
+			if n, err := value.Int64(); err == nil {
+				cursor.Values[i] = n
+			} else if f, err := value.Float64(); err == nil {
+				cursor.Values[i] = f
+			} else {
+				return nil, ErrInvalidCursor
+			}
+		case string:
+			if key.Time {
+				t, err := time.Parse(time.RFC3339Nano, value)
+				if err != nil {
+					return nil, ErrInvalidCursor
+				}
+				cursor.Values[i] = t
+			}
+		case nil:
+			return nil, ErrInvalidCursor
+		}
+	}
+	return &cursor, nil
+}
@@ -0,0 +1,117 @@
+package services
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/geoo115/Ecommerce/db"
+	"github.com/geoo115/Ecommerce/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFetchPage(t *testing.T) {
+	originalDB := db.DB
+	testDB := db.SetupTestDB(t)
+	db.DB = testDB
+	defer func() { db.DB = originalDB }()
+
+	// Two products share each price so ties are broken by ID
+	start := time.Now().Add(-time.Hour)
+	for i := 1; i <= 7; i++ {
+		product := models.Product{Name: fmt.Sprintf("P%d", i), Price: float64((i + 1) / 2)}
+		testDB.Create(&product)
+		testDB.Model(&product).Update("created_at", start.Add(time.Duration(i)*time.Minute))
+	}
+
+	page := func(order PageOrder, request PageRequest) ([]string, *pageInfo) {
+		t.Helper()
+		var products []models.Product
+		pagination, err := FetchPage(testDB.Model(&models.Product{}), order, request, &products)
+		if !assert.NoError(t, err) {
+			return nil, nil
+		}
+		names := make([]string, len(products))
+		for i, product := range products {
+			names[i] = product.Name
+		}
+		return names, &pageInfo{next: pagination.NextCursor, prev: pagination.PrevCursor, total: pagination.Total}
+	}
+
+	t.Run("ForwardAndBack", func(t *testing.T) {
+		byPrice := PageOrder{Table: "products", Keys: productSortKeys[ProductSortPriceDesc]}
+		names, info := page(byPrice, PageRequest{Limit: 3, IncludeTotal: true})
+		assert.Equal(t, []string{"P7", "P5", "P6"}, names)
+		assert.Empty(t, info.prev)
+		if assert.NotNil(t, info.total) {
+			assert.Equal(t, int64(7), *info.total)
+		}
+
+		names, info = page(byPrice, PageRequest{Limit: 3, Cursor: info.next})
+		assert.Equal(t, []string{"P3", "P4", "P1"}, names)
+		second := info
+
+		names, info = page(byPrice, PageRequest{Limit: 3, Cursor: second.next})
+		assert.Equal(t, []string{"P2"}, names)
+		assert.Empty(t, info.next)
+
+		names, info = page(byPrice, PageRequest{Limit: 3, Cursor: second.prev})
+		assert.Equal(t, []string{"P7", "P5", "P6"}, names)
+		assert.Empty(t, info.prev)
+		assert.NotEmpty(t, info.next)
+	})
+
+	t.Run("StableUnderInserts", func(t *testing.T) {
+		newest := NewestFirst("products")
+		names, info := page(newest, PageRequest{Limit: 2})
+		assert.Equal(t, []string{"P7", "P6"}, names)
+
+		// A product added after the first page does not shift the next one
+		testDB.Create(&models.Product{Name: "P8", Price: 9})
+		names, _ = page(newest, PageRequest{Limit: 2, Cursor: info.next})
+		assert.Equal(t, []string{"P5", "P4"}, names)
+		testDB.Where("name = ?", "P8").Delete(&models.Product{})
+	})
+
+	t.Run("LegacyOffset", func(t *testing.T) {
+		names, info := page(OrderByID("products"), PageRequest{Limit: 3, Offset: 3})
+		assert.Equal(t, []string{"P4", "P5", "P6"}, names)
+		assert.NotEmpty(t, info.prev)
+		names, _ = page(OrderByID("products"), PageRequest{Limit: 3, Cursor: info.next})
+		assert.Equal(t, []string{"P7"}, names)
+	})
+
+	t.Run("Positional", func(t *testing.T) {
+		ranked := PageOrder{}
+		var products []models.Product
+		pagination, err := FetchPage(testDB.Model(&models.Product{}).Order("name DESC"), ranked, PageRequest{Limit: 4}, &products)
+		assert.NoError(t, err)
+		assert.Len(t, products, 4)
+		assert.Empty(t, pagination.PrevCursor)
+
+		products = nil
+		pagination, err = FetchPage(testDB.Model(&models.Product{}).Order("name DESC"), ranked, PageRequest{Limit: 4, Cursor: pagination.NextCursor}, &products)
+		assert.NoError(t, err)
+		if assert.Len(t, products, 3) {
+			assert.Equal(t, "P3", products[0].Name)
+		}
+		assert.Empty(t, pagination.NextCursor)
+		assert.NotEmpty(t, pagination.PrevCursor)
+	})
+
+	t.Run("InvalidCursor", func(t *testing.T) {
+		_, info := page(OrderByID("products"), PageRequest{Limit: 2})
+		var products []models.Product
+		for _, cursor := range []string{"not-a-cursor!", "e30", info.next} {
+			// The last cursor was issued for a different ordering
+			_, err := FetchPage(testDB.Model(&models.Product{}), NewestFirst("products"), PageRequest{Limit: 2, Cursor: cursor}, &products)
+			assert.ErrorIs(t, err, ErrInvalidCursor, cursor)
+		}
+	})
+}
+
+// pageInfo is the part of a page's pagination the tests follow
+type pageInfo struct {
+	next, prev string
+	total      *int64
+}
@@ -0,0 +1,200 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/geoo115/Ecommerce/db"
+	"github.com/geoo115/Ecommerce/models"
+	"github.com/geoo115/Ecommerce/utils"
+	"gorm.io/gorm"
+)
+
+// ErrPaymentDeclined is returned when the gateway refuses a charge
+var (
+	ErrPaymentDeclined = errors.New("payment declined")
+	ErrOrderNotPayable = errors.New("only pending orders can be paid")
+	ErrNoPayment       = errors.New("order has no successful payment")
+)
+
+// PaymentGateway charges and refunds orders through an external payment provider
+type PaymentGateway interface {
+	Charge(order *models.Order, method string, amount float64) error
+	Refund(order *models.Order, method string, amount float64) error
+}
+
+// manualGateway accepts every charge and refund; used until a real provider is integrated
+type manualGateway struct{}
+
+// Charge always succeeds for the manual gateway
+func (manualGateway) Charge(order *models.Order, method string, amount float64) error {
+	return nil
+}
+
+// Refund always succeeds for the manual gateway
+func (manualGateway) Refund(order *models.Order, method string, amount float64) error {
+	return nil
+}
+
+// Gateway is the payment provider used for all charges
+var Gateway PaymentGateway = manualGateway{}
+
+// PaymentService interface defines payment business logic
+type PaymentService interface {
+	ChargeOrder(order *models.Order, method string) (*models.Payment, error)
+	ChargeAdditional(order *models.Order, amount float64) (*models.Payment, error)
+	RefundPartial(order *models.Order, amount float64) (*models.Payment, error)
+}
+
+// paymentService implements PaymentService interface
+type paymentService struct {
+	db *gorm.DB
+}
+
+// NewPaymentService creates a new payment service instance
+func NewPaymentService() PaymentService {
+	return NewPaymentServiceWithDB(db.DB)
+}
+
+// NewPaymentServiceWithDB creates a payment service bound to a specific database handle
+func NewPaymentServiceWithDB(database *gorm.DB) PaymentService {
+	return &paymentService{db: database}
+}
+
+// ChargeOrder charges the order total through the gateway, records the payment
+// and marks the order paid. Declined charges are recorded as failed payments.
+// Licence keys for digital items are drawn with the payment, and an order with
+// nothing to ship is marked delivered straight away. Only pending orders can be charged.
+func (s *paymentService) ChargeOrder(order *models.Order, method string) (*models.Payment, error) {
+	if order.Status != "Pending" {
+		return nil, ErrOrderNotPayable
+	}
+	if err := ensureLicenceKeys(s.db, order.ID); err != nil {
+		return nil, err
+	}
+
+	payment := models.Payment{
+		OrderID:     order.ID,
+		PaymentMode: method,
+		Amount:      order.TotalAmount,
+		Status:      "Success",
+	}
+
+	if err := Gateway.Charge(order, method, order.TotalAmount); err != nil {
+		payment.Status = "Failed"
+		if createErr := s.db.Create(&payment).Error; createErr != nil {
+			return nil, createErr
+		}
+		if !errors.Is(err, ErrPaymentDeclined) {
+			err = fmt.Errorf("%w: %v", ErrPaymentDeclined, err)
+		}
+		return &payment, err
+	}
+
+	digitalOnly := false
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		// Claim the order so a concurrent charge of it fails
+		result := tx.Model(&models.Order{}).Where("id = ? AND status = ?", order.ID, "Pending").Update("status", "Paid")
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrOrderNotPayable
+		}
+		if err := tx.Create(&payment).Error; err != nil {
+			return err
+		}
+		var err error
+		if digitalOnly, err = issueDigitalGoods(tx, order.ID, time.Now()); err != nil {
+			return err
+		}
+		if digitalOnly {
+			return tx.Model(&models.Order{}).Where("id = ?", order.ID).Update("status", "Delivered").Error
+		}
+		return nil
+	})
+	if err != nil {
+		// The charge went through but could not be recorded, for instance because the order was
+		// paid concurrently or its licence keys ran out, so give the money back
+		if refundErr := Gateway.Refund(order, method, order.TotalAmount); refundErr != nil {
+			utils.Warn("Failed to refund order %d after recording its payment failed: %v", order.ID, refundErr)
+		}
+		return nil, err
+	}
+	order.Status = "Paid"
+	if digitalOnly {
+		order.Status = "Delivered"
+	}
+
+	// Award loyalty points for the paid order
+	if _, err := NewLoyaltyServiceWithDB(s.db).AwardOrderPoints(order.ID); err != nil {
+		utils.Warn("Failed to award loyalty points for order %d: %v", order.ID, err)
+	}
+
+	return &payment, nil
+}
+
+// ChargeAdditional charges an extra amount on an already paid order using its original payment method
+func (s *paymentService) ChargeAdditional(order *models.Order, amount float64) (*models.Payment, error) {
+	method, err := s.originalMethod(order.ID)
+	if err != nil {
+		return nil, err
+	}
+	payment := models.Payment{
+		OrderID:     order.ID,
+		PaymentMode: method,
+		Amount:      amount,
+		Status:      "Success",
+	}
+
+	if err := Gateway.Charge(order, payment.PaymentMode, amount); err != nil {
+		payment.Status = "Failed"
+		if createErr := s.db.Create(&payment).Error; createErr != nil {
+			return nil, createErr
+		}
+		if !errors.Is(err, ErrPaymentDeclined) {
+			err = fmt.Errorf("%w: %v", ErrPaymentDeclined, err)
+		}
+		return &payment, err
+	}
+
+	if err := s.db.Create(&payment).Error; err != nil {
+		return nil, err
+	}
+	return &payment, nil
+}
+
+// RefundPartial refunds part of a paid order to its original payment method
+func (s *paymentService) RefundPartial(order *models.Order, amount float64) (*models.Payment, error) {
+	method, err := s.originalMethod(order.ID)
+	if err != nil {
+		return nil, err
+	}
+	refund := models.Payment{
+		OrderID:     order.ID,
+		PaymentMode: method,
+		Amount:      amount,
+		Status:      "Refunded",
+	}
+
+	if err := Gateway.Refund(order, refund.PaymentMode, amount); err != nil {
+		return nil, err
+	}
+	if err := s.db.Create(&refund).Error; err != nil {
+		return nil, err
+	}
+	return &refund, nil
+}
+
+// originalMethod returns the payment method of the order's first successful payment
+func (s *paymentService) originalMethod(orderID uint) (string, error) {
+	var original models.Payment
+	if err := s.db.Where("order_id = ? AND status = ?", orderID, "Success").Order("id").First(&original).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return "", ErrNoPayment
+		}
+		return "", err
+	}
+	return original.PaymentMode, nil
+}
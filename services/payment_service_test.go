@@ -0,0 +1,117 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/geoo115/Ecommerce/db"
+	"github.com/geoo115/Ecommerce/models"
+	"github.com/stretchr/testify/assert"
+)
+
+// decliningGateway rejects every charge
+type decliningGateway struct{}
+
+func (decliningGateway) Charge(order *models.Order, method string, amount float64) error {
+	return errors.New("card expired")
+}
+
+func (decliningGateway) Refund(order *models.Order, method string, amount float64) error {
+	return nil
+}
+
+// recordingGateway accepts every charge and refund, counting them
+type recordingGateway struct {
+	charges, refunds int
+}
+
+func (g *recordingGateway) Charge(order *models.Order, method string, amount float64) error {
+	g.charges++
+	return nil
+}
+
+func (g *recordingGateway) Refund(order *models.Order, method string, amount float64) error {
+	g.refunds++
+	return nil
+}
+
+func TestPaymentService_ChargeOrder(t *testing.T) {
+	originalDB := db.DB
+	testDB := db.SetupTestDB(t)
+	db.DB = testDB
+	defer func() { db.DB = originalDB }()
+
+	user := models.User{Username: "payer", Email: "payer@example.com"}
+	testDB.Create(&user)
+	order := createLoyaltyOrder(t, user.ID, 0, 20, 2, "Pending")
+
+	payment, err := NewPaymentService().ChargeOrder(&order, "card")
+	assert.NoError(t, err)
+	assert.Equal(t, "Success", payment.Status)
+
+	var stored models.Order
+	testDB.First(&stored, order.ID)
+	assert.Equal(t, "Paid", stored.Status)
+
+	balance, _ := NewLoyaltyService().GetBalance(user.ID)
+	assert.Equal(t, 40, balance)
+}
+
+func TestPaymentService_ChargeOrder_Declined(t *testing.T) {
+	originalDB := db.DB
+	testDB := db.SetupTestDB(t)
+	db.DB = testDB
+	originalGateway := Gateway
+	Gateway = decliningGateway{}
+	defer func() {
+		db.DB = originalDB
+		Gateway = originalGateway
+	}()
+
+	order := models.Order{UserID: 1, TotalAmount: 15, Status: "Pending"}
+	testDB.Create(&order)
+
+	payment, err := NewPaymentService().ChargeOrder(&order, "card")
+	assert.ErrorIs(t, err, ErrPaymentDeclined)
+	assert.Equal(t, "Failed", payment.Status)
+
+	var stored models.Order
+	testDB.First(&stored, order.ID)
+	assert.Equal(t, "Pending", stored.Status)
+}
+
+func TestPaymentService_ChargeOrder_OnlyPending(t *testing.T) {
+	originalDB := db.DB
+	testDB := db.SetupTestDB(t)
+	db.DB = testDB
+	originalGateway := Gateway
+	gateway := &recordingGateway{}
+	Gateway = gateway
+	defer func() {
+		db.DB = originalDB
+		Gateway = originalGateway
+	}()
+
+	for _, status := range []string{"Paid", "Cancelled", "Refunded"} {
+		order := models.Order{UserID: 1, TotalAmount: 15, Status: status}
+		testDB.Create(&order)
+		_, err := NewPaymentService().ChargeOrder(&order, "card")
+		assert.ErrorIs(t, err, ErrOrderNotPayable)
+	}
+	assert.Equal(t, 0, gateway.charges)
+
+	// An order paid since it was loaded is refunded rather than charged twice
+	order := models.Order{UserID: 1, TotalAmount: 15, Status: "Pending"}
+	testDB.Create(&order)
+	stale := order
+	_, err := NewPaymentService().ChargeOrder(&order, "card")
+	assert.NoError(t, err)
+	_, err = NewPaymentService().ChargeOrder(&stale, "card")
+	assert.ErrorIs(t, err, ErrOrderNotPayable)
+	assert.Equal(t, 2, gateway.charges)
+	assert.Equal(t, 1, gateway.refunds)
+
+	var payments int64
+	testDB.Model(&models.Payment{}).Where("order_id = ?", order.ID).Count(&payments)
+	assert.Equal(t, int64(1), payments)
+}
@@ -0,0 +1,327 @@
+package services
+
+import (
+	"errors"
+	"time"
+
+	"github.com/geoo115/Ecommerce/db"
+	"github.com/geoo115/Ecommerce/models"
+	"github.com/geoo115/Ecommerce/utils"
+	"gorm.io/gorm"
+)
+
+// Pricing errors surfaced to handlers
+var (
+	ErrInvalidPrice          = errors.New("price must be 0 or greater than 0 and less than 999999.99")
+	ErrInvalidPriceSchedule  = errors.New("invalid price schedule")
+	ErrSaleOverlap           = errors.New("sale overlaps another sale of the product")
+	ErrPriceScheduleNotFound = errors.New("price schedule not found")
+	ErrPriceScheduleClosed   = errors.New("price schedule has already completed or been cancelled")
+)
+
+// PriceScheduleInput describes a future price change or a sale
+type PriceScheduleInput struct {
+	Kind      string
+	Price     float64
+	StartsAt  time.Time
+	EndsAt    *time.Time // Sales only
+	Reason    string
+	CreatedBy uint
+}
+
+// PriceTimeline is a product's current prices, the changes that led to them and its schedules
+type PriceTimeline struct {
+	ProductID      uint                   `json:"product_id"`
+	Price          float64                `json:"price"`
+	CompareAtPrice *float64               `json:"compare_at_price,omitempty"`
+	History        []models.PriceHistory  `json:"history"`   // Newest first
+	Schedules      []models.PriceSchedule `json:"schedules"` // By start time
+}
+
+// PriceService interface defines price changes, scheduling and history
+type PriceService interface {
+	SetPrice(product *models.Product, price float64, actorID uint, reason string) error
+	Schedule(product *models.Product, input PriceScheduleInput) (*models.PriceSchedule, error)
+	Cancel(schedule *models.PriceSchedule, actorID uint) error
+	ApplyDueSchedules(now time.Time) ([]uint, error)
+	Timeline(productID uint) (*PriceTimeline, error)
+}
+
+// priceService implements PriceService interface
+type priceService struct {
+	db *gorm.DB
+}
+
+// NewPriceService creates a new price service instance
+func NewPriceService() PriceService {
+	return NewPriceServiceWithDB(db.DB)
+}
+
+// NewPriceServiceWithDB creates a price service bound to a specific database handle
+func NewPriceServiceWithDB(database *gorm.DB) PriceService {
+	return &priceService{db: database}
+}
+
+// SetPrice changes a product's regular price and records it. During a sale the sale price
+// stays and the new price is shown as compare-at, and restored when the sale ends.
+func (s *priceService) SetPrice(product *models.Product, price float64, actorID uint, reason string) error {
+	if !validPrice(price) {
+		return ErrInvalidPrice
+	}
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		return setRegularPrice(tx, product, price, models.PriceHistory{
+			Source: models.PriceSourceManual, Reason: reason, ChangedBy: actorID,
+		})
+	})
+}
+
+// Schedule adds a price change or sale. Sales of a product may not overlap.
+func (s *priceService) Schedule(product *models.Product, input PriceScheduleInput) (*models.PriceSchedule, error) {
+	if !validPrice(input.Price) {
+		return nil, ErrInvalidPrice
+	}
+	switch {
+	case input.Kind != models.PriceChangeKind && input.Kind != models.PriceSaleKind:
+		return nil, ErrInvalidPriceSchedule
+	case input.StartsAt.IsZero():
+		return nil, ErrInvalidPriceSchedule
+	case input.Kind == models.PriceChangeKind && input.EndsAt != nil:
+		return nil, ErrInvalidPriceSchedule
+	case input.EndsAt != nil && !input.EndsAt.After(input.StartsAt):
+		return nil, ErrInvalidPriceSchedule
+	}
+
+	if input.Kind == models.PriceSaleKind {
+		query := s.db.Model(&models.PriceSchedule{}).
+			Where("product_id = ? AND kind = ? AND status IN ?", product.ID, models.PriceSaleKind,
+				[]string{models.PriceScheduleScheduled, models.PriceScheduleActive}).
+			Where("ends_at IS NULL OR ends_at > ?", input.StartsAt)
+		if input.EndsAt != nil {
+			query = query.Where("starts_at < ?", *input.EndsAt)
+		}
+		var count int64
+		if err := query.Count(&count).Error; err != nil {
+			return nil, err
+		}
+		if count > 0 {
+			return nil, ErrSaleOverlap
+		}
+	}
+
+	schedule := &models.PriceSchedule{
+		ProductID: product.ID,
+		Kind:      input.Kind,
+		Price:     input.Price,
+		StartsAt:  input.StartsAt,
+		EndsAt:    input.EndsAt,
+		Status:    models.PriceScheduleScheduled,
+		Reason:    input.Reason,
+		CreatedBy: input.CreatedBy,
+	}
+	if err := s.db.Create(schedule).Error; err != nil {
+		return nil, err
+	}
+	return schedule, nil
+}
+
+// Cancel withdraws a schedule that has not run. Cancelling a running sale ends it now.
+func (s *priceService) Cancel(schedule *models.PriceSchedule, actorID uint) error {
+	switch schedule.Status {
+	case models.PriceScheduleScheduled:
+		claimed, err := claimSchedule(s.db, schedule, models.PriceScheduleCancelled)
+		if err != nil {
+			return err
+		}
+		if !claimed {
+			return ErrPriceScheduleClosed
+		}
+		return nil
+	case models.PriceScheduleActive:
+		return s.db.Transaction(func(tx *gorm.DB) error {
+			claimed, err := claimSchedule(tx, schedule, models.PriceScheduleCancelled)
+			if err != nil {
+				return err
+			}
+			if !claimed {
+				return ErrPriceScheduleClosed
+			}
+			return endSale(tx, schedule, models.PriceHistory{Reason: "Sale cancelled", ChangedBy: actorID})
+		})
+	}
+	return ErrPriceScheduleClosed
+}
+
+// ApplyDueSchedules ends sales whose end has passed, then applies price changes and starts
+// sales that are due, oldest first. It returns the products whose prices changed so their
+// cached listings can be dropped. Each schedule is claimed by a conditional status update,
+// so several instances can run the job at once.
+func (s *priceService) ApplyDueSchedules(now time.Time) ([]uint, error) {
+	var changed []uint
+	var errs []error
+	apply := func(schedule models.PriceSchedule, status string, fn func(tx *gorm.DB) error) {
+		err := s.db.Transaction(func(tx *gorm.DB) error {
+			claimed, err := claimSchedule(tx, &schedule, status)
+			if err != nil || !claimed || fn == nil {
+				return err
+			}
+			return fn(tx)
+		})
+		if err != nil {
+			errs = append(errs, err)
+		} else if fn != nil {
+			changed = append(changed, schedule.ProductID)
+		}
+	}
+
+	var ending []models.PriceSchedule
+	if err := s.db.Where("kind = ? AND status = ? AND ends_at <= ?", models.PriceSaleKind, models.PriceScheduleActive, now).
+		Order("ends_at, id").Find(&ending).Error; err != nil {
+		return nil, err
+	}
+	for _, schedule := range ending {
+		apply(schedule, models.PriceScheduleCompleted, func(tx *gorm.DB) error {
+			return endSale(tx, &schedule, models.PriceHistory{Reason: "Sale ended", ChangedBy: schedule.CreatedBy})
+		})
+	}
+
+	var due []models.PriceSchedule
+	if err := s.db.Where("status = ? AND starts_at <= ?", models.PriceScheduleScheduled, now).
+		Order("starts_at, id").Find(&due).Error; err != nil {
+		return changed, err
+	}
+	for _, schedule := range due {
+		entry := models.PriceHistory{Reason: schedule.Reason, ChangedBy: schedule.CreatedBy, ScheduleID: &schedule.ID}
+		switch {
+		case schedule.Kind == models.PriceChangeKind:
+			apply(schedule, models.PriceScheduleCompleted, func(tx *gorm.DB) error {
+				product, err := loadPricedProduct(tx, schedule.ProductID)
+				if err != nil || product == nil {
+					return err
+				}
+				entry.Source = models.PriceSourceScheduled
+				return setRegularPrice(tx, product, schedule.Price, entry)
+			})
+		case schedule.EndsAt != nil && !schedule.EndsAt.After(now):
+			// The whole sale fell in a gap between runs, so there is nothing to apply
+			apply(schedule, models.PriceScheduleCompleted, nil)
+		default:
+			apply(schedule, models.PriceScheduleActive, func(tx *gorm.DB) error {
+				return startSale(tx, &schedule, entry)
+			})
+		}
+	}
+	return changed, errors.Join(errs...)
+}
+
+// Timeline returns a product's prices, price history and schedules
+func (s *priceService) Timeline(productID uint) (*PriceTimeline, error) {
+	product, err := loadPricedProduct(s.db, productID)
+	if err != nil {
+		return nil, err
+	}
+	if product == nil {
+		return nil, ErrProductNotFound
+	}
+	timeline := &PriceTimeline{ProductID: product.ID, Price: product.Price, CompareAtPrice: product.CompareAtPrice}
+	if err := s.db.Where("product_id = ?", productID).Order("created_at DESC, id DESC").Find(&timeline.History).Error; err != nil {
+		return nil, err
+	}
+	if err := s.db.Where("product_id = ?", productID).Order("starts_at, id").Find(&timeline.Schedules).Error; err != nil {
+		return nil, err
+	}
+	return timeline, nil
+}
+
+// setRegularPrice changes the price a product sells at outside sales and records the change.
+// entry supplies the source, reason and actor.
+func setRegularPrice(tx *gorm.DB, product *models.Product, price float64, entry models.PriceHistory) error {
+	if price == product.RegularPrice() {
+		return nil
+	}
+	entry.OldPrice = product.Price
+	updates := map[string]interface{}{}
+	if product.CompareAtPrice != nil {
+		product.CompareAtPrice = &price
+		updates["compare_at_price"] = price
+	} else {
+		product.Price = price
+		updates["price"] = price
+	}
+	if err := tx.Model(&models.Product{}).Where("id = ?", product.ID).Updates(updates).Error; err != nil {
+		return err
+	}
+	return recordPrice(tx, product, entry)
+}
+
+// startSale puts a product on sale at the schedule's price, keeping its regular price as compare-at
+func startSale(tx *gorm.DB, schedule *models.PriceSchedule, entry models.PriceHistory) error {
+	product, err := loadPricedProduct(tx, schedule.ProductID)
+	if err != nil || product == nil {
+		return err
+	}
+	regular := product.RegularPrice()
+	entry.OldPrice = product.Price
+	entry.Source = models.PriceSourceSaleStart
+	product.Price, product.CompareAtPrice = schedule.Price, &regular
+	if err := tx.Model(&models.Product{}).Where("id = ?", product.ID).
+		Updates(map[string]interface{}{"price": product.Price, "compare_at_price": regular}).Error; err != nil {
+		return err
+	}
+	return recordPrice(tx, product, entry)
+}
+
+// endSale restores a product's regular price
+func endSale(tx *gorm.DB, schedule *models.PriceSchedule, entry models.PriceHistory) error {
+	product, err := loadPricedProduct(tx, schedule.ProductID)
+	if err != nil || product == nil || product.CompareAtPrice == nil {
+		return err
+	}
+	entry.OldPrice = product.Price
+	entry.Source = models.PriceSourceSaleEnd
+	entry.ScheduleID = &schedule.ID
+	product.Price, product.CompareAtPrice = *product.CompareAtPrice, nil
+	if err := tx.Model(&models.Product{}).Where("id = ?", product.ID).
+		Updates(map[string]interface{}{"price": product.Price, "compare_at_price": nil}).Error; err != nil {
+		return err
+	}
+	return recordPrice(tx, product, entry)
+}
+
+// recordPrice stores a history entry for the product's new prices
+func recordPrice(tx *gorm.DB, product *models.Product, entry models.PriceHistory) error {
+	entry.ProductID = product.ID
+	entry.NewPrice = product.Price
+	entry.CompareAtPrice = product.CompareAtPrice
+	return tx.Create(&entry).Error
+}
+
+// claimSchedule moves a schedule on from its current status, reporting false if another
+// request or job got there first
+func claimSchedule(tx *gorm.DB, schedule *models.PriceSchedule, status string) (bool, error) {
+	result := tx.Model(&models.PriceSchedule{}).Where("id = ? AND status = ?", schedule.ID, schedule.Status).Update("status", status)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return false, nil
+	}
+	schedule.Status = status
+	return true, nil
+}
+
+// loadPricedProduct reads a product's prices, returning nil if it has been deleted
+func loadPricedProduct(tx *gorm.DB, productID uint) (*models.Product, error) {
+	var product models.Product
+	if err := tx.Select("id", "price", "compare_at_price").First(&product, productID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &product, nil
+}
+
+// validPrice accepts free products and the standard price range
+func validPrice(price float64) bool {
+	return price == 0 || utils.ValidatePrice(price)
+}
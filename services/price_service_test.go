@@ -0,0 +1,141 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/geoo115/Ecommerce/db"
+	"github.com/geoo115/Ecommerce/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPriceService(t *testing.T) {
+	originalDB := db.DB
+	testDB := db.SetupTestDB(t)
+	db.DB = testDB
+	defer func() { db.DB = originalDB }()
+
+	pricing := NewPriceServiceWithDB(testDB)
+	kettle := createStockedProduct(t, "Kettle", 40, 5)
+	start := time.Date(2026, 11, 27, 0, 0, 0, 0, time.UTC)
+	end := start.Add(72 * time.Hour)
+	prices := func() (float64, *float64) {
+		t.Helper()
+		var product models.Product
+		testDB.First(&product, kettle.ID)
+		return product.Price, product.CompareAtPrice
+	}
+
+	t.Run("Validation", func(t *testing.T) {
+		_, err := pricing.Schedule(&kettle, PriceScheduleInput{Kind: models.PriceSaleKind, Price: -1, StartsAt: start})
+		assert.ErrorIs(t, err, ErrInvalidPrice)
+		_, err = pricing.Schedule(&kettle, PriceScheduleInput{Kind: "discount", Price: 30, StartsAt: start})
+		assert.ErrorIs(t, err, ErrInvalidPriceSchedule)
+		_, err = pricing.Schedule(&kettle, PriceScheduleInput{Kind: models.PriceChangeKind, Price: 30, StartsAt: start, EndsAt: &end})
+		assert.ErrorIs(t, err, ErrInvalidPriceSchedule)
+		_, err = pricing.Schedule(&kettle, PriceScheduleInput{Kind: models.PriceSaleKind, Price: 30, StartsAt: end, EndsAt: &start})
+		assert.ErrorIs(t, err, ErrInvalidPriceSchedule)
+	})
+
+	var sale *models.PriceSchedule
+	t.Run("SaleLifecycle", func(t *testing.T) {
+		var err error
+		sale, err = pricing.Schedule(&kettle, PriceScheduleInput{Kind: models.PriceSaleKind, Price: 30, StartsAt: start, EndsAt: &end, Reason: "Black Friday", CreatedBy: 7})
+		assert.NoError(t, err)
+
+		// Overlapping sales are rejected, back-to-back ones are not
+		overlapEnd := end.Add(time.Hour)
+		_, err = pricing.Schedule(&kettle, PriceScheduleInput{Kind: models.PriceSaleKind, Price: 25, StartsAt: start.Add(time.Hour), EndsAt: &overlapEnd})
+		assert.ErrorIs(t, err, ErrSaleOverlap)
+		_, err = pricing.Schedule(&kettle, PriceScheduleInput{Kind: models.PriceSaleKind, Price: 25, StartsAt: start.Add(-time.Hour)})
+		assert.ErrorIs(t, err, ErrSaleOverlap)
+
+		changed, err := pricing.ApplyDueSchedules(start.Add(-time.Minute))
+		assert.NoError(t, err)
+		assert.Empty(t, changed)
+
+		changed, err = pricing.ApplyDueSchedules(start)
+		assert.NoError(t, err)
+		assert.Equal(t, []uint{kettle.ID}, changed)
+		price, compareAt := prices()
+		assert.Equal(t, 30.0, price)
+		if assert.NotNil(t, compareAt) {
+			assert.Equal(t, 40.0, *compareAt)
+		}
+
+		// A manual change during the sale moves the regular price the sale ends on
+		kettle.Price, kettle.CompareAtPrice = price, compareAt
+		assert.NoError(t, pricing.SetPrice(&kettle, 45, 3, "Supplier increase"))
+		price, compareAt = prices()
+		assert.Equal(t, 30.0, price)
+		assert.Equal(t, 45.0, *compareAt)
+
+		// Running the job twice applies the end once
+		changed, err = pricing.ApplyDueSchedules(end)
+		assert.NoError(t, err)
+		assert.Equal(t, []uint{kettle.ID}, changed)
+		changed, _ = pricing.ApplyDueSchedules(end)
+		assert.Empty(t, changed)
+		price, compareAt = prices()
+		assert.Equal(t, 45.0, price)
+		assert.Nil(t, compareAt)
+	})
+
+	t.Run("ScheduledChangeAndCancel", func(t *testing.T) {
+		kettle.Price, kettle.CompareAtPrice = prices()
+		change, err := pricing.Schedule(&kettle, PriceScheduleInput{Kind: models.PriceChangeKind, Price: 50, StartsAt: end.Add(time.Hour), CreatedBy: 7})
+		assert.NoError(t, err)
+		cancelled, err := pricing.Schedule(&kettle, PriceScheduleInput{Kind: models.PriceChangeKind, Price: 99, StartsAt: end.Add(2 * time.Hour)})
+		assert.NoError(t, err)
+		assert.NoError(t, pricing.Cancel(cancelled, 3))
+		assert.ErrorIs(t, pricing.Cancel(cancelled, 3), ErrPriceScheduleClosed)
+
+		_, err = pricing.ApplyDueSchedules(end.Add(3 * time.Hour))
+		assert.NoError(t, err)
+		price, _ := prices()
+		assert.Equal(t, 50.0, price)
+
+		var applied models.PriceSchedule
+		testDB.First(&applied, change.ID)
+		assert.Equal(t, models.PriceScheduleCompleted, applied.Status)
+	})
+
+	t.Run("Timeline", func(t *testing.T) {
+		timeline, err := pricing.Timeline(kettle.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, 50.0, timeline.Price)
+		assert.Len(t, timeline.Schedules, 3)
+
+		sources := make([]string, len(timeline.History))
+		for i, entry := range timeline.History {
+			sources[i] = entry.Source
+		}
+		assert.Equal(t, []string{models.PriceSourceScheduled, models.PriceSourceSaleEnd, models.PriceSourceManual, models.PriceSourceSaleStart}, sources)
+		last := timeline.History[len(timeline.History)-1]
+		assert.Equal(t, 40.0, last.OldPrice)
+		assert.Equal(t, 30.0, last.NewPrice)
+		assert.Equal(t, "Black Friday", last.Reason)
+		assert.Equal(t, uint(7), last.ChangedBy)
+		if assert.NotNil(t, last.ScheduleID) {
+			assert.Equal(t, sale.ID, *last.ScheduleID)
+		}
+
+		_, err = pricing.Timeline(9999)
+		assert.ErrorIs(t, err, ErrProductNotFound)
+	})
+
+	t.Run("MissedSale", func(t *testing.T) {
+		// A sale that started and ended while the job was not running is not applied
+		from := end.Add(24 * time.Hour)
+		to := from.Add(time.Hour)
+		missed, err := pricing.Schedule(&kettle, PriceScheduleInput{Kind: models.PriceSaleKind, Price: 10, StartsAt: from, EndsAt: &to})
+		assert.NoError(t, err)
+		changed, err := pricing.ApplyDueSchedules(to.Add(time.Hour))
+		assert.NoError(t, err)
+		assert.Empty(t, changed)
+		testDB.First(missed, missed.ID)
+		assert.Equal(t, models.PriceScheduleCompleted, missed.Status)
+		price, _ := prices()
+		assert.Equal(t, 50.0, price)
+	})
+}
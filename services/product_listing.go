@@ -0,0 +1,182 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/geoo115/Ecommerce/models"
+	"gorm.io/gorm"
+)
+
+// ErrInvalidProductFilter is returned for unknown sort keys and malformed listing filters
+var ErrInvalidProductFilter = errors.New("invalid product filter")
+
+// Product listing sort keys
+const (
+	ProductSortPriceAsc    = "price_asc"
+	ProductSortPriceDesc   = "price_desc"
+	ProductSortNewest      = "newest"
+	ProductSortName        = "name"
+	ProductSortBestSelling = "best_selling"
+	ProductSortRating      = "rating"
+)
+
+// productSortKeys maps sort keys to keyset orderings. Every ordering ends with the product ID
+// so products that tie keep a stable order across pages.
+var productSortKeys = map[string][]SortKey{
+	ProductSortPriceAsc:  {{Column: "products.price"}, {Column: "products.id"}},
+	ProductSortPriceDesc: {{Column: "products.price", Desc: true}, {Column: "products.id"}},
+	ProductSortNewest:    {{Column: "products.created_at", Desc: true, Time: true}, {Column: "products.id", Desc: true}},
+	ProductSortName:      {{Column: "LOWER(products.name)"}, {Column: "products.id"}},
+	// Units still on orders that were not cancelled
+	ProductSortBestSelling: {{Column: `(SELECT COALESCE(SUM(order_items.quantity), 0) FROM order_items
+		JOIN orders ON orders.id = order_items.order_id
+		WHERE order_items.product_id = products.id AND order_items.deleted_at IS NULL
+		AND orders.deleted_at IS NULL AND orders.status <> 'Cancelled')`, Desc: true}, {Column: "products.id"}},
+	// Average rating, with unreviewed products last
+	ProductSortRating: {{Column: `(SELECT CAST(COALESCE(AVG(reviews.rating), 0) AS DOUBLE PRECISION) FROM reviews
+		WHERE reviews.product_id = products.id AND reviews.deleted_at IS NULL)`, Desc: true}, {Column: "products.id"}},
+}
+
+// ProductListOptions are the sorting and filtering parameters of a product listing
+type ProductListOptions struct {
+	Sort               string
+	MinPrice           *float64
+	MaxPrice           *float64
+	CategoryIDs        []uint // Sorted and without repeats
+	IncludeDescendants bool
+	InStock            bool
+}
+
+// ParseProductListOptions reads sort, min_price, max_price, category_id, include_descendants
+// and in_stock. Several categories can be given as category_id=1,2 or by repeating the parameter.
+func ParseProductListOptions(values url.Values) (ProductListOptions, error) {
+	options := ProductListOptions{
+		Sort:               values.Get("sort"),
+		IncludeDescendants: values.Get("include_descendants") == "true",
+		InStock:            values.Get("in_stock") == "true",
+	}
+	if _, ok := productSortKeys[options.Sort]; options.Sort != "" && !ok {
+		return options, fmt.Errorf("%w: sort must be one of price_asc, price_desc, newest, name, best_selling or rating", ErrInvalidProductFilter)
+	}
+
+	for _, bound := range []struct {
+		name  string
+		value **float64
+	}{{"min_price", &options.MinPrice}, {"max_price", &options.MaxPrice}} {
+		raw := values.Get(bound.name)
+		if raw == "" {
+			continue
+		}
+		price, err := strconv.ParseFloat(raw, 64)
+		if err != nil || price < 0 {
+			return options, fmt.Errorf("%w: %s must be a non-negative number", ErrInvalidProductFilter, bound.name)
+		}
+		*bound.value = &price
+	}
+	if options.MinPrice != nil && options.MaxPrice != nil && *options.MinPrice > *options.MaxPrice {
+		return options, fmt.Errorf("%w: min_price cannot exceed max_price", ErrInvalidProductFilter)
+	}
+
+	seen := map[uint]bool{}
+	for _, value := range values["category_id"] {
+		for _, raw := range strings.Split(value, ",") {
+			id, err := strconv.ParseUint(strings.TrimSpace(raw), 10, 32)
+			if err != nil || id == 0 {
+				return options, fmt.Errorf("%w: invalid category_id", ErrInvalidProductFilter)
+			}
+			if !seen[uint(id)] {
+				seen[uint(id)] = true
+				options.CategoryIDs = append(options.CategoryIDs, uint(id))
+			}
+		}
+	}
+	sort.Slice(options.CategoryIDs, func(i, j int) bool { return options.CategoryIDs[i] < options.CategoryIDs[j] })
+	return options, nil
+}
+
+// Key renders the options in a stable form for cache keys
+func (o ProductListOptions) Key() string {
+	price := func(value *float64) string {
+		if value == nil {
+			return ""
+		}
+		return strconv.FormatFloat(*value, 'f', -1, 64)
+	}
+	categories := make([]string, len(o.CategoryIDs))
+	for i, id := range o.CategoryIDs {
+		categories[i] = strconv.FormatUint(uint64(id), 10)
+	}
+	return fmt.Sprintf("sort=%s;price=%s-%s;categories=%s;descendants=%t;in_stock=%t",
+		o.Sort, price(o.MinPrice), price(o.MaxPrice), strings.Join(categories, ","), o.IncludeDescendants, o.InStock)
+}
+
+// FilterScope restricts products to the options' categories, price range and availability.
+// Categories include their subcategories when IncludeDescendants is set.
+func (o ProductListOptions) FilterScope(database *gorm.DB) (func(*gorm.DB) *gorm.DB, error) {
+	categoryIDs := o.CategoryIDs
+	if o.IncludeDescendants && len(categoryIDs) > 0 {
+		categories := NewCategoryServiceWithDB(database)
+		seen := map[uint]bool{}
+		categoryIDs = nil
+		for _, id := range o.CategoryIDs {
+			ids, err := categories.DescendantIDs(id)
+			if err != nil {
+				return nil, err
+			}
+			for _, descendant := range ids {
+				if !seen[descendant] {
+					seen[descendant] = true
+					categoryIDs = append(categoryIDs, descendant)
+				}
+			}
+		}
+	}
+
+	return func(query *gorm.DB) *gorm.DB {
+		if len(categoryIDs) > 0 {
+			query = query.Where("products.category_id IN ?", categoryIDs)
+		}
+		if o.MinPrice != nil {
+			query = query.Where("products.price >= ?", *o.MinPrice)
+		}
+		if o.MaxPrice != nil {
+			query = query.Where("products.price <= ?", *o.MaxPrice)
+		}
+		if o.InStock {
+			// Products with variants are in stock when any variant is, bundles when every component
+			// has stock for one bundle, digital products unless their licence keys have run out;
+			// others use their inventory row
+			query = query.Where(`(EXISTS (SELECT 1 FROM product_variants WHERE product_variants.product_id = products.id
+				AND product_variants.deleted_at IS NULL AND product_variants.stock > 0)
+			OR (products.type = ? AND EXISTS (SELECT 1 FROM bundle_items WHERE bundle_items.bundle_id = products.id)
+			AND NOT EXISTS (SELECT 1 FROM bundle_items WHERE bundle_items.bundle_id = products.id
+				AND NOT EXISTS (SELECT 1 FROM inventories JOIN products AS components ON components.id = inventories.product_id
+					AND components.deleted_at IS NULL
+				WHERE inventories.product_id = bundle_items.component_id AND inventories.deleted_at IS NULL
+					AND inventories.stock >= bundle_items.quantity)))
+			OR (products.type = ? AND (NOT EXISTS (SELECT 1 FROM licence_keys WHERE licence_keys.product_id = products.id
+				AND licence_keys.deleted_at IS NULL)
+			OR EXISTS (SELECT 1 FROM licence_keys WHERE licence_keys.product_id = products.id
+				AND licence_keys.deleted_at IS NULL AND licence_keys.order_item_id IS NULL)))
+			OR (products.type NOT IN ? AND NOT EXISTS (SELECT 1 FROM product_variants WHERE product_variants.product_id = products.id
+				AND product_variants.deleted_at IS NULL)
+			AND EXISTS (SELECT 1 FROM inventories WHERE inventories.product_id = products.id
+				AND inventories.deleted_at IS NULL AND inventories.stock > 0)))`,
+				models.ProductTypeBundle, models.ProductTypeDigital, []string{models.ProductTypeBundle, models.ProductTypeDigital})
+		}
+		return query
+	}, nil
+}
+
+// PageOrder sorts products by the options' sort key, or by ID when none is set
+func (o ProductListOptions) PageOrder() PageOrder {
+	if keys, ok := productSortKeys[o.Sort]; ok {
+		return PageOrder{Table: "products", Keys: keys}
+	}
+	return OrderByID("products")
+}
@@ -0,0 +1,100 @@
+package services
+
+import (
+	"fmt"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/geoo115/Ecommerce/db"
+	"github.com/geoo115/Ecommerce/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseProductListOptions(t *testing.T) {
+	options, err := ParseProductListOptions(url.Values{
+		"sort":        {"price_desc"},
+		"min_price":   {"10"},
+		"max_price":   {"99.5"},
+		"category_id": {"3,1", "2", "1"},
+		"in_stock":    {"true"},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []uint{1, 2, 3}, options.CategoryIDs)
+	assert.Equal(t, "sort=price_desc;price=10-99.5;categories=1,2,3;descendants=false;in_stock=true", options.Key())
+
+	// Equivalent queries share a cache key
+	same, _ := ParseProductListOptions(url.Values{"category_id": {"2,3,1"}, "max_price": {"99.50"}, "min_price": {"10.0"}, "sort": {"price_desc"}, "in_stock": {"true"}})
+	assert.Equal(t, options.Key(), same.Key())
+
+	for _, values := range []url.Values{
+		{"sort": {"cheapest"}},
+		{"min_price": {"-1"}},
+		{"max_price": {"lots"}},
+		{"min_price": {"20"}, "max_price": {"10"}},
+		{"category_id": {"1,x"}},
+		{"category_id": {"0"}},
+	} {
+		_, err := ParseProductListOptions(values)
+		assert.ErrorIs(t, err, ErrInvalidProductFilter, values.Encode())
+	}
+}
+
+func TestProductListOptions_Scopes(t *testing.T) {
+	originalDB := db.DB
+	testDB := db.SetupTestDB(t)
+	db.DB = testDB
+	defer func() { db.DB = originalDB }()
+
+	parent := models.Category{Name: "Outdoor"}
+	testDB.Create(&parent)
+	child := models.Category{Name: "Tents", ParentID: &parent.ID}
+	testDB.Create(&child)
+
+	stove := createStockedProduct(t, "Camping Stove", 45, 3)
+	tent := createStockedProduct(t, "Tent", 120, 0)
+	lantern := createStockedProduct(t, "lantern", 15, 8)
+	testDB.Model(&tent).Update("category_id", child.ID)
+	testDB.Model(&stove).Update("category_id", parent.ID)
+	testDB.Model(&lantern).Update("created_at", time.Now().Add(time.Hour))
+
+	// A product with variants is in stock when any variant is, whatever its inventory row says
+	hammock := createStockedProduct(t, "Hammock", 30, 0)
+	testDB.Create(&models.ProductVariant{ProductID: hammock.ID, SKU: "HM-1", Stock: 2})
+
+	user := models.User{Username: "camper", Email: "camper@example.com"}
+	testDB.Create(&user)
+	testDB.Create(&models.Order{UserID: user.ID, Status: "Paid", Items: []models.OrderItem{{ProductID: lantern.ID, Quantity: 2}, {ProductID: tent.ID, Quantity: 1}}})
+	testDB.Create(&models.Order{UserID: user.ID, Status: "Cancelled", Items: []models.OrderItem{{ProductID: stove.ID, Quantity: 9}}})
+	testDB.Create(&models.Review{ProductID: tent.ID, UserID: user.ID, Rating: 5})
+	testDB.Create(&models.Review{ProductID: stove.ID, UserID: user.ID, Rating: 3})
+
+	list := func(values url.Values) []string {
+		t.Helper()
+		options, err := ParseProductListOptions(values)
+		assert.NoError(t, err)
+		scope, err := options.FilterScope(testDB)
+		assert.NoError(t, err)
+		var products []models.Product
+		_, err = FetchPage(testDB.Model(&models.Product{}).Scopes(scope), options.PageOrder(), PageRequest{Limit: 10}, &products)
+		assert.NoError(t, err)
+		names := make([]string, len(products))
+		for i, product := range products {
+			names[i] = product.Name
+		}
+		return names
+	}
+
+	assert.Equal(t, []string{"lantern", "Hammock", "Camping Stove", "Tent"}, list(url.Values{"sort": {"price_asc"}}))
+	assert.Equal(t, []string{"Tent", "Camping Stove", "Hammock", "lantern"}, list(url.Values{"sort": {"price_desc"}}))
+	assert.Equal(t, []string{"Camping Stove", "Hammock", "lantern", "Tent"}, list(url.Values{"sort": {"name"}}))
+	assert.Equal(t, "lantern", list(url.Values{"sort": {"newest"}})[0])
+	assert.Equal(t, []string{"lantern", "Tent", "Camping Stove", "Hammock"}, list(url.Values{"sort": {"best_selling"}}))
+	assert.Equal(t, []string{"Tent", "Camping Stove", "lantern", "Hammock"}, list(url.Values{"sort": {"rating"}}))
+
+	assert.Equal(t, []string{"Camping Stove", "Hammock"}, list(url.Values{"min_price": {"20"}, "max_price": {"45"}, "sort": {"name"}}))
+	assert.Equal(t, []string{"Camping Stove", "Hammock", "lantern"}, list(url.Values{"in_stock": {"true"}, "sort": {"name"}}))
+	assert.Equal(t, []string{"Camping Stove"}, list(url.Values{"category_id": {fmt.Sprint(parent.ID)}}))
+	assert.Equal(t, []string{"Camping Stove", "Tent"}, list(url.Values{"category_id": {fmt.Sprint(parent.ID)}, "include_descendants": {"true"}, "sort": {"name"}}))
+	assert.Equal(t, []string{"Camping Stove"}, list(url.Values{"category_id": {fmt.Sprint(parent.ID) + "," + fmt.Sprint(child.ID)}, "in_stock": {"true"}}))
+}
@@ -0,0 +1,133 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/geoo115/Ecommerce/db"
+	"github.com/geoo115/Ecommerce/models"
+	"gorm.io/gorm"
+)
+
+// Publishing errors surfaced to handlers
+var (
+	ErrInvalidPublishing = errors.New("invalid publishing schedule")
+	// ErrProductNotPublished is returned when a draft is carted or ordered. It wraps
+	// ErrProductNotFound so drafts look like missing products to shoppers.
+	ErrProductNotPublished = fmt.Errorf("%w: product is not published", ErrProductNotFound)
+)
+
+// PublishingInput is a product's status with optional publish and unpublish times
+type PublishingInput struct {
+	Status      string     `json:"status"`
+	PublishAt   *time.Time `json:"publish_at"`   // Drafts only
+	UnpublishAt *time.Time `json:"unpublish_at"` // After PublishAt when both are given
+}
+
+// PublishingService interface defines the draft/published workflow of products
+type PublishingService interface {
+	Set(product *models.Product, input PublishingInput, now time.Time) error
+	ApplyDue(now time.Time) ([]uint, error)
+}
+
+// publishingService implements PublishingService interface
+type publishingService struct {
+	db *gorm.DB
+}
+
+// NewPublishingService creates a new publishing service instance
+func NewPublishingService() PublishingService {
+	return NewPublishingServiceWithDB(db.DB)
+}
+
+// NewPublishingServiceWithDB creates a publishing service bound to a specific database handle
+func NewPublishingServiceWithDB(database *gorm.DB) PublishingService {
+	return &publishingService{db: database}
+}
+
+// PublishedProducts restricts a product query to products shown in the public catalogue
+func PublishedProducts(query *gorm.DB) *gorm.DB {
+	return query.Where("products.status = ?", models.ProductStatusPublished)
+}
+
+// ApplyPublishing validates a status and schedule and sets them on a product without saving
+// it. A draft may be scheduled to publish and a published product to unpublish; both times
+// must be in the future.
+func ApplyPublishing(product *models.Product, input PublishingInput, now time.Time) error {
+	switch input.Status {
+	case models.ProductStatusDraft, models.ProductStatusPublished:
+	default:
+		return fmt.Errorf("%w: status must be draft or published", ErrInvalidPublishing)
+	}
+	if input.PublishAt != nil {
+		if input.Status != models.ProductStatusDraft {
+			return fmt.Errorf("%w: only drafts can be scheduled to publish", ErrInvalidPublishing)
+		}
+		if !input.PublishAt.After(now) {
+			return fmt.Errorf("%w: publish_at must be in the future", ErrInvalidPublishing)
+		}
+	}
+	if input.UnpublishAt != nil {
+		if input.Status == models.ProductStatusDraft && input.PublishAt == nil {
+			return fmt.Errorf("%w: drafts can only be scheduled to unpublish after a publish_at", ErrInvalidPublishing)
+		}
+		if !input.UnpublishAt.After(now) || (input.PublishAt != nil && !input.UnpublishAt.After(*input.PublishAt)) {
+			return fmt.Errorf("%w: unpublish_at must be in the future and after publish_at", ErrInvalidPublishing)
+		}
+	}
+
+	product.Status = input.Status
+	product.PublishAt = input.PublishAt
+	product.UnpublishAt = input.UnpublishAt
+	return nil
+}
+
+// Set changes a product's status and replaces its publishing schedule
+func (s *publishingService) Set(product *models.Product, input PublishingInput, now time.Time) error {
+	updated := *product
+	if err := ApplyPublishing(&updated, input, now); err != nil {
+		return err
+	}
+	if err := s.db.Model(&models.Product{}).Where("id = ?", product.ID).Updates(map[string]interface{}{
+		"status": updated.Status, "publish_at": updated.PublishAt, "unpublish_at": updated.UnpublishAt,
+	}).Error; err != nil {
+		return err
+	}
+	product.Status, product.PublishAt, product.UnpublishAt = updated.Status, updated.PublishAt, updated.UnpublishAt
+	return nil
+}
+
+// ApplyDue publishes drafts whose publish time has passed, then unpublishes products whose
+// unpublish time has passed, so a product whose whole window fell between runs ends as a
+// draft. It returns the products that changed so their cached listings can be dropped. Each
+// change is a conditional update, so several instances can run the job at once.
+func (s *publishingService) ApplyDue(now time.Time) ([]uint, error) {
+	var changed []uint
+	var errs []error
+	seen := map[uint]bool{}
+	steps := []struct {
+		from, to, column string
+	}{
+		{models.ProductStatusDraft, models.ProductStatusPublished, "publish_at"},
+		{models.ProductStatusPublished, models.ProductStatusDraft, "unpublish_at"},
+	}
+	for _, step := range steps {
+		var due []uint
+		if err := s.db.Model(&models.Product{}).Where("status = ? AND "+step.column+" <= ?", step.from, now).
+			Order("id").Pluck("id", &due).Error; err != nil {
+			return changed, err
+		}
+		for _, id := range due {
+			result := s.db.Model(&models.Product{}).Where("id = ? AND status = ? AND "+step.column+" <= ?", id, step.from, now).
+				Updates(map[string]interface{}{"status": step.to, step.column: nil})
+			if result.Error != nil {
+				errs = append(errs, result.Error)
+			} else if result.RowsAffected > 0 && !seen[id] {
+				seen[id] = true
+				changed = append(changed, id)
+			}
+		}
+	}
+	return changed, errors.Join(errs...)
+}
@@ -0,0 +1,104 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/geoo115/Ecommerce/db"
+	"github.com/geoo115/Ecommerce/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPublishingService(t *testing.T) {
+	originalDB := db.DB
+	testDB := db.SetupTestDB(t)
+	db.DB = testDB
+	defer func() { db.DB = originalDB }()
+
+	publishing := NewPublishingServiceWithDB(testDB)
+	now := time.Date(2026, 11, 1, 9, 0, 0, 0, time.UTC)
+	at := func(d time.Duration) *time.Time {
+		when := now.Add(d)
+		return &when
+	}
+	status := func(id uint) string {
+		var product models.Product
+		testDB.First(&product, id)
+		return product.Status
+	}
+
+	t.Run("Validation", func(t *testing.T) {
+		var product models.Product
+		for _, input := range []PublishingInput{
+			{Status: "archived"},
+			{Status: models.ProductStatusPublished, PublishAt: at(time.Hour)},
+			{Status: models.ProductStatusDraft, PublishAt: at(-time.Hour)},
+			{Status: models.ProductStatusDraft, UnpublishAt: at(time.Hour)},
+			{Status: models.ProductStatusDraft, PublishAt: at(2 * time.Hour), UnpublishAt: at(time.Hour)},
+			{Status: models.ProductStatusPublished, UnpublishAt: at(0)},
+		} {
+			assert.ErrorIs(t, ApplyPublishing(&product, input, now), ErrInvalidPublishing, "%+v", input)
+		}
+		assert.NoError(t, ApplyPublishing(&product, PublishingInput{Status: models.ProductStatusDraft, PublishAt: at(time.Hour), UnpublishAt: at(2 * time.Hour)}, now))
+		assert.Equal(t, models.ProductStatusDraft, product.Status)
+	})
+
+	t.Run("ProductsArePublishedByDefault", func(t *testing.T) {
+		product := createStockedProduct(t, "Mug", 8, 5)
+		assert.Equal(t, models.ProductStatusPublished, status(product.ID))
+		assert.True(t, product.IsPublished())
+	})
+
+	t.Run("DraftsCannotBeBought", func(t *testing.T) {
+		draft := createStockedProduct(t, "Teapot", 30, 5)
+		assert.NoError(t, publishing.Set(&draft, PublishingInput{Status: models.ProductStatusDraft}, now))
+		assert.False(t, draft.IsPublished())
+
+		_, _, err := ResolveVariant(testDB, draft.ID, nil)
+		assert.ErrorIs(t, err, ErrProductNotPublished)
+		assert.ErrorIs(t, err, ErrProductNotFound)
+		err = NewOrderServiceWithDB(testDB).CreateOrder(&models.Order{UserID: 1}, []OrderLine{{ProductID: draft.ID, Quantity: 1}})
+		assert.ErrorIs(t, err, ErrProductNotFound)
+
+		var count int64
+		testDB.Model(&models.Product{}).Scopes(PublishedProducts).Where("id = ?", draft.ID).Count(&count)
+		assert.Zero(t, count)
+	})
+
+	t.Run("ApplyDue", func(t *testing.T) {
+		launch := createStockedProduct(t, "Launch", 50, 5)
+		window := createStockedProduct(t, "Window", 50, 5)
+		retiring := createStockedProduct(t, "Retiring", 50, 5)
+		flash := createStockedProduct(t, "Flash", 50, 5)
+		assert.NoError(t, publishing.Set(&launch, PublishingInput{Status: models.ProductStatusDraft, PublishAt: at(time.Hour), UnpublishAt: at(48 * time.Hour)}, now))
+		assert.NoError(t, publishing.Set(&window, PublishingInput{Status: models.ProductStatusDraft, PublishAt: at(time.Hour), UnpublishAt: at(2 * time.Hour)}, now))
+		assert.NoError(t, publishing.Set(&retiring, PublishingInput{Status: models.ProductStatusPublished, UnpublishAt: at(3 * time.Hour)}, now))
+		assert.NoError(t, publishing.Set(&flash, PublishingInput{Status: models.ProductStatusDraft, PublishAt: at(2 * time.Hour), UnpublishAt: at(3 * time.Hour)}, now))
+
+		changed, err := publishing.ApplyDue(now)
+		assert.NoError(t, err)
+		assert.Empty(t, changed)
+
+		changed, err = publishing.ApplyDue(now.Add(time.Hour))
+		assert.NoError(t, err)
+		assert.ElementsMatch(t, []uint{launch.ID, window.ID}, changed)
+		assert.Equal(t, models.ProductStatusPublished, status(launch.ID))
+		var published models.Product
+		testDB.First(&published, launch.ID)
+		assert.Nil(t, published.PublishAt)
+		assert.NotNil(t, published.UnpublishAt)
+
+		// A run after a gap applies both ends of a window that fell inside it
+		changed, err = publishing.ApplyDue(now.Add(4 * time.Hour))
+		assert.NoError(t, err)
+		assert.ElementsMatch(t, []uint{window.ID, retiring.ID, flash.ID}, changed)
+		assert.Equal(t, models.ProductStatusDraft, status(window.ID))
+		assert.Equal(t, models.ProductStatusDraft, status(retiring.ID))
+		assert.Equal(t, models.ProductStatusDraft, status(flash.ID))
+		assert.Equal(t, models.ProductStatusPublished, status(launch.ID))
+
+		changed, err = publishing.ApplyDue(now.Add(4 * time.Hour))
+		assert.NoError(t, err)
+		assert.Empty(t, changed)
+	})
+}
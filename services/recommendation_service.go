@@ -0,0 +1,273 @@
+package services
+
+import (
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/geoo115/Ecommerce/config"
+	"github.com/geoo115/Ecommerce/db"
+	"github.com/geoo115/Ecommerce/models"
+	"gorm.io/gorm"
+)
+
+// Recommendation is a recommended product and why it was chosen
+type Recommendation struct {
+	Product models.Product `json:"product"`
+	Reason  string         `json:"reason"`
+	Score   float64        `json:"score"` // Orders containing both products, summed over a basket
+}
+
+// RecommendationService interface defines related product recommendation logic
+type RecommendationService interface {
+	Rebuild(now time.Time) (int, error)
+	Related(productID uint, limit int, scopes ...func(*gorm.DB) *gorm.DB) ([]Recommendation, error)
+	ForProducts(productIDs []uint, limit int, scopes ...func(*gorm.DB) *gorm.DB) ([]Recommendation, error)
+}
+
+// recommendationService implements RecommendationService interface
+type recommendationService struct {
+	db     *gorm.DB
+	config config.RecommendationConfig
+}
+
+// NewRecommendationService creates a new recommendation service instance
+func NewRecommendationService() RecommendationService {
+	return NewRecommendationServiceWithDB(db.DB)
+}
+
+// NewRecommendationServiceWithDB creates a recommendation service bound to a specific database handle
+func NewRecommendationServiceWithDB(database *gorm.DB) RecommendationService {
+	return &recommendationService{db: database, config: config.GetRecommendationConfig()}
+}
+
+// pricedProduct is the part of a product recommendations are computed from
+type pricedProduct struct {
+	ID         uint
+	CategoryID uint
+	Price      float64
+}
+
+// coPurchase counts the orders two products were bought in together
+type coPurchase struct {
+	ProductID uint
+	RelatedID uint
+	Orders    float64
+}
+
+// Rebuild recomputes every published product's recommendations and returns how many were
+// stored. Products bought in the same orders come first, most shared orders first; the rest are
+// filled with products from the same category and then of a similar price, closest price first.
+func (s *recommendationService) Rebuild(now time.Time) (int, error) {
+	var products []pricedProduct
+	if err := s.db.Model(&models.Product{}).Scopes(PublishedProducts).Select("id, category_id, price").Order("price, id").Find(&products).Error; err != nil {
+		return 0, err
+	}
+	live := make(map[uint]bool, len(products))
+	byCategory := make(map[uint][]pricedProduct)
+	for _, product := range products {
+		live[product.ID] = true
+		byCategory[product.CategoryID] = append(byCategory[product.CategoryID], product)
+	}
+
+	// Fully cancelled lines and cancelled orders are not purchases
+	var pairs []coPurchase
+	query := s.db.Table("order_items AS items").
+		Select("items.product_id AS product_id, others.product_id AS related_id, COUNT(DISTINCT items.order_id) AS orders").
+		Joins("JOIN order_items AS others ON others.order_id = items.order_id AND others.product_id <> items.product_id AND others.deleted_at IS NULL AND others.quantity > 0").
+		Joins("JOIN orders ON orders.id = items.order_id AND orders.deleted_at IS NULL").
+		Where("items.deleted_at IS NULL AND items.quantity > 0 AND orders.status <> ?", "Cancelled")
+	if s.config.LookbackDays > 0 {
+		query = query.Where("orders.created_at >= ?", now.AddDate(0, 0, -s.config.LookbackDays))
+	}
+	if err := query.Group("items.product_id, others.product_id").Scan(&pairs).Error; err != nil {
+		return 0, err
+	}
+	sort.Slice(pairs, func(i, j int) bool {
+		if pairs[i].Orders != pairs[j].Orders {
+			return pairs[i].Orders > pairs[j].Orders
+		}
+		return pairs[i].RelatedID < pairs[j].RelatedID
+	})
+	boughtWith := make(map[uint][]coPurchase)
+	for _, pair := range pairs {
+		if live[pair.ProductID] && live[pair.RelatedID] {
+			boughtWith[pair.ProductID] = append(boughtWith[pair.ProductID], pair)
+		}
+	}
+
+	var rows []models.ProductRecommendation
+	for _, product := range products {
+		chosen := map[uint]bool{product.ID: true}
+		add := func(relatedID uint, reason string, score float64) bool {
+			if chosen[relatedID] {
+				return true
+			}
+			chosen[relatedID] = true
+			rows = append(rows, models.ProductRecommendation{ProductID: product.ID, RelatedID: relatedID,
+				Position: len(chosen) - 1, Reason: reason, Score: score})
+			return len(chosen) <= s.config.PerProduct
+		}
+
+		full := false
+		for _, pair := range boughtWith[product.ID] {
+			if full = !add(pair.RelatedID, models.RecommendationBoughtTogether, pair.Orders); full {
+				break
+			}
+		}
+		if !full {
+			full = !nearestByPrice(byCategory[product.CategoryID], product, -1, func(other pricedProduct) bool {
+				return add(other.ID, models.RecommendationSameCategory, 0)
+			})
+		}
+		if !full && s.config.PriceRange > 0 {
+			nearestByPrice(products, product, product.Price*s.config.PriceRange, func(other pricedProduct) bool {
+				return add(other.ID, models.RecommendationSimilarPrice, 0)
+			})
+		}
+	}
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Where("1 = 1").Delete(&models.ProductRecommendation{}).Error; err != nil {
+			return err
+		}
+		if len(rows) == 0 {
+			return nil
+		}
+		return tx.CreateInBatches(&rows, 500).Error
+	})
+	if err != nil {
+		return 0, err
+	}
+	return len(rows), nil
+}
+
+// nearestByPrice visits the products of a price-sorted slice in order of closeness to the
+// product's price, skipping the product itself, until visit returns false or the difference
+// exceeds maxDiff (no limit when negative). It reports whether every candidate was visited.
+func nearestByPrice(sorted []pricedProduct, product pricedProduct, maxDiff float64, visit func(pricedProduct) bool) bool {
+	right := sort.Search(len(sorted), func(i int) bool { return sorted[i].Price >= product.Price })
+	left := right - 1
+	for left >= 0 || right < len(sorted) {
+		var next pricedProduct
+		if right >= len(sorted) || (left >= 0 && product.Price-sorted[left].Price <= sorted[right].Price-product.Price) {
+			next = sorted[left]
+			left--
+		} else {
+			next = sorted[right]
+			right++
+		}
+		diff := next.Price - product.Price
+		if diff < 0 {
+			diff = -diff
+		}
+		if maxDiff >= 0 && diff > maxDiff {
+			return true
+		}
+		if next.ID != product.ID && !visit(next) {
+			return false
+		}
+	}
+	return true
+}
+
+// Related returns a published product's precomputed recommendations, strongest first. Products
+// added or published since recommendations were last computed have none yet.
+func (s *recommendationService) Related(productID uint, limit int, scopes ...func(*gorm.DB) *gorm.DB) ([]Recommendation, error) {
+	var product models.Product
+	if err := s.db.Scopes(PublishedProducts).Select("id").First(&product, productID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrProductNotFound
+		}
+		return nil, err
+	}
+
+	var rows []models.ProductRecommendation
+	if err := s.db.Where("product_id = ?", productID).Order("position").Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	return s.load(rows, limit, scopes)
+}
+
+// ForProducts suggests products to go with a basket of products, such as a cart, by combining
+// their recommendations. Products recommended for several of them, or bought with them more
+// often, come first; products already in the basket are left out.
+func (s *recommendationService) ForProducts(productIDs []uint, limit int, scopes ...func(*gorm.DB) *gorm.DB) ([]Recommendation, error) {
+	if len(productIDs) == 0 {
+		return []Recommendation{}, nil
+	}
+	var rows []models.ProductRecommendation
+	if err := s.db.Where("product_id IN ? AND related_id NOT IN ?", productIDs, productIDs).
+		Order("position, id").Find(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	type suggestion struct {
+		row   models.ProductRecommendation
+		count int
+	}
+	var order []uint
+	merged := make(map[uint]*suggestion)
+	for _, row := range rows {
+		entry, seen := merged[row.RelatedID]
+		if !seen {
+			// Rows arrive strongest position first, so the first row gives the best position
+			merged[row.RelatedID] = &suggestion{row: row, count: 1}
+			order = append(order, row.RelatedID)
+			continue
+		}
+		entry.count++
+		entry.row.Score += row.Score
+		if row.Reason == models.RecommendationBoughtTogether {
+			entry.row.Reason = row.Reason
+		}
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		a, b := merged[order[i]], merged[order[j]]
+		if a.row.Score != b.row.Score {
+			return a.row.Score > b.row.Score
+		}
+		return a.count > b.count
+	})
+
+	combined := make([]models.ProductRecommendation, len(order))
+	for i, id := range order {
+		combined[i] = merged[id].row
+	}
+	return s.load(combined, limit, scopes)
+}
+
+// load fetches the recommended products in order, dropping deleted and unpublished ones, up to limit
+func (s *recommendationService) load(rows []models.ProductRecommendation, limit int, scopes []func(*gorm.DB) *gorm.DB) ([]Recommendation, error) {
+	recommendations := []Recommendation{}
+	if len(rows) == 0 {
+		return recommendations, nil
+	}
+	ids := make([]uint, len(rows))
+	for i, row := range rows {
+		ids[i] = row.RelatedID
+	}
+	var products []models.Product
+	if err := s.db.Scopes(PublishedProducts).Scopes(scopes...).Where("id IN ?", ids).Find(&products).Error; err != nil {
+		return nil, err
+	}
+	if err := AttachComputedStock(s.db, products); err != nil {
+		return nil, err
+	}
+	byID := make(map[uint]models.Product, len(products))
+	for _, product := range products {
+		byID[product.ID] = product
+	}
+
+	for _, row := range rows {
+		product, ok := byID[row.RelatedID]
+		if !ok {
+			continue
+		}
+		recommendations = append(recommendations, Recommendation{Product: product, Reason: row.Reason, Score: row.Score})
+		if len(recommendations) == limit {
+			break
+		}
+	}
+	return recommendations, nil
+}
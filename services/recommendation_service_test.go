@@ -0,0 +1,121 @@
+package services
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/geoo115/Ecommerce/db"
+	"github.com/geoo115/Ecommerce/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecommendationService(t *testing.T) {
+	originalDB := db.DB
+	testDB := db.SetupTestDB(t)
+	db.DB = testDB
+	defer func() { db.DB = originalDB }()
+	os.Setenv("RECOMMENDATIONS_PER_PRODUCT", "4")
+	defer os.Unsetenv("RECOMMENDATIONS_PER_PRODUCT")
+
+	categories := map[string]uint{}
+	for _, name := range []string{"Cameras", "Bags", "Books"} {
+		category := models.Category{Name: name}
+		testDB.Create(&category)
+		categories[name] = category.ID
+	}
+	product := func(name, category string, price float64) models.Product {
+		p := createStockedProduct(t, name, price, 10)
+		testDB.Model(&p).Update("category_id", categories[category])
+		return p
+	}
+	camera := product("Camera", "Cameras", 500)
+	lens := product("Lens", "Cameras", 300)
+	tripod := product("Tripod", "Cameras", 80)
+	bag := product("Bag", "Bags", 90)
+	strap := product("Strap", "Bags", 20)
+	novel := product("Novel", "Books", 16)
+	atlas := product("Atlas", "Books", 480)
+
+	user := models.User{Username: "shopper", Email: "shopper@example.com"}
+	testDB.Create(&user)
+	order := func(status string, items ...models.OrderItem) models.Order {
+		o := models.Order{UserID: user.ID, Status: status, Items: items}
+		testDB.Create(&o)
+		return o
+	}
+	line := func(p models.Product, quantity int) models.OrderItem {
+		return models.OrderItem{ProductID: p.ID, Quantity: quantity, Price: p.Price}
+	}
+	order("Paid", line(camera, 1), line(lens, 1), line(bag, 1))
+	order("Delivered", line(camera, 1), line(bag, 2))
+	// Cancelled orders, cancelled lines and orders older than the lookback are not purchases
+	order("Cancelled", line(camera, 1), line(novel, 1))
+	order("Paid", line(camera, 1), line(strap, 0))
+	old := order("Delivered", line(camera, 1), line(atlas, 1))
+	testDB.Model(&old).Update("created_at", time.Now().AddDate(-1, 0, 0))
+
+	recommendations := NewRecommendationServiceWithDB(testDB)
+	stored, err := recommendations.Rebuild(time.Now())
+	assert.NoError(t, err)
+	assert.Greater(t, stored, 0)
+
+	names := func(recs []Recommendation) []string {
+		result := make([]string, len(recs))
+		for i, rec := range recs {
+			result[i] = rec.Product.Name
+		}
+		return result
+	}
+
+	t.Run("Related", func(t *testing.T) {
+		related, err := recommendations.Related(camera.ID, 4)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"Bag", "Lens", "Tripod", "Atlas"}, names(related))
+		if assert.Len(t, related, 4) {
+			assert.Equal(t, models.RecommendationBoughtTogether, related[0].Reason)
+			assert.Equal(t, 2.0, related[0].Score)
+			assert.Equal(t, models.RecommendationSameCategory, related[2].Reason)
+			assert.Equal(t, models.RecommendationSimilarPrice, related[3].Reason)
+		}
+
+		// Without co-purchases the category and then price fallbacks fill the list
+		related, err = recommendations.Related(strap.ID, 4)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"Bag", "Novel"}, names(related))
+
+		related, _ = recommendations.Related(camera.ID, 2)
+		assert.Equal(t, []string{"Bag", "Lens"}, names(related))
+
+		_, err = recommendations.Related(9999, 4)
+		assert.ErrorIs(t, err, ErrProductNotFound)
+	})
+
+	t.Run("ForProducts", func(t *testing.T) {
+		suggested, err := recommendations.ForProducts([]uint{camera.ID, bag.ID}, 4)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"Lens", "Tripod", "Strap", "Atlas"}, names(suggested))
+		if assert.NotEmpty(t, suggested) {
+			assert.Equal(t, 2.0, suggested[0].Score)
+		}
+
+		suggested, err = recommendations.ForProducts(nil, 4)
+		assert.NoError(t, err)
+		assert.Empty(t, suggested)
+	})
+
+	t.Run("DeletedProducts", func(t *testing.T) {
+		testDB.Delete(&lens)
+		related, _ := recommendations.Related(camera.ID, 4)
+		assert.Equal(t, []string{"Bag", "Tripod", "Atlas"}, names(related))
+
+		// A rebuild stops recommending the deleted product at all
+		_, err := recommendations.Rebuild(time.Now())
+		assert.NoError(t, err)
+		var count int64
+		testDB.Model(&models.ProductRecommendation{}).Where("related_id = ? OR product_id = ?", lens.ID, lens.ID).Count(&count)
+		assert.Zero(t, count)
+		related, _ = recommendations.Related(tripod.ID, 4)
+		assert.Equal(t, []string{"Camera", "Bag"}, names(related))
+	})
+}
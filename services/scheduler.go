@@ -0,0 +1,36 @@
+package services
+
+import (
+	"time"
+
+	"github.com/geoo115/Ecommerce/db"
+	"github.com/geoo115/Ecommerce/utils"
+)
+
+// StartJob runs fn every interval in the background until the returned stop function is called.
+// Runs are skipped while the database is unavailable.
+func StartJob(name string, interval time.Duration, fn func(now time.Time) error) (stop func()) {
+	ticker := time.NewTicker(interval)
+	done := make(chan struct{})
+
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case now := <-ticker.C:
+				if db.DB == nil {
+					utils.Warn("Skipping %s job: database not available", name)
+					continue
+				}
+				if err := fn(now); err != nil {
+					utils.Error("%s job failed: %v", name, err)
+				}
+			}
+		}
+	}()
+
+	utils.Info("Started %s job with interval %v", name, interval)
+	return func() { close(done) }
+}
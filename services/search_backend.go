@@ -0,0 +1,185 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/geoo115/Ecommerce/db"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// maxSearchTerms bounds how many words of a query are matched
+const maxSearchTerms = 10
+
+// SearchQuery is a parsed search. A product matches when it contains every term of at least
+// one alternative; synonym expansion adds alternatives to the terms the customer typed.
+type SearchQuery struct {
+	Alternatives [][]string
+}
+
+// ParseSearchQuery splits text into a query with a single alternative
+func ParseSearchQuery(text string) SearchQuery {
+	terms := searchTerms(text)
+	if len(terms) == 0 {
+		return SearchQuery{}
+	}
+	return SearchQuery{Alternatives: [][]string{terms}}
+}
+
+// Text renders the first alternative, which holds the terms as typed
+func (q SearchQuery) Text() string {
+	if len(q.Alternatives) == 0 {
+		return ""
+	}
+	return strings.Join(q.Alternatives[0], " ")
+}
+
+// SearchBackend matches products against a search query using the database's full-text index.
+// Every word of an alternative must match the product's name, category or description;
+// stemming lets "running" match "run". Matches are ordered most relevant first.
+type SearchBackend interface {
+	Name() string
+	Search(query *gorm.DB, search SearchQuery) *gorm.DB
+}
+
+// NewSearchBackend picks the backend for a database: Postgres tsvector, SQLite FTS5 or FTS4,
+// or a LIKE scan when no full-text index has been created
+func NewSearchBackend(database *gorm.DB) SearchBackend {
+	switch database.Dialector.Name() {
+	case "postgres":
+		return postgresSearch{}
+	case "sqlite":
+		var definition string
+		database.Raw("SELECT sql FROM sqlite_master WHERE type = 'table' AND name = ?", db.SearchTable).Scan(&definition)
+		switch definition = strings.ToLower(definition); {
+		case strings.Contains(definition, "fts5"):
+			return sqliteSearch{fts5: true}
+		case strings.Contains(definition, "fts4"):
+			return sqliteSearch{}
+		}
+	}
+	return likeSearch{}
+}
+
+// searchTerms splits text into lowercase words, dropping punctuation and repeats
+func searchTerms(text string) []string {
+	var terms []string
+	seen := map[string]bool{}
+	for _, word := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		if seen[word] {
+			continue
+		}
+		seen[word] = true
+		terms = append(terms, word)
+		if len(terms) == maxSearchTerms {
+			break
+		}
+	}
+	return terms
+}
+
+// matchExpression renders alternatives as an FTS query, e.g. ("a" "b") OR ("c"), with each
+// term passed through format
+func matchExpression(search SearchQuery, format func(term string) string) string {
+	groups := make([]string, len(search.Alternatives))
+	for i, terms := range search.Alternatives {
+		formatted := make([]string, len(terms))
+		for j, term := range terms {
+			formatted[j] = format(term)
+		}
+		groups[i] = "(" + strings.Join(formatted, " ") + ")"
+	}
+	return strings.Join(groups, " OR ")
+}
+
+// postgresSearch matches the weighted search_vector column and ranks with ts_rank
+type postgresSearch struct{}
+
+func (postgresSearch) Name() string { return "postgres" }
+
+func (postgresSearch) Search(query *gorm.DB, search SearchQuery) *gorm.DB {
+	if len(search.Alternatives) == 0 {
+		return query.Where("1 = 0")
+	}
+	// Alternatives are OR-ed together with the tsquery || operator
+	parts := make([]string, len(search.Alternatives))
+	vars := make([]interface{}, len(search.Alternatives))
+	for i, terms := range search.Alternatives {
+		parts[i] = fmt.Sprintf("plainto_tsquery('%s', ?)", db.SearchLanguage)
+		vars[i] = strings.Join(terms, " ")
+	}
+	tsquery := "(" + strings.Join(parts, " || ") + ")"
+	return query.Where("products.search_vector @@ "+tsquery, vars...).
+		Order(clause.OrderBy{Expression: clause.Expr{
+			SQL:                "ts_rank(products.search_vector, " + tsquery + ") DESC, products.id",
+			Vars:               vars,
+			WithoutParentheses: true,
+		}})
+}
+
+// sqliteSearch matches the products_fts table. FTS5 ranks with bm25, weighting name over
+// category over description; FTS4 has no ranking function so name matches simply come first.
+type sqliteSearch struct {
+	fts5 bool
+}
+
+func (s sqliteSearch) Name() string {
+	if s.fts5 {
+		return "sqlite-fts5"
+	}
+	return "sqlite-fts4"
+}
+
+func (s sqliteSearch) Search(query *gorm.DB, search SearchQuery) *gorm.DB {
+	if len(search.Alternatives) == 0 {
+		return query.Where("1 = 0")
+	}
+
+	var matches string
+	var vars []interface{}
+	if s.fts5 {
+		matches = "SELECT rowid AS product_id, bm25(products_fts, 10.0, 5.0, 1.0) AS search_rank FROM products_fts WHERE products_fts MATCH ?"
+		vars = []interface{}{matchExpression(search, func(term string) string { return `"` + term + `"` })}
+	} else {
+		matches = "SELECT rowid AS product_id, CASE WHEN rowid IN (SELECT rowid FROM products_fts WHERE products_fts MATCH ?) THEN 0 ELSE 1 END AS search_rank FROM products_fts WHERE products_fts MATCH ?"
+		vars = []interface{}{
+			matchExpression(search, func(term string) string { return "name:" + term }),
+			matchExpression(search, func(term string) string { return term }),
+		}
+	}
+
+	return query.Joins("JOIN ("+matches+") AS search_matches ON search_matches.product_id = products.id", vars...).
+		Order("search_matches.search_rank, products.id")
+}
+
+// likeSearch scans product names and descriptions when no full-text index exists
+type likeSearch struct{}
+
+func (likeSearch) Name() string { return "like" }
+
+func (likeSearch) Search(query *gorm.DB, search SearchQuery) *gorm.DB {
+	if len(search.Alternatives) == 0 {
+		return query.Where("1 = 0")
+	}
+
+	var groups []string
+	var vars []interface{}
+	for _, terms := range search.Alternatives {
+		conditions := make([]string, len(terms))
+		for i, term := range terms {
+			conditions[i] = "(LOWER(products.name) LIKE ? OR LOWER(products.description) LIKE ?)"
+			vars = append(vars, "%"+term+"%", "%"+term+"%")
+		}
+		groups = append(groups, "("+strings.Join(conditions, " AND ")+")")
+	}
+	return query.Where("("+strings.Join(groups, " OR ")+")", vars...).
+		Order(clause.OrderBy{Expression: clause.Expr{
+			SQL:                "CASE WHEN LOWER(products.name) LIKE ? THEN 0 ELSE 1 END, products.id",
+			Vars:               []interface{}{"%" + search.Alternatives[0][0] + "%"},
+			WithoutParentheses: true,
+		}})
+}
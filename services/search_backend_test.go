@@ -0,0 +1,83 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/geoo115/Ecommerce/db"
+	"github.com/geoo115/Ecommerce/models"
+	"github.com/stretchr/testify/assert"
+)
+
+// searchNames runs a search and returns the matching product names in result order
+func searchNames(t *testing.T, backend SearchBackend, text string) []string {
+	t.Helper()
+	var products []models.Product
+	if err := backend.Search(db.DB.Model(&models.Product{}), ParseSearchQuery(text)).Find(&products).Error; err != nil {
+		t.Fatalf("search failed: %v", err)
+	}
+	names := make([]string, len(products))
+	for i, product := range products {
+		names[i] = product.Name
+	}
+	return names
+}
+
+func TestSearchBackend_FullText(t *testing.T) {
+	originalDB := db.DB
+	testDB := db.SetupTestDB(t)
+	db.DB = testDB
+	defer func() { db.DB = originalDB }()
+
+	footwear := models.Category{Name: "Footwear"}
+	testDB.Create(&footwear)
+	testDB.Create(&models.Product{Name: "Trail Shoe", Description: "Grippy sole for running on mud", CategoryID: footwear.ID})
+	testDB.Create(&models.Product{Name: "Running Shoes", Description: "Lightweight road trainers", CategoryID: footwear.ID})
+	testDB.Create(&models.Product{Name: "Water Bottle", Description: "Keeps drinks cold on long runs"})
+
+	backend := NewSearchBackend(testDB)
+	assert.Contains(t, []string{"sqlite-fts5", "sqlite-fts4"}, backend.Name())
+
+	// Stemming matches "run", "runs" and "running"; name matches rank above description matches
+	names := searchNames(t, backend, "run")
+	if assert.Len(t, names, 3) {
+		assert.Equal(t, "Running Shoes", names[0])
+	}
+
+	// Every word must match somewhere in the name, category or description
+	assert.ElementsMatch(t, []string{"Trail Shoe", "Running Shoes"}, searchNames(t, backend, "footwear running"))
+	assert.Equal(t, []string{"Trail Shoe"}, searchNames(t, backend, "shoe mud"))
+	assert.Empty(t, searchNames(t, backend, "?!"))
+
+	// The index follows product and category changes
+	var bottle models.Product
+	testDB.Where("name = ?", "Water Bottle").First(&bottle)
+	testDB.Model(&bottle).Updates(map[string]interface{}{"name": "Hydration Flask", "category_id": footwear.ID})
+	assert.Equal(t, []string{"Hydration Flask"}, searchNames(t, backend, "flask footwear"))
+	assert.Empty(t, searchNames(t, backend, "bottle"))
+
+	testDB.Model(&footwear).Update("name", "Trainers & Boots")
+	assert.Len(t, searchNames(t, backend, "boots"), 3)
+
+	testDB.Unscoped().Delete(&bottle)
+	assert.Len(t, searchNames(t, backend, "boots"), 2)
+}
+
+func TestSearchBackend_IndexesExistingProducts(t *testing.T) {
+	originalDB := db.DB
+	testDB := db.SetupTestDB(t)
+	db.DB = testDB
+	defer func() { db.DB = originalDB }()
+
+	// Products written before the index existed are picked up when it is rebuilt
+	for _, trigger := range []string{"products_fts_insert", "products_fts_update", "products_fts_delete", "categories_fts_update"} {
+		testDB.Exec("DROP TRIGGER " + trigger)
+	}
+	testDB.Exec("DROP TABLE " + db.SearchTable)
+	testDB.Create(&models.Product{Name: "Ceramic Mug"})
+	assert.Equal(t, "like", NewSearchBackend(testDB).Name())
+	assert.Equal(t, []string{"Ceramic Mug"}, searchNames(t, NewSearchBackend(testDB), "mug"))
+
+	assert.NoError(t, db.EnsureSearchIndex(testDB))
+	assert.NotEqual(t, "like", NewSearchBackend(testDB).Name())
+	assert.Equal(t, []string{"Ceramic Mug"}, searchNames(t, NewSearchBackend(testDB), "mugs"))
+}
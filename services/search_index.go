@@ -0,0 +1,362 @@
+package services
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/geoo115/Ecommerce/config"
+	"github.com/geoo115/Ecommerce/db"
+	"github.com/geoo115/Ecommerce/models"
+	"gorm.io/gorm"
+)
+
+// maxSearchAlternatives bounds how many synonym expansions of a query are searched
+const maxSearchAlternatives = 16
+
+// Suggestion is an autocomplete completion for a partly typed search
+type Suggestion struct {
+	Type string `json:"type"` // "category" or "product"
+	ID   uint   `json:"id"`
+	Text string `json:"text"`
+	Slug string `json:"slug,omitempty"`
+}
+
+// SearchService interface defines typo tolerance, synonyms and autocomplete for product search.
+// It is backed by an in-process index of the catalogue that is rebuilt after catalogue changes.
+type SearchService interface {
+	Suggest(prefix string, limit int) ([]Suggestion, error)
+	Expand(search SearchQuery) (SearchQuery, error)
+	Correct(search SearchQuery) (SearchQuery, bool, error)
+}
+
+// searchService implements SearchService interface
+type searchService struct {
+	db *gorm.DB
+}
+
+// NewSearchService creates a new search service instance
+func NewSearchService() SearchService {
+	return NewSearchServiceWithDB(db.DB)
+}
+
+// NewSearchServiceWithDB creates a search service bound to a specific database handle
+func NewSearchServiceWithDB(database *gorm.DB) SearchService {
+	return &searchService{db: database}
+}
+
+// indexKey is one word-aligned suffix of a product or category name, e.g. "running shoes"
+// and "shoes" for "Running Shoes", so prefixes of any word in a name can be completed
+type indexKey struct {
+	key      string
+	entry    int
+	position int // Index of the first word of key within the name
+}
+
+// catalogSnapshot is an immutable build of the search index
+type catalogSnapshot struct {
+	suggestions []Suggestion
+	keys        []indexKey // Sorted by key
+	vocabulary  map[string]int
+	synonyms    [][][]string // Groups of phrases, each phrase a list of words
+}
+
+// catalogIndex holds the current snapshot and when it needs rebuilding
+type catalogIndex struct {
+	mu      sync.RWMutex
+	current *catalogSnapshot
+	pool    gorm.ConnPool
+	builtAt time.Time
+	stale   bool
+}
+
+var searchIndex = &catalogIndex{stale: true}
+
+// InvalidateSearchIndex marks the in-process search index for rebuilding on next use.
+// Call it after changing products, categories or synonyms.
+func InvalidateSearchIndex() {
+	searchIndex.mu.Lock()
+	searchIndex.stale = true
+	searchIndex.mu.Unlock()
+}
+
+// snapshot returns the index for a database, rebuilding it when it is stale, older than the
+// configured TTL or was built from a different database
+func (i *catalogIndex) snapshot(database *gorm.DB) (*catalogSnapshot, error) {
+	pool := database.Statement.ConnPool
+	ttl := config.GetSearchConfig().IndexTTL
+	fresh := func() bool {
+		return i.current != nil && !i.stale && i.pool == pool && time.Since(i.builtAt) < ttl
+	}
+
+	i.mu.RLock()
+	if fresh() {
+		current := i.current
+		i.mu.RUnlock()
+		return current, nil
+	}
+	i.mu.RUnlock()
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	if fresh() {
+		return i.current, nil
+	}
+	current, err := buildCatalogSnapshot(database)
+	if err != nil {
+		return nil, err
+	}
+	i.current, i.pool, i.builtAt, i.stale = current, pool, time.Now(), false
+	return current, nil
+}
+
+// buildCatalogSnapshot loads published product and category names, the words used across the
+// public catalogue and the synonym groups
+func buildCatalogSnapshot(database *gorm.DB) (*catalogSnapshot, error) {
+	var products []models.Product
+	if err := database.Scopes(PublishedProducts).Select("id, name, description").Find(&products).Error; err != nil {
+		return nil, err
+	}
+	var categories []models.Category
+	if err := database.Select("id, name, slug").Find(&categories).Error; err != nil {
+		return nil, err
+	}
+	var groups []models.SynonymGroup
+	if err := database.Find(&groups).Error; err != nil {
+		return nil, err
+	}
+
+	snapshot := &catalogSnapshot{vocabulary: map[string]int{}}
+	addWords := func(text string) []string {
+		words := searchTerms(text)
+		for _, word := range words {
+			snapshot.vocabulary[word]++
+		}
+		return words
+	}
+	addEntry := func(suggestion Suggestion) {
+		words := addWords(suggestion.Text)
+		for position := range words {
+			snapshot.keys = append(snapshot.keys, indexKey{
+				key:      strings.Join(words[position:], " "),
+				entry:    len(snapshot.suggestions),
+				position: position,
+			})
+		}
+		snapshot.suggestions = append(snapshot.suggestions, suggestion)
+	}
+
+	for _, category := range categories {
+		addEntry(Suggestion{Type: "category", ID: category.ID, Text: category.Name, Slug: category.Slug})
+	}
+	for _, product := range products {
+		addEntry(Suggestion{Type: "product", ID: product.ID, Text: product.Name})
+		addWords(product.Description)
+	}
+	sort.Slice(snapshot.keys, func(a, b int) bool { return snapshot.keys[a].key < snapshot.keys[b].key })
+
+	for _, group := range groups {
+		var phrases [][]string
+		for _, term := range group.Terms {
+			if words := addWords(term); len(words) > 0 {
+				phrases = append(phrases, words)
+			}
+		}
+		if len(phrases) > 1 {
+			snapshot.synonyms = append(snapshot.synonyms, phrases)
+		}
+	}
+	return snapshot, nil
+}
+
+// Suggest completes a partly typed search with category and product names. Names starting
+// with the prefix come before names with a later word starting with it, categories before
+// products and shorter names first.
+func (s *searchService) Suggest(prefix string, limit int) ([]Suggestion, error) {
+	suggestions := []Suggestion{}
+	normalized := strings.Join(searchTerms(prefix), " ")
+	if normalized == "" || limit <= 0 {
+		return suggestions, nil
+	}
+	snapshot, err := searchIndex.snapshot(s.db)
+	if err != nil {
+		return nil, err
+	}
+
+	best := map[int]int{} // entry -> 0 when the name starts with the prefix, 1 otherwise
+	start := sort.Search(len(snapshot.keys), func(i int) bool { return snapshot.keys[i].key >= normalized })
+	for i := start; i < len(snapshot.keys) && strings.HasPrefix(snapshot.keys[i].key, normalized); i++ {
+		score := 1
+		if snapshot.keys[i].position == 0 {
+			score = 0
+		}
+		if current, ok := best[snapshot.keys[i].entry]; !ok || score < current {
+			best[snapshot.keys[i].entry] = score
+		}
+	}
+
+	entries := make([]int, 0, len(best))
+	for entry := range best {
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(a, b int) bool {
+		x, y := snapshot.suggestions[entries[a]], snapshot.suggestions[entries[b]]
+		switch {
+		case best[entries[a]] != best[entries[b]]:
+			return best[entries[a]] < best[entries[b]]
+		case x.Type != y.Type:
+			return x.Type == "category"
+		case len(x.Text) != len(y.Text):
+			return len(x.Text) < len(y.Text)
+		default:
+			return x.Text < y.Text
+		}
+	})
+
+	seen := map[string]bool{}
+	for _, entry := range entries {
+		suggestion := snapshot.suggestions[entry]
+		// Products sharing a name are suggested once
+		if key := suggestion.Type + ":" + strings.ToLower(suggestion.Text); !seen[key] {
+			seen[key] = true
+			suggestions = append(suggestions, suggestion)
+		}
+		if len(suggestions) == limit {
+			break
+		}
+	}
+	return suggestions, nil
+}
+
+// Expand adds an alternative for each synonym of a phrase found in the query, so "tee shirt"
+// also searches for "t shirt" when both are in a synonym group
+func (s *searchService) Expand(search SearchQuery) (SearchQuery, error) {
+	if len(search.Alternatives) == 0 {
+		return search, nil
+	}
+	snapshot, err := searchIndex.snapshot(s.db)
+	if err != nil {
+		return search, err
+	}
+
+	alternatives := search.Alternatives
+	seen := map[string]bool{}
+	for _, terms := range alternatives {
+		seen[strings.Join(terms, " ")] = true
+	}
+	for _, group := range snapshot.synonyms {
+		for _, terms := range alternatives {
+			for _, phrase := range group {
+				at := indexOfPhrase(terms, phrase)
+				if at < 0 {
+					continue
+				}
+				for _, synonym := range group {
+					expanded := append(append(append([]string{}, terms[:at]...), synonym...), terms[at+len(phrase):]...)
+					if key := strings.Join(expanded, " "); !seen[key] && len(alternatives) < maxSearchAlternatives {
+						seen[key] = true
+						alternatives = append(alternatives, expanded)
+					}
+				}
+				break
+			}
+		}
+	}
+	return SearchQuery{Alternatives: alternatives}, nil
+}
+
+// indexOfPhrase returns where phrase occurs as consecutive words in terms, or -1
+func indexOfPhrase(terms, phrase []string) int {
+	for start := 0; start+len(phrase) <= len(terms); start++ {
+		match := true
+		for offset, word := range phrase {
+			if terms[start+offset] != word {
+				match = false
+				break
+			}
+		}
+		if match {
+			return start
+		}
+	}
+	return -1
+}
+
+// Correct replaces words that appear nowhere in the catalogue with the closest catalogue word
+// within the allowed edit distance, preferring more common words. It reports whether any
+// word changed.
+func (s *searchService) Correct(search SearchQuery) (SearchQuery, bool, error) {
+	if len(search.Alternatives) == 0 {
+		return search, false, nil
+	}
+	snapshot, err := searchIndex.snapshot(s.db)
+	if err != nil {
+		return search, false, err
+	}
+	maxDistance := config.GetSearchConfig().FuzzyMaxDistance
+
+	changed := false
+	corrected := make([]string, len(search.Alternatives[0]))
+	for i, term := range search.Alternatives[0] {
+		corrected[i] = term
+		if snapshot.vocabulary[term] > 0 {
+			continue
+		}
+		// Short words allow fewer edits so "cat" is not corrected to "hat"
+		allowed := (len([]rune(term)) - 1) / 3
+		if allowed > maxDistance {
+			allowed = maxDistance
+		}
+
+		bestDistance, bestCount := allowed+1, 0
+		for word, count := range snapshot.vocabulary {
+			distance := editDistance(term, word, allowed)
+			if distance < bestDistance || (distance == bestDistance && (count > bestCount || (count == bestCount && word < corrected[i]))) {
+				if distance <= allowed {
+					bestDistance, bestCount, corrected[i] = distance, count, word
+				}
+			}
+		}
+		if corrected[i] != term {
+			changed = true
+		}
+	}
+	return SearchQuery{Alternatives: [][]string{corrected}}, changed, nil
+}
+
+// editDistance returns the Damerau-Levenshtein (optimal string alignment) distance between two
+// words, or limit+1 once it is known to exceed limit
+func editDistance(a, b string, limit int) int {
+	x, y := []rune(a), []rune(b)
+	if diff := len(x) - len(y); diff > limit || -diff > limit {
+		return limit + 1
+	}
+
+	previous2 := make([]int, len(y)+1)
+	previous := make([]int, len(y)+1)
+	current := make([]int, len(y)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(x); i++ {
+		current[0] = i
+		rowMin := current[0]
+		for j := 1; j <= len(y); j++ {
+			cost := 1
+			if x[i-1] == y[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+			if i > 1 && j > 1 && x[i-1] == y[j-2] && x[i-2] == y[j-1] {
+				current[j] = min(current[j], previous2[j-2]+1)
+			}
+			rowMin = min(rowMin, current[j])
+		}
+		if rowMin > limit {
+			return limit + 1
+		}
+		previous2, previous, current = previous, current, previous2
+	}
+	return previous[len(y)]
+}
@@ -0,0 +1,107 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/geoo115/Ecommerce/db"
+	"github.com/geoo115/Ecommerce/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEditDistance(t *testing.T) {
+	assert.Equal(t, 0, editDistance("shoe", "shoe", 2))
+	assert.Equal(t, 1, editDistance("labtop", "laptop", 2))
+	assert.Equal(t, 1, editDistance("hte", "the", 2)) // Transposition
+	assert.Equal(t, 2, editDistance("kettle", "bottle", 2))
+	assert.Equal(t, 3, editDistance("kettle", "mantle", 2)) // Exceeds the limit
+	assert.Equal(t, 3, editDistance("mug", "mugshots", 2))
+}
+
+func TestSearchService(t *testing.T) {
+	originalDB := db.DB
+	testDB := db.SetupTestDB(t)
+	db.DB = testDB
+	defer func() { db.DB = originalDB }()
+	InvalidateSearchIndex()
+
+	kitchen := models.Category{Name: "Kitchen"}
+	testDB.Create(&kitchen)
+	testDB.Create(&models.Product{Name: "Laptop Stand", Description: "Aluminium stand for laptops"})
+	testDB.Create(&models.Product{Name: "Gaming Laptop", Description: "Fast laptop"})
+	testDB.Create(&models.Product{Name: "Kettle", Description: "Stainless steel", CategoryID: kitchen.ID})
+	testDB.Create(&models.Product{Name: "Tee Shirt", Description: "Cotton"})
+	testDB.Create(&models.Product{Name: "Sun Hat"})
+	service := NewSearchService()
+
+	t.Run("Suggest", func(t *testing.T) {
+		suggestions, err := service.Suggest("lap", 10)
+		assert.NoError(t, err)
+		// Names starting with the prefix come first
+		if assert.Len(t, suggestions, 2) {
+			assert.Equal(t, "Laptop Stand", suggestions[0].Text)
+			assert.Equal(t, "Gaming Laptop", suggestions[1].Text)
+			assert.Equal(t, "product", suggestions[0].Type)
+		}
+
+		suggestions, _ = service.Suggest("K", 10)
+		if assert.Len(t, suggestions, 2) {
+			assert.Equal(t, Suggestion{Type: "category", ID: kitchen.ID, Text: "Kitchen", Slug: "kitchen"}, suggestions[0])
+		}
+
+		suggestions, _ = service.Suggest("laptop st", 10)
+		assert.Len(t, suggestions, 1)
+		suggestions, _ = service.Suggest("lap", 1)
+		assert.Len(t, suggestions, 1)
+		suggestions, _ = service.Suggest("?!", 10)
+		assert.Empty(t, suggestions)
+	})
+
+	t.Run("Correct", func(t *testing.T) {
+		corrected, changed, err := service.Correct(ParseSearchQuery("labtop stnad"))
+		assert.NoError(t, err)
+		assert.True(t, changed)
+		assert.Equal(t, "laptop stand", corrected.Text())
+
+		// Known words and short words are left alone
+		_, changed, _ = service.Correct(ParseSearchQuery("kettle"))
+		assert.False(t, changed)
+		_, changed, _ = service.Correct(ParseSearchQuery("cat"))
+		assert.False(t, changed)
+	})
+
+	t.Run("Synonyms", func(t *testing.T) {
+		synonyms := NewSynonymService()
+		_, err := synonyms.Create([]string{"T-Shirt", "t shirt"})
+		assert.ErrorIs(t, err, ErrSynonymTerms)
+
+		group, err := synonyms.Create([]string{"T-Shirt", "Tee Shirt", "top"})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"t shirt", "tee shirt", "top"}, group.Terms)
+
+		expanded, err := service.Expand(ParseSearchQuery("white t-shirt"))
+		assert.NoError(t, err)
+		assert.Equal(t, [][]string{{"white", "t", "shirt"}, {"white", "tee", "shirt"}, {"white", "top"}}, expanded.Alternatives)
+		assert.Equal(t, "white t shirt", expanded.Text())
+
+		assert.NoError(t, synonyms.Update(group, []string{"t-shirt", "tee"}))
+		var stored models.SynonymGroup
+		testDB.First(&stored, group.ID)
+		assert.Equal(t, []string{"t shirt", "tee"}, stored.Terms)
+		expanded, _ = service.Expand(ParseSearchQuery("tee"))
+		assert.Equal(t, [][]string{{"tee"}, {"t", "shirt"}}, expanded.Alternatives)
+
+		assert.NoError(t, synonyms.Delete(group))
+		expanded, _ = service.Expand(ParseSearchQuery("tee"))
+		assert.Len(t, expanded.Alternatives, 1)
+	})
+
+	t.Run("RebuildsOnInvalidate", func(t *testing.T) {
+		testDB.Create(&models.Product{Name: "Lamp"})
+		suggestions, _ := service.Suggest("lam", 10)
+		assert.Empty(t, suggestions)
+
+		InvalidateSearchIndex()
+		suggestions, _ = service.Suggest("lam", 10)
+		assert.Len(t, suggestions, 1)
+	})
+}
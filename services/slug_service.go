@@ -0,0 +1,115 @@
+package services
+
+import (
+	"errors"
+
+	"github.com/geoo115/Ecommerce/db"
+	"github.com/geoo115/Ecommerce/models"
+	"github.com/geoo115/Ecommerce/utils"
+	"gorm.io/gorm"
+)
+
+// ErrSlugNotFound is returned when neither a current nor a former slug matches
+var ErrSlugNotFound = errors.New("slug not found")
+
+// SlugService interface defines slug lookup and product slug changes
+type SlugService interface {
+	Resolve(resourceType, slug string) (id uint, current string, err error)
+	ChangeProductSlug(product *models.Product, slug string) error
+}
+
+// slugService implements SlugService interface
+type slugService struct {
+	db *gorm.DB
+}
+
+// NewSlugService creates a new slug service instance
+func NewSlugService() SlugService {
+	return NewSlugServiceWithDB(db.DB)
+}
+
+// NewSlugServiceWithDB creates a slug service bound to a specific database handle
+func NewSlugServiceWithDB(database *gorm.DB) SlugService {
+	return &slugService{db: database}
+}
+
+// Resolve finds the product or category a slug belongs to. A former slug resolves to the record
+// it now belongs to, and current then differs from slug so callers can redirect.
+func (s *slugService) Resolve(resourceType, slug string) (uint, string, error) {
+	var model interface{}
+	switch resourceType {
+	case models.SlugResourceProduct:
+		model = &models.Product{}
+	case models.SlugResourceCategory:
+		model = &models.Category{}
+	default:
+		return 0, "", ErrSlugNotFound
+	}
+
+	var target struct {
+		ID   uint
+		Slug string
+	}
+	result := s.db.Model(model).Select("id", "slug").Where("slug = ?", slug).Limit(1).Scan(&target)
+	if result.Error != nil {
+		return 0, "", result.Error
+	}
+	if result.RowsAffected > 0 {
+		return target.ID, target.Slug, nil
+	}
+
+	var redirect models.SlugRedirect
+	if err := s.db.Where("resource_type = ? AND slug = ?", resourceType, slug).First(&redirect).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return 0, "", ErrSlugNotFound
+		}
+		return 0, "", err
+	}
+	result = s.db.Model(model).Select("id", "slug").Where("id = ?", redirect.ResourceID).Limit(1).Scan(&target)
+	if result.Error != nil {
+		return 0, "", result.Error
+	}
+	if result.RowsAffected == 0 {
+		return 0, "", ErrSlugNotFound
+	}
+	return target.ID, target.Slug, nil
+}
+
+// ChangeProductSlug gives a product a new slug and keeps the old one as a redirect
+func (s *slugService) ChangeProductSlug(product *models.Product, slug string) error {
+	if !utils.ValidateSlug(slug) {
+		return ErrInvalidSlug
+	}
+	if slug == product.Slug {
+		return nil
+	}
+	inUse, err := models.SlugInUse(s.db, &models.Product{}, slug, product.ID)
+	if err != nil {
+		return err
+	}
+	if inUse {
+		return ErrDuplicateSlug
+	}
+
+	oldSlug := product.Slug
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		product.Slug = slug
+		if err := tx.Model(product).Update("slug", slug).Error; err != nil {
+			return err
+		}
+		return recordSlugChange(tx, models.SlugResourceProduct, product.ID, oldSlug, slug)
+	})
+}
+
+// recordSlugChange redirects a record's old slug to it. A redirect already held by the new
+// slug is dropped, since the slug is live again.
+func recordSlugChange(tx *gorm.DB, resourceType string, id uint, oldSlug, newSlug string) error {
+	if oldSlug == "" || oldSlug == newSlug {
+		return nil
+	}
+	if err := tx.Unscoped().Where("resource_type = ? AND slug IN ?", resourceType, []string{oldSlug, newSlug}).
+		Delete(&models.SlugRedirect{}).Error; err != nil {
+		return err
+	}
+	return tx.Create(&models.SlugRedirect{ResourceType: resourceType, Slug: oldSlug, ResourceID: id}).Error
+}
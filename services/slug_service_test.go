@@ -0,0 +1,76 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/geoo115/Ecommerce/db"
+	"github.com/geoo115/Ecommerce/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSlugService(t *testing.T) {
+	originalDB := db.DB
+	testDB := db.SetupTestDB(t)
+	db.DB = testDB
+	defer func() { db.DB = originalDB }()
+
+	slugs := NewSlugServiceWithDB(testDB)
+	kettle := createStockedProduct(t, "Kettle", 30, 1)
+	toaster := createStockedProduct(t, "Toaster", 25, 1)
+
+	t.Run("ChangeProductSlug", func(t *testing.T) {
+		assert.ErrorIs(t, slugs.ChangeProductSlug(&kettle, "Steel Kettle"), ErrInvalidSlug)
+		assert.ErrorIs(t, slugs.ChangeProductSlug(&kettle, "toaster"), ErrDuplicateSlug)
+
+		assert.NoError(t, slugs.ChangeProductSlug(&kettle, "steel-kettle"))
+		assert.NoError(t, slugs.ChangeProductSlug(&kettle, "kettle-1-7l"))
+
+		// Both former slugs lead to the current one
+		for _, slug := range []string{"kettle", "steel-kettle", "kettle-1-7l"} {
+			id, current, err := slugs.Resolve(models.SlugResourceProduct, slug)
+			assert.NoError(t, err, slug)
+			assert.Equal(t, kettle.ID, id, slug)
+			assert.Equal(t, "kettle-1-7l", current, slug)
+		}
+	})
+
+	t.Run("ReclaimedSlug", func(t *testing.T) {
+		// Taking a former slug makes it live for its new owner
+		assert.NoError(t, slugs.ChangeProductSlug(&toaster, "kettle"))
+		id, current, err := slugs.Resolve(models.SlugResourceProduct, "kettle")
+		assert.NoError(t, err)
+		assert.Equal(t, toaster.ID, id)
+		assert.Equal(t, "kettle", current)
+
+		// Moving the kettle back to it replaces the redirect
+		assert.NoError(t, slugs.ChangeProductSlug(&toaster, "toaster"))
+		assert.NoError(t, slugs.ChangeProductSlug(&kettle, "kettle"))
+		id, current, err = slugs.Resolve(models.SlugResourceProduct, "kettle-1-7l")
+		assert.NoError(t, err)
+		assert.Equal(t, kettle.ID, id)
+		assert.Equal(t, "kettle", current)
+	})
+
+	t.Run("Categories", func(t *testing.T) {
+		category := models.Category{Name: "Small Appliances"}
+		testDB.Create(&category)
+		slug := "appliances"
+		assert.NoError(t, NewCategoryServiceWithDB(testDB).Update(&category, CategoryUpdate{Slug: &slug}))
+		assert.Equal(t, "/categories/appliances", category.CanonicalURL)
+
+		id, current, err := slugs.Resolve(models.SlugResourceCategory, "small-appliances")
+		assert.NoError(t, err)
+		assert.Equal(t, category.ID, id)
+		assert.Equal(t, "appliances", current)
+
+		// Redirects are per resource type
+		_, _, err = slugs.Resolve(models.SlugResourceProduct, "small-appliances")
+		assert.ErrorIs(t, err, ErrSlugNotFound)
+	})
+
+	t.Run("DeletedTarget", func(t *testing.T) {
+		testDB.Delete(&toaster)
+		_, _, err := slugs.Resolve(models.SlugResourceProduct, "toaster")
+		assert.ErrorIs(t, err, ErrSlugNotFound)
+	})
+}
@@ -0,0 +1,213 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/geoo115/Ecommerce/config"
+	"github.com/geoo115/Ecommerce/db"
+	"github.com/geoo115/Ecommerce/models"
+	"github.com/geoo115/Ecommerce/utils"
+	"gorm.io/gorm"
+)
+
+// Subscription statuses
+const (
+	SubscriptionActive    = "Active"
+	SubscriptionPaused    = "Paused"
+	SubscriptionCancelled = "Cancelled"
+)
+
+// Subscription errors surfaced to handlers and schedulers
+var (
+	// ErrInvalidSubscriptionState is returned when an action is not allowed in the subscription's current status
+	ErrInvalidSubscriptionState = errors.New("action not allowed for subscription status")
+	// ErrCycleClaimed is returned when another run already claimed the subscription's due cycle
+	ErrCycleClaimed = errors.New("subscription cycle already claimed")
+)
+
+// SubscriptionService interface defines recurring order business logic
+type SubscriptionService interface {
+	Skip(sub *models.Subscription) error
+	Pause(sub *models.Subscription) error
+	Resume(sub *models.Subscription, now time.Time) error
+	Cancel(sub *models.Subscription) error
+	ProcessDueSubscriptions(now time.Time) (int, error)
+	RunCycle(sub *models.Subscription, now time.Time) (*models.Order, error)
+}
+
+// subscriptionService implements SubscriptionService interface
+type subscriptionService struct {
+	db     *gorm.DB
+	config config.SubscriptionConfig
+}
+
+// NewSubscriptionService creates a new subscription service instance
+func NewSubscriptionService() SubscriptionService {
+	return &subscriptionService{db: db.DB, config: config.GetSubscriptionConfig()}
+}
+
+// interval returns the subscription's cycle length
+func interval(sub *models.Subscription) time.Duration {
+	return time.Duration(sub.IntervalDays) * 24 * time.Hour
+}
+
+// Skip moves the next delivery of an active subscription forward by one interval
+func (s *subscriptionService) Skip(sub *models.Subscription) error {
+	if sub.Status != SubscriptionActive {
+		return ErrInvalidSubscriptionState
+	}
+	sub.NextRunAt = sub.NextRunAt.Add(interval(sub))
+	return s.db.Model(sub).Update("next_run_at", sub.NextRunAt).Error
+}
+
+// Pause stops an active subscription from generating orders
+func (s *subscriptionService) Pause(sub *models.Subscription) error {
+	if sub.Status != SubscriptionActive {
+		return ErrInvalidSubscriptionState
+	}
+	sub.Status = SubscriptionPaused
+	return s.db.Model(sub).Update("status", sub.Status).Error
+}
+
+// Resume reactivates a paused subscription, clearing failures and scheduling
+// the next run no earlier than now
+func (s *subscriptionService) Resume(sub *models.Subscription, now time.Time) error {
+	if sub.Status != SubscriptionPaused {
+		return ErrInvalidSubscriptionState
+	}
+	sub.Status = SubscriptionActive
+	sub.FailedAttempts = 0
+	sub.LastError = ""
+	if sub.NextRunAt.Before(now) {
+		sub.NextRunAt = now
+	}
+	return s.db.Model(sub).Updates(map[string]interface{}{
+		"status":          sub.Status,
+		"failed_attempts": 0,
+		"last_error":      "",
+		"next_run_at":     sub.NextRunAt,
+	}).Error
+}
+
+// Cancel permanently stops a subscription
+func (s *subscriptionService) Cancel(sub *models.Subscription) error {
+	if sub.Status == SubscriptionCancelled {
+		return ErrInvalidSubscriptionState
+	}
+	sub.Status = SubscriptionCancelled
+	return s.db.Model(sub).Update("status", sub.Status).Error
+}
+
+// ProcessDueSubscriptions runs a cycle for every active subscription due at or before now.
+// It returns the number of orders successfully placed and paid.
+func (s *subscriptionService) ProcessDueSubscriptions(now time.Time) (int, error) {
+	var due []models.Subscription
+	if err := s.db.Preload("Items").
+		Where("status = ? AND next_run_at <= ?", SubscriptionActive, now).
+		Find(&due).Error; err != nil {
+		return 0, err
+	}
+
+	placed := 0
+	for i := range due {
+		_, err := s.RunCycle(&due[i], now)
+		if errors.Is(err, ErrCycleClaimed) {
+			continue
+		}
+		if err != nil {
+			utils.Warn("Subscription %d cycle failed: %v", due[i].ID, err)
+			continue
+		}
+		placed++
+	}
+	return placed, nil
+}
+
+// RunCycle places and charges one order for the subscription. Failures are
+// retried after the configured retry interval and pause the subscription once
+// the maximum number of attempts is reached. The cycle is claimed first by moving
+// next_run_at on, so overlapping scheduler runs place it only once.
+func (s *subscriptionService) RunCycle(sub *models.Subscription, now time.Time) (*models.Order, error) {
+	nextRunAt := now.Add(interval(sub))
+	result := s.db.Model(&models.Subscription{}).
+		Where("id = ? AND status = ? AND next_run_at = ?", sub.ID, SubscriptionActive, sub.NextRunAt).
+		Update("next_run_at", nextRunAt)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrCycleClaimed
+	}
+	sub.NextRunAt = nextRunAt
+
+	order, err := s.placeOrder(sub)
+	if err != nil {
+		return nil, s.recordFailure(sub, now, err)
+	}
+
+	sub.FailedAttempts = 0
+	sub.LastError = ""
+	sub.LastOrderID = &order.ID
+	if err := s.db.Model(sub).Updates(map[string]interface{}{
+		"failed_attempts": 0,
+		"last_error":      "",
+		"last_order_id":   order.ID,
+	}).Error; err != nil {
+		return order, err
+	}
+	return order, nil
+}
+
+// placeOrder creates the cycle's order and charges it, cancelling the order if payment fails
+func (s *subscriptionService) placeOrder(sub *models.Subscription) (*models.Order, error) {
+	lines := make([]OrderLine, 0, len(sub.Items))
+	for _, item := range sub.Items {
+		lines = append(lines, OrderLine{ProductID: item.ProductID, VariantID: item.VariantID, Quantity: item.Quantity})
+	}
+	if len(lines) == 0 {
+		return nil, errors.New("subscription has no items")
+	}
+
+	addressID := sub.AddressID
+	subscriptionID := sub.ID
+	order := models.Order{
+		UserID:         sub.UserID,
+		AddressID:      &addressID,
+		SubscriptionID: &subscriptionID,
+	}
+
+	orders := NewOrderServiceWithDB(s.db)
+	if err := orders.CreateOrder(&order, lines); err != nil {
+		return nil, err
+	}
+
+	if _, err := NewPaymentServiceWithDB(s.db).ChargeOrder(&order, sub.PaymentMethod); err != nil {
+		if cancelErr := orders.CancelOrder(&order); cancelErr != nil {
+			utils.Warn("Failed to cancel unpaid subscription order %d: %v", order.ID, cancelErr)
+		}
+		return nil, err
+	}
+	return &order, nil
+}
+
+// recordFailure schedules a retry or pauses the subscription after too many failures
+func (s *subscriptionService) recordFailure(sub *models.Subscription, now time.Time, cause error) error {
+	sub.FailedAttempts++
+	sub.LastError = cause.Error()
+	sub.NextRunAt = now.Add(s.config.RetryInterval)
+	if sub.FailedAttempts >= s.config.MaxRetries {
+		sub.Status = SubscriptionPaused
+	}
+
+	if err := s.db.Model(sub).Updates(map[string]interface{}{
+		"failed_attempts": sub.FailedAttempts,
+		"last_error":      sub.LastError,
+		"next_run_at":     sub.NextRunAt,
+		"status":          sub.Status,
+	}).Error; err != nil {
+		return fmt.Errorf("%v (and failed to record failure: %w)", cause, err)
+	}
+	return cause
+}
@@ -0,0 +1,161 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/geoo115/Ecommerce/db"
+	"github.com/geoo115/Ecommerce/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func createTestSubscription(t *testing.T, productID uint, quantity int, nextRun time.Time) models.Subscription {
+	t.Helper()
+	user := models.User{Username: "subscriber", Email: "subscriber@example.com"}
+	db.DB.Create(&user)
+	address := models.Address{UserID: user.ID, Address: "1 Main St", City: "Town", ZipCode: "12345"}
+	db.DB.Create(&address)
+
+	sub := models.Subscription{
+		UserID:        user.ID,
+		AddressID:     address.ID,
+		IntervalDays:  7,
+		NextRunAt:     nextRun,
+		Status:        SubscriptionActive,
+		PaymentMethod: "card",
+		Items:         []models.SubscriptionItem{{ProductID: productID, Quantity: quantity}},
+	}
+	if err := db.DB.Create(&sub).Error; err != nil {
+		t.Fatalf("failed to create subscription: %v", err)
+	}
+	return sub
+}
+
+func TestSubscriptionService_ProcessDueSubscriptions(t *testing.T) {
+	originalDB := db.DB
+	testDB := db.SetupTestDB(t)
+	db.DB = testDB
+	defer func() { db.DB = originalDB }()
+
+	now := time.Now()
+	product := createStockedProduct(t, "Filters", 3, 10)
+	due := createTestSubscription(t, product.ID, 2, now.Add(-time.Minute))
+	future := createTestSubscription(t, product.ID, 1, now.Add(time.Hour))
+
+	placed, err := NewSubscriptionService().ProcessDueSubscriptions(now)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, placed)
+
+	var order models.Order
+	assert.NoError(t, testDB.Where("subscription_id = ?", due.ID).First(&order).Error)
+	assert.Equal(t, "Paid", order.Status)
+	assert.Equal(t, 6.0, order.TotalAmount)
+	assert.Equal(t, due.AddressID, *order.AddressID)
+
+	var stored models.Subscription
+	testDB.First(&stored, due.ID)
+	assert.WithinDuration(t, now.Add(7*24*time.Hour), stored.NextRunAt, time.Second)
+	assert.Equal(t, order.ID, *stored.LastOrderID)
+
+	var count int64
+	testDB.Model(&models.Order{}).Where("subscription_id = ?", future.ID).Count(&count)
+	assert.Equal(t, int64(0), count)
+}
+
+func TestSubscriptionService_RunCycleOnce(t *testing.T) {
+	originalDB := db.DB
+	testDB := db.SetupTestDB(t)
+	db.DB = testDB
+	defer func() { db.DB = originalDB }()
+
+	now := time.Now()
+	product := createStockedProduct(t, "Beans", 4, 10)
+	sub := createTestSubscription(t, product.ID, 1, now.Add(-time.Minute))
+	service := NewSubscriptionService()
+
+	// Two overlapping runs loaded the same due subscription
+	var first, second models.Subscription
+	testDB.Preload("Items").First(&first, sub.ID)
+	testDB.Preload("Items").First(&second, sub.ID)
+
+	_, err := service.RunCycle(&first, now)
+	assert.NoError(t, err)
+	_, err = service.RunCycle(&second, now)
+	assert.ErrorIs(t, err, ErrCycleClaimed)
+
+	var count int64
+	testDB.Model(&models.Order{}).Where("subscription_id = ?", sub.ID).Count(&count)
+	assert.Equal(t, int64(1), count)
+
+	var payments int64
+	testDB.Model(&models.Payment{}).Count(&payments)
+	assert.Equal(t, int64(1), payments)
+}
+
+func TestSubscriptionService_RetriesThenPauses(t *testing.T) {
+	originalDB := db.DB
+	testDB := db.SetupTestDB(t)
+	db.DB = testDB
+	originalGateway := Gateway
+	Gateway = decliningGateway{}
+	defer func() {
+		db.DB = originalDB
+		Gateway = originalGateway
+	}()
+	t.Setenv("SUBSCRIPTION_MAX_RETRIES", "2")
+	t.Setenv("SUBSCRIPTION_RETRY_INTERVAL", "1h")
+
+	now := time.Now()
+	product := createStockedProduct(t, "Pods", 5, 10)
+	sub := createTestSubscription(t, product.ID, 1, now)
+	service := NewSubscriptionService()
+
+	_, err := service.RunCycle(&sub, now)
+	assert.ErrorIs(t, err, ErrPaymentDeclined)
+	assert.Equal(t, 1, sub.FailedAttempts)
+	assert.Equal(t, SubscriptionActive, sub.Status)
+	assert.WithinDuration(t, now.Add(time.Hour), sub.NextRunAt, time.Second)
+
+	// The unpaid order is cancelled and its stock returned
+	var inv models.Inventory
+	testDB.Where("product_id = ?", product.ID).First(&inv)
+	assert.Equal(t, 10, inv.Stock)
+
+	_, err = service.RunCycle(&sub, now.Add(time.Hour))
+	assert.Error(t, err)
+
+	var stored models.Subscription
+	testDB.First(&stored, sub.ID)
+	assert.Equal(t, SubscriptionPaused, stored.Status)
+	assert.Equal(t, 2, stored.FailedAttempts)
+	assert.Contains(t, stored.LastError, "card expired")
+}
+
+func TestSubscriptionService_StatusActions(t *testing.T) {
+	originalDB := db.DB
+	testDB := db.SetupTestDB(t)
+	db.DB = testDB
+	defer func() { db.DB = originalDB }()
+
+	now := time.Now()
+	product := createStockedProduct(t, "Soap", 2, 10)
+	sub := createTestSubscription(t, product.ID, 1, now.Add(-48*time.Hour))
+	service := NewSubscriptionService()
+
+	assert.NoError(t, service.Skip(&sub))
+	assert.WithinDuration(t, now.Add(5*24*time.Hour), sub.NextRunAt, time.Second)
+
+	assert.NoError(t, service.Pause(&sub))
+	assert.ErrorIs(t, service.Pause(&sub), ErrInvalidSubscriptionState)
+	assert.ErrorIs(t, service.Skip(&sub), ErrInvalidSubscriptionState)
+
+	assert.NoError(t, service.Resume(&sub, now))
+	assert.Equal(t, SubscriptionActive, sub.Status)
+
+	assert.NoError(t, service.Cancel(&sub))
+	assert.ErrorIs(t, service.Resume(&sub, now), ErrInvalidSubscriptionState)
+
+	var stored models.Subscription
+	testDB.First(&stored, sub.ID)
+	assert.Equal(t, SubscriptionCancelled, stored.Status)
+}
@@ -0,0 +1,101 @@
+package services
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/geoo115/Ecommerce/db"
+	"github.com/geoo115/Ecommerce/models"
+	"gorm.io/gorm"
+)
+
+// ErrSynonymTerms is returned when a synonym group has fewer than two distinct terms
+var ErrSynonymTerms = errors.New("synonym groups need at least two distinct terms")
+
+// SynonymService interface defines management of search synonym groups
+type SynonymService interface {
+	List() ([]models.SynonymGroup, error)
+	Create(terms []string) (*models.SynonymGroup, error)
+	Update(group *models.SynonymGroup, terms []string) error
+	Delete(group *models.SynonymGroup) error
+}
+
+// synonymService implements SynonymService interface
+type synonymService struct {
+	db *gorm.DB
+}
+
+// NewSynonymService creates a new synonym service instance
+func NewSynonymService() SynonymService {
+	return NewSynonymServiceWithDB(db.DB)
+}
+
+// NewSynonymServiceWithDB creates a synonym service bound to a specific database handle
+func NewSynonymServiceWithDB(database *gorm.DB) SynonymService {
+	return &synonymService{db: database}
+}
+
+// List returns every synonym group, oldest first
+func (s *synonymService) List() ([]models.SynonymGroup, error) {
+	groups := []models.SynonymGroup{}
+	if err := s.db.Order("id").Find(&groups).Error; err != nil {
+		return nil, err
+	}
+	return groups, nil
+}
+
+// Create adds a synonym group; searches pick it up immediately
+func (s *synonymService) Create(terms []string) (*models.SynonymGroup, error) {
+	normalized, err := synonymTerms(terms)
+	if err != nil {
+		return nil, err
+	}
+	group := &models.SynonymGroup{Terms: normalized}
+	if err := s.db.Create(group).Error; err != nil {
+		return nil, err
+	}
+	InvalidateSearchIndex()
+	return group, nil
+}
+
+// Update replaces the terms of a synonym group
+func (s *synonymService) Update(group *models.SynonymGroup, terms []string) error {
+	normalized, err := synonymTerms(terms)
+	if err != nil {
+		return err
+	}
+	group.Terms = normalized
+	if err := s.db.Model(group).Select("terms").Updates(group).Error; err != nil {
+		return err
+	}
+	InvalidateSearchIndex()
+	return nil
+}
+
+// Delete removes a synonym group
+func (s *synonymService) Delete(group *models.SynonymGroup) error {
+	if err := s.db.Unscoped().Delete(group).Error; err != nil {
+		return err
+	}
+	InvalidateSearchIndex()
+	return nil
+}
+
+// synonymTerms normalises terms to lowercase words the way searches are parsed, dropping
+// blanks and repeats
+func synonymTerms(terms []string) ([]string, error) {
+	var normalized []string
+	seen := map[string]bool{}
+	for _, term := range terms {
+		term = strings.Join(searchTerms(term), " ")
+		if term == "" || seen[term] {
+			continue
+		}
+		seen[term] = true
+		normalized = append(normalized, term)
+	}
+	if len(normalized) < 2 {
+		return nil, ErrSynonymTerms
+	}
+	return normalized, nil
+}
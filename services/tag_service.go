@@ -0,0 +1,95 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/geoo115/Ecommerce/db"
+	"github.com/geoo115/Ecommerce/models"
+	"gorm.io/gorm"
+)
+
+// maxProductTags bounds how many tags one product may have
+const maxProductTags = 50
+
+// ErrInvalidTag is returned for empty or overlong tags, tags with commas, which separate tags in
+// collection rules, and for too many tags on a product
+var ErrInvalidTag = errors.New("tags must be 1-64 characters without commas and a product may have at most 50")
+
+// TagService interface defines free-form product tags
+type TagService interface {
+	SetTags(product *models.Product, tags []string) error
+}
+
+// tagService implements TagService interface
+type tagService struct {
+	db *gorm.DB
+}
+
+// NewTagService creates a new tag service instance
+func NewTagService() TagService {
+	return NewTagServiceWithDB(db.DB)
+}
+
+// NewTagServiceWithDB creates a tag service bound to a specific database handle
+func NewTagServiceWithDB(database *gorm.DB) TagService {
+	return &tagService{db: database}
+}
+
+// NormalizeTag lowercases a tag and collapses its whitespace, so "Summer  Sale" and
+// "summer sale" are the same tag
+func NormalizeTag(tag string) (string, error) {
+	normalized := strings.Join(strings.Fields(strings.ToLower(tag)), " ")
+	if normalized == "" || utf8.RuneCountInString(normalized) > 64 || strings.Contains(normalized, ",") {
+		return "", ErrInvalidTag
+	}
+	return normalized, nil
+}
+
+// NormalizeTags normalizes a list of tags, dropping repeats and keeping the first occurrence's position
+func NormalizeTags(tags []string) ([]string, error) {
+	seen := map[string]bool{}
+	normalized := []string{}
+	for _, tag := range tags {
+		name, err := NormalizeTag(tag)
+		if err != nil {
+			return nil, err
+		}
+		if !seen[name] {
+			seen[name] = true
+			normalized = append(normalized, name)
+		}
+	}
+	return normalized, nil
+}
+
+// SetTags replaces a product's tags
+func (s *tagService) SetTags(product *models.Product, tags []string) error {
+	names, err := NormalizeTags(tags)
+	if err != nil {
+		return err
+	}
+	if len(names) > maxProductTags {
+		return ErrInvalidTag
+	}
+
+	rows := make([]models.ProductTag, len(names))
+	for i, name := range names {
+		rows[i] = models.ProductTag{ProductID: product.ID, Name: name}
+	}
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Where("product_id = ?", product.ID).Delete(&models.ProductTag{}).Error; err != nil {
+			return err
+		}
+		if len(rows) == 0 {
+			return nil
+		}
+		return tx.Create(&rows).Error
+	})
+	if err != nil {
+		return err
+	}
+	product.Tags = rows
+	return nil
+}
@@ -0,0 +1,322 @@
+package services
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/geoo115/Ecommerce/db"
+	"github.com/geoo115/Ecommerce/models"
+	"gorm.io/gorm"
+)
+
+// Variant errors surfaced to handlers
+var (
+	ErrVariantNotFound  = errors.New("variant not found")
+	ErrVariantRequired  = errors.New("a variant must be selected for this product")
+	ErrVariantOptions   = errors.New("variant must set one value for each of the product's options")
+	ErrDuplicateVariant = errors.New("a variant with these options already exists")
+	ErrSKURequired      = errors.New("SKU is required")
+	ErrDuplicateSKU     = errors.New("SKU is already in use")
+)
+
+// VariantOption is an option name and the value a variant takes for it
+type VariantOption struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// VariantUpdate describes changes to a variant's sellable fields; nil fields are left unchanged
+type VariantUpdate struct {
+	SKU           *string
+	Barcode       *string
+	PriceOverride *float64
+	ClearPrice    bool // Remove the price override so the product price applies
+	Stock         *int
+}
+
+// VariantService interface defines product variant business logic
+type VariantService interface {
+	CreateVariant(product *models.Product, variant *models.ProductVariant, options []VariantOption) error
+	UpdateVariant(variant *models.ProductVariant, update VariantUpdate) error
+	DeleteVariant(variant *models.ProductVariant) error
+}
+
+// variantService implements VariantService interface
+type variantService struct {
+	db *gorm.DB
+}
+
+// NewVariantService creates a new variant service instance
+func NewVariantService() VariantService {
+	return &variantService{db: db.DB}
+}
+
+// CreateVariant adds a variant to a product. The first variant defines the product's option
+// types in the order given; later variants must set a value for exactly those options and
+// may introduce new values. Each combination of values and each SKU must be unique.
+func (s *variantService) CreateVariant(product *models.Product, variant *models.ProductVariant, options []VariantOption) error {
+	variant.SKU = strings.TrimSpace(variant.SKU)
+	if len(options) == 0 {
+		return ErrVariantOptions
+	}
+
+	if product.IsBundle() {
+		return ErrBundleVariants
+	}
+	if product.IsDigital() {
+		return ErrDigitalConflict
+	}
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := ensureSKUAvailable(tx, variant.SKU, 0); err != nil {
+			return err
+		}
+		var components int64
+		if err := tx.Model(&models.BundleItem{}).Where("component_id = ?", product.ID).Count(&components).Error; err != nil {
+			return err
+		}
+		if components > 0 {
+			return ErrBundleVariants
+		}
+
+		var existing []models.ProductOption
+		if err := tx.Preload("Values").Where("product_id = ?", product.ID).Order("position").Find(&existing).Error; err != nil {
+			return err
+		}
+
+		chosen := make(map[string]string, len(options))
+		for _, opt := range options {
+			name, value := strings.TrimSpace(opt.Name), strings.TrimSpace(opt.Value)
+			key := strings.ToLower(name)
+			if name == "" || value == "" {
+				return ErrVariantOptions
+			}
+			if _, dup := chosen[key]; dup {
+				return ErrVariantOptions
+			}
+			chosen[key] = value
+		}
+
+		if len(existing) == 0 {
+			for i, opt := range options {
+				option := models.ProductOption{ProductID: product.ID, Name: strings.TrimSpace(opt.Name), Position: i}
+				if err := tx.Create(&option).Error; err != nil {
+					return err
+				}
+				existing = append(existing, option)
+			}
+		} else if len(existing) != len(chosen) {
+			return ErrVariantOptions
+		}
+
+		values := make([]models.ProductOptionValue, 0, len(existing))
+		titles := make([]string, 0, len(existing))
+		for _, option := range existing {
+			value, ok := chosen[strings.ToLower(option.Name)]
+			if !ok {
+				return ErrVariantOptions
+			}
+
+			var match *models.ProductOptionValue
+			for i := range option.Values {
+				if strings.EqualFold(option.Values[i].Value, value) {
+					match = &option.Values[i]
+					break
+				}
+			}
+			if match == nil {
+				match = &models.ProductOptionValue{OptionID: option.ID, Value: value}
+				if err := tx.Create(match).Error; err != nil {
+					return err
+				}
+			}
+			values = append(values, *match)
+			titles = append(titles, match.Value)
+		}
+
+		if err := ensureUniqueCombination(tx, product.ID, values); err != nil {
+			return err
+		}
+
+		variant.ProductID = product.ID
+		variant.Title = strings.Join(titles, " / ")
+		variant.OptionValues = values
+		return tx.Create(variant).Error
+	})
+}
+
+// UpdateVariant changes a variant's SKU, barcode, price override or stock
+func (s *variantService) UpdateVariant(variant *models.ProductVariant, update VariantUpdate) error {
+	updates := map[string]interface{}{}
+	if update.SKU != nil {
+		sku := strings.TrimSpace(*update.SKU)
+		if err := ensureSKUAvailable(s.db, sku, variant.ID); err != nil {
+			return err
+		}
+		variant.SKU = sku
+		updates["sku"] = sku
+	}
+	if update.Barcode != nil {
+		variant.Barcode = strings.TrimSpace(*update.Barcode)
+		updates["barcode"] = variant.Barcode
+	}
+	if update.ClearPrice {
+		variant.PriceOverride = nil
+		updates["price_override"] = nil
+	} else if update.PriceOverride != nil {
+		price := *update.PriceOverride
+		variant.PriceOverride = &price
+		updates["price_override"] = price
+	}
+	if update.Stock != nil {
+		variant.Stock = *update.Stock
+		updates["stock"] = variant.Stock
+	}
+	if len(updates) == 0 {
+		return ErrNoChanges
+	}
+	return s.db.Model(variant).Updates(updates).Error
+}
+
+// DeleteVariant removes a variant and any cart lines holding it. Deleting a product's last
+// variant also removes its option types, turning it back into a simple product.
+func (s *variantService) DeleteVariant(variant *models.ProductVariant) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("variant_id = ?", variant.ID).Delete(&models.Cart{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Delete(variant).Error; err != nil {
+			return err
+		}
+
+		var remaining int64
+		if err := tx.Model(&models.ProductVariant{}).Where("product_id = ?", variant.ProductID).Count(&remaining).Error; err != nil {
+			return err
+		}
+		if remaining > 0 {
+			return nil
+		}
+
+		var optionIDs []uint
+		if err := tx.Model(&models.ProductOption{}).Where("product_id = ?", variant.ProductID).Pluck("id", &optionIDs).Error; err != nil {
+			return err
+		}
+		if len(optionIDs) == 0 {
+			return nil
+		}
+		if err := tx.Where("option_id IN ?", optionIDs).Delete(&models.ProductOptionValue{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("id IN ?", optionIDs).Delete(&models.ProductOption{}).Error
+	})
+}
+
+// ResolveVariant loads a product and the selected variant. Products with variants require
+// one to be selected; simple products must be ordered without one. Drafts cannot be bought.
+func ResolveVariant(database *gorm.DB, productID uint, variantID *uint) (*models.Product, *models.ProductVariant, error) {
+	var product models.Product
+	if err := database.First(&product, productID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, ErrProductNotFound
+		}
+		return nil, nil, err
+	}
+	if !product.IsPublished() {
+		return nil, nil, ErrProductNotPublished
+	}
+
+	if variantID == nil {
+		var count int64
+		if err := database.Model(&models.ProductVariant{}).Where("product_id = ?", productID).Count(&count).Error; err != nil {
+			return nil, nil, err
+		}
+		if count > 0 {
+			return nil, nil, ErrVariantRequired
+		}
+		return &product, nil, nil
+	}
+
+	var variant models.ProductVariant
+	if err := database.Where("id = ? AND product_id = ?", *variantID, productID).First(&variant).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, ErrVariantNotFound
+		}
+		return nil, nil, err
+	}
+	return &product, &variant, nil
+}
+
+// AvailableStock returns the stock of a variant, of a simple product's inventory row, or
+// for a bundle the number of complete bundles its components' stock makes up
+func AvailableStock(database *gorm.DB, productID uint, variantID *uint) (int, error) {
+	product, variant, err := ResolveVariant(database, productID, variantID)
+	if err != nil {
+		return 0, err
+	}
+	if variant != nil {
+		return variant.Stock, nil
+	}
+	if product.IsDigital() {
+		return digitalStock(database, productID)
+	}
+	if product.IsBundle() {
+		items, err := bundleComponents(database, productID)
+		if err != nil {
+			return 0, err
+		}
+		return bundleStock(items), nil
+	}
+
+	var inventory models.Inventory
+	if err := database.Where("product_id = ?", productID).First(&inventory).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return 0, ErrProductNotFound
+		}
+		return 0, err
+	}
+	return inventory.Stock, nil
+}
+
+// ensureSKUAvailable rejects empty SKUs and SKUs held by another variant, including deleted ones
+func ensureSKUAvailable(tx *gorm.DB, sku string, variantID uint) error {
+	if sku == "" {
+		return ErrSKURequired
+	}
+	var count int64
+	if err := tx.Unscoped().Model(&models.ProductVariant{}).Where("sku = ? AND id <> ?", sku, variantID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrDuplicateSKU
+	}
+	return nil
+}
+
+// ensureUniqueCombination rejects a set of option values already used by another variant of the product
+func ensureUniqueCombination(tx *gorm.DB, productID uint, values []models.ProductOptionValue) error {
+	var variants []models.ProductVariant
+	if err := tx.Preload("OptionValues").Where("product_id = ?", productID).Find(&variants).Error; err != nil {
+		return err
+	}
+
+	wanted := make(map[uint]bool, len(values))
+	for _, v := range values {
+		wanted[v.ID] = true
+	}
+	for _, variant := range variants {
+		if len(variant.OptionValues) != len(wanted) {
+			continue
+		}
+		same := true
+		for _, v := range variant.OptionValues {
+			if !wanted[v.ID] {
+				same = false
+				break
+			}
+		}
+		if same {
+			return ErrDuplicateVariant
+		}
+	}
+	return nil
+}
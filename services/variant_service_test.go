@@ -0,0 +1,121 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/geoo115/Ecommerce/db"
+	"github.com/geoo115/Ecommerce/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVariantService_CreateVariant(t *testing.T) {
+	originalDB := db.DB
+	testDB := db.SetupTestDB(t)
+	db.DB = testDB
+	defer func() { db.DB = originalDB }()
+
+	product := createStockedProduct(t, "T-Shirt", 15, 0)
+	service := NewVariantService()
+
+	first := models.ProductVariant{SKU: "TS-M-RED", Stock: 4}
+	err := service.CreateVariant(&product, &first, []VariantOption{{Name: "Size", Value: "M"}, {Name: "Colour", Value: "Red"}})
+	assert.NoError(t, err)
+	assert.Equal(t, "M / Red", first.Title)
+
+	// Option names match case-insensitively and the title follows the product's option order
+	price := 18.0
+	second := models.ProductVariant{SKU: "TS-L-RED", Stock: 2, PriceOverride: &price}
+	err = service.CreateVariant(&product, &second, []VariantOption{{Name: "colour", Value: "red"}, {Name: "Size", Value: "L"}})
+	assert.NoError(t, err)
+	assert.Equal(t, "L / Red", second.Title)
+
+	var options []models.ProductOption
+	testDB.Preload("Values").Where("product_id = ?", product.ID).Order("position").Find(&options)
+	if assert.Len(t, options, 2) {
+		assert.Equal(t, "Size", options[0].Name)
+		assert.Len(t, options[0].Values, 2)
+		assert.Len(t, options[1].Values, 1)
+	}
+
+	duplicate := models.ProductVariant{SKU: "TS-M-RED-2"}
+	err = service.CreateVariant(&product, &duplicate, []VariantOption{{Name: "Size", Value: "m"}, {Name: "Colour", Value: "Red"}})
+	assert.ErrorIs(t, err, ErrDuplicateVariant)
+
+	sameSKU := models.ProductVariant{SKU: "TS-M-RED"}
+	err = service.CreateVariant(&product, &sameSKU, []VariantOption{{Name: "Size", Value: "S"}, {Name: "Colour", Value: "Red"}})
+	assert.ErrorIs(t, err, ErrDuplicateSKU)
+
+	missing := models.ProductVariant{SKU: "TS-S"}
+	err = service.CreateVariant(&product, &missing, []VariantOption{{Name: "Size", Value: "S"}})
+	assert.ErrorIs(t, err, ErrVariantOptions)
+}
+
+func TestVariantService_OrdersUseVariantPriceAndStock(t *testing.T) {
+	originalDB := db.DB
+	testDB := db.SetupTestDB(t)
+	db.DB = testDB
+	defer func() { db.DB = originalDB }()
+
+	user := models.User{Username: "variantbuyer", Email: "variantbuyer@example.com"}
+	testDB.Create(&user)
+	product := createStockedProduct(t, "Hoodie", 30, 100)
+	price := 35.0
+	large := models.ProductVariant{SKU: "HD-L", Stock: 3, PriceOverride: &price}
+	assert.NoError(t, NewVariantService().CreateVariant(&product, &large, []VariantOption{{Name: "Size", Value: "L"}}))
+
+	orders := NewOrderService()
+
+	// Products with variants cannot be ordered without choosing one
+	err := orders.CreateOrder(&models.Order{UserID: user.ID}, []OrderLine{{ProductID: product.ID, Quantity: 1}})
+	assert.ErrorIs(t, err, ErrVariantRequired)
+
+	order := models.Order{UserID: user.ID}
+	err = orders.CreateOrder(&order, []OrderLine{{ProductID: product.ID, VariantID: &large.ID, Quantity: 2}})
+	assert.NoError(t, err)
+	assert.Equal(t, 70.0, order.TotalAmount)
+	assert.Equal(t, "HD-L", order.Items[0].SKU)
+
+	var reloaded models.ProductVariant
+	testDB.First(&reloaded, large.ID)
+	assert.Equal(t, 1, reloaded.Stock)
+
+	// The product's own inventory row is untouched
+	var inventory models.Inventory
+	testDB.Where("product_id = ?", product.ID).First(&inventory)
+	assert.Equal(t, 100, inventory.Stock)
+
+	err = orders.CreateOrder(&models.Order{UserID: user.ID}, []OrderLine{{ProductID: product.ID, VariantID: &large.ID, Quantity: 2}})
+	assert.ErrorIs(t, err, ErrInsufficientStock)
+
+	assert.NoError(t, orders.CancelOrder(&order))
+	testDB.First(&reloaded, large.ID)
+	assert.Equal(t, 3, reloaded.Stock)
+}
+
+func TestVariantService_DeleteLastVariant(t *testing.T) {
+	originalDB := db.DB
+	testDB := db.SetupTestDB(t)
+	db.DB = testDB
+	defer func() { db.DB = originalDB }()
+
+	user := models.User{Username: "variantcart", Email: "variantcart@example.com"}
+	testDB.Create(&user)
+	product := createStockedProduct(t, "Cap", 10, 5)
+	service := NewVariantService()
+	variant := models.ProductVariant{SKU: "CAP-BLUE", Stock: 5}
+	assert.NoError(t, service.CreateVariant(&product, &variant, []VariantOption{{Name: "Colour", Value: "Blue"}}))
+	testDB.Create(&models.Cart{UserID: user.ID, ProductID: product.ID, VariantID: &variant.ID, Quantity: 1})
+
+	assert.NoError(t, service.DeleteVariant(&variant))
+
+	var carts, options int64
+	testDB.Model(&models.Cart{}).Where("user_id = ?", user.ID).Count(&carts)
+	testDB.Model(&models.ProductOption{}).Where("product_id = ?", product.ID).Count(&options)
+	assert.Zero(t, carts)
+	assert.Zero(t, options)
+
+	// The product is sold as a simple product again
+	stock, err := AvailableStock(testDB, product.ID, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 5, stock)
+}
@@ -2,7 +2,10 @@ package utils
 
 import (
 	"errors"
+	"fmt"
 	"net/http"
+	"net/url"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 )
@@ -15,11 +18,21 @@ var (
 
 // APIResponse represents a standardized API response structure
 type APIResponse struct {
-	Success bool        `json:"success"`
-	Message string      `json:"message,omitempty"`
-	Data    interface{} `json:"data,omitempty"`
-	Error   string      `json:"error,omitempty"`
-	Code    int         `json:"code,omitempty"`
+	Success    bool        `json:"success"`
+	Message    string      `json:"message,omitempty"`
+	Data       interface{} `json:"data,omitempty"`
+	Pagination *Pagination `json:"pagination,omitempty"`
+	Error      string      `json:"error,omitempty"`
+	Code       int         `json:"code,omitempty"`
+}
+
+// Pagination describes one page of a list response. NextCursor and PrevCursor are opaque and
+// passed back as the cursor query parameter; they are empty on the last and first pages.
+type Pagination struct {
+	Limit      int    `json:"limit"`
+	NextCursor string `json:"next_cursor,omitempty"`
+	PrevCursor string `json:"prev_cursor,omitempty"`
+	Total      *int64 `json:"total,omitempty"` // Only when requested with include_total=true
 }
 
 // SendSuccess sends a successful response
@@ -32,6 +45,43 @@ func SendSuccess(c *gin.Context, status int, message string, data interface{}) {
 	})
 }
 
+// SendPage sends one page of a list with its pagination details and RFC 8288 Link headers
+// pointing to the first, next and previous pages
+func SendPage(c *gin.Context, message string, data interface{}, pagination *Pagination) {
+	SetLinkHeader(c, pagination)
+	c.JSON(http.StatusOK, APIResponse{
+		Success:    true,
+		Message:    message,
+		Data:       data,
+		Pagination: pagination,
+		Code:       http.StatusOK,
+	})
+}
+
+// SetLinkHeader sets the Link header for a page, for handlers with their own response shape.
+// Links keep the request's other query parameters.
+func SetLinkHeader(c *gin.Context, pagination *Pagination) {
+	link := func(cursor, rel string) string {
+		query := c.Request.URL.Query()
+		query.Del("cursor")
+		query.Del("page")
+		if cursor != "" {
+			query.Set("cursor", cursor)
+		}
+		target := url.URL{Path: c.Request.URL.Path, RawQuery: query.Encode()}
+		return fmt.Sprintf("<%s>; rel=\"%s\"", target.String(), rel)
+	}
+
+	links := []string{link("", "first")}
+	if pagination.NextCursor != "" {
+		links = append(links, link(pagination.NextCursor, "next"))
+	}
+	if pagination.PrevCursor != "" {
+		links = append(links, link(pagination.PrevCursor, "prev"))
+	}
+	c.Header("Link", strings.Join(links, ", "))
+}
+
 // SendError sends an error response
 func SendError(c *gin.Context, status int, message string) {
 	c.JSON(status, APIResponse{
@@ -4,6 +4,8 @@ import (
 	"regexp"
 	"strings"
 	"unicode"
+
+	"github.com/geoo115/Ecommerce/models"
 )
 
 // ValidateEmail checks if the email format is valid
@@ -140,3 +142,8 @@ func SanitizeString(input string) string {
 
 	return strings.TrimSpace(input)
 }
+
+// ValidateSlug checks a slug contains only lowercase letters, digits and single hyphens
+func ValidateSlug(slug string) bool {
+	return len(slug) > 0 && len(slug) <= 128 && models.Slugify(slug) == slug
+}
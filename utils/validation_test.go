@@ -476,3 +476,10 @@ func BenchmarkSanitizeString(b *testing.B) {
 		SanitizeString(input)
 	}
 }
+
+func TestValidateSlug(t *testing.T) {
+	assert.True(t, ValidateSlug("home-garden"))
+	assert.False(t, ValidateSlug("Home Garden"))
+	assert.False(t, ValidateSlug("-home"))
+	assert.False(t, ValidateSlug(""))
+}